@@ -0,0 +1,195 @@
+// Package fake_json provides a fake of the go-interfaces
+// encoding/json.Decoder interface that decodes a scripted stream.
+//
+// Unlike mock_json.MockDecoder, which needs an expectation for every
+// Decode, More, Token, Buffered and InputOffset call, a Decoder is
+// built from the values or raw JSON the code under test should read,
+// and answers every call as a real decoder reading that stream would.
+// Errors are injected at a token in the stream, so they surface from
+// whichever of Decode, More or Token reaches it first:
+//
+//	dec := fake_json.FromValues([]any{
+//		map[string]int{"id": 1},
+//		map[string]int{"id": 2},
+//		map[string]int{"id": 3},
+//	}, fake_json.UnexpectedEOFAt(9))
+//
+// Here the first two records decode, and the third, whose opening
+// brace is token 8 and whose "id" key is token 9, fails with
+// io.ErrUnexpectedEOF.
+package fake_json
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	jsoni "github.com/pdutton/go-interfaces/encoding/json"
+)
+
+// Option configures a Decoder created by FromValues or FromJSON.
+type Option func(*config)
+
+type config struct {
+	decoderOptions []jsoni.DecoderOption
+	fault          *fault
+}
+
+// fault is an error injected at a token of the stream.
+type fault struct {
+	token  int
+	syntax bool
+}
+
+// WithDecoderOptions applies go-interfaces decoder options, such as
+// jsoni.WithUseNumber, to the decoder.
+func WithDecoderOptions(options ...jsoni.DecoderOption) Option {
+	return func(c *config) {
+		c.decoderOptions = append(c.decoderOptions, options...)
+	}
+}
+
+// SyntaxErrorAt puts an invalid character in the stream in front of
+// the token with index token, counting from zero every token Token
+// would return: delimiters, object keys and scalar values.  Reaching
+// it returns a *json.SyntaxError whose Offset, as encoding/json
+// reports it, is just past the invalid character.  An index past the
+// last token puts it at the end of the stream.  It replaces an earlier
+// SyntaxErrorAt or UnexpectedEOFAt.
+func SyntaxErrorAt(token int) Option {
+	return func(c *config) {
+		c.fault = &fault{token: token, syntax: true}
+	}
+}
+
+// UnexpectedEOFAt cuts the stream off in front of the token with index
+// token, counted as for SyntaxErrorAt, so that reaching it returns
+// io.ErrUnexpectedEOF.  It replaces an earlier SyntaxErrorAt or
+// UnexpectedEOFAt.
+func UnexpectedEOFAt(token int) Option {
+	return func(c *config) {
+		c.fault = &fault{token: token}
+	}
+}
+
+// Decoder implements the go-interfaces encoding/json.Decoder interface
+// by running a real decoder over a scripted stream, so that Decode,
+// More, Token, Buffered and InputOffset agree with each other exactly
+// as they do in production.
+type Decoder struct {
+	dec  *json.Decoder
+	data []byte
+}
+
+// FromValues returns a Decoder reading values, marshaled one per line
+// as an Encoder writes them.  It panics if a value cannot be
+// marshaled.
+func FromValues(values []any, options ...Option) *Decoder {
+	var buf bytes.Buffer
+	var enc = json.NewEncoder(&buf)
+	for _, v := range values {
+		if err := enc.Encode(v); err != nil {
+			panic("fake_json: cannot marshal value: " + err.Error())
+		}
+	}
+
+	return FromJSON(buf.Bytes(), options...)
+}
+
+// FromJSON returns a Decoder reading data, which may hold any number
+// of JSON values and need not be valid.
+func FromJSON(data []byte, options ...Option) *Decoder {
+	var c config
+	for _, f := range options {
+		f(&c)
+	}
+
+	data = bytes.Clone(data)
+	var r io.Reader = bytes.NewReader(data)
+	if c.fault != nil {
+		var off = tokenOffset(data, c.fault.token)
+		if c.fault.syntax {
+			data = append(data[:off:off], append([]byte{'?'}, data[off:]...)...)
+			r = bytes.NewReader(data)
+		} else {
+			data = data[:off]
+			r = truncated{bytes.NewReader(data)}
+		}
+	}
+
+	var dec = json.NewDecoder(r)
+	for _, f := range c.decoderOptions {
+		f(dec)
+	}
+
+	return &Decoder{dec: dec, data: data}
+}
+
+// tokenOffset returns the offset in data at which the token with the
+// given index starts, or the length of data if it has fewer tokens.
+func tokenOffset(data []byte, token int) int {
+	var (
+		dec = json.NewDecoder(bytes.NewReader(data))
+		off int
+	)
+	for i := 0; i < token; i++ {
+		if _, err := dec.Token(); err != nil {
+			return len(data)
+		}
+		off = int(dec.InputOffset())
+	}
+
+	// Skip the separators the decoder has not consumed.
+	for off < len(data) && bytes.IndexByte([]byte(" \t\r\n,:"), data[off]) >= 0 {
+		off++
+	}
+
+	return off
+}
+
+// truncated is a reader whose data ends unexpectedly.
+type truncated struct {
+	r io.Reader
+}
+
+func (t truncated) Read(p []byte) (int, error) {
+	var n, err = t.r.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+// Stream returns the bytes the decoder reads, including any injected
+// fault.
+func (d *Decoder) Stream() []byte {
+	return bytes.Clone(d.data)
+}
+
+func (d *Decoder) Decode(v any) error {
+	return d.dec.Decode(v)
+}
+
+func (d *Decoder) Buffered() io.Reader {
+	return d.dec.Buffered()
+}
+
+func (d *Decoder) InputOffset() int64 {
+	return d.dec.InputOffset()
+}
+
+func (d *Decoder) More() bool {
+	return d.dec.More()
+}
+
+func (d *Decoder) Token() (jsoni.Token, error) {
+	return d.dec.Token()
+}
+
+// Nub returns the real decoder reading the stream.
+func (d *Decoder) Nub() *json.Decoder {
+	return d.dec
+}
+
+var _ jsoni.Decoder = (*Decoder)(nil)
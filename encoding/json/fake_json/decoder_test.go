@@ -0,0 +1,114 @@
+package fake_json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	jsoni "github.com/pdutton/go-interfaces/encoding/json"
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+type record struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// TestFromValues tests decoding a stream of values until io.EOF.
+func TestFromValues(t *testing.T) {
+	dec := FromValues([]any{record{1, "a"}, record{2, "b"}})
+
+	var got []record
+	for {
+		var r record
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			break
+		}
+		testutil.AssertNil(t, err)
+		got = append(got, r)
+	}
+	testutil.AssertEqual(t, 2, len(got))
+	testutil.AssertEqual(t, "b", got[1].Name)
+	testutil.AssertEqual(t, int64(len(dec.Stream())-1), dec.InputOffset())
+}
+
+// TestFromJSON_Tokens tests that Token, More, Decode and InputOffset
+// agree with a real decoder reading the same stream.
+func TestFromJSON_Tokens(t *testing.T) {
+	data := []byte(` [ {"id": 1}, {"id": 2, "name": "x"} ] `)
+	dec := FromJSON(data)
+	std := jsoni.NewDecoder(bytes.NewReader(data))
+
+	step := func(d jsoni.Decoder) string {
+		tok, err := d.Token()
+		testutil.AssertNil(t, err)
+		return fmtToken(tok, d.More(), d.InputOffset())
+	}
+
+	testutil.AssertEqual(t, step(std), step(dec))
+	for dec.More() {
+		var want, got record
+		testutil.AssertNil(t, std.Decode(&want))
+		testutil.AssertNil(t, dec.Decode(&got))
+		testutil.AssertEqual(t, want, got)
+		testutil.AssertEqual(t, std.InputOffset(), dec.InputOffset())
+	}
+	testutil.AssertEqual(t, step(std), step(dec))
+
+	_, err := dec.Token()
+	testutil.AssertEqual(t, io.EOF, err)
+}
+
+func fmtToken(tok jsoni.Token, more bool, off int64) string {
+	data, _ := json.Marshal([]any{tok, more, off})
+	return string(data)
+}
+
+// TestSyntaxErrorAt tests a syntax error reached by Token.
+func TestSyntaxErrorAt(t *testing.T) {
+	dec := FromJSON([]byte(`[1, 2, 3]`), SyntaxErrorAt(3))
+
+	for i := 0; i < 3; i++ {
+		_, err := dec.Token()
+		testutil.AssertNil(t, err)
+	}
+	_, err := dec.Token()
+	var serr *json.SyntaxError
+	testutil.AssertEqual(t, true, errors.As(err, &serr))
+	testutil.AssertEqual(t, int64(8), serr.Offset)
+	testutil.AssertEqual(t, "invalid character '?' looking for beginning of value", err.Error())
+	testutil.AssertEqual(t, `[1, 2, ?3]`, string(dec.Stream()))
+}
+
+// TestUnexpectedEOFAt tests a stream cut off part way through a value
+// reached by Decode.
+func TestUnexpectedEOFAt(t *testing.T) {
+	dec := FromValues([]any{record{1, "a"}, record{2, "b"}}, UnexpectedEOFAt(6))
+
+	var r record
+	testutil.AssertNil(t, dec.Decode(&r))
+	testutil.AssertError(t, io.ErrUnexpectedEOF, dec.Decode(&r))
+
+	// At a boundary between values, the stream still ends early.
+	dec = FromValues([]any{1, 2}, UnexpectedEOFAt(1))
+	var n int
+	testutil.AssertNil(t, dec.Decode(&n))
+	testutil.AssertError(t, io.ErrUnexpectedEOF, dec.Decode(&n))
+}
+
+// TestWithDecoderOptions tests passing go-interfaces decoder options.
+func TestWithDecoderOptions(t *testing.T) {
+	dec := FromJSON([]byte(`{"id": 1, "extra": true}`),
+		WithDecoderOptions(jsoni.WithDisallowUnknownFields(), jsoni.WithUseNumber()))
+
+	var r record
+	testutil.AssertNotNil(t, dec.Decode(&r))
+
+	dec = FromJSON([]byte(`12.50`), WithDecoderOptions(jsoni.WithUseNumber()))
+	var v any
+	testutil.AssertNil(t, dec.Decode(&v))
+	testutil.AssertEqual(t, jsoni.Number("12.50"), v.(jsoni.Number))
+}
@@ -0,0 +1,94 @@
+package fixture
+
+import (
+	"strings"
+)
+
+// context is the number of unchanged lines shown either side of a
+// change.
+const context = 3
+
+// diff returns a line diff turning want into got, or "" if they are the
+// same.  Runs of unchanged lines far from any change are elided.
+func diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+
+	var a = lines(want)
+	var b = lines(got)
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	var lcs = make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	var i, j int
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, " "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "-"+a[i])
+			i++
+		default:
+			out = append(out, "+"+b[j])
+			j++
+		}
+	}
+
+	return elide(out)
+}
+
+// lines splits s after each newline.
+func lines(s string) []string {
+	var l = strings.SplitAfter(s, "\n")
+	if l[len(l)-1] == "" {
+		l = l[:len(l)-1]
+	}
+
+	return l
+}
+
+// elide joins lines, replacing the unchanged ones more than context
+// lines from a change with "...".
+func elide(lines []string) string {
+	var near = make([]bool, len(lines))
+	for i, l := range lines {
+		if l[0] == ' ' {
+			continue
+		}
+		for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+			near[k] = true
+		}
+	}
+
+	var sb strings.Builder
+	for i, l := range lines {
+		switch {
+		case near[i]:
+			sb.WriteString(l)
+			if !strings.HasSuffix(l, "\n") {
+				sb.WriteString("\n")
+			}
+		case i == 0 || near[i-1]:
+			sb.WriteString("...\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,239 @@
+// Package fixture loads filesystem fixtures, written as txtar archives
+// or testdata directories, into fakes for the go-interfaces os,
+// io/fs and path/filepath packages that share a single tree.
+//
+// A test describes its starting tree inline, runs the code under test
+// against whichever fakes it needs, and compares the tree it is left
+// with against the one expected:
+//
+//	fx, err := fixture.Parse([]byte(`
+//	-- config.json --
+//	{"debug": false}
+//	-- logs/ --
+//	`))
+//
+//	err = Rotate(fx.OS, fx.FilePath)
+//
+//	if diff := fx.Diff([]byte(`
+//	-- config.json --
+//	{"debug": false}
+//	-- logs/app.log.1 --
+//	`)); diff != "" {
+//		t.Errorf("tree differs (-want +got):\n%s", diff)
+//	}
+//
+// File names in an archive are relative to the fixture's directory,
+// which is also the working directory of its OS.  A name ending in a
+// slash is an empty directory.
+package fixture
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pdutton/go-mocks/internal/txtar"
+	"github.com/pdutton/go-mocks/io/fs/fake_fs"
+	"github.com/pdutton/go-mocks/os/fake_os"
+	"github.com/pdutton/go-mocks/path/filepath/fake_filepath"
+)
+
+// DefaultDir is the directory a fixture is loaded into, unless the
+// WithDir option is used.
+const DefaultDir = "/work"
+
+// Option configures a Fixture created by Parse or Load.
+type Option func(*config)
+
+type config struct {
+	dir       string
+	osOptions []fake_os.Option
+}
+
+// WithDir sets the absolute directory the tree is loaded into.
+func WithDir(dir string) Option {
+	return func(c *config) {
+		c.dir = path.Clean(dir)
+	}
+}
+
+// WithOSOptions passes options to fake_os.New when the OS is made.
+// They are applied after the working directory is set to the
+// fixture's directory, so they can override it.
+func WithOSOptions(options ...fake_os.Option) Option {
+	return func(c *config) {
+		c.osOptions = append(c.osOptions, options...)
+	}
+}
+
+// Fixture is a tree of files loaded into fakes.  Changes made through
+// any of them are seen by the others.
+type Fixture struct {
+	// Dir is the absolute directory holding the tree.
+	Dir string
+
+	// OS holds the tree, and has Dir as its working directory.
+	OS *fake_os.OS
+
+	// FS is an io/fs view of the tree rooted at Dir.  It is also a
+	// go-interfaces io/fs.FileSystem, and can inject faults.
+	FS *fake_fs.FileSystem
+
+	// FilePath runs Walk, WalkDir, Glob, Abs and EvalSymlinks against
+	// OS.
+	FilePath *fake_filepath.FilePath
+}
+
+func newFixture(options []Option) *Fixture {
+	var c = config{dir: DefaultDir}
+	for _, opt := range options {
+		opt(&c)
+	}
+
+	var fos = fake_os.New(append([]fake_os.Option{fake_os.WithWorkingDir(c.dir)}, c.osOptions...)...)
+
+	return &Fixture{
+		Dir:      c.dir,
+		OS:       fos,
+		FS:       fake_fs.New(fos.DirFS(c.dir)),
+		FilePath: fake_filepath.New(fos),
+	}
+}
+
+// Parse returns a Fixture holding the files of a txtar archive.
+func Parse(data []byte, options ...Option) (*Fixture, error) {
+	var fx = newFixture(options)
+
+	for _, f := range txtar.Parse(data).Files {
+		if err := fx.add(f.Name, f.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return fx, nil
+}
+
+// Load returns a Fixture holding the files of the txtar archive in the
+// named file, or, if name is a directory, a copy of the directory's
+// tree.  The name is read from the real filesystem, typically from a
+// testdata directory.
+func Load(name string, options ...Option) (*Fixture, error) {
+	var info, err = os.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("fixture: %w", err)
+	}
+
+	if !info.IsDir() {
+		var data, err = os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("fixture: %w", err)
+		}
+		return Parse(data, options...)
+	}
+
+	var fx = newFixture(options)
+	if err := fx.OS.CopyFS(fx.Dir, os.DirFS(name)); err != nil {
+		return nil, fmt.Errorf("fixture: %w", err)
+	}
+
+	return fx, nil
+}
+
+// add creates the file or, for a name ending in a slash, the directory
+// name in the tree.
+func (fx *Fixture) add(name string, data []byte) error {
+	var dir, isDir = strings.CutSuffix(name, "/")
+	if !fs.ValidPath(dir) || dir == "." {
+		return fmt.Errorf("fixture: invalid file name %q", name)
+	}
+
+	var full = path.Join(fx.Dir, dir)
+	if isDir {
+		if err := fx.OS.MkdirAll(full, 0o755); err != nil {
+			return fmt.Errorf("fixture: %w", err)
+		}
+		return nil
+	}
+
+	if err := fx.OS.MkdirAll(path.Dir(full), 0o755); err != nil {
+		return fmt.Errorf("fixture: %w", err)
+	}
+	if err := fx.OS.WriteFile(full, data, 0o644); err != nil {
+		return fmt.Errorf("fixture: %w", err)
+	}
+
+	return nil
+}
+
+// Txtar returns the tree as it is now, as a txtar archive with its
+// files sorted by name.  Empty directories are listed with a trailing
+// slash.  Symbolic links and file modes are not recorded.
+func (fx *Fixture) Txtar() ([]byte, error) {
+	var a, err = fx.archive()
+	if err != nil {
+		return nil, err
+	}
+
+	return txtar.Format(a), nil
+}
+
+func (fx *Fixture) archive() (*txtar.Archive, error) {
+	var fsys = fx.OS.DirFS(fx.Dir)
+	var a = &txtar.Archive{}
+
+	var err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			var entries, err = fs.ReadDir(fsys, name)
+			if err == nil && len(entries) == 0 && name != "." {
+				a.Files = append(a.Files, txtar.File{Name: name + "/"})
+			}
+			return err
+
+		case d.Type().IsRegular():
+			var data, err = fs.ReadFile(fsys, name)
+			a.Files = append(a.Files, txtar.File{Name: name, Data: data})
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fixture: %w", err)
+	}
+
+	sortFiles(a)
+
+	return a, nil
+}
+
+func sortFiles(a *txtar.Archive) {
+	sort.SliceStable(a.Files, func(i, j int) bool {
+		return a.Files[i].Name < a.Files[j].Name
+	})
+}
+
+// Diff compares the tree with the txtar archive want, ignoring its
+// comment and the order of its files.  It returns the differences as
+// lines of the two archives prefixed with "-" for want and "+" for the
+// tree, or "" if they are the same.  If the tree cannot be read, Diff
+// returns the error's text instead.
+func (fx *Fixture) Diff(want []byte) string {
+	var got, err = fx.archive()
+	if err != nil {
+		return err.Error()
+	}
+
+	var w = txtar.Parse(want)
+	w.Comment = nil
+	sortFiles(w)
+
+	return diff(string(txtar.Format(w)), string(txtar.Format(got)))
+}
@@ -0,0 +1,145 @@
+package fixture
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+	"github.com/pdutton/go-mocks/os/fake_os"
+)
+
+const tree = `-- mod.txt --
+module example
+-- src/main.go --
+package main
+`
+
+// TestParse tests that every fake sees the loaded tree.
+func TestParse(t *testing.T) {
+	fx, err := Parse([]byte("A comment.\n" + tree + "-- empty/ --\n"))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/work", fx.Dir)
+
+	data, err := fx.OS.ReadFile("src/main.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "package main\n", string(data))
+
+	wd, _ := fx.OS.Getwd()
+	testutil.AssertEqual(t, "/work", wd)
+
+	data, err = fs.ReadFile(fx.FS, "mod.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "module example\n", string(data))
+
+	matches, err := fx.FilePath.Glob("/work/*/*.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/work/src/main.go", strings.Join(matches, " "))
+
+	info, err := fx.OS.Stat("empty")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, info.IsDir())
+}
+
+// TestParse_Shared tests that changes through one fake are seen by the
+// others.
+func TestParse_Shared(t *testing.T) {
+	fx, err := Parse([]byte(tree), WithDir("/home/user/project/"))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/home/user/project", fx.Dir)
+
+	testutil.AssertNil(t, fx.OS.WriteFile("src/util.go", []byte("package main\n"), 0o644))
+	testutil.AssertNil(t, fx.OS.Symlink("src", "link"))
+
+	entries, err := fx.FS.ReadDir(fx.FS, "src")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 2, len(entries))
+
+	real, err := fx.FilePath.EvalSymlinks("link/util.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "src/util.go", real)
+
+	abs, err := fx.FilePath.Abs("link")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/home/user/project/link", abs)
+}
+
+// TestParse_Invalid tests file names that leave the tree.
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("-- ../escape --\n"))
+	testutil.AssertEqual(t, `fixture: invalid file name "../escape"`, err.Error())
+
+	_, err = Parse([]byte("-- / --\n"))
+	testutil.AssertNotNil(t, err)
+
+	_, err = Parse([]byte("-- a --\n-- a/b --\n"))
+	testutil.AssertNotNil(t, err)
+}
+
+// TestLoad tests loading an archive file and a directory.
+func TestLoad(t *testing.T) {
+	fromFile, err := Load("testdata/tree.txtar", WithOSOptions(fake_os.WithWorkingDir("/")))
+	testutil.AssertNil(t, err)
+	wd, _ := fromFile.OS.Getwd()
+	testutil.AssertEqual(t, "/", wd)
+
+	fromDir, err := Load("testdata/tree")
+	testutil.AssertNil(t, err)
+
+	testutil.AssertEqual(t, "", fromFile.Diff([]byte(tree)))
+	testutil.AssertEqual(t, "", fromDir.Diff([]byte(tree)))
+
+	_, err = Load("testdata/missing")
+	testutil.AssertError(t, fs.ErrNotExist, err)
+}
+
+// TestFixture_Txtar tests writing the tree out as an archive.
+func TestFixture_Txtar(t *testing.T) {
+	fx, err := Parse([]byte(tree))
+	testutil.AssertNil(t, err)
+
+	testutil.AssertNil(t, fx.OS.Mkdir("out", 0o755))
+	testutil.AssertNil(t, fx.OS.WriteFile("a.txt", []byte("no newline"), 0o644))
+	testutil.AssertNil(t, fx.OS.Symlink("a.txt", "link"))
+
+	data, err := fx.Txtar()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `-- a.txt --
+no newline
+-- mod.txt --
+module example
+-- out/ --
+-- src/main.go --
+package main
+`, string(data))
+}
+
+// TestFixture_Diff tests comparing the tree with an expected archive.
+func TestFixture_Diff(t *testing.T) {
+	fx, err := Parse([]byte(tree))
+	testutil.AssertNil(t, err)
+
+	// Order and comments do not matter.
+	testutil.AssertEqual(t, "", fx.Diff([]byte("comment\n-- src/main.go --\npackage main\n-- mod.txt --\nmodule example\n")))
+
+	testutil.AssertNil(t, fx.OS.WriteFile("src/main.go", []byte("package main\n\nfunc main() {}\n"), 0o644))
+	testutil.AssertNil(t, fx.OS.Remove("mod.txt"))
+
+	testutil.AssertEqual(t, `--- mod.txt --
+-module example
+ -- src/main.go --
+ package main
++
++func main() {}
+`, fx.Diff([]byte(tree)))
+}
+
+// TestDiff_Elide tests eliding lines far from a change.
+func TestDiff_Elide(t *testing.T) {
+	want := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	got := "1\n2\n3\n4\n5\nfive\n7\n8\n9\n10\n"
+
+	testutil.AssertEqual(t, "...\n 3\n 4\n 5\n-6\n+five\n 7\n 8\n 9\n...\n", diff(want, got))
+	testutil.AssertEqual(t, "", diff(want, want))
+	testutil.AssertEqual(t, "+a\n", diff("", "a\n"))
+}
@@ -0,0 +1,35 @@
+// Package pathpattern matches URL paths against the patterns accepted
+// by fake_client routes and mock_client request matchers, so that a
+// pattern means the same in both.
+//
+// A pattern is a path.Match pattern, in which "*" matches within one
+// path segment, optionally ending in "/**", which matches the rest of
+// the path: "/api/**" matches "/api/users/1" and "/api" itself.
+package pathpattern
+
+import (
+	"path"
+	"strings"
+)
+
+// Validate returns path.ErrBadPattern if pattern is malformed.
+func Validate(pattern string) error {
+	var _, err = path.Match(pattern, "")
+	return err
+}
+
+// Match reports whether p matches pattern.  A malformed pattern
+// matches nothing.
+func Match(pattern, p string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		for dir := p; dir != "/" && dir != "."; dir = path.Dir(dir) {
+			if ok, _ := path.Match(prefix, dir); ok {
+				return true
+			}
+		}
+		return prefix == ""
+	}
+
+	var ok, _ = path.Match(pattern, p)
+	return ok
+}
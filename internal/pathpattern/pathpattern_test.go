@@ -0,0 +1,42 @@
+package pathpattern
+
+import (
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestMatch tests exact, wildcard and trailing "/**" patterns.
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/users", "/users", true},
+		{"/users", "/users/", false},
+		{"/users/*", "/users/42", true},
+		{"/users/*", "/users/42/posts", false},
+		{"/users/*/posts", "/users/42/posts", true},
+		{"/files/*.txt", "/files/a.txt", true},
+		{"/api/**", "/api", true},
+		{"/api/**", "/api/v1/users", true},
+		{"/api/**", "/apiv1", false},
+		{"/api/*/**", "/api/v1/users/1", true},
+		{"/api/*/**", "/api", false},
+		{"/**", "/anything/at/all", true},
+		{"/**", "/", true},
+		{"/[", "/[", false},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestValidate tests rejecting a malformed pattern.
+func TestValidate(t *testing.T) {
+	testutil.AssertNil(t, Validate("/users/*"))
+	testutil.AssertNotNil(t, Validate("/users/["))
+}
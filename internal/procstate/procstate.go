@@ -0,0 +1,28 @@
+// Package procstate builds *os.ProcessState values for fake
+// processes.
+//
+// os.ProcessState has no exported constructor, yet it is what
+// exec.ExitError and Process.Wait hand back to callers.  The fakes
+// need genuine values so that ExitCode, Exited, Success, String and
+// Sys behave exactly as they do for a real child process, so this
+// package fills in the unexported fields through a struct with the
+// same layout.  Where the layout is not known the constructors return
+// nil, which the os package treats as a process that has not exited.
+// Where it is known but does not match, the package panics when it is
+// initialized, so the break is reported at once.
+package procstate
+
+import (
+	"os"
+)
+
+// Exited returns the state of process pid after it exited with code.
+func Exited(pid, code int) *os.ProcessState {
+	return exitState(pid, code)
+}
+
+// Signaled returns the state of process pid after it was terminated
+// by sig.
+func Signaled(pid int, sig os.Signal) *os.ProcessState {
+	return signalState(pid, sig)
+}
@@ -0,0 +1,54 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package procstate
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestCheckLayout tests that the mirrored layout matches the running
+// Go release.
+func TestCheckLayout(t *testing.T) {
+	testutil.AssertNil(t, checkLayout())
+}
+
+// TestExited tests the state of a process that exited normally.
+func TestExited(t *testing.T) {
+	ps := Exited(1234, 3)
+
+	testutil.AssertEqual(t, 1234, ps.Pid())
+	testutil.AssertEqual(t, 3, ps.ExitCode())
+	testutil.AssertEqual(t, true, ps.Exited())
+	testutil.AssertEqual(t, false, ps.Success())
+	testutil.AssertEqual(t, "exit status 3", ps.String())
+}
+
+// TestExited_Success tests a zero exit code.
+func TestExited_Success(t *testing.T) {
+	ps := Exited(1, 0)
+
+	testutil.AssertEqual(t, true, ps.Success())
+	testutil.AssertEqual(t, 0, ps.ExitCode())
+}
+
+// TestSignaled tests the state of a process killed by a signal.
+func TestSignaled(t *testing.T) {
+	ps := Signaled(1, syscall.SIGTERM)
+
+	testutil.AssertEqual(t, -1, ps.ExitCode())
+	testutil.AssertEqual(t, false, ps.Exited())
+	testutil.AssertEqual(t, syscall.SIGTERM, ps.Sys().(syscall.WaitStatus).Signal())
+	testutil.AssertEqual(t, "signal: terminated", ps.String())
+}
+
+// TestExitError tests that the state works inside an exec.ExitError.
+func TestExitError(t *testing.T) {
+	err := &exec.ExitError{ProcessState: Exited(1, 2)}
+
+	testutil.AssertEqual(t, 2, err.ExitCode())
+	testutil.AssertEqual(t, "exit status 2", err.Error())
+}
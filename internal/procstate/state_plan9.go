@@ -0,0 +1,15 @@
+//go:build plan9
+
+package procstate
+
+import (
+	"os"
+)
+
+func exitState(int, int) *os.ProcessState {
+	return nil
+}
+
+func signalState(int, os.Signal) *os.ProcessState {
+	return nil
+}
@@ -0,0 +1,77 @@
+//go:build !plan9
+
+package procstate
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// processState mirrors the layout of os.ProcessState.
+type processState struct {
+	pid    int
+	status syscall.WaitStatus
+	rusage *syscall.Rusage
+}
+
+func exitState(pid, code int) *os.ProcessState {
+	var status, ok = exitStatus(code)
+	if !ok {
+		return nil
+	}
+
+	return build(pid, status)
+}
+
+func signalState(pid int, sig os.Signal) *os.ProcessState {
+	var s, isSyscall = sig.(syscall.Signal)
+	if !isSyscall {
+		return nil
+	}
+
+	var status, ok = signalStatus(s)
+	if !ok {
+		return nil
+	}
+
+	return build(pid, status)
+}
+
+// A Go release that changes the layout fails every program using the
+// fakes as soon as it starts, rather than handing callers a state that
+// is nil or wrong.
+func init() {
+	if err := checkLayout(); err != nil {
+		panic(err)
+	}
+}
+
+// checkLayout returns an error if processState does not match
+// os.ProcessState.
+func checkLayout() error {
+	if unsafe.Sizeof(processState{}) != unsafe.Sizeof(os.ProcessState{}) {
+		return errors.New("procstate: os.ProcessState has changed size; update processState to match")
+	}
+
+	// A layout that changed without changing size shows up here.
+	var status, _ = exitStatus(3)
+	var ps = build(4242, status)
+	if ps.Pid() != 4242 || ps.Sys() != any(status) {
+		return errors.New("procstate: os.ProcessState has changed layout; update processState to match")
+	}
+
+	return nil
+}
+
+func build(pid int, status syscall.WaitStatus) *os.ProcessState {
+	var ps = &os.ProcessState{}
+	*(*processState)(unsafe.Pointer(ps)) = processState{
+		pid:    pid,
+		status: status,
+		rusage: &syscall.Rusage{},
+	}
+
+	return ps
+}
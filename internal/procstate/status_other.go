@@ -0,0 +1,17 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd || windows || plan9)
+
+package procstate
+
+import (
+	"syscall"
+)
+
+func exitStatus(int) (syscall.WaitStatus, bool) {
+	var zero syscall.WaitStatus
+	return zero, false
+}
+
+func signalStatus(syscall.Signal) (syscall.WaitStatus, bool) {
+	var zero syscall.WaitStatus
+	return zero, false
+}
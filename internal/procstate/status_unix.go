@@ -0,0 +1,18 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package procstate
+
+import (
+	"syscall"
+)
+
+// The traditional wait(2) encoding: the exit code lives in bits
+// 8-15 and a terminating signal in the low seven bits.
+
+func exitStatus(code int) (syscall.WaitStatus, bool) {
+	return syscall.WaitStatus((code & 0xff) << 8), true
+}
+
+func signalStatus(sig syscall.Signal) (syscall.WaitStatus, bool) {
+	return syscall.WaitStatus(sig & 0x7f), true
+}
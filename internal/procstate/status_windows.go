@@ -0,0 +1,17 @@
+//go:build windows
+
+package procstate
+
+import (
+	"syscall"
+)
+
+func exitStatus(code int) (syscall.WaitStatus, bool) {
+	return syscall.WaitStatus{ExitCode: uint32(code)}, true
+}
+
+// Windows has no terminating signals; a killed process exits with
+// status 1, which is what os.Process.Kill produces.
+func signalStatus(sig syscall.Signal) (syscall.WaitStatus, bool) {
+	return syscall.WaitStatus{ExitCode: 1}, true
+}
@@ -0,0 +1,115 @@
+// Package txtar reads and writes the txtar archive format used by the
+// Go project's tests.
+//
+// An archive is an optional comment followed by a sequence of files,
+// each introduced by a "-- name --" marker line:
+//
+//	Comment text.
+//	-- hello.txt --
+//	Hello, world.
+//	-- dir/empty/ --
+//
+// This is the format of golang.org/x/tools/txtar, reimplemented here
+// so the fakes can load test trees without another dependency.
+package txtar
+
+import (
+	"bytes"
+	"strings"
+)
+
+var (
+	markerStart = []byte("-- ")
+	markerEnd   = []byte(" --")
+)
+
+// Archive is a collection of files.
+type Archive struct {
+	Comment []byte
+	Files   []File
+}
+
+// File is a single file in an archive.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Parse parses data as an archive.  Any text is valid: lines before the
+// first marker are the comment, and a final line with no newline is
+// given one.
+func Parse(data []byte) *Archive {
+	var a = &Archive{}
+
+	var name string
+	a.Comment, name, data = findMarker(data)
+	for name != "" {
+		var f = File{Name: name}
+		f.Data, name, data = findMarker(data)
+		a.Files = append(a.Files, f)
+	}
+
+	return a
+}
+
+// Format returns the archive in txtar form.  Data that does not end in
+// a newline is given one, as Parse would on reading it back.
+func Format(a *Archive) []byte {
+	var buf bytes.Buffer
+
+	buf.Write(fixNL(a.Comment))
+	for _, f := range a.Files {
+		buf.WriteString("-- " + f.Name + " --\n")
+		buf.Write(fixNL(f.Data))
+	}
+
+	return buf.Bytes()
+}
+
+// findMarker returns the text before the first marker line in data,
+// the marker's name, and the text after the marker line.  With no
+// marker, the name is empty and all of data is returned as before.
+func findMarker(data []byte) (before []byte, name string, after []byte) {
+	var i int
+	for {
+		if name, after = isMarker(data[i:]); name != "" {
+			return fixNL(data[:i]), name, after
+		}
+
+		var j = bytes.Index(data[i:], []byte("\n-- "))
+		if j < 0 {
+			return fixNL(data), "", nil
+		}
+		i += j + 1
+	}
+}
+
+// isMarker reports whether data begins with a marker line, returning
+// its name and the text after it.
+func isMarker(data []byte) (string, []byte) {
+	if !bytes.HasPrefix(data, markerStart) {
+		return "", nil
+	}
+
+	var line, after = data, []byte(nil)
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line, after = data[:i], data[i+1:]
+	}
+	line = bytes.TrimSuffix(line, []byte("\r"))
+
+	if !bytes.HasSuffix(line, markerEnd) || len(line) < len(markerStart)+len(markerEnd) {
+		return "", nil
+	}
+
+	var name = strings.TrimSpace(string(line[len(markerStart) : len(line)-len(markerEnd)]))
+	return name, after
+}
+
+// fixNL returns data with a final newline added if it is missing.
+func fixNL(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+
+	return append(data[:len(data):len(data)], '\n')
+}
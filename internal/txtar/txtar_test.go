@@ -0,0 +1,53 @@
+package txtar
+
+import (
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestParse tests splitting an archive into its comment and files.
+func TestParse(t *testing.T) {
+	a := Parse([]byte("comment\n-- a.txt --\nhello\n-- dir/b.txt --\n-- not a marker\n--  c.txt  --\r\nno newline"))
+
+	testutil.AssertEqual(t, "comment\n", string(a.Comment))
+	testutil.AssertEqual(t, 3, len(a.Files))
+	testutil.AssertEqual(t, "a.txt", a.Files[0].Name)
+	testutil.AssertEqual(t, "hello\n", string(a.Files[0].Data))
+	testutil.AssertEqual(t, "dir/b.txt", a.Files[1].Name)
+	testutil.AssertEqual(t, "-- not a marker\n", string(a.Files[1].Data))
+	testutil.AssertEqual(t, "c.txt", a.Files[2].Name)
+	testutil.AssertEqual(t, "no newline\n", string(a.Files[2].Data))
+}
+
+// TestParse_Empty tests archives with no files.
+func TestParse_Empty(t *testing.T) {
+	a := Parse(nil)
+	testutil.AssertEqual(t, 0, len(a.Comment))
+	testutil.AssertEqual(t, 0, len(a.Files))
+
+	a = Parse([]byte("-- empty --\n"))
+	testutil.AssertEqual(t, 0, len(a.Comment))
+	testutil.AssertEqual(t, 1, len(a.Files))
+	testutil.AssertEqual(t, 0, len(a.Files[0].Data))
+}
+
+// TestFormat tests that formatting an archive parses back the same.
+func TestFormat(t *testing.T) {
+	a := &Archive{
+		Comment: []byte("comment"),
+		Files: []File{
+			{Name: "a.txt", Data: []byte("hello")},
+			{Name: "dir/", Data: nil},
+		},
+	}
+
+	data := Format(a)
+	testutil.AssertEqual(t, "comment\n-- a.txt --\nhello\n-- dir/ --\n", string(data))
+	testutil.AssertEqual(t, "hello", string(a.Files[0].Data))
+
+	b := Parse(data)
+	testutil.AssertEqual(t, 2, len(b.Files))
+	testutil.AssertEqual(t, "dir/", b.Files[1].Name)
+	testutil.AssertEqual(t, string(data), string(Format(b)))
+}
@@ -0,0 +1,142 @@
+// Package conformance checks that implementations of the go-interfaces
+// io interfaces follow the contracts documented by the io package, the
+// way testing/iotest does for the standard library's own readers.
+//
+// Each Test function takes a factory, so every check starts from a
+// fresh value, and reports what it finds on a TB, so it can run a
+// fake, a hand-configured mock or a wrapper through the same rules as
+// strings.Reader or bytes.Buffer:
+//
+//	func TestFakeReader(t *testing.T) {
+//		conformance.TestReader(t, func() io.Reader {
+//			return newFakeReader("hello, world")
+//		}, []byte("hello, world"))
+//	}
+//
+// The readers check that data comes back unchanged whatever the
+// buffer size, that n and err agree, that EOF is sticky and that
+// unreading and seeking go where they should.  The writers check that
+// a short write reports an error and that the written slice is
+// neither modified nor retained.
+package conformance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// TB is the part of testing.TB a check reports through.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// maxZeroReads is how many reads in a row may return nothing before a
+// reader is taken to be stuck, as bufio does.
+const maxZeroReads = 100
+
+// timeout is how long a check waits for a call that must not block.
+const timeout = 2 * time.Second
+
+// errTest is the error the checks inject into readers and writers.
+var errTest = errors.New("conformance: injected error")
+
+// pattern returns n bytes that differ from their neighbours, so that
+// misplaced data shows.
+func pattern(n int) []byte {
+	var p = make([]byte, n)
+	for i := range p {
+		p[i] = byte('a' + i%23)
+	}
+
+	return p
+}
+
+// readAll reads r to the end with buffers of size, checking every
+// call, and returns the data and the first error other than io.EOF.
+// It reports a reader that returns nothing too many times in a row.
+func readAll(t TB, what string, r io.Reader, size int) ([]byte, error) {
+	t.Helper()
+
+	var (
+		data  []byte
+		buf   = make([]byte, size)
+		zeros int
+	)
+	for {
+		var n, err = r.Read(buf)
+		if n < 0 || n > len(buf) {
+			t.Errorf("%s: Read of %d bytes returned n = %d", what, len(buf), n)
+			return data, err
+		}
+		data = append(data, buf[:n]...)
+
+		switch {
+		case err == io.EOF:
+			return data, nil
+		case err != nil:
+			return data, err
+		case n == 0:
+			zeros++
+			if zeros >= maxZeroReads {
+				t.Errorf("%s: Read returned 0, nil %d times in a row", what, zeros)
+				return data, io.ErrNoProgress
+			}
+		default:
+			zeros = 0
+		}
+	}
+}
+
+// within runs f, reporting it if it does not return in time.  It
+// reports whether f returned.
+func within(t TB, what string, f func()) bool {
+	t.Helper()
+
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		t.Errorf("%s: blocked for %v", what, timeout)
+		return false
+	}
+}
+
+// sameBytes reports a difference between the bytes got and want.
+func sameBytes(t TB, what string, got, want []byte) bool {
+	t.Helper()
+
+	if bytes.Equal(got, want) {
+		return true
+	}
+
+	var i = 0
+	for i < len(got) && i < len(want) && got[i] == want[i] {
+		i++
+	}
+	t.Errorf("%s: got %d bytes, want %d; they first differ at offset %d (%s)",
+		what, len(got), len(want), i, excerpt(got, want, i))
+
+	return false
+}
+
+func excerpt(got, want []byte, i int) string {
+	var at = func(b []byte) string {
+		if i >= len(b) {
+			return "end"
+		}
+
+		return fmt.Sprintf("%q", b[i:min(i+8, len(b))])
+	}
+
+	return "got " + at(got) + ", want " + at(want)
+}
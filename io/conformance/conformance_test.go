@@ -0,0 +1,195 @@
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	ioi "github.com/pdutton/go-interfaces/io"
+	osi "github.com/pdutton/go-interfaces/os"
+	"github.com/pdutton/go-mocks/internal/testutil"
+	"github.com/pdutton/go-mocks/io/fake_io"
+	"github.com/pdutton/go-mocks/io/mock_io"
+	"github.com/pdutton/go-mocks/os/fake_os"
+	"go.uber.org/mock/gomock"
+)
+
+const text = "hello, wörld\n\xffand the rest of it"
+
+// recorder is a TB that records errors.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// TestStandard tests that the standard library's implementations
+// conform.
+func TestStandard(t *testing.T) {
+	TestReader(t, func() io.Reader { return strings.NewReader(text) }, []byte(text))
+	TestReader(t, func() io.Reader { return bytes.NewBufferString(text) }, []byte(text))
+	TestReaderAt(t, func() io.ReaderAt { return strings.NewReader(text) }, []byte(text))
+	TestReaderAt(t, func() io.ReaderAt {
+		return io.NewSectionReader(strings.NewReader("xx"+text+"yy"), 2, int64(len(text)))
+	}, []byte(text))
+	TestSeeker(t, func() io.ReadSeeker { return strings.NewReader(text) }, []byte(text))
+	TestByteScanner(t, func() io.ByteScanner { return bufio.NewReader(strings.NewReader(text)) }, []byte(text))
+	TestRuneScanner(t, func() io.RuneScanner { return strings.NewReader(text) }, text)
+	TestWriterTo(t, func() io.WriterTo { return bytes.NewBufferString(text) }, []byte(text))
+
+	TestWriter(t, func() (io.Writer, func() []byte) {
+		var buf bytes.Buffer
+		return &buf, buf.Bytes
+	})
+	TestReaderFrom(t, func() (io.ReaderFrom, func() []byte) {
+		var buf bytes.Buffer
+		return &buf, buf.Bytes
+	})
+	TestPipe(t, func() (ioi.PipeReader, ioi.PipeWriter) { return ioi.NewIO().Pipe() })
+}
+
+// TestFakes tests that the fakes of this module conform.
+func TestFakes(t *testing.T) {
+	fos := fake_os.New()
+	testutil.AssertNil(t, fos.WriteFile("/text", []byte(text), 0o644))
+	open := func() osi.File {
+		f, err := fos.Open("/text")
+		testutil.AssertNil(t, err)
+		return f
+	}
+
+	TestReader(t, func() io.Reader { return open() }, []byte(text))
+	TestReaderAt(t, func() io.ReaderAt { return open() }, []byte(text))
+	TestSeeker(t, func() io.ReadSeeker { return open() }, []byte(text))
+	TestWriterTo(t, func() io.WriterTo { return open() }, []byte(text))
+
+	create := func() (osi.File, func() []byte) {
+		f, err := fos.OpenFile("/out", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+		testutil.AssertNil(t, err)
+		return f, func() []byte {
+			data, _ := fos.ReadFile("/out")
+			return data
+		}
+	}
+	TestWriter(t, func() (io.Writer, func() []byte) { return create() })
+	TestWriterAt(t, func() (io.WriterAt, func() []byte) { return create() })
+	TestReaderFrom(t, func() (io.ReaderFrom, func() []byte) { return create() })
+
+	// Short reads are within the contract.
+	TestReader(t, func() io.Reader {
+		return fake_io.NewReader(strings.NewReader(text), fake_io.Plan{fake_io.Short(fake_io.OpRead, 2)})
+	}, []byte(text))
+}
+
+// TestMock tests a hand-configured mock.
+func TestMock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	TestReader(t, func() io.Reader {
+		r := strings.NewReader(text)
+		m := mock_io.NewMockReader(ctrl)
+		m.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+		return m
+	}, []byte(text))
+}
+
+// TestReader_Broken tests that a reader breaking the contract is
+// reported.
+func TestReader_Broken(t *testing.T) {
+	r := &recorder{}
+	TestReader(r, func() io.Reader { return &forgetfulReader{data: []byte(text)} }, []byte(text))
+	testutil.AssertEqual(t, true, len(r.errors) > 0)
+	testutil.AssertEqual(t, "Reader: Read after io.EOF returned 8, <nil>, want 0, io.EOF", r.errors[len(r.errors)-1])
+
+	// A reader that never returns anything is stuck.
+	r = &recorder{}
+	TestReader(r, func() io.Reader { return &forgetfulReader{} }, nil)
+	testutil.AssertEqual(t, "Reader: reading with a 1 byte buffer: Read returned 0, nil 100 times in a row", r.errors[0])
+}
+
+// TestWriter_Broken tests that short writes are reported.
+func TestWriter_Broken(t *testing.T) {
+	r := &recorder{}
+	TestWriter(r, func() (io.Writer, func() []byte) {
+		var buf bytes.Buffer
+		return &shortWriter{&buf}, buf.Bytes
+	})
+	testutil.AssertEqual(t, "Writer: writing 7 bytes at a time: Write of 7 bytes returned n = 6 and no error", r.errors[0])
+}
+
+// TestByteScanner_Broken tests that a scanner that cannot unread is
+// reported.
+func TestByteScanner_Broken(t *testing.T) {
+	r := &recorder{}
+	TestByteScanner(r, func() io.ByteScanner {
+		return &stuckScanner{strings.NewReader(text)}
+	}, []byte(text))
+	testutil.AssertEqual(t, "ByteScanner: ReadByte after UnreadByte at 0 returned 'e', <nil>, want 'h', nil", r.errors[0])
+}
+
+// TestSeeker_Broken tests that a seeker ignoring whence is reported.
+func TestSeeker_Broken(t *testing.T) {
+	r := &recorder{}
+	TestSeeker(r, func() io.ReadSeeker {
+		return &absoluteSeeker{strings.NewReader(text)}
+	}, []byte(text))
+	testutil.AssertEqual(t, fmt.Sprintf("Seeker: Seek(0, io.SeekEnd) returned 0, <nil>, want %d, nil", len(text)), r.errors[0])
+}
+
+// forgetfulReader starts again after reaching the end, and returns
+// 0, nil when it has nothing.
+type forgetfulReader struct {
+	data []byte
+	off  int
+}
+
+func (r *forgetfulReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, nil
+	}
+	if r.off == len(r.data) {
+		r.off = 0
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[r.off:])
+	r.off += n
+	return n, nil
+}
+
+// shortWriter drops the last byte of writes longer than one.
+type shortWriter struct {
+	w io.Writer
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:len(p)-1]
+	}
+	return w.w.Write(p)
+}
+
+// stuckScanner ignores UnreadByte.
+type stuckScanner struct {
+	*strings.Reader
+}
+
+func (s *stuckScanner) UnreadByte() error {
+	return nil
+}
+
+// absoluteSeeker treats every offset as absolute.
+type absoluteSeeker struct {
+	*strings.Reader
+}
+
+func (s *absoluteSeeker) Seek(offset int64, whence int) (int64, error) {
+	return s.Reader.Seek(offset, io.SeekStart)
+}
@@ -0,0 +1,97 @@
+package conformance
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	ioi "github.com/pdutton/go-interfaces/io"
+)
+
+// TestPipe checks the pipes made by newPipe, which must behave like
+// io.Pipe: data written comes out of the reader unchanged, a Write
+// returns only once its data has been read, closing the writer ends
+// the reader with io.EOF or the error given to CloseWithError, and
+// closing the reader fails later writes with io.ErrClosedPipe or the
+// error given.
+func TestPipe(t TB, newPipe func() (ioi.PipeReader, ioi.PipeWriter)) {
+	t.Helper()
+
+	var want = pattern(1000)
+	var pr, pw = newPipe()
+	go func() {
+		for off := 0; off < len(want); off += 100 {
+			if _, err := pw.Write(want[off : off+100]); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.Close()
+	}()
+	within(t, "Pipe: reading", func() {
+		var got, err = readAll(t, "Pipe", pr, 64)
+		if err != nil {
+			t.Errorf("Pipe: reading: %v", err)
+		}
+		sameBytes(t, "Pipe: reading", got, want)
+	})
+
+	pr, pw = newPipe()
+	var wrote = make(chan error, 1)
+	go func() {
+		var _, err = pw.Write([]byte("0123456789"))
+		wrote <- err
+	}()
+	within(t, "Pipe: reading part of a write", func() {
+		io.ReadFull(pr, make([]byte, 4))
+	})
+	select {
+	case <-wrote:
+		t.Errorf("Pipe: Write returned before its data was read")
+	case <-time.After(20 * time.Millisecond):
+	}
+	within(t, "Pipe: reading the rest of a write", func() {
+		io.ReadFull(pr, make([]byte, 6))
+		if err := <-wrote; err != nil {
+			t.Errorf("Pipe: Write: %v", err)
+		}
+	})
+
+	checkPipeEnd(t, "Pipe: Read after the writer closed", io.EOF, newPipe, func(pr ioi.PipeReader, pw ioi.PipeWriter) error {
+		pw.Close()
+		var _, err = pr.Read(make([]byte, 1))
+		return err
+	})
+	checkPipeEnd(t, "Pipe: Read after the writer closed with an error", errTest, newPipe, func(pr ioi.PipeReader, pw ioi.PipeWriter) error {
+		pw.CloseWithError(errTest)
+		var _, err = pr.Read(make([]byte, 1))
+		return err
+	})
+	checkPipeEnd(t, "Pipe: Write after the reader closed", io.ErrClosedPipe, newPipe, func(pr ioi.PipeReader, pw ioi.PipeWriter) error {
+		pr.Close()
+		var _, err = pw.Write([]byte("x"))
+		return err
+	})
+	checkPipeEnd(t, "Pipe: Write after the reader closed with an error", errTest, newPipe, func(pr ioi.PipeReader, pw ioi.PipeWriter) error {
+		pr.CloseWithError(errTest)
+		var _, err = pw.Write([]byte("x"))
+		return err
+	})
+	checkPipeEnd(t, "Pipe: Read after the reader closed", io.ErrClosedPipe, newPipe, func(pr ioi.PipeReader, pw ioi.PipeWriter) error {
+		pr.Close()
+		var _, err = pr.Read(make([]byte, 1))
+		return err
+	})
+}
+
+// checkPipeEnd runs f on a new pipe and checks that it returns want.
+func checkPipeEnd(t TB, what string, want error, newPipe func() (ioi.PipeReader, ioi.PipeWriter), f func(ioi.PipeReader, ioi.PipeWriter) error) {
+	t.Helper()
+
+	var pr, pw = newPipe()
+	within(t, what, func() {
+		if err := f(pr, pw); !errors.Is(err, want) {
+			t.Errorf("%s: returned %v, want %v", what, err, want)
+		}
+	})
+}
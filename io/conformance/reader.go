@@ -0,0 +1,329 @@
+package conformance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
+)
+
+// TestReader checks the readers made by newReader, each of which must
+// yield want.  Reads of every size must return the data unchanged, n
+// and err must agree, an empty read must return nothing and, once a
+// reader has returned io.EOF, it must keep doing so.  A read may
+// return data alongside io.EOF.
+func TestReader(t TB, newReader func() io.Reader, want []byte) {
+	t.Helper()
+
+	for _, size := range []int{1, 3, 512, len(want) + 1} {
+		var what = fmt.Sprintf("Reader: reading with a %d byte buffer", size)
+		var got, err = readAll(t, what, newReader(), size)
+		if err != nil {
+			t.Errorf("%s: %v", what, err)
+			continue
+		}
+		sameBytes(t, what, got, want)
+	}
+
+	var r = newReader()
+	if n, _ := r.Read(nil); n != 0 {
+		t.Errorf("Reader: Read into an empty slice returned n = %d", n)
+	}
+
+	if _, err := readAll(t, "Reader", r, 7); err == nil {
+		for i := 0; i < 2; i++ {
+			if n, err := r.Read(make([]byte, 8)); n != 0 || err != io.EOF {
+				t.Errorf("Reader: Read after io.EOF returned %d, %v, want 0, io.EOF", n, err)
+				break
+			}
+		}
+	}
+}
+
+// TestReaderAt checks the readers made by newReaderAt, each of which
+// must yield want.  A read that fits must fill the buffer, returning at
+// most io.EOF and only when it ends at the end of the data; a read
+// past the end must return what there is and an error; a negative
+// offset is an error; parallel reads must not interfere; and a reader
+// that is also an io.ReadSeeker must keep ReadAt and the seek offset
+// apart.
+func TestReaderAt(t TB, newReaderAt func() io.ReaderAt, want []byte) {
+	t.Helper()
+
+	var size = int64(len(want))
+	for _, off := range offsets(len(want)) {
+		for _, n := range []int64{0, 1, 5, size - off, size - off + 3} {
+			if n < 0 {
+				continue
+			}
+			checkReadAt(t, newReaderAt(), want, off, int(n))
+		}
+	}
+
+	if _, err := newReaderAt().ReadAt(make([]byte, 1), -1); err == nil {
+		t.Errorf("ReaderAt: ReadAt at offset -1 succeeded")
+	}
+
+	var (
+		r  = newReaderAt()
+		wg sync.WaitGroup
+	)
+	for off := int64(0); off < size; off += max(size/8, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkReadAt(t, r, want, off, int(min(16, size-off)))
+		}()
+	}
+	wg.Wait()
+
+	if rs, ok := newReaderAt().(io.ReadSeeker); ok && size > 1 {
+		if _, err := rs.Seek(1, io.SeekStart); err != nil {
+			t.Errorf("ReaderAt: Seek: %v", err)
+			return
+		}
+		checkReadAt(t, rs.(io.ReaderAt), want, 0, len(want))
+		if pos, err := rs.Seek(0, io.SeekCurrent); pos != 1 || err != nil {
+			t.Errorf("ReaderAt: ReadAt moved the seek offset from 1 to %d (%v)", pos, err)
+		}
+		var got, _ = readAll(t, "ReaderAt", rs, 64)
+		sameBytes(t, "ReaderAt: reading after ReadAt", got, want[1:])
+	}
+}
+
+// checkReadAt reads n bytes at off from r, which holds want.
+func checkReadAt(t TB, r io.ReaderAt, want []byte, off int64, n int) {
+	t.Helper()
+
+	var (
+		what     = fmt.Sprintf("ReaderAt: ReadAt of %d bytes at %d", n, off)
+		buf      = make([]byte, n)
+		got, err = r.ReadAt(buf, off)
+		end      = min(off+int64(n), int64(len(want)))
+		avail    = int(max(end-off, 0))
+	)
+	switch {
+	case got < 0 || got > n:
+		t.Errorf("%s: returned n = %d", what, got)
+		return
+	case avail == n && got != n:
+		t.Errorf("%s: returned %d bytes (%v)", what, got, err)
+	case avail == n && err != nil && (err != io.EOF || end != int64(len(want))):
+		t.Errorf("%s: returned error %v", what, err)
+	case avail < n && err == nil:
+		t.Errorf("%s: returned %d bytes and no error", what, got)
+	}
+
+	if got <= avail {
+		sameBytes(t, what, buf[:got], want[off:off+int64(got)])
+	} else {
+		t.Errorf("%s: returned %d bytes, but only %d remain", what, got, avail)
+	}
+}
+
+// offsets returns the offsets into data of size n worth checking.
+func offsets(n int) []int64 {
+	var offs = []int64{0}
+	for _, off := range []int{1, n / 2, n - 1, n} {
+		if off > int(offs[len(offs)-1]) {
+			offs = append(offs, int64(off))
+		}
+	}
+
+	return offs
+}
+
+// TestSeeker checks the read seekers made by newReadSeeker, each of
+// which must yield want.  Seeking from the start, the current offset
+// and the end must return the new offset and move the reads there;
+// seeking before the start and an unknown whence are errors.
+func TestSeeker(t TB, newReadSeeker func() io.ReadSeeker, want []byte) {
+	t.Helper()
+
+	var size = int64(len(want))
+	var seek = func(s io.Seeker, offset int64, whence int, wantPos int64) bool {
+		t.Helper()
+
+		var pos, err = s.Seek(offset, whence)
+		if pos != wantPos || err != nil {
+			t.Errorf("Seeker: Seek(%d, %s) returned %d, %v, want %d, nil",
+				offset, whenceName(whence), pos, err, wantPos)
+			return false
+		}
+
+		return true
+	}
+
+	for _, off := range offsets(len(want)) {
+		var s = newReadSeeker()
+		if !seek(s, off, io.SeekStart, off) {
+			continue
+		}
+		var got, _ = readAll(t, "Seeker", s, 64)
+		sameBytes(t, fmt.Sprintf("Seeker: reading from %d", off), got, want[off:])
+	}
+
+	var s = newReadSeeker()
+	seek(s, 0, io.SeekEnd, size)
+	if size > 0 && seek(s, -1, io.SeekEnd, size-1) {
+		var got, _ = readAll(t, "Seeker", s, 64)
+		sameBytes(t, "Seeker: reading from the end less one", got, want[size-1:])
+	}
+
+	s = newReadSeeker()
+	if size >= 3 {
+		if _, err := io.ReadFull(s, make([]byte, 2)); err != nil {
+			t.Errorf("Seeker: reading 2 bytes: %v", err)
+			return
+		}
+		seek(s, 0, io.SeekCurrent, 2)
+		seek(s, -1, io.SeekCurrent, 1)
+		seek(s, 1, io.SeekCurrent, 2)
+		var got, _ = readAll(t, "Seeker", s, 64)
+		sameBytes(t, "Seeker: reading after relative seeks", got, want[2:])
+	}
+
+	s = newReadSeeker()
+	if _, err := s.Seek(-1, io.SeekStart); err == nil {
+		t.Errorf("Seeker: Seek(-1, io.SeekStart) succeeded")
+	}
+	if _, err := s.Seek(0, 42); err == nil {
+		t.Errorf("Seeker: Seek with whence 42 succeeded")
+	}
+}
+
+func whenceName(whence int) string {
+	switch whence {
+	case io.SeekStart:
+		return "io.SeekStart"
+	case io.SeekCurrent:
+		return "io.SeekCurrent"
+	case io.SeekEnd:
+		return "io.SeekEnd"
+	}
+
+	return fmt.Sprint(whence)
+}
+
+// TestByteScanner checks the byte scanners made by newByteScanner,
+// each of which must yield want.  ReadByte must return the bytes in
+// order and then io.EOF, and UnreadByte after a successful ReadByte
+// must make the next ReadByte return the same byte again.
+func TestByteScanner(t TB, newByteScanner func() io.ByteScanner, want []byte) {
+	t.Helper()
+
+	var (
+		s   = newByteScanner()
+		got []byte
+	)
+	for i := 0; i <= len(want); i++ {
+		var c, err = s.ReadByte()
+		if err != nil {
+			if err != io.EOF || i < len(want) {
+				t.Errorf("ByteScanner: ReadByte at %d: %v", i, err)
+			}
+			break
+		}
+		got = append(got, c)
+
+		if err := s.UnreadByte(); err != nil {
+			t.Errorf("ByteScanner: UnreadByte at %d: %v", i, err)
+			break
+		}
+		if again, err := s.ReadByte(); again != c || err != nil {
+			t.Errorf("ByteScanner: ReadByte after UnreadByte at %d returned %q, %v, want %q, nil",
+				i, again, err, c)
+			break
+		}
+	}
+	sameBytes(t, "ByteScanner: ReadByte", got, want)
+
+	if c, err := s.ReadByte(); err != io.EOF {
+		t.Errorf("ByteScanner: ReadByte at the end returned %q, %v, want io.EOF", c, err)
+	}
+}
+
+// TestRuneScanner checks the rune scanners made by newRuneScanner,
+// each of which must yield want.  ReadRune must decode the runes in
+// order, returning each one's encoded size and utf8.RuneError with
+// size 1 for invalid bytes, then 0, 0, io.EOF; UnreadRune after a
+// successful ReadRune must make the next ReadRune return the same rune
+// again.
+func TestRuneScanner(t TB, newRuneScanner func() io.RuneScanner, want string) {
+	t.Helper()
+
+	var s = newRuneScanner()
+	for off := 0; off < len(want); {
+		var wantRune, wantSize = utf8.DecodeRuneInString(want[off:])
+		var r, size, err = s.ReadRune()
+		if r != wantRune || size != wantSize || err != nil {
+			t.Errorf("RuneScanner: ReadRune at %d returned %q, %d, %v, want %q, %d, nil",
+				off, r, size, err, wantRune, wantSize)
+			return
+		}
+
+		if err := s.UnreadRune(); err != nil {
+			t.Errorf("RuneScanner: UnreadRune at %d: %v", off, err)
+			return
+		}
+		if r, size, err = s.ReadRune(); r != wantRune || size != wantSize || err != nil {
+			t.Errorf("RuneScanner: ReadRune after UnreadRune at %d returned %q, %d, %v, want %q, %d, nil",
+				off, r, size, err, wantRune, wantSize)
+			return
+		}
+		off += size
+	}
+
+	if r, size, err := s.ReadRune(); r != 0 || size != 0 || err != io.EOF {
+		t.Errorf("RuneScanner: ReadRune at the end returned %q, %d, %v, want 0, 0, io.EOF", r, size, err)
+	}
+}
+
+// TestWriterTo checks the writer tos made by newWriterTo, each of
+// which must yield want.  WriteTo must write all of it and return its
+// length, and when the writer fails, return the writer's error and
+// the number of bytes the writer took.
+func TestWriterTo(t TB, newWriterTo func() io.WriterTo, want []byte) {
+	t.Helper()
+
+	var buf = &limitWriter{limit: -1}
+	var n, err = newWriterTo().WriteTo(buf)
+	if n != int64(len(want)) || err != nil {
+		t.Errorf("WriterTo: WriteTo returned %d, %v, want %d, nil", n, err, len(want))
+	}
+	sameBytes(t, "WriterTo: WriteTo", buf.data, want)
+
+	if len(want) == 0 {
+		return
+	}
+
+	buf = &limitWriter{limit: len(want) / 2}
+	n, err = newWriterTo().WriteTo(buf)
+	if !errors.Is(err, errTest) {
+		t.Errorf("WriterTo: WriteTo to a failing writer returned error %v, want the writer's", err)
+	}
+	if n != int64(len(buf.data)) {
+		t.Errorf("WriterTo: WriteTo to a failing writer returned n = %d, but the writer took %d bytes",
+			n, len(buf.data))
+	}
+}
+
+// limitWriter takes up to limit bytes, or all of them if limit is
+// negative, and then fails with errTest.
+type limitWriter struct {
+	data  []byte
+	limit int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.limit < 0 || len(w.data)+len(p) <= w.limit {
+		w.data = append(w.data, p...)
+		return len(p), nil
+	}
+
+	var n = w.limit - len(w.data)
+	w.data = append(w.data, p[:n]...)
+
+	return n, errTest
+}
@@ -0,0 +1,157 @@
+package conformance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing/iotest"
+)
+
+// TestWriter checks the writers made by newWriter, which also returns
+// a function reporting everything written so far.  Writes of every
+// size must take all their bytes, a short write must return an error,
+// and Write must neither modify nor retain the slice it is given.
+func TestWriter(t TB, newWriter func() (io.Writer, func() []byte)) {
+	t.Helper()
+
+	var want = pattern(1000)
+	for _, size := range []int{1, 7, 512} {
+		var (
+			what      = fmt.Sprintf("Writer: writing %d bytes at a time", size)
+			w, output = newWriter()
+		)
+		for off := 0; off < len(want); off += size {
+			var (
+				p      = bytes.Clone(want[off:min(off+size, len(want))])
+				n, err = w.Write(p)
+			)
+			if !checkWrite(t, what, p, want[off:], n, err) {
+				break
+			}
+			clear(p)
+		}
+		sameBytes(t, what, output(), want)
+	}
+
+	var w, output = newWriter()
+	if n, err := w.Write(nil); n != 0 || err != nil {
+		t.Errorf("Writer: Write of an empty slice returned %d, %v, want 0, nil", n, err)
+	}
+	sameBytes(t, "Writer: Write of an empty slice", output(), nil)
+}
+
+// checkWrite checks the result of writing p, which must hold want, and
+// reports whether the write succeeded.
+func checkWrite(t TB, what string, p, want []byte, n int, err error) bool {
+	t.Helper()
+
+	switch {
+	case n < 0 || n > len(p):
+		t.Errorf("%s: Write of %d bytes returned n = %d", what, len(p), n)
+		return false
+	case n < len(p) && err == nil:
+		t.Errorf("%s: Write of %d bytes returned n = %d and no error", what, len(p), n)
+		return false
+	case err != nil:
+		t.Errorf("%s: %v", what, err)
+		return false
+	case !bytes.Equal(p, want[:len(p)]):
+		t.Errorf("%s: Write modified the slice it was given", what)
+		return false
+	}
+
+	return true
+}
+
+// TestWriterAt checks the writers made by newWriterAt, which also
+// returns a function reporting the contents of the destination.
+// Writes at any offset, in any order and in parallel over ranges that
+// do not overlap, must land where they were aimed; a negative offset is
+// an error; and WriteAt must neither modify nor retain its slice.
+func TestWriterAt(t TB, newWriterAt func() (io.WriterAt, func() []byte)) {
+	t.Helper()
+
+	const size = 64
+	var want = pattern(1000)
+	var writeAt = func(w io.WriterAt, off int, what string) bool {
+		t.Helper()
+
+		var (
+			p      = bytes.Clone(want[off:min(off+size, len(want))])
+			n, err = w.WriteAt(p, int64(off))
+			ok     = checkWrite(t, fmt.Sprintf("%s at %d", what, off), p, want[off:], n, err)
+		)
+		clear(p)
+
+		return ok
+	}
+
+	var w, output = newWriterAt()
+	for off := len(want) / size * size; off >= 0; off -= size {
+		if !writeAt(w, off, "WriterAt: writing backwards") {
+			break
+		}
+	}
+	sameBytes(t, "WriterAt: writing backwards", output(), want)
+
+	var wg sync.WaitGroup
+	w, output = newWriterAt()
+	for off := 0; off < len(want); off += size {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			writeAt(w, off, "WriterAt: writing in parallel")
+		}()
+	}
+	wg.Wait()
+	sameBytes(t, "WriterAt: writing in parallel", output(), want)
+
+	w, _ = newWriterAt()
+	if _, err := w.WriteAt([]byte("x"), -1); err == nil {
+		t.Errorf("WriterAt: WriteAt at offset -1 succeeded")
+	}
+}
+
+// TestReaderFrom checks the reader froms made by newReaderFrom, which
+// also returns a function reporting everything read so far.  ReadFrom
+// must read until io.EOF without returning it, whether the data comes
+// in large reads, single bytes or alongside io.EOF, and must return
+// any other error from the reader with the number of bytes read
+// before it.
+func TestReaderFrom(t TB, newReaderFrom func() (io.ReaderFrom, func() []byte)) {
+	t.Helper()
+
+	var want = pattern(1000)
+	var sources = []struct {
+		name string
+		r    func() io.Reader
+	}{
+		{"a reader", func() io.Reader { return bytes.NewReader(want) }},
+		{"one byte at a time", func() io.Reader { return iotest.OneByteReader(bytes.NewReader(want)) }},
+		{"data alongside io.EOF", func() io.Reader { return iotest.DataErrReader(bytes.NewReader(want)) }},
+	}
+	for _, src := range sources {
+		var (
+			what      = "ReaderFrom: reading from " + src.name
+			w, output = newReaderFrom()
+			n, err    = w.ReadFrom(src.r())
+		)
+		if n != int64(len(want)) || err != nil {
+			t.Errorf("%s: ReadFrom returned %d, %v, want %d, nil", what, n, err, len(want))
+		}
+		sameBytes(t, what, output(), want)
+	}
+
+	var (
+		w, output = newReaderFrom()
+		r         = io.MultiReader(strings.NewReader("0123456789"), iotest.ErrReader(errTest))
+		n, err    = w.ReadFrom(r)
+	)
+	if n != 10 || !errors.Is(err, errTest) {
+		t.Errorf("ReaderFrom: ReadFrom of a failing reader returned %d, %v, want 10 and the reader's error", n, err)
+	}
+	sameBytes(t, "ReaderFrom: reading from a failing reader", output(), []byte("0123456789"))
+}
@@ -0,0 +1,24 @@
+// Package fake_io provides fakes for the go-interfaces io package and
+// the readers, writers and files built on it.
+//
+// The wrappers in this package decorate a real value with a
+// declarative Plan of faults, so a test can check how code copes with
+// the I/O errors that are hard to provoke from a real disk or socket:
+//
+//	f, _ := fos.Create("/data/out")
+//	faulty := fake_io.NewFile(f, fake_io.Plan{
+//		fake_io.Short(fake_io.OpWrite, 100),
+//		fake_io.FailCall(fake_io.OpWrite, 3, syscall.ENOSPC).After(50),
+//		fake_io.FailCall(fake_io.OpSync, 1, syscall.EIO),
+//	})
+//
+// Every wrapper counts its calls by Op, which Calls reports.
+//
+// IO is the go-interfaces io.IO interface over the real io package,
+// recording each call with the bytes it moved, the buffer it was given
+// and the error it returned, so a test can check how data flowed
+// without scripting every function on a mock_io.MockIO.
+//
+// Pipe is a synchronous in-memory pipe, like io.Pipe, that a test can
+// pause, step, observe and close from either end at a chosen point.
+package fake_io
@@ -0,0 +1,280 @@
+package fake_io
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Op names an operation that a fault can be injected into.
+type Op string
+
+const (
+	// OpRead faults Read, and the reads of WriteTo.
+	OpRead Op = "read"
+
+	// OpWrite faults Write and WriteString, and the writes of ReadFrom.
+	OpWrite Op = "write"
+
+	// OpReadAt faults ReadAt.
+	OpReadAt Op = "readat"
+
+	// OpWriteAt faults WriteAt.
+	OpWriteAt Op = "writeat"
+
+	// OpSeek faults Seek.
+	OpSeek Op = "seek"
+
+	// OpSync faults Sync.
+	OpSync Op = "sync"
+
+	// OpClose faults Close.
+	OpClose Op = "close"
+)
+
+// Fault is one entry in a Plan, made by Short, FailAt, FailCall or
+// Delay.  A fault for the op "" applies to every operation.
+type Fault struct {
+	op     Op
+	call   int           // the 1-based call it applies to, or 0 for all
+	offset int64         // the offset it fails at, or -1
+	limit  int           // the most bytes a call moves, or -1
+	err    error         // the error the call returns
+	delay  time.Duration // the latency added to the call
+}
+
+// Plan is a list of faults.  Every fault matching a call applies: the
+// call moves no more bytes than the smallest limit, returns the error
+// of the first failing fault, and waits for the sum of the delays.
+type Plan []Fault
+
+// Short makes every call of op move at most n bytes, which must be at
+// least one.  Short reads succeed; short writes, and short reads with
+// ReadAt, report io.ErrShortWrite or io.ErrUnexpectedEOF as their
+// contracts require.
+func Short(op Op, n int) Fault {
+	if n < 1 {
+		panic("fake_io: Short with n < 1")
+	}
+
+	return Fault{op: op, offset: -1, limit: n}
+}
+
+// FailAt makes op fail with err when it reaches offset: the call that
+// would cross it moves the bytes before it and returns err, and every
+// later call returns err at once.  Offsets count from the start of the
+// stream for Read and Write, follow Seek, and are the off argument of
+// ReadAt and WriteAt.
+func FailAt(op Op, offset int64, err error) Fault {
+	return Fault{op: op, offset: offset, limit: -1, err: err}
+}
+
+// FailCall makes the nth call of op, counting from one, return err
+// without moving any bytes.  Use After to move some first:
+//
+//	fake_io.FailCall(fake_io.OpWrite, 3, syscall.ENOSPC).After(512)
+func FailCall(op Op, n int, err error) Fault {
+	return Fault{op: op, call: n, offset: -1, limit: 0, err: err}
+}
+
+// After returns the fault, letting the failing call move up to n bytes
+// before it returns its error.
+func (f Fault) After(n int) Fault {
+	f.limit = n
+	return f
+}
+
+// Delay makes every call of op take d longer.
+func Delay(op Op, d time.Duration) Fault {
+	return Fault{op: op, offset: -1, limit: -1, delay: d}
+}
+
+// injector applies a plan to the calls of one wrapper.
+type injector struct {
+	mu    sync.Mutex
+	plan  Plan
+	calls map[Op]int
+	pos   int64
+}
+
+func newInjector(plan Plan) *injector {
+	return &injector{
+		plan:  append(Plan(nil), plan...),
+		calls: make(map[Op]int),
+	}
+}
+
+// begin counts a call of op moving n bytes at off, waits out its
+// delay, and returns the most bytes it may move, or -1 for no limit,
+// and the error it must return after moving them.
+func (in *injector) begin(op Op, off int64, n int) (int, error) {
+	in.mu.Lock()
+	in.calls[op]++
+	var (
+		call  = in.calls[op]
+		limit = -1
+		err   error
+		delay time.Duration
+	)
+	for _, f := range in.plan {
+		if f.op != "" && f.op != op {
+			continue
+		}
+		delay += f.delay
+		if f.call != 0 && f.call != call {
+			continue
+		}
+
+		var l = f.limit
+		if f.offset >= 0 {
+			if off < f.offset && off+int64(n) <= f.offset {
+				continue
+			}
+			l = int(max(f.offset-off, 0))
+		}
+		if l >= 0 && (limit < 0 || l < limit) {
+			limit = l
+		}
+		if err == nil {
+			err = f.err
+		}
+	}
+	in.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+
+	return limit, err
+}
+
+// position returns the offset of the next Read or Write.
+func (in *injector) position() int64 {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return in.pos
+}
+
+func (in *injector) advance(n int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.pos += int64(n)
+}
+
+func (in *injector) seek(pos int64) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.pos = pos
+}
+
+// Calls returns how many times op has been called.
+func (in *injector) Calls(op Op) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return in.calls[op]
+}
+
+func clip(p []byte, limit int) []byte {
+	if limit >= 0 && limit < len(p) {
+		return p[:limit]
+	}
+
+	return p
+}
+
+func (in *injector) read(r io.Reader, p []byte) (int, error) {
+	var limit, err = in.begin(OpRead, in.position(), len(p))
+	var q = clip(p, limit)
+	if len(q) == 0 && err != nil {
+		return 0, err
+	}
+
+	var n, rerr = r.Read(q)
+	in.advance(n)
+	if err != nil {
+		return n, err
+	}
+
+	return n, rerr
+}
+
+func (in *injector) write(w io.Writer, p []byte) (int, error) {
+	var limit, err = in.begin(OpWrite, in.position(), len(p))
+	var q = clip(p, limit)
+	if len(q) == 0 && err != nil {
+		return 0, err
+	}
+
+	var n, werr = w.Write(q)
+	in.advance(n)
+	switch {
+	case err != nil:
+		return n, err
+	case werr == nil && n < len(p):
+		return n, io.ErrShortWrite
+	}
+
+	return n, werr
+}
+
+func (in *injector) readAt(r io.ReaderAt, p []byte, off int64) (int, error) {
+	var limit, err = in.begin(OpReadAt, off, len(p))
+	var q = clip(p, limit)
+	if len(q) == 0 && err != nil {
+		return 0, err
+	}
+
+	var n, rerr = r.ReadAt(q, off)
+	switch {
+	case err != nil:
+		return n, err
+	case rerr == nil && n < len(p):
+		return n, io.ErrUnexpectedEOF
+	}
+
+	return n, rerr
+}
+
+func (in *injector) writeAt(w io.WriterAt, p []byte, off int64) (int, error) {
+	var limit, err = in.begin(OpWriteAt, off, len(p))
+	var q = clip(p, limit)
+	if len(q) == 0 && err != nil {
+		return 0, err
+	}
+
+	var n, werr = w.WriteAt(q, off)
+	switch {
+	case err != nil:
+		return n, err
+	case werr == nil && n < len(p):
+		return n, io.ErrShortWrite
+	}
+
+	return n, werr
+}
+
+func (in *injector) doSeek(s io.Seeker, offset int64, whence int) (int64, error) {
+	if _, err := in.begin(OpSeek, in.position(), 0); err != nil {
+		return 0, err
+	}
+
+	var pos, err = s.Seek(offset, whence)
+	if err == nil {
+		in.seek(pos)
+	}
+
+	return pos, err
+}
+
+// do runs a call of op that moves no bytes, such as Sync.
+func (in *injector) do(op Op, f func() error) error {
+	if _, err := in.begin(op, in.position(), 0); err != nil {
+		return err
+	}
+
+	return f()
+}
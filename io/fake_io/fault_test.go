@@ -0,0 +1,157 @@
+package fake_io
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+	"github.com/pdutton/go-mocks/os/fake_os"
+)
+
+// TestReader_Short tests that short reads still deliver every byte.
+func TestReader_Short(t *testing.T) {
+	r := NewReader(strings.NewReader("hello, world"), Plan{Short(OpRead, 5)})
+
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 5, n)
+
+	rest, err := io.ReadAll(r)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ", world", string(rest))
+	testutil.AssertEqual(t, true, r.Calls(OpRead) > 2)
+}
+
+// TestReader_FailAt tests failing a read at a byte offset.
+func TestReader_FailAt(t *testing.T) {
+	boom := errors.New("boom")
+	r := NewReader(strings.NewReader("0123456789"), Plan{FailAt(OpRead, 7, boom)})
+
+	data, err := io.ReadAll(r)
+	testutil.AssertError(t, boom, err)
+	testutil.AssertEqual(t, "0123456", string(data))
+
+	_, err = r.Read(make([]byte, 1))
+	testutil.AssertError(t, boom, err)
+}
+
+// TestWriter_FailCall tests failing the nth write part way through.
+func TestWriter_FailCall(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Plan{FailCall(OpWrite, 3, syscall.ENOSPC).After(2)})
+
+	for i := 0; i < 2; i++ {
+		_, err := w.Write([]byte("abcd"))
+		testutil.AssertNil(t, err)
+	}
+	n, err := w.Write([]byte("abcd"))
+	testutil.AssertError(t, syscall.ENOSPC, err)
+	testutil.AssertEqual(t, 2, n)
+
+	_, err = w.Write([]byte("ef"))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "abcdabcdabef", buf.String())
+}
+
+// TestWriter_Short tests that a short write reports io.ErrShortWrite.
+func TestWriter_Short(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, Plan{Short(OpWrite, 3)})
+
+	n, err := w.Write([]byte("abcdef"))
+	testutil.AssertEqual(t, 3, n)
+	testutil.AssertError(t, io.ErrShortWrite, err)
+
+	_, err = io.Copy(w, strings.NewReader("xyz"))
+	testutil.AssertNil(t, err)
+}
+
+// TestReaderAt tests faults at absolute offsets and short ReadAt.
+func TestReaderAt(t *testing.T) {
+	boom := errors.New("bad sector")
+	r := NewReaderAt(strings.NewReader("0123456789"), Plan{FailAt(OpReadAt, 8, boom)})
+
+	buf := make([]byte, 4)
+	n, err := r.ReadAt(buf, 2)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "2345", string(buf[:n]))
+
+	n, err = r.ReadAt(buf, 6)
+	testutil.AssertError(t, boom, err)
+	testutil.AssertEqual(t, "67", string(buf[:n]))
+
+	r = NewReaderAt(strings.NewReader("0123456789"), Plan{Short(OpReadAt, 1)})
+	n, err = r.ReadAt(buf, 0)
+	testutil.AssertEqual(t, 1, n)
+	testutil.AssertError(t, io.ErrUnexpectedEOF, err)
+}
+
+// TestSeeker tests that a failed seek leaves the position alone.
+func TestSeeker(t *testing.T) {
+	sr := strings.NewReader("0123456789")
+	s := NewSeeker(sr, Plan{FailCall(OpSeek, 2, syscall.ESPIPE)})
+
+	pos, err := s.Seek(4, io.SeekStart)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, int64(4), pos)
+
+	_, err = s.Seek(0, io.SeekEnd)
+	testutil.AssertError(t, syscall.ESPIPE, err)
+	pos, _ = sr.Seek(0, io.SeekCurrent)
+	testutil.AssertEqual(t, int64(4), pos)
+}
+
+// TestFile tests a plan on a fake_os file, whose offsets follow Seek.
+func TestFile(t *testing.T) {
+	fos := fake_os.New()
+	f, err := fos.Create("/out")
+	testutil.AssertNil(t, err)
+
+	ff := NewFile(f, Plan{
+		FailAt(OpWrite, 10, syscall.ENOSPC),
+		FailCall(OpSync, 1, syscall.EIO),
+		FailCall(OpClose, 1, syscall.EIO),
+	})
+
+	_, err = ff.WriteString("0123456789abc")
+	testutil.AssertError(t, syscall.ENOSPC, err)
+
+	// ReadFrom cannot get around the fault.
+	_, err = ff.ReadFrom(strings.NewReader("more"))
+	testutil.AssertError(t, syscall.ENOSPC, err)
+
+	// Seeking back moves below the offset again.
+	_, err = ff.Seek(8, io.SeekStart)
+	testutil.AssertNil(t, err)
+	n, err := ff.Write([]byte("xyz"))
+	testutil.AssertError(t, syscall.ENOSPC, err)
+	testutil.AssertEqual(t, 2, n)
+
+	testutil.AssertError(t, syscall.EIO, ff.Sync())
+	testutil.AssertNil(t, ff.Sync())
+	testutil.AssertError(t, syscall.EIO, ff.Close())
+
+	// The file was closed all the same.
+	_, err = f.Write([]byte("x"))
+	testutil.AssertNotNil(t, err)
+
+	data, err := fos.ReadFile("/out")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "01234567xy", string(data))
+}
+
+// TestDelay tests adding latency to every call.
+func TestDelay(t *testing.T) {
+	r := NewReader(strings.NewReader("abc"), Plan{Delay("", 5*time.Millisecond)})
+
+	start := time.Now()
+	_, err := io.ReadAll(r)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, time.Since(start) >= 10*time.Millisecond)
+}
@@ -0,0 +1,326 @@
+package fake_io
+
+import (
+	"io"
+	"slices"
+	"sync"
+
+	ioi "github.com/pdutton/go-interfaces/io"
+)
+
+// Call records one call of an IO function.
+type Call struct {
+	// Func is the name of the function, such as "Copy" or "TeeReader".
+	Func string
+
+	// Readers and Writers are the readers and writers passed to it.
+	Readers []ioi.Reader
+	Writers []ioi.Writer
+
+	// Buffer is the length of the buffer passed to CopyBuffer,
+	// ReadAtLeast or ReadFull.
+	Buffer int
+
+	// Limit is the n of CopyN, LimitReader and NewLimitedReader, the
+	// min of ReadAtLeast and the size of NewSectionReader.
+	Limit int64
+
+	// Offset is the offset passed to NewSectionReader or
+	// NewOffsetWriter.
+	Offset int64
+
+	// N is the number of bytes the call moved.  For a function that
+	// returns a reader or writer, it counts the bytes that have gone
+	// through that reader or writer so far.
+	N int64
+
+	// Err is the error the call returned.  For a function that returns
+	// a reader or writer, it is the first error other than io.EOF that
+	// has come out of it so far.
+	Err error
+}
+
+// IO implements the go-interfaces io.IO interface by calling the real
+// io package, recording every call along with the bytes it moved and
+// the error it returned:
+//
+//	fio := fake_io.New()
+//	Upload(fio, dst, body)
+//	for _, c := range fio.CallsTo("ReadAll") {
+//		if slices.Contains(c.Readers, body) {
+//			t.Errorf("the untrusted body was read into memory")
+//		}
+//	}
+//
+// It is safe for concurrent use.
+type IO struct {
+	mu    sync.Mutex
+	real  ioi.IO
+	calls []*Call
+}
+
+// New returns an IO with no calls recorded.
+func New() *IO {
+	return &IO{real: ioi.NewIO()}
+}
+
+// Calls returns a copy of every call so far, in order.
+func (fio *IO) Calls() []Call {
+	return fio.filter("")
+}
+
+// CallsTo returns a copy of every call to the named function so far,
+// in order.
+func (fio *IO) CallsTo(name string) []Call {
+	return fio.filter(name)
+}
+
+func (fio *IO) filter(name string) []Call {
+	fio.mu.Lock()
+	defer fio.mu.Unlock()
+
+	var calls []Call
+	for _, c := range fio.calls {
+		if name == "" || c.Func == name {
+			calls = append(calls, *c)
+		}
+	}
+
+	return calls
+}
+
+// record adds c to the calls.
+func (fio *IO) record(c *Call) *Call {
+	fio.mu.Lock()
+	defer fio.mu.Unlock()
+
+	fio.calls = append(fio.calls, c)
+
+	return c
+}
+
+// finish sets the result of c.
+func (fio *IO) finish(c *Call, n int64, err error) {
+	fio.mu.Lock()
+	defer fio.mu.Unlock()
+
+	c.N, c.Err = n, err
+}
+
+// count adds n bytes, and err unless it is io.EOF or c already has an
+// error, to c.
+func (fio *IO) count(c *Call, n int, err error) {
+	fio.mu.Lock()
+	defer fio.mu.Unlock()
+
+	c.N += int64(n)
+	if err != nil && err != io.EOF && c.Err == nil {
+		c.Err = err
+	}
+}
+
+func (fio *IO) Copy(w ioi.Writer, r ioi.Reader) (int64, error) {
+	var c = fio.record(&Call{Func: "Copy", Readers: []ioi.Reader{r}, Writers: []ioi.Writer{w}})
+	var n, err = fio.real.Copy(w, r)
+	fio.finish(c, n, err)
+
+	return n, err
+}
+
+func (fio *IO) CopyBuffer(w ioi.Writer, r ioi.Reader, buf []byte) (int64, error) {
+	var c = fio.record(&Call{
+		Func:    "CopyBuffer",
+		Readers: []ioi.Reader{r},
+		Writers: []ioi.Writer{w},
+		Buffer:  len(buf),
+	})
+	var n, err = fio.real.CopyBuffer(w, r, buf)
+	fio.finish(c, n, err)
+
+	return n, err
+}
+
+func (fio *IO) CopyN(w ioi.Writer, r ioi.Reader, n int64) (int64, error) {
+	var c = fio.record(&Call{
+		Func:    "CopyN",
+		Readers: []ioi.Reader{r},
+		Writers: []ioi.Writer{w},
+		Limit:   n,
+	})
+	var written, err = fio.real.CopyN(w, r, n)
+	fio.finish(c, written, err)
+
+	return written, err
+}
+
+// Pipe returns a pipe whose call counts the bytes read from it.
+func (fio *IO) Pipe() (ioi.PipeReader, ioi.PipeWriter) {
+	var c = fio.record(&Call{Func: "Pipe"})
+	var pr, pw = fio.real.Pipe()
+
+	return &pipeReader{PipeReader: pr, fio: fio, call: c}, pw
+}
+
+func (fio *IO) ReadAll(r ioi.Reader) ([]byte, error) {
+	var c = fio.record(&Call{Func: "ReadAll", Readers: []ioi.Reader{r}})
+	var data, err = fio.real.ReadAll(r)
+	fio.finish(c, int64(len(data)), err)
+
+	return data, err
+}
+
+func (fio *IO) ReadAtLeast(r ioi.Reader, buf []byte, min int) (int, error) {
+	var c = fio.record(&Call{
+		Func:    "ReadAtLeast",
+		Readers: []ioi.Reader{r},
+		Buffer:  len(buf),
+		Limit:   int64(min),
+	})
+	var n, err = fio.real.ReadAtLeast(r, buf, min)
+	fio.finish(c, int64(n), err)
+
+	return n, err
+}
+
+func (fio *IO) ReadFull(r ioi.Reader, buf []byte) (int, error) {
+	var c = fio.record(&Call{Func: "ReadFull", Readers: []ioi.Reader{r}, Buffer: len(buf)})
+	var n, err = fio.real.ReadFull(r, buf)
+	fio.finish(c, int64(n), err)
+
+	return n, err
+}
+
+func (fio *IO) WriteString(w ioi.Writer, s string) (int, error) {
+	var c = fio.record(&Call{Func: "WriteString", Writers: []ioi.Writer{w}})
+	var n, err = fio.real.WriteString(w, s)
+	fio.finish(c, int64(n), err)
+
+	return n, err
+}
+
+func (fio *IO) NewLimitedReader(r ioi.Reader, n int64) ioi.LimitedReader {
+	var c = fio.record(&Call{Func: "NewLimitedReader", Readers: []ioi.Reader{r}, Limit: n})
+	return &reader{r: fio.real.NewLimitedReader(r, n), fio: fio, call: c}
+}
+
+func (fio *IO) NewOffsetWriter(w ioi.WriterAt, off int64) ioi.OffsetWriter {
+	var c = fio.record(&Call{Func: "NewOffsetWriter", Offset: off})
+	return &offsetWriter{OffsetWriter: fio.real.NewOffsetWriter(w, off), fio: fio, call: c}
+}
+
+// NopCloser returns a ReadCloser whose call counts the bytes read from
+// it.
+func (fio *IO) NopCloser(r ioi.Reader) ioi.ReadCloser {
+	var c = fio.record(&Call{Func: "NopCloser", Readers: []ioi.Reader{r}})
+	return fio.real.NopCloser(&reader{r: r, fio: fio, call: c})
+}
+
+func (fio *IO) LimitReader(r ioi.Reader, n int64) ioi.Reader {
+	var c = fio.record(&Call{Func: "LimitReader", Readers: []ioi.Reader{r}, Limit: n})
+	return &reader{r: fio.real.LimitReader(r, n), fio: fio, call: c}
+}
+
+func (fio *IO) MultiReader(readers ...ioi.Reader) ioi.Reader {
+	var c = fio.record(&Call{Func: "MultiReader", Readers: slices.Clone(readers)})
+	return &reader{r: fio.real.MultiReader(readers...), fio: fio, call: c}
+}
+
+// TeeReader returns a reader whose call counts the bytes read from it,
+// which are also the bytes written to w.
+func (fio *IO) TeeReader(r ioi.Reader, w ioi.Writer) ioi.Reader {
+	var c = fio.record(&Call{Func: "TeeReader", Readers: []ioi.Reader{r}, Writers: []ioi.Writer{w}})
+	return &reader{r: fio.real.TeeReader(r, w), fio: fio, call: c}
+}
+
+func (fio *IO) NewSectionReader(r ioi.ReaderAt, off int64, n int64) ioi.SectionReader {
+	var c = fio.record(&Call{Func: "NewSectionReader", Offset: off, Limit: n})
+	return &sectionReader{SectionReader: fio.real.NewSectionReader(r, off, n), fio: fio, call: c}
+}
+
+func (fio *IO) MultiWriter(writers ...ioi.Writer) ioi.Writer {
+	var c = fio.record(&Call{Func: "MultiWriter", Writers: slices.Clone(writers)})
+	return &writer{w: fio.real.MultiWriter(writers...), fio: fio, call: c}
+}
+
+// reader counts the bytes read through it into its call.
+type reader struct {
+	r    ioi.Reader
+	fio  *IO
+	call *Call
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	var n, err = r.r.Read(p)
+	r.fio.count(r.call, n, err)
+
+	return n, err
+}
+
+// writer counts the bytes written through it into its call.
+type writer struct {
+	w    ioi.Writer
+	fio  *IO
+	call *Call
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	var n, err = w.w.Write(p)
+	w.fio.count(w.call, n, err)
+
+	return n, err
+}
+
+type pipeReader struct {
+	ioi.PipeReader
+	fio  *IO
+	call *Call
+}
+
+func (pr *pipeReader) Read(p []byte) (int, error) {
+	var n, err = pr.PipeReader.Read(p)
+	pr.fio.count(pr.call, n, err)
+
+	return n, err
+}
+
+type offsetWriter struct {
+	ioi.OffsetWriter
+	fio  *IO
+	call *Call
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	var n, err = ow.OffsetWriter.Write(p)
+	ow.fio.count(ow.call, n, err)
+
+	return n, err
+}
+
+func (ow *offsetWriter) WriteAt(p []byte, off int64) (int, error) {
+	var n, err = ow.OffsetWriter.WriteAt(p, off)
+	ow.fio.count(ow.call, n, err)
+
+	return n, err
+}
+
+type sectionReader struct {
+	ioi.SectionReader
+	fio  *IO
+	call *Call
+}
+
+func (sr *sectionReader) Read(p []byte) (int, error) {
+	var n, err = sr.SectionReader.Read(p)
+	sr.fio.count(sr.call, n, err)
+
+	return n, err
+}
+
+func (sr *sectionReader) ReadAt(p []byte, off int64) (int, error) {
+	var n, err = sr.SectionReader.ReadAt(p, off)
+	sr.fio.count(sr.call, n, err)
+
+	return n, err
+}
+
+var _ ioi.IO = (*IO)(nil)
@@ -0,0 +1,113 @@
+package fake_io
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestIO_Copy tests recording the bytes the copy functions move.
+func TestIO_Copy(t *testing.T) {
+	fio := New()
+	var buf bytes.Buffer
+	src := strings.NewReader(strings.Repeat("x", 4096))
+
+	n, err := fio.Copy(&buf, src)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, int64(4096), n)
+
+	_, err = fio.CopyBuffer(&buf, strings.NewReader("abc"), make([]byte, 2))
+	testutil.AssertNil(t, err)
+
+	boom := errors.New("boom")
+	_, err = fio.CopyN(&buf, io.MultiReader(strings.NewReader("ab"), iotest.ErrReader(boom)), 10)
+	testutil.AssertError(t, boom, err)
+
+	calls := fio.Calls()
+	testutil.AssertEqual(t, 3, len(calls))
+	testutil.AssertEqual(t, "Copy", calls[0].Func)
+	testutil.AssertEqual(t, int64(4096), calls[0].N)
+	testutil.AssertEqual(t, io.Reader(src), calls[0].Readers[0])
+	testutil.AssertEqual(t, 2, calls[1].Buffer)
+	testutil.AssertEqual(t, int64(3), calls[1].N)
+	testutil.AssertEqual(t, int64(10), calls[2].Limit)
+	testutil.AssertEqual(t, int64(2), calls[2].N)
+	testutil.AssertError(t, boom, calls[2].Err)
+}
+
+// TestIO_Read tests recording the read functions.
+func TestIO_Read(t *testing.T) {
+	fio := New()
+	body := strings.NewReader("0123456789")
+
+	buf := make([]byte, 4)
+	_, err := fio.ReadFull(body, buf)
+	testutil.AssertNil(t, err)
+	_, err = fio.ReadAtLeast(body, make([]byte, 8), 8)
+	testutil.AssertError(t, io.ErrUnexpectedEOF, err)
+	_, err = fio.WriteString(io.Discard, "hello")
+	testutil.AssertNil(t, err)
+
+	testutil.AssertEqual(t, 0, len(fio.CallsTo("ReadAll")))
+	calls := fio.CallsTo("ReadAtLeast")
+	testutil.AssertEqual(t, 1, len(calls))
+	testutil.AssertEqual(t, int64(6), calls[0].N)
+	testutil.AssertEqual(t, int64(8), calls[0].Limit)
+	testutil.AssertEqual(t, 8, calls[0].Buffer)
+	testutil.AssertError(t, io.ErrUnexpectedEOF, calls[0].Err)
+	testutil.AssertEqual(t, int64(5), fio.CallsTo("WriteString")[0].N)
+}
+
+// TestIO_Readers tests that returned readers and writers count the
+// bytes that go through them.
+func TestIO_Readers(t *testing.T) {
+	fio := New()
+	var tee, out bytes.Buffer
+
+	r := fio.TeeReader(fio.LimitReader(strings.NewReader("0123456789"), 6), &tee)
+	w := fio.MultiWriter(&out, io.Discard)
+	_, err := io.Copy(w, r)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "012345", tee.String())
+
+	data, err := io.ReadAll(fio.NopCloser(fio.MultiReader(strings.NewReader("a"), strings.NewReader("b"))))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "ab", string(data))
+
+	calls := fio.Calls()
+	testutil.AssertEqual(t, 5, len(calls))
+	for i, want := range []int64{6, 6, 6, 2, 2} {
+		testutil.AssertEqual(t, want, calls[i].N)
+	}
+	testutil.AssertEqual(t, "MultiReader", calls[3].Func)
+	testutil.AssertEqual(t, 2, len(calls[3].Readers))
+}
+
+// TestIO_Pipe tests counting the bytes through a pipe and a section.
+func TestIO_Pipe(t *testing.T) {
+	fio := New()
+	pr, pw := fio.Pipe()
+	go func() {
+		fio.WriteString(pw, "hello, pipe")
+		pw.Close()
+	}()
+
+	data, err := fio.ReadAll(pr)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "hello, pipe", string(data))
+	testutil.AssertEqual(t, int64(11), fio.CallsTo("Pipe")[0].N)
+
+	sr := fio.NewSectionReader(strings.NewReader("0123456789"), 2, 5)
+	_, err = sr.ReadAt(make([]byte, 3), 0)
+	testutil.AssertNil(t, err)
+	_, err = io.ReadAll(sr)
+	testutil.AssertNil(t, err)
+	call := fio.CallsTo("NewSectionReader")[0]
+	testutil.AssertEqual(t, int64(8), call.N)
+	testutil.AssertEqual(t, int64(2), call.Offset)
+}
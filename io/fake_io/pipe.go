@@ -0,0 +1,442 @@
+package fake_io
+
+import (
+	"io"
+	"sync"
+
+	ioi "github.com/pdutton/go-interfaces/io"
+)
+
+// PipeOption configures a Pipe created by NewPipe.
+type PipeOption func(*Pipe)
+
+// WithPipeBuffer lets a Write return once its data fits in a buffer of
+// n bytes, rather than when the reader has taken all of it.
+func WithPipeBuffer(n int) PipeOption {
+	return func(p *Pipe) {
+		p.capacity = n
+	}
+}
+
+// StartPaused makes the pipe start paused, as if Pause had been
+// called.
+func StartPaused() PipeOption {
+	return func(p *Pipe) {
+		p.paused = true
+	}
+}
+
+// PipeState is a snapshot of a Pipe.
+type PipeState struct {
+	// Buffered is the number of bytes written and accepted, which the
+	// reader has not yet taken.
+	Buffered int
+
+	// Blocked is the number of bytes of a Write still waiting, either
+	// for the reader or for room in the buffer.
+	Blocked int
+
+	// Written is the number of bytes accepted from writes, and Read
+	// the number taken by the reader.
+	Written, Read int64
+
+	// Readers and Writers are the numbers of goroutines blocked in
+	// Read and Write.
+	Readers, Writers int
+
+	Paused       bool
+	ReaderClosed bool
+	WriterClosed bool
+}
+
+// Pipe is an in-memory pipe implementing the go-interfaces
+// io.PipeReader and io.PipeWriter, whose flow a test controls.  Like
+// io.Pipe it is synchronous: a Write returns once the reader has taken
+// all of its data, unless WithPipeBuffer gives it room to wait in.
+//
+// While the pipe is paused, reads take nothing, so writers block as
+// though the consumer had stalled; Step lets a chosen number of bytes
+// through.  State and WaitUntil report how many bytes are buffered and
+// blocked, and CloseReaderAt and CloseWriterAt close either end once a
+// chosen number of bytes has been read:
+//
+//	p := fake_io.NewPipe(fake_io.StartPaused())
+//	go Produce(p.Writer())
+//	p.WaitUntil(func(s fake_io.PipeState) bool { return s.Blocked > 0 })
+//	p.CloseReaderAt(100, errors.New("consumer gone"))
+//	p.Resume()
+type Pipe struct {
+	mu       sync.Mutex
+	cond     *sync.Cond // signals readers and writers
+	changed  *sync.Cond // signals WaitUntil
+	buf      []byte
+	cur      []byte // the part of the current Write not yet accepted
+	writing  bool
+	capacity int
+
+	paused    bool
+	allowance int
+
+	written, read    int64
+	readers, writers int
+
+	rerr, werr error
+	closeAt    []closeAt
+
+	reader *PipeReader
+	writer *PipeWriter
+}
+
+// closeAt closes an end of the pipe once n bytes have been read.
+type closeAt struct {
+	n      int64
+	reader bool
+	err    error
+}
+
+// NewPipe returns a running, empty pipe.
+func NewPipe(options ...PipeOption) *Pipe {
+	var p = &Pipe{}
+	p.cond = sync.NewCond(&p.mu)
+	p.changed = sync.NewCond(&p.mu)
+	p.reader = &PipeReader{p: p}
+	p.writer = &PipeWriter{p: p}
+
+	for _, f := range options {
+		f(p)
+	}
+
+	return p
+}
+
+// Reader returns the read end of the pipe.
+func (p *Pipe) Reader() *PipeReader {
+	return p.reader
+}
+
+// Writer returns the write end of the pipe.
+func (p *Pipe) Writer() *PipeWriter {
+	return p.writer
+}
+
+// Pause stops the reader taking data until Resume or Step.
+func (p *Pipe) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused = true
+	p.allowance = 0
+	p.notify()
+}
+
+// Resume lets data flow again.
+func (p *Pipe) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused = false
+	p.notify()
+}
+
+// Step lets the reader take up to n more bytes while the pipe is
+// paused.
+func (p *Pipe) Step(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.allowance += n
+	p.notify()
+}
+
+// CloseReaderAt closes the read end with err, as CloseWithError does,
+// once n bytes have been read.  A read that would go past n stops
+// there.
+func (p *Pipe) CloseReaderAt(n int64, err error) {
+	p.addCloseAt(closeAt{n: n, reader: true, err: err})
+}
+
+// CloseWriterAt closes the write end with err, as CloseWithError does,
+// once n bytes have been read.
+func (p *Pipe) CloseWriterAt(n int64, err error) {
+	p.addCloseAt(closeAt{n: n, err: err})
+}
+
+func (p *Pipe) addCloseAt(c closeAt) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeAt = append(p.closeAt, c)
+	p.checkCloseAt()
+}
+
+// State returns a snapshot of the pipe.
+func (p *Pipe) State() PipeState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.state()
+}
+
+func (p *Pipe) state() PipeState {
+	return PipeState{
+		Buffered:     len(p.buf),
+		Blocked:      len(p.cur),
+		Written:      p.written,
+		Read:         p.read,
+		Readers:      p.readers,
+		Writers:      p.writers,
+		Paused:       p.paused,
+		ReaderClosed: p.rerr != nil,
+		WriterClosed: p.werr != nil,
+	}
+}
+
+// WaitUntil blocks until f, called with a snapshot each time the pipe
+// changes, returns true.
+func (p *Pipe) WaitUntil(f func(PipeState) bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for !f(p.state()) {
+		p.changed.Wait()
+	}
+}
+
+// notify wakes everything waiting for the pipe to change.  It must be
+// called with mu held.
+func (p *Pipe) notify() {
+	p.cond.Broadcast()
+	p.changed.Broadcast()
+}
+
+// wait blocks until the pipe changes, counting the goroutine as
+// blocked in the meantime.  It must be called with mu held.
+func (p *Pipe) wait(blocked *int) {
+	*blocked++
+	p.changed.Broadcast()
+	p.cond.Wait()
+	*blocked--
+}
+
+// checkCloseAt applies the closes whose point has been reached.  It
+// must be called with mu held.
+func (p *Pipe) checkCloseAt() {
+	var pending = p.closeAt[:0]
+	for _, c := range p.closeAt {
+		switch {
+		case p.read < c.n:
+			pending = append(pending, c)
+		case c.reader:
+			p.closeRead(c.err)
+		default:
+			p.closeWrite(c.err)
+		}
+	}
+	p.closeAt = pending
+}
+
+// limit returns the most bytes the reader may take next, or -1 for
+// no limit.  It must be called with mu held.
+func (p *Pipe) limit() int64 {
+	var limit = int64(-1)
+	if p.paused {
+		limit = int64(p.allowance)
+	}
+	for _, c := range p.closeAt {
+		if limit < 0 || c.n-p.read < limit {
+			limit = c.n - p.read
+		}
+	}
+
+	return limit
+}
+
+// accept moves as much of the current Write into the buffer as fits,
+// unless either end is closed.  It must be called with mu held.
+func (p *Pipe) accept() {
+	if p.rerr != nil || p.werr != nil {
+		return
+	}
+
+	var n = min(len(p.cur), p.capacity-len(p.buf))
+	if n > 0 {
+		p.buf = append(p.buf, p.cur[:n]...)
+		p.cur = p.cur[n:]
+		p.written += int64(n)
+	}
+}
+
+func (p *Pipe) closeRead(err error) {
+	if p.rerr != nil {
+		return
+	}
+	if err == nil {
+		err = io.ErrClosedPipe
+	}
+	p.rerr = err
+	p.buf = nil
+	p.notify()
+}
+
+func (p *Pipe) closeWrite(err error) {
+	if p.werr != nil {
+		return
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	p.werr = err
+	p.notify()
+}
+
+func (p *Pipe) readPipe(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if p.rerr != nil {
+			return 0, io.ErrClosedPipe
+		}
+
+		// A write cut short by closing the writer is not read.
+		var avail = len(p.buf)
+		if p.werr == nil {
+			avail += len(p.cur)
+		}
+
+		var limit = p.limit()
+		if len(b) > 0 && limit != 0 && avail > 0 {
+			var want = b
+			if limit > 0 && int64(len(want)) > limit {
+				want = want[:limit]
+			}
+
+			var n = copy(want, p.buf)
+			p.buf = p.buf[n:]
+			var m = 0
+			if p.werr == nil {
+				m = copy(want[n:], p.cur)
+				p.cur = p.cur[m:]
+			}
+			p.written += int64(m)
+			n += m
+
+			p.read += int64(n)
+			if p.paused {
+				p.allowance -= n
+			}
+			p.accept()
+			p.checkCloseAt()
+			p.notify()
+
+			return n, nil
+		}
+
+		if p.werr != nil && avail == 0 {
+			return 0, p.werr
+		}
+		if len(b) == 0 {
+			return 0, nil
+		}
+
+		p.wait(&p.readers)
+	}
+}
+
+func (p *Pipe) writePipe(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.writing && p.rerr == nil && p.werr == nil {
+		p.wait(&p.writers)
+	}
+	switch {
+	case p.werr != nil:
+		return 0, io.ErrClosedPipe
+	case p.rerr != nil:
+		return 0, p.rerr
+	}
+
+	p.writing = true
+	p.cur = b
+	defer func() {
+		p.writing = false
+		p.cur = nil
+		p.notify()
+	}()
+
+	for {
+		p.accept()
+		p.notify()
+
+		var n = len(b) - len(p.cur)
+		switch {
+		case len(p.cur) == 0:
+			return n, nil
+		case p.rerr != nil:
+			return n, p.rerr
+		case p.werr != nil:
+			return n, io.ErrClosedPipe
+		}
+
+		p.wait(&p.writers)
+	}
+}
+
+// PipeReader is the read end of a Pipe.
+type PipeReader struct {
+	p *Pipe
+}
+
+func (r *PipeReader) Read(b []byte) (int, error) {
+	return r.p.readPipe(b)
+}
+
+// Close closes the reader; later writes return io.ErrClosedPipe.
+func (r *PipeReader) Close() error {
+	return r.CloseWithError(nil)
+}
+
+// CloseWithError closes the reader; later writes return err, or
+// io.ErrClosedPipe if it is nil.  It never overwrites an earlier
+// error, and always returns nil.
+func (r *PipeReader) CloseWithError(err error) error {
+	r.p.mu.Lock()
+	defer r.p.mu.Unlock()
+
+	r.p.closeRead(err)
+
+	return nil
+}
+
+// PipeWriter is the write end of a Pipe.
+type PipeWriter struct {
+	p *Pipe
+}
+
+func (w *PipeWriter) Write(b []byte) (int, error) {
+	return w.p.writePipe(b)
+}
+
+// Close closes the writer; once the data written has been read, reads
+// return io.EOF.
+func (w *PipeWriter) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError closes the writer; once the data written has been
+// read, reads return err, or io.EOF if it is nil.  It never overwrites
+// an earlier error, and always returns nil.
+func (w *PipeWriter) CloseWithError(err error) error {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+
+	w.p.closeWrite(err)
+
+	return nil
+}
+
+var (
+	_ ioi.PipeReader = (*PipeReader)(nil)
+	_ ioi.PipeWriter = (*PipeWriter)(nil)
+)
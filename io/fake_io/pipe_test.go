@@ -0,0 +1,121 @@
+package fake_io
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	ioi "github.com/pdutton/go-interfaces/io"
+	"github.com/pdutton/go-mocks/internal/testutil"
+	"github.com/pdutton/go-mocks/io/conformance"
+)
+
+type writeResult struct {
+	n   int
+	err error
+}
+
+// write writes data to w in a new goroutine, returning its result on
+// the channel.
+func write(w io.Writer, data string) <-chan writeResult {
+	var done = make(chan writeResult, 1)
+	go func() {
+		n, err := w.Write([]byte(data))
+		done <- writeResult{n, err}
+	}()
+
+	return done
+}
+
+// TestPipe_Conformance tests that a Pipe behaves like io.Pipe.
+func TestPipe_Conformance(t *testing.T) {
+	conformance.TestPipe(t, func() (ioi.PipeReader, ioi.PipeWriter) {
+		p := NewPipe()
+		return p.Reader(), p.Writer()
+	})
+}
+
+// TestPipe_Step tests stepping a paused pipe a few bytes at a time.
+func TestPipe_Step(t *testing.T) {
+	p := NewPipe(StartPaused())
+	done := write(p.Writer(), "0123456789")
+
+	p.WaitUntil(func(s PipeState) bool { return s.Blocked == 10 && s.Writers == 1 })
+
+	p.Step(4)
+	buf := make([]byte, 10)
+	n, err := p.Reader().Read(buf)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "0123", string(buf[:n]))
+
+	s := p.State()
+	testutil.AssertEqual(t, 6, s.Blocked)
+	testutil.AssertEqual(t, int64(4), s.Read)
+	testutil.AssertEqual(t, true, s.Paused)
+
+	go p.Reader().Read(buf)
+	p.WaitUntil(func(s PipeState) bool { return s.Readers == 1 })
+	p.Resume()
+
+	r := <-done
+	testutil.AssertNil(t, r.err)
+	testutil.AssertEqual(t, 10, r.n)
+	testutil.AssertEqual(t, int64(10), p.State().Read)
+}
+
+// TestPipe_Buffer tests that a write returns once its data is buffered.
+func TestPipe_Buffer(t *testing.T) {
+	p := NewPipe(WithPipeBuffer(4), StartPaused())
+
+	r := <-write(p.Writer(), "abc")
+	testutil.AssertNil(t, r.err)
+
+	done := write(p.Writer(), "defgh")
+	p.WaitUntil(func(s PipeState) bool { return s.Buffered == 4 && s.Blocked == 4 })
+
+	p.Resume()
+	buf := make([]byte, 8)
+	n, err := io.ReadFull(p.Reader(), buf)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "abcdefgh", string(buf[:n]))
+	testutil.AssertNil(t, (<-done).err)
+}
+
+// TestPipe_CloseReaderAt tests the consumer going away part way
+// through a write.
+func TestPipe_CloseReaderAt(t *testing.T) {
+	gone := errors.New("consumer gone")
+	p := NewPipe()
+	p.CloseReaderAt(5, gone)
+	done := write(p.Writer(), "0123456789")
+
+	data, err := io.ReadAll(p.Reader())
+	testutil.AssertError(t, io.ErrClosedPipe, err)
+	testutil.AssertEqual(t, "01234", string(data))
+
+	r := <-done
+	testutil.AssertEqual(t, 5, r.n)
+	testutil.AssertError(t, gone, r.err)
+	testutil.AssertEqual(t, true, p.State().ReaderClosed)
+
+	_, err = p.Writer().Write([]byte("x"))
+	testutil.AssertError(t, gone, err)
+}
+
+// TestPipe_CloseWriterAt tests the producer failing part way through
+// a write.
+func TestPipe_CloseWriterAt(t *testing.T) {
+	failed := errors.New("producer failed")
+	p := NewPipe(WithPipeBuffer(2))
+	p.CloseWriterAt(3, failed)
+	done := write(p.Writer(), "0123456789")
+
+	// The data buffered before the writer closed can still be read.
+	data, err := io.ReadAll(p.Reader())
+	testutil.AssertError(t, failed, err)
+	testutil.AssertEqual(t, "01234", string(data))
+
+	r := <-done
+	testutil.AssertError(t, io.ErrClosedPipe, r.err)
+	testutil.AssertEqual(t, 5, r.n)
+}
@@ -0,0 +1,162 @@
+package fake_io
+
+import (
+	"io"
+
+	osi "github.com/pdutton/go-interfaces/os"
+)
+
+// Reader is an io.Reader whose reads follow a Plan.
+type Reader struct {
+	*injector
+	r io.Reader
+}
+
+// NewReader returns r with the faults of plan for OpRead.
+func NewReader(r io.Reader, plan Plan) *Reader {
+	return &Reader{injector: newInjector(plan), r: r}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	return r.read(r.r, p)
+}
+
+// Writer is an io.Writer whose writes follow a Plan.
+type Writer struct {
+	*injector
+	w io.Writer
+}
+
+// NewWriter returns w with the faults of plan for OpWrite.
+func NewWriter(w io.Writer, plan Plan) *Writer {
+	return &Writer{injector: newInjector(plan), w: w}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.write(w.w, p)
+}
+
+// ReaderAt is an io.ReaderAt whose reads follow a Plan.
+type ReaderAt struct {
+	*injector
+	r io.ReaderAt
+}
+
+// NewReaderAt returns r with the faults of plan for OpReadAt.
+func NewReaderAt(r io.ReaderAt, plan Plan) *ReaderAt {
+	return &ReaderAt{injector: newInjector(plan), r: r}
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.readAt(r.r, p, off)
+}
+
+// WriterAt is an io.WriterAt whose writes follow a Plan.
+type WriterAt struct {
+	*injector
+	w io.WriterAt
+}
+
+// NewWriterAt returns w with the faults of plan for OpWriteAt.
+func NewWriterAt(w io.WriterAt, plan Plan) *WriterAt {
+	return &WriterAt{injector: newInjector(plan), w: w}
+}
+
+func (w *WriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return w.writeAt(w.w, p, off)
+}
+
+// Seeker is an io.Seeker whose seeks follow a Plan.
+type Seeker struct {
+	*injector
+	s io.Seeker
+}
+
+// NewSeeker returns s with the faults of plan for OpSeek.
+func NewSeeker(s io.Seeker, plan Plan) *Seeker {
+	return &Seeker{injector: newInjector(plan), s: s}
+}
+
+func (s *Seeker) Seek(offset int64, whence int) (int64, error) {
+	return s.doSeek(s.s, offset, whence)
+}
+
+// File is a go-interfaces os.File whose reads, writes, seeks, syncs
+// and close follow a Plan.  The methods that move data in bulk,
+// ReadFrom, WriteTo and WriteString, go through the faulted Read and
+// Write, so a plan cannot be bypassed; the others reach the wrapped
+// file directly.
+type File struct {
+	osi.File
+	*injector
+}
+
+// NewFile returns f with the faults of plan.  Offsets for Read and
+// Write start from the position of f.
+func NewFile(f osi.File, plan Plan) *File {
+	var in = newInjector(plan)
+	if pos, err := f.Seek(0, io.SeekCurrent); err == nil {
+		in.pos = pos
+	}
+
+	return &File{File: f, injector: in}
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	return f.read(f.File, p)
+}
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	return f.readAt(f.File, p, off)
+}
+
+// ReadFrom copies r into the file with Write.
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *File) Write(p []byte) (int, error) {
+	return f.write(f.File, p)
+}
+
+func (f *File) WriteAt(p []byte, off int64) (int, error) {
+	return f.writeAt(f.File, p, off)
+}
+
+func (f *File) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// WriteTo copies the file into w with Read.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{f})
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	return f.doSeek(f.File, offset, whence)
+}
+
+func (f *File) Sync() error {
+	return f.do(OpSync, f.File.Sync)
+}
+
+// Close closes the wrapped file even when the plan makes it fail, so
+// the failure is only what the caller sees.
+func (f *File) Close() error {
+	var _, err = f.begin(OpClose, f.position(), 0)
+	var cerr = f.File.Close()
+	if err != nil {
+		return err
+	}
+
+	return cerr
+}
+
+var (
+	_ io.Reader   = (*Reader)(nil)
+	_ io.Writer   = (*Writer)(nil)
+	_ io.ReaderAt = (*ReaderAt)(nil)
+	_ io.WriterAt = (*WriterAt)(nil)
+	_ io.Seeker   = (*Seeker)(nil)
+	_ osi.File    = (*File)(nil)
+)
@@ -0,0 +1,333 @@
+package fake_fs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+)
+
+// Op names an operation that a fault can be injected into.
+type Op string
+
+const (
+	// OpOpen faults opening a file, and reading a directory or file by
+	// name, which opens it.
+	OpOpen Op = "open"
+
+	// OpStat faults Stat on a path or an open file, and Info on its
+	// directory entry.
+	OpStat Op = "stat"
+
+	// OpRead faults reading the contents of an open file.
+	OpRead Op = "read"
+
+	// OpReadDir faults reading the entries of an open directory.
+	OpReadDir Op = "readdir"
+)
+
+// fault is an error injected into op on paths matching pattern.  A
+// fault with no op applies to opening and statting.  A read fault
+// lets the first offset bytes be read before failing.
+type fault struct {
+	op      Op
+	pattern string
+	offset  int64
+	err     error
+}
+
+func (f fault) matches(op Op, name string) bool {
+	if f.op != op && (f.op != "" || (op != OpOpen && op != OpStat)) {
+		return false
+	}
+	if f.pattern == name {
+		return true
+	}
+
+	var ok, _ = path.Match(f.pattern, name)
+	return ok
+}
+
+// tree is the state shared by a FileSystem and its subtrees.
+type tree struct {
+	mu     sync.Mutex
+	base   fs.FS
+	faults []fault
+}
+
+// find returns the most recently injected fault for op on name, which
+// is relative to the root of the tree.
+func (t *tree) find(op Op, name string) (fault, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := len(t.faults) - 1; i >= 0; i-- {
+		if t.faults[i].matches(op, name) {
+			return t.faults[i], true
+		}
+	}
+
+	return fault{}, false
+}
+
+func (t *tree) add(f fault) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.faults = append(t.faults, f)
+}
+
+// Fail makes opening or statting name fail with err, so that reading
+// it, listing it as a directory or calling Info on its directory entry
+// fails too.  The name may be a path.Match pattern, and is relative to
+// f.  The error is returned wrapped in an *fs.PathError.
+//
+// A path made to fail with fs.ErrNotExist is still listed in its
+// directory, as though it had been removed after the listing was read.
+func (f *FileSystem) Fail(name string, err error) {
+	f.fsys.tree.add(fault{pattern: path.Join(f.fsys.dir, name), err: err})
+}
+
+// FailOp makes only the operation op on name fail with err.  Faults
+// injected later take precedence over earlier ones.
+func (f *FileSystem) FailOp(op Op, name string, err error) {
+	f.fsys.tree.add(fault{op: op, pattern: path.Join(f.fsys.dir, name), err: err})
+}
+
+// FailRead makes reading name fail with err once the first offset
+// bytes have been read, as an I/O error part way through a file would.
+func (f *FileSystem) FailRead(name string, offset int64, err error) {
+	f.fsys.tree.add(fault{op: OpRead, pattern: path.Join(f.fsys.dir, name), offset: offset, err: err})
+}
+
+// ClearFaults removes every injected fault, including those injected
+// through other FileSystems sharing the tree.
+func (f *FileSystem) ClearFaults() {
+	f.fsys.tree.mu.Lock()
+	defer f.fsys.tree.mu.Unlock()
+
+	f.fsys.tree.faults = nil
+}
+
+// faultFS is the subtree at dir of a tree, with names relative to it.
+type faultFS struct {
+	tree *tree
+	dir  string
+}
+
+var (
+	_ fs.StatFS     = (*faultFS)(nil)
+	_ fs.ReadDirFS  = (*faultFS)(nil)
+	_ fs.ReadFileFS = (*faultFS)(nil)
+	_ fs.SubFS      = (*faultFS)(nil)
+)
+
+// check returns an error for op on name if it is invalid or faulted,
+// and otherwise name's path in the base FS.
+func (f *faultFS) check(op Op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: string(op), Path: name, Err: fs.ErrInvalid}
+	}
+
+	var full = path.Join(f.dir, name)
+	if ft, ok := f.tree.find(op, full); ok {
+		return "", &fs.PathError{Op: string(op), Path: name, Err: ft.err}
+	}
+
+	return full, nil
+}
+
+// relative returns err with the path in the base FS replaced by name,
+// as fs.Sub does.
+func relative(err error, full, name string) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) && pe.Path == full && full != name {
+		return &fs.PathError{Op: pe.Op, Path: name, Err: pe.Err}
+	}
+
+	return err
+}
+
+func (f *faultFS) Open(name string) (fs.File, error) {
+	var full, err = f.check(OpOpen, name)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := f.tree.base.Open(full)
+	if err != nil {
+		return nil, relative(err, full, name)
+	}
+
+	return &openFile{File: file, fsys: f, name: name, full: full}, nil
+}
+
+func (f *faultFS) Stat(name string) (fs.FileInfo, error) {
+	var full, err = f.check(OpStat, name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := fs.Stat(f.tree.base, full)
+	return info, relative(err, full, name)
+}
+
+func (f *faultFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var full, err = f.check(OpOpen, name)
+	if err == nil {
+		_, err = f.check(OpReadDir, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(f.tree.base, full)
+	return f.entries(entries, name), relative(err, full, name)
+}
+
+func (f *faultFS) ReadFile(name string) ([]byte, error) {
+	var file, err = f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+func (f *faultFS) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+
+	return &faultFS{tree: f.tree, dir: path.Join(f.dir, dir)}, nil
+}
+
+// entries wraps the entries of directory dir so that their Info is
+// subject to stat faults.
+func (f *faultFS) entries(entries []fs.DirEntry, dir string) []fs.DirEntry {
+	for i, e := range entries {
+		entries[i] = dirEntry{DirEntry: e, fsys: f, name: path.Join(dir, e.Name())}
+	}
+
+	return entries
+}
+
+// openFile is an open file whose operations are subject to the faults of
+// its tree.
+type openFile struct {
+	fs.File
+	fsys *faultFS
+	name string
+	full string
+	off  int64
+}
+
+var (
+	_ fs.ReadDirFile = (*openFile)(nil)
+	_ io.Seeker      = (*openFile)(nil)
+	_ io.ReaderAt    = (*openFile)(nil)
+)
+
+// readFault returns the offset at which reading fails and the error
+// it fails with, if reading is faulted.
+func (f *openFile) readFault() (int64, error) {
+	var ft, ok = f.fsys.tree.find(OpRead, f.full)
+	if !ok {
+		return 0, nil
+	}
+
+	return ft.offset, &fs.PathError{Op: string(OpRead), Path: f.name, Err: ft.err}
+}
+
+func (f *openFile) Read(p []byte) (int, error) {
+	var at, ferr = f.readFault()
+	if ferr != nil {
+		if f.off >= at {
+			return 0, ferr
+		}
+		p = p[:min(int64(len(p)), at-f.off)]
+	}
+
+	var n, err = f.File.Read(p)
+	f.off += int64(n)
+
+	return n, relative(err, f.full, f.name)
+}
+
+func (f *openFile) ReadAt(p []byte, off int64) (int, error) {
+	var ra, ok = f.File.(io.ReaderAt)
+	if !ok {
+		return 0, &fs.PathError{Op: "readat", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	var at, ferr = f.readFault()
+	if ferr != nil {
+		if off >= at {
+			return 0, ferr
+		}
+		if int64(len(p)) > at-off {
+			var n, err = ra.ReadAt(p[:at-off], off)
+			if err == nil {
+				err = ferr
+			}
+			return n, relative(err, f.full, f.name)
+		}
+	}
+
+	var n, err = ra.ReadAt(p, off)
+	return n, relative(err, f.full, f.name)
+}
+
+func (f *openFile) Seek(offset int64, whence int) (int64, error) {
+	var s, ok = f.File.(io.Seeker)
+	if !ok {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: errors.ErrUnsupported}
+	}
+
+	var off, err = s.Seek(offset, whence)
+	if err == nil {
+		f.off = off
+	}
+
+	return off, relative(err, f.full, f.name)
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) {
+	if ft, ok := f.fsys.tree.find(OpStat, f.full); ok {
+		return nil, &fs.PathError{Op: string(OpStat), Path: f.name, Err: ft.err}
+	}
+
+	var info, err = f.File.Stat()
+	return info, relative(err, f.full, f.name)
+}
+
+func (f *openFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	var rd, ok = f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: string(OpReadDir), Path: f.name, Err: errors.New("not implemented")}
+	}
+
+	if ft, ok := f.fsys.tree.find(OpReadDir, f.full); ok {
+		return nil, &fs.PathError{Op: string(OpReadDir), Path: f.name, Err: ft.err}
+	}
+
+	var entries, err = rd.ReadDir(n)
+	return f.fsys.entries(entries, f.name), relative(err, f.full, f.name)
+}
+
+// dirEntry is a directory entry whose Info is subject to stat faults.
+type dirEntry struct {
+	fs.DirEntry
+	fsys *faultFS
+	name string
+}
+
+func (e dirEntry) Info() (fs.FileInfo, error) {
+	if ft, ok := e.fsys.tree.find(OpStat, path.Join(e.fsys.dir, e.name)); ok {
+		return nil, &fs.PathError{Op: string(OpStat), Path: e.name, Err: ft.err}
+	}
+
+	return e.DirEntry.Info()
+}
@@ -0,0 +1,168 @@
+// Package fake_fs provides an implementation of the go-interfaces
+// io/fs.FileSystem interface whose results come from a declarative
+// tree.
+//
+// Unlike mock_fs.MockFileSystem, which needs an expectation for every
+// call, a FileSystem runs the real io/fs functions over an fs.FS,
+// usually an fstest.MapFS built from a map or a txtar archive:
+//
+//	fsys := fake_fs.FromTxtar([]byte(`
+//	-- config/app.json --
+//	{"debug": true}
+//	-- config/secret/key --
+//	hunter2
+//	`))
+//	fsys.Fail("config/secret", fs.ErrPermission)
+//
+//	err := LoadConfig(fsys)
+//
+// Errors can be injected at given paths, so that code walking or
+// reading the tree can be tested against permission errors, files that
+// vanish, and I/O errors part way through a read.
+package fake_fs
+
+import (
+	"io/fs"
+	"strings"
+	"testing/fstest"
+
+	fsi "github.com/pdutton/go-interfaces/io/fs"
+
+	"github.com/pdutton/go-mocks/internal/txtar"
+)
+
+// FileSystem is an fsi.FileSystem whose functions operate on a tree of
+// files with injected faults.  It is also an fs.FS for that tree, so
+// it can be passed as the fsys argument of its own methods.
+type FileSystem struct {
+	fsys *faultFS
+}
+
+var (
+	_ fsi.FileSystem = (*FileSystem)(nil)
+	_ fs.FS          = (*FileSystem)(nil)
+)
+
+// New returns a FileSystem over the files of fsys.
+func New(fsys fs.FS) *FileSystem {
+	return &FileSystem{fsys: &faultFS{tree: &tree{base: fsys}, dir: "."}}
+}
+
+// FromMap returns a FileSystem over the files in the map, which holds
+// the contents of each file by name.  A name ending in a slash is an
+// empty directory.  Directories holding files need no entry of their
+// own.
+func FromMap(files map[string]string) *FileSystem {
+	var m = fstest.MapFS{}
+	for name, data := range files {
+		addFile(m, name, []byte(data))
+	}
+
+	return New(m)
+}
+
+// FromTxtar returns a FileSystem over the files in a txtar archive.
+// As with FromMap, a name ending in a slash is an empty directory.
+func FromTxtar(data []byte) *FileSystem {
+	var m = fstest.MapFS{}
+	for _, f := range txtar.Parse(data).Files {
+		addFile(m, f.Name, f.Data)
+	}
+
+	return New(m)
+}
+
+func addFile(m fstest.MapFS, name string, data []byte) {
+	if dir, ok := strings.CutSuffix(name, "/"); ok {
+		m[dir] = &fstest.MapFile{Mode: fs.ModeDir | 0o755}
+		return
+	}
+
+	m[name] = &fstest.MapFile{Data: data, Mode: 0o644}
+}
+
+// FS returns the tree as an fs.FS, with StatFS, ReadDirFS, ReadFileFS
+// and SubFS methods that are subject to the injected faults.
+func (f *FileSystem) FS() fs.FS {
+	return f.fsys
+}
+
+// Open opens the named file.
+func (f *FileSystem) Open(name string) (fs.File, error) {
+	return f.fsys.Open(name)
+}
+
+// target returns the fs.FS that a method should operate on: the tree
+// of a FileSystem, any other fs.FS, or f's own tree for an argument
+// that is not an fs.FS at all, such as a MockFileSystem.
+func (f *FileSystem) target(fsys fsi.FileSystem) fs.FS {
+	switch t := fsys.(type) {
+	case *FileSystem:
+		return t.fsys
+	case fs.FS:
+		return t
+	}
+
+	return f.fsys
+}
+
+func (f *FileSystem) FormatDirEntry(dir fsi.DirEntry) string {
+	return dir.Format()
+}
+
+func (f *FileSystem) FormatFileInfo(info fsi.FileInfo) string {
+	return fs.FormatFileInfo(info.Nub())
+}
+
+func (f *FileSystem) Glob(fsys fsi.FileSystem, pattern string) ([]string, error) {
+	return fs.Glob(f.target(fsys), pattern)
+}
+
+func (f *FileSystem) ReadFile(fsys fsi.FileSystem, name string) ([]byte, error) {
+	return fs.ReadFile(f.target(fsys), name)
+}
+
+func (f *FileSystem) ValidPath(name string) bool {
+	return fs.ValidPath(name)
+}
+
+func (f *FileSystem) WalkDir(fsys fsi.FileSystem, root string, fn fsi.WalkDirFunc) error {
+	return fs.WalkDir(f.target(fsys), root, fn)
+}
+
+func (f *FileSystem) FileInfoToDirEntry(info fsi.FileInfo) fsi.DirEntry {
+	return fsi.NewDirEntry(fs.FileInfoToDirEntry(info.Nub()))
+}
+
+func (f *FileSystem) ReadDir(fsys fsi.FileSystem, name string) ([]fsi.DirEntry, error) {
+	var entries, err = fs.ReadDir(f.target(fsys), name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsi.NewDirEntryList(entries), nil
+}
+
+// Sub returns a FileSystem for the subtree at dir, which shares its
+// faults with the FileSystem it came from.
+func (f *FileSystem) Sub(fsys fsi.FileSystem, dir string) (fsi.FileSystem, error) {
+	var sub, err = fs.Sub(f.target(fsys), dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if ffs, ok := sub.(*faultFS); ok {
+		return &FileSystem{fsys: ffs}, nil
+	}
+
+	return New(sub), nil
+}
+
+func (f *FileSystem) Stat(fsys fsi.FileSystem, name string) (fsi.FileInfo, error) {
+	var info, err = fs.Stat(f.target(fsys), name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsi.NewFileInfo(info), nil
+}
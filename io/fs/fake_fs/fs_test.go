@@ -0,0 +1,271 @@
+package fake_fs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+var errIO = errors.New("input/output error")
+
+func newTree() *FileSystem {
+	return FromTxtar([]byte(`A test tree.
+-- a.txt --
+alpha
+-- dir/b.txt --
+bravo
+-- dir/sub/c.txt --
+charlie
+-- dir/sub/d.txt --
+delta
+-- empty/ --
+-- z.txt --
+zulu
+`))
+}
+
+// walk returns the paths visited by WalkDir, with the errors passed to
+// fn, and the error WalkDir returned.
+func walk(f *FileSystem, fn func(string, fs.DirEntry, error) error) (string, error) {
+	var visited []string
+	var err = f.WalkDir(f, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			visited = append(visited, name+"("+err.Error()+")")
+		} else {
+			visited = append(visited, name)
+		}
+		return fn(name, d, err)
+	})
+
+	return strings.Join(visited, " "), err
+}
+
+// TestFileSystem_Conformance tests the tree with testing/fstest.
+func TestFileSystem_Conformance(t *testing.T) {
+	f := newTree()
+
+	err := fstest.TestFS(f.FS(), "a.txt", "dir/b.txt", "dir/sub/c.txt", "empty", "z.txt")
+	testutil.AssertNil(t, err)
+
+	sub, err := fs.Sub(f.FS(), "dir")
+	testutil.AssertNil(t, err)
+	testutil.AssertNil(t, fstest.TestFS(sub, "b.txt", "sub/d.txt"))
+}
+
+// TestFileSystem_Functions tests the FileSystem methods over a tree
+// built from a map.
+func TestFileSystem_Functions(t *testing.T) {
+	f := FromMap(map[string]string{
+		"a.txt":     "alpha",
+		"dir/b.txt": "bravo",
+		"empty/":    "",
+	})
+
+	data, err := f.ReadFile(f, "dir/b.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "bravo", string(data))
+
+	info, err := f.Stat(f, "a.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, int64(5), info.Size())
+	testutil.AssertEqual(t, "-rw-r--r-- 5 0001-01-01 00:00:00 a.txt", f.FormatFileInfo(info))
+	testutil.AssertEqual(t, "- a.txt", f.FormatDirEntry(f.FileInfoToDirEntry(info)))
+
+	entries, err := f.ReadDir(f, ".")
+	testutil.AssertNil(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	testutil.AssertEqual(t, "a.txt dir empty", strings.Join(names, " "))
+	testutil.AssertEqual(t, "d empty/", f.FormatDirEntry(entries[2]))
+
+	matches, err := f.Glob(f, "*/*.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "dir/b.txt", strings.Join(matches, " "))
+
+	testutil.AssertEqual(t, true, f.ValidPath("dir/b.txt"))
+	testutil.AssertEqual(t, false, f.ValidPath("/dir"))
+
+	_, err = f.Stat(f, "missing")
+	testutil.AssertError(t, fs.ErrNotExist, err)
+}
+
+// TestFileSystem_Sub tests that a subtree shares its faults.
+func TestFileSystem_Sub(t *testing.T) {
+	f := newTree()
+
+	sub, err := f.Sub(f, "dir")
+	testutil.AssertNil(t, err)
+
+	data, err := sub.ReadFile(sub, "sub/c.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "charlie\n", string(data))
+
+	f.Fail("dir/b.txt", fs.ErrPermission)
+	_, err = sub.ReadFile(sub, "b.txt")
+	testutil.AssertError(t, fs.ErrPermission, err)
+	testutil.AssertEqual(t, "open b.txt: permission denied", err.Error())
+
+	sub.(*FileSystem).FailOp(OpStat, "sub/c.txt", fs.ErrNotExist)
+	_, err = f.Stat(f, "dir/sub/c.txt")
+	testutil.AssertError(t, fs.ErrNotExist, err)
+
+	f.ClearFaults()
+	_, err = sub.ReadFile(sub, "b.txt")
+	testutil.AssertNil(t, err)
+
+	_, err = f.Sub(f, "../dir")
+	testutil.AssertError(t, fs.ErrInvalid, err)
+}
+
+// TestFileSystem_WalkDirPermission tests walking past a directory
+// that cannot be read.
+func TestFileSystem_WalkDirPermission(t *testing.T) {
+	f := newTree()
+	f.Fail("dir/sub", fs.ErrPermission)
+
+	visited, err := walk(f, func(name string, d fs.DirEntry, err error) error {
+		return nil
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ". a.txt dir dir/b.txt dir/sub dir/sub(open dir/sub: permission denied) empty z.txt", visited)
+
+	visited, err = walk(f, func(name string, d fs.DirEntry, err error) error {
+		return err
+	})
+	testutil.AssertError(t, fs.ErrPermission, err)
+	testutil.AssertEqual(t, ". a.txt dir dir/b.txt dir/sub dir/sub(open dir/sub: permission denied)", visited)
+}
+
+// TestFileSystem_WalkDirSkip tests SkipDir and SkipAll, including when
+// returned for an error.
+func TestFileSystem_WalkDirSkip(t *testing.T) {
+	f := newTree()
+
+	visited, err := walk(f, func(name string, d fs.DirEntry, err error) error {
+		if name == "dir/sub" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ". a.txt dir dir/b.txt dir/sub empty z.txt", visited)
+
+	// SkipDir from a file skips the rest of its directory.
+	visited, err = walk(f, func(name string, d fs.DirEntry, err error) error {
+		if name == "dir/sub/c.txt" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ". a.txt dir dir/b.txt dir/sub dir/sub/c.txt empty z.txt", visited)
+
+	f.FailOp(OpReadDir, "dir", errIO)
+	visited, err = walk(f, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ". a.txt dir dir(readdir dir: input/output error)", visited)
+
+	// A root that cannot be statted is reported once, with no entry.
+	f.Fail(".", fs.ErrNotExist)
+	visited, err = walk(f, func(name string, d fs.DirEntry, err error) error {
+		testutil.AssertNil(t, d)
+		return err
+	})
+	testutil.AssertError(t, fs.ErrNotExist, err)
+	testutil.AssertEqual(t, ".(stat .: file does not exist)", visited)
+}
+
+// TestFileSystem_EntryInfo tests a file that vanishes after its
+// directory is listed.
+func TestFileSystem_EntryInfo(t *testing.T) {
+	f := newTree()
+	f.Fail("dir/*.txt", fs.ErrNotExist)
+
+	var infoErr error
+	_, err := walk(f, func(name string, d fs.DirEntry, err error) error {
+		if name == "dir/b.txt" {
+			_, infoErr = d.Info()
+		}
+		return err
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertError(t, fs.ErrNotExist, infoErr)
+	testutil.AssertEqual(t, "stat dir/b.txt: file does not exist", infoErr.Error())
+
+	_, err = f.ReadFile(f, "dir/b.txt")
+	testutil.AssertError(t, fs.ErrNotExist, err)
+	_, err = f.ReadFile(f, "dir/sub/c.txt")
+	testutil.AssertNil(t, err)
+}
+
+// TestFileSystem_FailRead tests an I/O error part way through a file.
+func TestFileSystem_FailRead(t *testing.T) {
+	f := newTree()
+	f.FailRead("dir/sub/c.txt", 4, errIO)
+
+	data, err := f.ReadFile(f, "dir/sub/c.txt")
+	testutil.AssertError(t, errIO, err)
+	testutil.AssertEqual(t, "read dir/sub/c.txt: input/output error", err.Error())
+	testutil.AssertEqual(t, "char", string(data))
+
+	file, err := f.Open("dir/sub/c.txt")
+	testutil.AssertNil(t, err)
+	defer file.Close()
+
+	buf := make([]byte, 3)
+	n, err := file.Read(buf)
+	testutil.AssertEqual(t, 3, n)
+	testutil.AssertNil(t, err)
+	n, err = file.Read(buf)
+	testutil.AssertEqual(t, 1, n)
+	testutil.AssertNil(t, err)
+	_, err = file.Read(buf)
+	testutil.AssertError(t, errIO, err)
+
+	// Reading before the fault with ReadAt or after seeking back works.
+	ra := file.(io.ReaderAt)
+	n, err = ra.ReadAt(buf, 0)
+	testutil.AssertEqual(t, 3, n)
+	testutil.AssertNil(t, err)
+	n, err = ra.ReadAt(buf, 2)
+	testutil.AssertEqual(t, 2, n)
+	testutil.AssertError(t, errIO, err)
+
+	_, err = file.(io.Seeker).Seek(0, io.SeekStart)
+	testutil.AssertNil(t, err)
+	n, err = file.Read(buf)
+	testutil.AssertEqual(t, "cha", string(buf[:n]))
+	testutil.AssertNil(t, err)
+
+	f.FailOp(OpRead, "a.txt", errIO)
+	data, err = f.ReadFile(f, "a.txt")
+	testutil.AssertError(t, errIO, err)
+	testutil.AssertEqual(t, 0, len(data))
+}
+
+// TestFileSystem_Target tests operating on an fs.FS that is not a
+// FileSystem.
+func TestFileSystem_Target(t *testing.T) {
+	f := newTree()
+	other := New(fstest.MapFS{"other.txt": {Data: []byte("other")}})
+
+	data, err := f.ReadFile(other, "other.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "other", string(data))
+
+	data, err = f.ReadFile(nil, "a.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "alpha\n", string(data))
+}
@@ -0,0 +1,241 @@
+package fake_net
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+)
+
+// streamConn is the part of a connection shared by TCP and Unix
+// stream sockets: two streams, deadlines and close handling.
+type streamConn struct {
+	network string
+	laddr   net.Addr
+	raddr   net.Addr
+
+	rx   *stream
+	tx   *stream
+	flow *flow
+
+	readDeadline  *deadline
+	writeDeadline *deadline
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mu     sync.Mutex
+	linger int
+}
+
+// newStreamPair returns the two ends of a connected stream socket.
+func newStreamPair(network string, a, b net.Addr) (*streamConn, *streamConn) {
+	var ab, ba = newStream(), newStream()
+
+	var mk = func(laddr, raddr net.Addr, rx, tx *stream) *streamConn {
+		return &streamConn{
+			network:       network,
+			laddr:         laddr,
+			raddr:         raddr,
+			rx:            rx,
+			tx:            tx,
+			readDeadline:  newDeadline(),
+			writeDeadline: newDeadline(),
+			closed:        make(chan struct{}),
+			linger:        -1,
+		}
+	}
+
+	return mk(a, b, ba, ab), mk(b, a, ab, ba)
+}
+
+func (c *streamConn) opError(op string, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+
+	switch err.(type) {
+	case syscall.Errno:
+		err = os.NewSyscallError(op, err)
+	}
+
+	return &net.OpError{Op: op, Net: c.network, Source: c.laddr, Addr: c.raddr, Err: err}
+}
+
+func (c *streamConn) Read(b []byte) (int, error) {
+	var n, err = c.rx.read(b, c.closed, c.readDeadline.wait())
+	return n, c.opError("read", err)
+}
+
+func (c *streamConn) Write(b []byte) (int, error) {
+	var n, err = c.tx.write(b, c.closed, c.writeDeadline.wait())
+	return n, c.opError("write", err)
+}
+
+// Close closes the connection.  The peer reads any data already
+// written followed by io.EOF, unless SetLinger(0) was used, in which
+// case it sees the connection reset.
+func (c *streamConn) Close() error {
+	c.mu.Lock()
+	var abort = c.linger == 0
+	c.mu.Unlock()
+
+	if !c.shut(abort) {
+		return c.opError("close", net.ErrClosed)
+	}
+
+	return nil
+}
+
+// shut closes the connection, resetting it if abort is set.  It
+// reports false if the connection was already closed.
+func (c *streamConn) shut(abort bool) bool {
+	var done bool
+
+	c.closeOnce.Do(func() {
+		done = true
+		close(c.closed)
+
+		if abort {
+			c.tx.reset()
+			c.rx.abort()
+		} else {
+			c.tx.shutdown()
+			c.rx.abandon()
+		}
+		c.flow.done()
+	})
+
+	return done
+}
+
+func (c *streamConn) CloseRead() error {
+	if isClosed(c.closed) {
+		return c.opError("close", net.ErrClosed)
+	}
+	c.rx.abandon()
+
+	return nil
+}
+
+func (c *streamConn) CloseWrite() error {
+	if isClosed(c.closed) {
+		return c.opError("close", net.ErrClosed)
+	}
+	c.tx.shutdown()
+
+	return nil
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.laddr
+}
+
+func (c *streamConn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+func (c *streamConn) SetDeadline(t time.Time) error {
+	if isClosed(c.closed) {
+		return c.opError("set", net.ErrClosed)
+	}
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+
+	return nil
+}
+
+func (c *streamConn) SetReadDeadline(t time.Time) error {
+	if isClosed(c.closed) {
+		return c.opError("set", net.ErrClosed)
+	}
+	c.readDeadline.set(t)
+
+	return nil
+}
+
+func (c *streamConn) SetWriteDeadline(t time.Time) error {
+	if isClosed(c.closed) {
+		return c.opError("set", net.ErrClosed)
+	}
+	c.writeDeadline.set(t)
+
+	return nil
+}
+
+// setOption implements the socket option setters, which only fail
+// on a closed connection.
+func (c *streamConn) setOption() error {
+	if isClosed(c.closed) {
+		return c.opError("set", net.ErrClosed)
+	}
+
+	return nil
+}
+
+func (c *streamConn) SetReadBuffer(int) error { return c.setOption() }
+
+// SetWriteBuffer sets how many bytes written to the connection may be
+// on their way across the link before writes block.
+func (c *streamConn) SetWriteBuffer(bytes int) error {
+	if err := c.setOption(); err != nil {
+		return err
+	}
+	c.tx.setWriteBuffer(bytes)
+
+	return nil
+}
+
+// File is not supported: there is no descriptor behind the socket.
+func (c *streamConn) File() (*os.File, error) {
+	return nil, c.opError("file", errors.ErrUnsupported)
+}
+
+// SyscallConn is not supported: there is no descriptor behind the
+// socket.
+func (c *streamConn) SyscallConn() (syscall.RawConn, error) {
+	return nil, c.opError("raw-control", errors.ErrUnsupported)
+}
+
+// TCPConn is a simulated TCP connection.
+type TCPConn struct {
+	*streamConn
+}
+
+var _ neti.TCPConn = (*TCPConn)(nil)
+
+func (c *TCPConn) MultipathTCP() (bool, error) {
+	return false, c.setOption()
+}
+
+func (c *TCPConn) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{c}, r)
+}
+
+func (c *TCPConn) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{c})
+}
+
+func (c *TCPConn) SetKeepAlive(bool) error                       { return c.setOption() }
+func (c *TCPConn) SetKeepAliveConfig(neti.KeepAliveConfig) error { return c.setOption() }
+func (c *TCPConn) SetKeepAlivePeriod(time.Duration) error        { return c.setOption() }
+func (c *TCPConn) SetNoDelay(bool) error                         { return c.setOption() }
+
+// SetLinger records the linger setting.  A value of zero makes Close
+// reset the connection instead of shutting it down gracefully.
+func (c *TCPConn) SetLinger(sec int) error {
+	if err := c.setOption(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.linger = sec
+	c.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package fake_net
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// connPair returns both ends of a TCP connection on one host.
+func connPair(t *testing.T) (*TCPConn, *TCPConn) {
+	t.Helper()
+
+	h := New()
+	l, err := h.Listen("tcp", "127.0.0.1:0")
+	testutil.AssertNil(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	c, err := h.Dial("tcp", l.Addr().String())
+	testutil.AssertNil(t, err)
+	s, err := l.Accept()
+	testutil.AssertNil(t, err)
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+
+	return c.(*TCPConn), s.(*TCPConn)
+}
+
+// TestTCPConn_Echo tests data flowing both ways.
+func TestTCPConn_Echo(t *testing.T) {
+	c, s := connPair(t)
+
+	go func() {
+		io.Copy(s, s)
+		s.Close()
+	}()
+
+	msg := testutil.RandomBytes(100000)
+	go func() {
+		c.Write(msg)
+		c.CloseWrite()
+	}()
+
+	got, err := io.ReadAll(c)
+	testutil.AssertNil(t, err)
+	testutil.AssertBytes(t, msg, got)
+}
+
+// TestTCPConn_Close tests the errors after closing either end.
+func TestTCPConn_Close(t *testing.T) {
+	c, s := connPair(t)
+
+	testutil.AssertNil(t, c.Close())
+
+	_, err := c.Read(make([]byte, 1))
+	testutil.AssertEqual(t, true, errors.Is(err, net.ErrClosed))
+
+	_, err = c.Write([]byte("x"))
+	testutil.AssertEqual(t, true, errors.Is(err, net.ErrClosed))
+
+	err = c.Close()
+	testutil.AssertEqual(t, true, errors.Is(err, net.ErrClosed))
+
+	_, err = s.Read(make([]byte, 1))
+	testutil.AssertEqual(t, io.EOF, err)
+
+	_, err = s.Write([]byte("x"))
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EPIPE))
+}
+
+// TestTCPConn_Linger tests resetting a connection with SetLinger(0).
+func TestTCPConn_Linger(t *testing.T) {
+	c, s := connPair(t)
+
+	c.Write([]byte("unread"))
+	testutil.AssertNil(t, s.SetLinger(0))
+	s.Close()
+
+	_, err := c.Read(make([]byte, 1))
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNRESET))
+
+	_, err = c.Write([]byte("x"))
+	assertOpError(t, err, "write tcp "+c.LocalAddr().String()+"->"+c.RemoteAddr().String()+": write: connection reset by peer")
+}
+
+// TestTCPConn_ReadDeadline tests read deadlines.
+func TestTCPConn_ReadDeadline(t *testing.T) {
+	c, _ := connPair(t)
+
+	testutil.AssertNil(t, c.SetReadDeadline(time.Now().Add(20*time.Millisecond)))
+
+	_, err := c.Read(make([]byte, 1))
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+
+	var ne net.Error
+	testutil.AssertEqual(t, true, errors.As(err, &ne))
+	testutil.AssertEqual(t, true, ne.Timeout())
+
+	// Clearing the deadline makes reads block again.
+	testutil.AssertNil(t, c.SetReadDeadline(time.Time{}))
+	done := make(chan error)
+	go func() {
+		_, err := c.Read(make([]byte, 1))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("read returned early: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.SetReadDeadline(time.Now())
+	testutil.AssertEqual(t, true, errors.Is(<-done, os.ErrDeadlineExceeded))
+}
+
+// TestTCPConn_WriteDeadline tests write deadlines.
+func TestTCPConn_WriteDeadline(t *testing.T) {
+	c, _ := connPair(t)
+
+	c.SetWriteDeadline(time.Now().Add(-time.Second))
+
+	_, err := c.Write([]byte("x"))
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+}
+
+// TestTCPConn_CloseRead tests shutting down the read side.
+func TestTCPConn_CloseRead(t *testing.T) {
+	c, s := connPair(t)
+
+	testutil.AssertNil(t, c.CloseRead())
+
+	_, err := s.Write([]byte("x"))
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EPIPE))
+
+	// The write side still works.
+	c.Write([]byte("y"))
+	b := make([]byte, 1)
+	_, err = s.Read(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "y", string(b))
+}
+
+// TestTCPConn_Options tests the socket option setters.
+func TestTCPConn_Options(t *testing.T) {
+	c, _ := connPair(t)
+
+	testutil.AssertNil(t, c.SetNoDelay(true))
+	testutil.AssertNil(t, c.SetKeepAlive(true))
+	testutil.AssertNil(t, c.SetKeepAlivePeriod(time.Second))
+	testutil.AssertNil(t, c.SetReadBuffer(1024))
+	mp, err := c.MultipathTCP()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, false, mp)
+
+	_, err = c.File()
+	testutil.AssertEqual(t, true, errors.Is(err, errors.ErrUnsupported))
+
+	c.Close()
+	testutil.AssertEqual(t, true, errors.Is(c.SetNoDelay(true), net.ErrClosed))
+}
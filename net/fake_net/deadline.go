@@ -0,0 +1,71 @@
+package fake_net
+
+import (
+	"sync"
+	"time"
+)
+
+// deadline turns a point in time into a channel that is closed when
+// the time passes.  Setting a new time re-arms it, which wakes any
+// operation blocked on the old channel so it can re-check.
+type deadline struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	expire chan struct{}
+}
+
+func newDeadline() *deadline {
+	return &deadline{expire: make(chan struct{})}
+}
+
+// set arms the deadline for t.  A zero t means no deadline.
+func (d *deadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		// The timer fired (or is firing); wait for its close.
+		<-d.expire
+	}
+	d.timer = nil
+
+	var expired = isClosed(d.expire)
+	if t.IsZero() {
+		if expired {
+			d.expire = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		if expired {
+			d.expire = make(chan struct{})
+		}
+		var ch = d.expire
+		d.timer = time.AfterFunc(dur, func() {
+			close(ch)
+		})
+		return
+	}
+
+	if !expired {
+		close(d.expire)
+	}
+}
+
+// wait returns a channel that is closed once the deadline passes.
+func (d *deadline) wait() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.expire
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,271 @@
+package fake_net
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+)
+
+// Dialer connects to listeners on the network from a host.  Its
+// Timeout, Deadline, LocalAddr and Cancel settings are honoured; the
+// others only affect real sockets and are ignored.
+type Dialer struct {
+	host *Host
+	d    net.Dialer
+}
+
+var _ neti.Dialer = (*Dialer)(nil)
+
+// NewDialer returns a Dialer for the host configured by the
+// go-interfaces dialer options.
+func (h *Host) NewDialer(options ...neti.DialerOption) neti.Dialer {
+	var d = &Dialer{host: h}
+	for _, opt := range options {
+		opt(&d.d)
+	}
+
+	return d
+}
+
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// context derives the context a dial runs under from the dialer's
+// timeout, deadline and cancel channel.
+func (d *Dialer) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	var deadline time.Time
+	if d.d.Timeout != 0 {
+		deadline = time.Now().Add(d.d.Timeout)
+	}
+	if !d.d.Deadline.IsZero() && (deadline.IsZero() || d.d.Deadline.Before(deadline)) {
+		deadline = d.d.Deadline
+	}
+
+	var cancel context.CancelFunc = func() {}
+	if !deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	}
+
+	if d.d.Cancel != nil {
+		var stop context.CancelFunc
+		ctx, stop = context.WithCancel(ctx)
+		var cancelDeadline = cancel
+		cancel = func() {
+			stop()
+			cancelDeadline()
+		}
+
+		if isClosed(d.d.Cancel) {
+			stop()
+		} else {
+			go func() {
+				select {
+				case <-d.d.Cancel:
+					stop()
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}
+
+	return ctx, cancel
+}
+
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	ctx, cancel := d.context(ctx)
+	defer cancel()
+
+	var laddr = d.d.LocalAddr
+	var p, err = proto(network)
+	if err != nil {
+		return nil, opErr("dial", network, laddr, nil, err)
+	}
+
+	switch p {
+	case "unix", "unixgram", "unixpacket":
+		var raddr = &net.UnixAddr{Name: address, Net: network}
+		var la, ok = laddr.(*net.UnixAddr)
+		if !ok && laddr != nil {
+			return nil, opErr("dial", network, laddr, raddr, &net.AddrError{Err: "mismatched local address type", Addr: laddr.String()})
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, opErr("dial", network, laddr, raddr, mapContextErr(err))
+		}
+		var c, err = d.host.dialUnix(network, la, raddr)
+		if err != nil {
+			return nil, opErr("dial", network, laddr, raddr, err)
+		}
+		return c, nil
+
+	case "ip":
+		return nil, opErr("dial", network, laddr, nil, sysErr("socket", syscall.EPERM))
+	}
+
+	var local netip.AddrPort
+	if laddr != nil {
+		var ok bool
+		switch la := laddr.(type) {
+		case *net.TCPAddr:
+			local, ok = addrPort(la.IP, la.Zone, la.Port), p == "tcp"
+		case *net.UDPAddr:
+			local, ok = addrPort(la.IP, la.Zone, la.Port), p == "udp"
+		}
+		if !ok {
+			return nil, opErr("dial", network, laddr, nil, &net.AddrError{Err: "mismatched local address type", Addr: laddr.String()})
+		}
+	}
+
+	aps, err := d.host.resolve(ctx, network, address)
+	if err != nil {
+		return nil, opErr("dial", network, laddr, nil, err)
+	}
+
+	var first error
+	for _, ap := range aps {
+		var raddr = addrFor(p, ap)
+		if err := ctx.Err(); err != nil {
+			return nil, opErr("dial", network, laddr, raddr, mapContextErr(err))
+		}
+
+		var c net.Conn
+		if p == "tcp" {
+			var tc *TCPConn
+			if tc, err = d.host.dialTCP(ctx, network, tcpAddr(local), ap); err == nil {
+				c = tc
+			}
+		} else {
+			var uc *UDPConn
+			if uc, err = d.host.dialUDP(network, local, ap); err == nil {
+				c = uc
+			}
+		}
+		if err == nil {
+			return c, nil
+		}
+		if first == nil {
+			first = opErr("dial", network, laddr, raddr, err)
+		}
+	}
+
+	return nil, first
+}
+
+// tcpAddr converts a local address back to a *net.TCPAddr, or nil if
+// it is unset.
+func tcpAddr(ap netip.AddrPort) *net.TCPAddr {
+	if !ap.IsValid() {
+		return nil
+	}
+
+	return net.TCPAddrFromAddrPort(ap)
+}
+
+// MultipathTCP reports false: multipath TCP is never used.
+func (d *Dialer) MultipathTCP() bool {
+	return false
+}
+
+// ListenConfig opens listeners on a host.  Its settings only affect
+// real sockets and are ignored.
+type ListenConfig struct {
+	host *Host
+	lc   net.ListenConfig
+}
+
+var _ neti.ListenConfig = (*ListenConfig)(nil)
+
+// NewListenConfig returns a ListenConfig for the host configured by
+// the go-interfaces listen config options.
+func (h *Host) NewListenConfig(options ...neti.ListenConfigOption) neti.ListenConfig {
+	var lc = &ListenConfig{host: h}
+	for _, opt := range options {
+		opt(&lc.lc)
+	}
+
+	return lc
+}
+
+func (lc *ListenConfig) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, opErr("listen", network, nil, nil, mapContextErr(err))
+	}
+
+	var p, err = proto(network)
+	if err != nil {
+		return nil, opErr("listen", network, nil, nil, err)
+	}
+
+	switch p {
+	case "tcp":
+		var aps, err = lc.host.resolve(ctx, network, address)
+		if err != nil {
+			return nil, opErr("listen", network, nil, nil, err)
+		}
+		l, err := lc.host.listenTCP(network, aps[0])
+		if err != nil {
+			return nil, opErr("listen", network, nil, net.TCPAddrFromAddrPort(aps[0]), err)
+		}
+		return l, nil
+
+	case "unix", "unixpacket":
+		var laddr = &net.UnixAddr{Name: address, Net: network}
+		var l, err = lc.host.listenUnix(network, laddr)
+		if err != nil {
+			return nil, opErr("listen", network, nil, laddr, err)
+		}
+		return l, nil
+	}
+
+	return nil, opErr("listen", network, nil, nil, net.UnknownNetworkError(network))
+}
+
+func (lc *ListenConfig) ListenPacket(ctx context.Context, network, address string) (net.PacketConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, opErr("listen", network, nil, nil, mapContextErr(err))
+	}
+
+	var p, err = proto(network)
+	if err != nil {
+		return nil, opErr("listen", network, nil, nil, err)
+	}
+
+	switch p {
+	case "udp":
+		var aps, err = lc.host.resolve(ctx, network, address)
+		if err != nil {
+			return nil, opErr("listen", network, nil, nil, err)
+		}
+		c, err := lc.host.listenUDP(network, aps[0], nil)
+		if err != nil {
+			return nil, opErr("listen", network, nil, net.UDPAddrFromAddrPort(aps[0]), err)
+		}
+		return c, nil
+
+	case "unixgram":
+		var laddr = &net.UnixAddr{Name: address, Net: network}
+		var c, err = lc.host.listenUnixgram(laddr)
+		if err != nil {
+			return nil, opErr("listen", network, nil, laddr, err)
+		}
+		return c, nil
+
+	case "ip":
+		return nil, opErr("listen", network, nil, nil, sysErr("socket", syscall.EPERM))
+	}
+
+	return nil, opErr("listen", network, nil, nil, net.UnknownNetworkError(network))
+}
+
+// MultipathTCP reports false: multipath TCP is never used.
+func (lc *ListenConfig) MultipathTCP() bool {
+	return false
+}
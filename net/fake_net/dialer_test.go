@@ -0,0 +1,113 @@
+package fake_net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestDialer_Canceled tests dialing with a canceled context.
+func TestDialer_Canceled(t *testing.T) {
+	h := New()
+	l, _ := h.Listen("tcp", "127.0.0.1:80")
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := h.NewDialer().DialContext(ctx, "tcp", "127.0.0.1:80")
+	assertOpError(t, err, "dial tcp 127.0.0.1:80: operation was canceled")
+	testutil.AssertEqual(t, true, errors.Is(err, context.Canceled))
+}
+
+// TestDialer_Deadline tests dialing after the dialer's deadline.
+func TestDialer_Deadline(t *testing.T) {
+	h := New()
+	l, _ := h.Listen("tcp", "127.0.0.1:80")
+	defer l.Close()
+
+	d := h.NewDialer(neti.WithDeadline(time.Now().Add(-time.Second)))
+	_, err := d.Dial("tcp", "127.0.0.1:80")
+	assertOpError(t, err, "dial tcp 127.0.0.1:80: i/o timeout")
+	testutil.AssertEqual(t, true, errors.Is(err, context.DeadlineExceeded))
+
+	var ne net.Error
+	testutil.AssertEqual(t, true, errors.As(err, &ne) && ne.Timeout())
+}
+
+// TestDialer_Cancel tests the dialer's cancel channel.
+func TestDialer_Cancel(t *testing.T) {
+	h := New()
+
+	ch := make(chan struct{})
+	close(ch)
+	d := h.NewDialer(neti.WithCancel(ch))
+
+	_, err := d.Dial("tcp", "127.0.0.1:80")
+	testutil.AssertEqual(t, true, errors.Is(err, context.Canceled))
+}
+
+// TestDialer_LocalAddr tests choosing the local address.
+func TestDialer_LocalAddr(t *testing.T) {
+	nw := NewNetwork()
+	a := nw.NewHost("a", "10.0.0.1", "10.0.1.1")
+	l, _ := a.Listen("tcp", ":80")
+	defer l.Close()
+
+	d := a.NewDialer(neti.WithLocalAddr(&net.TCPAddr{IP: net.IPv4(10, 0, 1, 1)}))
+	c, err := d.Dial("tcp", "10.0.0.1:80")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "10.0.1.1", c.LocalAddr().(*net.TCPAddr).IP.String())
+	c.Close()
+
+	d = a.NewDialer(neti.WithLocalAddr(&net.TCPAddr{IP: net.IPv4(10, 9, 9, 9)}))
+	_, err = d.Dial("tcp", "10.0.0.1:80")
+	assertOpError(t, err, "dial tcp 10.9.9.9:0->10.0.0.1:80: bind: cannot assign requested address")
+
+	d = a.NewDialer(neti.WithLocalAddr(&net.UDPAddr{}))
+	_, err = d.Dial("tcp", "10.0.0.1:80")
+	assertOpError(t, err, "dial tcp :0: address :0: mismatched local address type")
+}
+
+// TestDialer_Fallback tests trying each address a name resolves to.
+func TestDialer_Fallback(t *testing.T) {
+	nw := NewNetwork()
+	server := nw.NewHost("server", "fd00::1", "10.0.0.1")
+	client := nw.NewHost("client", "fd00::2", "10.0.0.2")
+
+	l, _ := server.Listen("tcp", "10.0.0.1:80")
+	defer l.Close()
+
+	c, err := client.NewDialer().Dial("tcp", "server:80")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "10.0.0.1:80", c.RemoteAddr().String())
+	c.Close()
+}
+
+// TestListenConfig tests listening through a ListenConfig.
+func TestListenConfig(t *testing.T) {
+	h := New()
+	lc := h.NewListenConfig(neti.WithKeepAliveLC(time.Second))
+
+	l, err := lc.Listen(context.Background(), "tcp4", ":0")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "0.0.0.0", l.Addr().(*net.TCPAddr).IP.String())
+	l.Close()
+
+	p, err := lc.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+	testutil.AssertNil(t, err)
+	p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = lc.Listen(ctx, "tcp", ":0")
+	testutil.AssertEqual(t, true, errors.Is(err, context.Canceled))
+
+	testutil.AssertEqual(t, false, lc.MultipathTCP())
+}
@@ -0,0 +1,72 @@
+package fake_net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+)
+
+var (
+	errMissingAddress       = errors.New("missing address")
+	errNoSuchInterface      = errors.New("no such network interface")
+	errInvalidInterfaceIdx  = errors.New("invalid network interface index")
+	errInvalidInterfaceName = errors.New("invalid network interface name")
+	errCanceled             = canceledError{}
+	errTimeout              = timeoutError{}
+)
+
+// canceledError matches the error the net package reports when an
+// operation is abandoned because its context was canceled.
+type canceledError struct{}
+
+func (canceledError) Error() string     { return "operation was canceled" }
+func (canceledError) Is(err error) bool { return err == context.Canceled }
+func (canceledError) Timeout() bool     { return false }
+func (canceledError) Temporary() bool   { return false }
+
+// timeoutError matches the error the net package reports when an
+// operation's context or timeout expires.
+type timeoutError struct{}
+
+func (timeoutError) Error() string     { return "i/o timeout" }
+func (timeoutError) Is(err error) bool { return err == context.DeadlineExceeded }
+func (timeoutError) Timeout() bool     { return true }
+func (timeoutError) Temporary() bool   { return true }
+
+// mapContextErr converts a context error into the net package's
+// equivalent.
+func mapContextErr(err error) error {
+	switch err {
+	case context.Canceled:
+		return errCanceled
+	case context.DeadlineExceeded:
+		return errTimeout
+	}
+
+	return err
+}
+
+// sysErr wraps an errno the way the net package does for a failed
+// system call.
+func sysErr(call string, errno syscall.Errno) error {
+	return os.NewSyscallError(call, errno)
+}
+
+func opErr(op, network string, source, addr net.Addr, err error) error {
+	return &net.OpError{Op: op, Net: network, Source: source, Addr: addr, Err: err}
+}
+
+// opAddr converts a possibly nil address pointer to a net.Addr that is
+// nil rather than a typed nil, as the net package does for errors.
+func opAddr[T any, P interface {
+	*T
+	net.Addr
+}](a P) net.Addr {
+	if a == nil {
+		return nil
+	}
+
+	return a
+}
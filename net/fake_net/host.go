@@ -0,0 +1,687 @@
+package fake_net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+)
+
+const (
+	firstEphemeralPort = 32768
+	lastEphemeralPort  = 60999
+)
+
+var (
+	ipv4Loopback    = netip.MustParseAddr("127.0.0.1")
+	ipv4Unspecified = netip.IPv4Unspecified()
+)
+
+// sockKey identifies a bound internet socket.  A zero ip is a wildcard
+// bind to every address of the host.
+type sockKey struct {
+	proto string
+	ip    netip.Addr
+	port  uint16
+}
+
+// unixEntry is a name in a host's Unix socket namespace.  Both fields
+// are nil for a stale name left behind by a listener that did not
+// unlink it.
+type unixEntry struct {
+	stream *listener
+	packet *packetSocket
+}
+
+// Host is a machine on a Network.  It implements net.Net: sockets are
+// bound in the host's own port and Unix socket namespaces, and
+// connections are made across the network it belongs to.
+type Host struct {
+	nw    *Network
+	id    int
+	name  string
+	addrs []netip.Addr
+
+	// The fields below are guarded by nw.mu.
+	streams   map[sockKey]*listener
+	packets   map[sockKey]*packetSocket
+	multicast []*packetSocket
+	unix      map[string]*unixEntry
+	nextPort  int
+}
+
+var _ neti.Net = (*Host)(nil)
+
+// Name returns the host's name.
+func (h *Host) Name() string {
+	return h.name
+}
+
+// Network returns the network the host belongs to.
+func (h *Host) Network() *Network {
+	return h.nw
+}
+
+// owns reports whether traffic for ip is delivered to the host.
+func (h *Host) owns(ip netip.Addr) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, a := range h.addrs {
+		if a == ip {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sourceFor picks the local address used to reach dst.
+func (h *Host) sourceFor(dst netip.Addr) (netip.Addr, bool) {
+	if dst.IsLoopback() {
+		if dst.Is4() {
+			return ipv4Loopback, true
+		}
+		return netip.IPv6Loopback(), true
+	}
+	if h.owns(dst) {
+		return dst, true
+	}
+	for _, a := range h.addrs {
+		if a.Is4() == dst.Is4() {
+			return a, true
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+// inUse reports whether binding port on ip would conflict with an
+// existing socket.  It must be called with nw.mu held.
+func (h *Host) inUse(proto string, ip netip.Addr, port uint16) bool {
+	var match = func(k sockKey) bool {
+		return k.proto == proto && k.port == port && (!k.ip.IsValid() || !ip.IsValid() || k.ip == ip)
+	}
+
+	switch proto {
+	case "tcp":
+		for k := range h.streams {
+			if match(k) {
+				return true
+			}
+		}
+	case "udp":
+		for k := range h.packets {
+			if match(k) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// allocPort returns a free ephemeral port for ip.  It must be called
+// with nw.mu held.
+func (h *Host) allocPort(proto string, ip netip.Addr) (uint16, error) {
+	for range lastEphemeralPort - firstEphemeralPort + 1 {
+		var port = uint16(h.nextPort)
+		if h.nextPort++; h.nextPort > lastEphemeralPort {
+			h.nextPort = firstEphemeralPort
+		}
+		if !h.inUse(proto, ip, port) {
+			return port, nil
+		}
+	}
+
+	return 0, sysErr("bind", syscall.EADDRINUSE)
+}
+
+// bind reserves a local address for a socket, allocating a port if
+// ap has none.  It returns the key to register the socket under.  It
+// must be called with nw.mu held.
+func (h *Host) bind(proto string, ap netip.AddrPort) (sockKey, error) {
+	var ip = ap.Addr()
+	if ip.IsUnspecified() {
+		ip = netip.Addr{}
+	} else if !h.owns(ip) {
+		return sockKey{}, sysErr("bind", syscall.EADDRNOTAVAIL)
+	}
+
+	var port = ap.Port()
+	if port == 0 {
+		var err error
+		if port, err = h.allocPort(proto, ip); err != nil {
+			return sockKey{}, err
+		}
+	} else if h.inUse(proto, ip, port) {
+		return sockKey{}, sysErr("bind", syscall.EADDRINUSE)
+	}
+
+	return sockKey{proto: proto, ip: ip, port: port}, nil
+}
+
+// lookupStream finds the listener accepting connections to dst.  It
+// must be called with nw.mu held.
+func (h *Host) lookupStream(dst netip.AddrPort) *listener {
+	if l := h.streams[sockKey{"tcp", dst.Addr(), dst.Port()}]; l != nil {
+		return l
+	}
+
+	return h.streams[sockKey{"tcp", netip.Addr{}, dst.Port()}]
+}
+
+// lookupPacket finds the socket receiving datagrams sent to dst.  It
+// must be called with nw.mu held.
+func (h *Host) lookupPacket(dst netip.AddrPort) *packetSocket {
+	if p := h.packets[sockKey{"udp", dst.Addr(), dst.Port()}]; p != nil {
+		return p
+	}
+
+	return h.packets[sockKey{"udp", netip.Addr{}, dst.Port()}]
+}
+
+// proto returns the protocol family of network: "tcp", "udp", "ip",
+// or the network itself for Unix sockets.
+func proto(network string) (string, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return "tcp", nil
+	case "udp", "udp4", "udp6":
+		return "udp", nil
+	case "unix", "unixgram", "unixpacket":
+		return network, nil
+	}
+
+	var afnet, _, _ = strings.Cut(network, ":")
+	switch afnet {
+	case "ip", "ip4", "ip6":
+		return "ip", nil
+	}
+
+	return "", net.UnknownNetworkError(network)
+}
+
+// resolve turns a host:port address into the candidate endpoints for
+// network.  An empty host yields a single unspecified address.
+func (h *Host) resolve(ctx context.Context, network, address string) ([]netip.AddrPort, error) {
+	var host, service, err = net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := lookupPort(network, service)
+	if err != nil {
+		return nil, err
+	}
+
+	if host == "" {
+		var ip = netip.IPv6Unspecified()
+		if strings.HasSuffix(network, "4") {
+			ip = ipv4Unspecified
+		}
+		return []netip.AddrPort{netip.AddrPortFrom(ip, uint16(port))}, nil
+	}
+
+	addrs, err := h.nw.resolver.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if addrs = filter(network, addrs); len(addrs) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: host}
+	}
+
+	var out = make([]netip.AddrPort, len(addrs))
+	for i, ip := range addrs {
+		out[i] = netip.AddrPortFrom(ip, uint16(port))
+	}
+
+	return out, nil
+}
+
+// addrPort converts an IP and port from the net package's address
+// types.
+func addrPort(ip net.IP, zone string, port int) netip.AddrPort {
+	var a, ok = netip.AddrFromSlice(ip)
+	if !ok {
+		a = netip.IPv6Unspecified()
+	}
+
+	return netip.AddrPortFrom(a.Unmap().WithZone(zone), uint16(port))
+}
+
+func (h *Host) JoinHostPort(host, port string) string {
+	return net.JoinHostPort(host, port)
+}
+
+func (h *Host) SplitHostPort(hostport string) (string, string, error) {
+	return net.SplitHostPort(hostport)
+}
+
+func (h *Host) ParseCIDR(s string) (net.IP, *net.IPNet, error) {
+	return net.ParseCIDR(s)
+}
+
+func (h *Host) ParseIP(s string) net.IP {
+	return net.ParseIP(s)
+}
+
+func (h *Host) IPv4(a, b, c, d byte) net.IP {
+	return net.IPv4(a, b, c, d)
+}
+
+func (h *Host) CIDRMask(ones, bits int) net.IPMask {
+	return net.CIDRMask(ones, bits)
+}
+
+func (h *Host) IPv4Mask(a, b, c, d byte) net.IPMask {
+	return net.IPv4Mask(a, b, c, d)
+}
+
+func (h *Host) TCPAddrFromAddrPort(addr netip.AddrPort) *net.TCPAddr {
+	return net.TCPAddrFromAddrPort(addr)
+}
+
+// Pipe returns the net package's synchronous in-memory pipe.
+func (h *Host) Pipe() (net.Conn, net.Conn) {
+	return net.Pipe()
+}
+
+func (h *Host) LookupAddr(addr string) ([]string, error) {
+	return h.nw.resolver.LookupAddr(context.Background(), addr)
+}
+
+func (h *Host) LookupCNAME(host string) (string, error) {
+	return h.nw.resolver.LookupCNAME(context.Background(), host)
+}
+
+func (h *Host) LookupHost(host string) ([]string, error) {
+	return h.nw.resolver.LookupHost(context.Background(), host)
+}
+
+func (h *Host) LookupIP(host string) ([]net.IP, error) {
+	return h.nw.resolver.LookupIP(context.Background(), "ip", host)
+}
+
+func (h *Host) LookupMX(name string) ([]*net.MX, error) {
+	return h.nw.resolver.LookupMX(context.Background(), name)
+}
+
+func (h *Host) LookupNS(name string) ([]*net.NS, error) {
+	return h.nw.resolver.LookupNS(context.Background(), name)
+}
+
+func (h *Host) LookupPort(network, service string) (int, error) {
+	return h.nw.resolver.LookupPort(context.Background(), network, service)
+}
+
+func (h *Host) LookupSRV(service, proto, name string) (string, []*net.SRV, error) {
+	return h.nw.resolver.LookupSRV(context.Background(), service, proto, name)
+}
+
+func (h *Host) LookupTXT(name string) ([]string, error) {
+	return h.nw.resolver.LookupTXT(context.Background(), name)
+}
+
+// NewResolver returns the network's resolver.  The options only
+// affect the real resolver and are ignored.
+func (h *Host) NewResolver(options ...neti.ResolverOption) neti.Resolver {
+	return h.nw.resolver
+}
+
+func (h *Host) ResolveIPAddr(network, address string) (*net.IPAddr, error) {
+	if p, err := proto(network); err != nil || p != "ip" {
+		return nil, net.UnknownNetworkError(network)
+	}
+	if address == "" {
+		return nil, nil
+	}
+
+	var afnet, _, _ = strings.Cut(network, ":")
+	var addrs, err = h.nw.resolver.lookup(context.Background(), address)
+	if err != nil {
+		return nil, err
+	}
+	if addrs = filter(afnet, addrs); len(addrs) == 0 {
+		return nil, &net.AddrError{Err: "no suitable address found", Addr: address}
+	}
+
+	return &net.IPAddr{IP: net.IP(addrs[0].AsSlice()), Zone: addrs[0].Zone()}, nil
+}
+
+func (h *Host) ResolveTCPAddr(network, address string) (*net.TCPAddr, error) {
+	if p, err := proto(network); err != nil || p != "tcp" {
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	var aps, err = h.resolve(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+	if aps[0].Addr().IsUnspecified() {
+		return &net.TCPAddr{Port: int(aps[0].Port())}, nil
+	}
+
+	return net.TCPAddrFromAddrPort(aps[0]), nil
+}
+
+func (h *Host) ResolveUDPAddr(network, address string) (*net.UDPAddr, error) {
+	if p, err := proto(network); err != nil || p != "udp" {
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	var aps, err = h.resolve(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+	if aps[0].Addr().IsUnspecified() {
+		return &net.UDPAddr{Port: int(aps[0].Port())}, nil
+	}
+
+	return net.UDPAddrFromAddrPort(aps[0]), nil
+}
+
+func (h *Host) ResolveUnixAddr(network, address string) (*net.UnixAddr, error) {
+	switch network {
+	case "unix", "unixgram", "unixpacket":
+		return &net.UnixAddr{Name: address, Net: network}, nil
+	}
+
+	return nil, net.UnknownNetworkError(network)
+}
+
+func (h *Host) Dial(network, address string) (net.Conn, error) {
+	return h.NewDialer().Dial(network, address)
+}
+
+func (h *Host) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	return h.NewDialer(neti.WithTimeout(timeout)).Dial(network, address)
+}
+
+func (h *Host) Listen(network, address string) (net.Listener, error) {
+	return h.NewListenConfig().Listen(context.Background(), network, address)
+}
+
+func (h *Host) ListenPacket(network, address string) (net.PacketConn, error) {
+	return h.NewListenConfig().ListenPacket(context.Background(), network, address)
+}
+
+func (h *Host) DialTCP(network string, laddr, raddr *net.TCPAddr) (neti.TCPConn, error) {
+	if p, err := proto(network); err != nil || p != "tcp" {
+		return nil, opErr("dial", network, opAddr(laddr), opAddr(raddr), net.UnknownNetworkError(network))
+	}
+	if raddr == nil {
+		return nil, opErr("dial", network, opAddr(laddr), nil, errMissingAddress)
+	}
+
+	var c, err = h.dialTCP(context.Background(), network, laddr, addrPort(raddr.IP, raddr.Zone, raddr.Port))
+	if err != nil {
+		return nil, opErr("dial", network, opAddr(laddr), raddr, err)
+	}
+
+	return c, nil
+}
+
+func (h *Host) ListenTCP(network string, laddr *net.TCPAddr) (neti.TCPListener, error) {
+	if p, err := proto(network); err != nil || p != "tcp" {
+		return nil, opErr("listen", network, nil, opAddr(laddr), net.UnknownNetworkError(network))
+	}
+	if laddr == nil {
+		laddr = &net.TCPAddr{}
+	}
+
+	var l, err = h.listenTCP(network, addrPort(laddr.IP, laddr.Zone, laddr.Port))
+	if err != nil {
+		return nil, opErr("listen", network, nil, laddr, err)
+	}
+
+	return l, nil
+}
+
+func (h *Host) DialUDP(network string, laddr, raddr *net.UDPAddr) (neti.UDPConn, error) {
+	if p, err := proto(network); err != nil || p != "udp" {
+		return nil, opErr("dial", network, opAddr(laddr), opAddr(raddr), net.UnknownNetworkError(network))
+	}
+	if raddr == nil {
+		return nil, opErr("dial", network, opAddr(laddr), nil, errMissingAddress)
+	}
+
+	var local = netip.AddrPortFrom(netip.IPv6Unspecified(), 0)
+	if laddr != nil {
+		local = addrPort(laddr.IP, laddr.Zone, laddr.Port)
+	}
+
+	var c, err = h.dialUDP(network, local, addrPort(raddr.IP, raddr.Zone, raddr.Port))
+	if err != nil {
+		return nil, opErr("dial", network, opAddr(laddr), raddr, err)
+	}
+
+	return c, nil
+}
+
+func (h *Host) ListenUDP(network string, laddr *net.UDPAddr) (neti.UDPConn, error) {
+	if p, err := proto(network); err != nil || p != "udp" {
+		return nil, opErr("listen", network, nil, opAddr(laddr), net.UnknownNetworkError(network))
+	}
+	if laddr == nil {
+		laddr = &net.UDPAddr{}
+	}
+
+	var c, err = h.listenUDP(network, addrPort(laddr.IP, laddr.Zone, laddr.Port), nil)
+	if err != nil {
+		return nil, opErr("listen", network, nil, laddr, err)
+	}
+
+	return c, nil
+}
+
+// ListenMulticastUDP joins the group gaddr on a wildcard socket bound
+// to its port.  The interface is ignored: every host on the network
+// can reach the group.
+func (h *Host) ListenMulticastUDP(network string, ifi *net.Interface, gaddr *net.UDPAddr) (neti.UDPConn, error) {
+	if p, err := proto(network); err != nil || p != "udp" {
+		return nil, opErr("listen", network, nil, opAddr(gaddr), net.UnknownNetworkError(network))
+	}
+	if gaddr == nil || gaddr.IP == nil {
+		return nil, opErr("listen", network, nil, opAddr(gaddr), errMissingAddress)
+	}
+
+	var group = addrPort(gaddr.IP, gaddr.Zone, gaddr.Port)
+	if !group.Addr().IsMulticast() {
+		return nil, opErr("listen", network, nil, gaddr, &net.AddrError{Err: "invalid multicast address", Addr: gaddr.IP.String()})
+	}
+
+	var c, err = h.listenUDP(network, netip.AddrPortFrom(netip.IPv6Unspecified(), group.Port()), &group)
+	if err != nil {
+		return nil, opErr("listen", network, nil, gaddr, err)
+	}
+
+	return c, nil
+}
+
+func (h *Host) DialUnix(network string, laddr, raddr *net.UnixAddr) (neti.UnixConn, error) {
+	switch network {
+	case "unix", "unixgram", "unixpacket":
+	default:
+		return nil, opErr("dial", network, opAddr(laddr), opAddr(raddr), net.UnknownNetworkError(network))
+	}
+	if raddr == nil {
+		return nil, opErr("dial", network, opAddr(laddr), nil, errMissingAddress)
+	}
+
+	var c, err = h.dialUnix(network, laddr, raddr)
+	if err != nil {
+		return nil, opErr("dial", network, opAddr(laddr), raddr, err)
+	}
+
+	return c, nil
+}
+
+func (h *Host) ListenUnix(network string, laddr *net.UnixAddr) (neti.UnixListener, error) {
+	switch network {
+	case "unix", "unixpacket":
+	default:
+		return nil, opErr("listen", network, nil, opAddr(laddr), net.UnknownNetworkError(network))
+	}
+	if laddr == nil {
+		return nil, opErr("listen", network, nil, nil, errMissingAddress)
+	}
+
+	var l, err = h.listenUnix(network, laddr)
+	if err != nil {
+		return nil, opErr("listen", network, nil, laddr, err)
+	}
+
+	return l, nil
+}
+
+func (h *Host) ListenUnixgram(network string, laddr *net.UnixAddr) (neti.UnixConn, error) {
+	switch network {
+	case "unixgram":
+	default:
+		return nil, opErr("listen", network, nil, opAddr(laddr), net.UnknownNetworkError(network))
+	}
+	if laddr == nil {
+		return nil, opErr("listen", network, nil, nil, errMissingAddress)
+	}
+
+	var c, err = h.listenUnixgram(laddr)
+	if err != nil {
+		return nil, opErr("listen", network, nil, laddr, err)
+	}
+
+	return c, nil
+}
+
+// DialIP fails as it does for an unprivileged process: raw sockets are
+// not simulated.
+func (h *Host) DialIP(network string, laddr, raddr *net.IPAddr) (neti.IPConn, error) {
+	return nil, opErr("dial", network, opAddr(laddr), opAddr(raddr), sysErr("socket", syscall.EPERM))
+}
+
+// ListenIP fails as it does for an unprivileged process: raw sockets
+// are not simulated.
+func (h *Host) ListenIP(network string, laddr *net.IPAddr) (neti.IPConn, error) {
+	return nil, opErr("listen", network, nil, opAddr(laddr), sysErr("socket", syscall.EPERM))
+}
+
+// FileConn is not supported: fake sockets have no descriptors.
+func (h *Host) FileConn(f *os.File) (net.Conn, error) {
+	return nil, fileErr(f)
+}
+
+// FileListener is not supported: fake sockets have no descriptors.
+func (h *Host) FileListener(f *os.File) (net.Listener, error) {
+	return nil, fileErr(f)
+}
+
+// FilePacketConn is not supported: fake sockets have no descriptors.
+func (h *Host) FilePacketConn(f *os.File) (net.PacketConn, error) {
+	return nil, fileErr(f)
+}
+
+func fileErr(f *os.File) error {
+	var name string
+	if f != nil {
+		name = f.Name()
+	}
+
+	return &net.OpError{Op: "file", Net: "file+net", Addr: fileAddr(name), Err: errors.ErrUnsupported}
+}
+
+type fileAddr string
+
+func (fileAddr) Network() string  { return "file+net" }
+func (f fileAddr) String() string { return string(f) }
+
+// interfaces describes the host's network interfaces: the loopback
+// interface and, if the host has addresses, one Ethernet interface.
+func (h *Host) interfaces() []net.Interface {
+	var ifs = []net.Interface{{
+		Index: 1,
+		MTU:   65536,
+		Name:  "lo",
+		Flags: net.FlagUp | net.FlagLoopback | net.FlagRunning,
+	}}
+
+	if len(h.addrs) > 0 {
+		var mac = net.HardwareAddr{0x02, 0x00, 0, 0, 0, 0}
+		var b = h.addrs[0].AsSlice()
+		copy(mac[2:], b[len(b)-4:])
+
+		ifs = append(ifs, net.Interface{
+			Index:        2,
+			MTU:          1500,
+			Name:         "eth0",
+			HardwareAddr: mac,
+			Flags:        net.FlagUp | net.FlagBroadcast | net.FlagMulticast | net.FlagRunning,
+		})
+	}
+
+	return ifs
+}
+
+// interfaceAddrs returns the addresses of the interface with the given
+// index, or of every interface if index is zero.
+func (h *Host) interfaceAddrs(index int) []net.Addr {
+	var addrs []net.Addr
+
+	if index == 0 || index == 1 {
+		addrs = append(addrs,
+			&net.IPNet{IP: net.IPv4(127, 0, 0, 1).To4(), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+		)
+	}
+
+	if index == 0 || index == 2 {
+		for _, ip := range h.addrs {
+			var bits = 64
+			if ip.Is4() {
+				bits = 24
+			}
+			addrs = append(addrs, &net.IPNet{IP: net.IP(ip.AsSlice()), Mask: net.CIDRMask(bits, ip.BitLen())})
+		}
+	}
+
+	return addrs
+}
+
+func (h *Host) Interfaces() ([]net.Interface, error) {
+	return h.interfaces(), nil
+}
+
+func (h *Host) InterfaceAddrs() ([]net.Addr, error) {
+	return h.interfaceAddrs(0), nil
+}
+
+func (h *Host) InterfaceByIndex(index int) (*net.Interface, error) {
+	if index <= 0 {
+		return nil, opErr("route", "ip+net", nil, nil, errInvalidInterfaceIdx)
+	}
+	for _, ifi := range h.interfaces() {
+		if ifi.Index == index {
+			return &ifi, nil
+		}
+	}
+
+	return nil, opErr("route", "ip+net", nil, nil, errNoSuchInterface)
+}
+
+func (h *Host) InterfaceByName(name string) (*net.Interface, error) {
+	if name == "" {
+		return nil, opErr("route", "ip+net", nil, nil, errInvalidInterfaceName)
+	}
+	for _, ifi := range h.interfaces() {
+		if ifi.Name == name {
+			return &ifi, nil
+		}
+	}
+
+	return nil, opErr("route", "ip+net", nil, nil, errNoSuchInterface)
+}
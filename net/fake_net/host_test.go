@@ -0,0 +1,211 @@
+package fake_net
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestHost_Refused tests dialing a port nobody listens on.
+func TestHost_Refused(t *testing.T) {
+	nw := NewNetwork()
+	nw.NewHost("server", "10.0.0.1")
+	client := nw.NewHost("client", "10.0.0.2")
+
+	_, err := client.Dial("tcp", "10.0.0.1:80")
+	oe := assertOpError(t, err, "dial tcp 10.0.0.1:80: connect: connection refused")
+	testutil.AssertEqual(t, "dial", oe.Op)
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNREFUSED))
+}
+
+// TestHost_Unreachable tests dialing an address no host owns.
+func TestHost_Unreachable(t *testing.T) {
+	nw := NewNetwork()
+	client := nw.NewHost("client", "10.0.0.2")
+
+	_, err := client.Dial("tcp", "10.0.0.9:80")
+	assertOpError(t, err, "dial tcp 10.0.0.9:80: connect: no route to host")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EHOSTUNREACH))
+
+	_, err = New().Dial("tcp", "10.0.0.9:80")
+	assertOpError(t, err, "dial tcp 10.0.0.9:80: connect: network is unreachable")
+}
+
+// TestHost_AddressInUse tests binding a port twice.
+func TestHost_AddressInUse(t *testing.T) {
+	h := New("10.0.0.1")
+
+	l, err := h.Listen("tcp", "10.0.0.1:80")
+	testutil.AssertNil(t, err)
+
+	_, err = h.Listen("tcp", "10.0.0.1:80")
+	assertOpError(t, err, "listen tcp 10.0.0.1:80: bind: address already in use")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EADDRINUSE))
+
+	_, err = h.Listen("tcp", "0.0.0.0:80")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EADDRINUSE))
+
+	// Different port, address or protocol is fine.
+	l2, err := h.Listen("tcp", "127.0.0.1:80")
+	testutil.AssertNil(t, err)
+	l2.Close()
+	u, err := h.ListenPacket("udp", "10.0.0.1:80")
+	testutil.AssertNil(t, err)
+	u.Close()
+
+	// Closing frees the port.
+	l.Close()
+	l, err = h.Listen("tcp", ":80")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "[::]:80", l.Addr().String())
+	l.Close()
+}
+
+// TestHost_ForeignAddress tests binding an address of another host.
+func TestHost_ForeignAddress(t *testing.T) {
+	nw := NewNetwork()
+	nw.NewHost("a", "10.0.0.1")
+	b := nw.NewHost("b", "10.0.0.2")
+
+	_, err := b.Listen("tcp", "10.0.0.1:80")
+	assertOpError(t, err, "listen tcp 10.0.0.1:80: bind: cannot assign requested address")
+}
+
+// TestHost_AddressErrors tests malformed addresses and networks.
+func TestHost_AddressErrors(t *testing.T) {
+	h := New()
+
+	_, err := h.Dial("tcp", "127.0.0.1")
+	assertOpError(t, err, "dial tcp: address 127.0.0.1: missing port in address")
+
+	_, err = h.Dial("foo", "127.0.0.1:1")
+	assertOpError(t, err, "dial foo: unknown network foo")
+
+	_, err = h.Dial("tcp4", "[::1]:80")
+	assertOpError(t, err, "dial tcp4: address ::1: no suitable address found")
+
+	_, err = h.Dial("tcp", "nosuch:80")
+	assertOpError(t, err, "dial tcp: lookup nosuch: no such host")
+
+	_, err = h.Listen("udp", ":0")
+	assertOpError(t, err, "listen udp: unknown network udp")
+
+	_, err = h.ListenPacket("ip4:icmp", "0.0.0.0")
+	assertOpError(t, err, "listen ip4:icmp: socket: operation not permitted")
+}
+
+// TestHost_Loopback tests that loopback only reaches the same host.
+func TestHost_Loopback(t *testing.T) {
+	nw := NewNetwork()
+	a := nw.NewHost("a", "10.0.0.1")
+	b := nw.NewHost("b", "10.0.0.2")
+
+	l, err := a.Listen("tcp", ":8080")
+	testutil.AssertNil(t, err)
+	defer l.Close()
+
+	c, err := a.Dial("tcp", "localhost:8080")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "127.0.0.1", c.LocalAddr().(*net.TCPAddr).IP.String())
+	c.Close()
+
+	_, err = b.Dial("tcp", "127.0.0.1:8080")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNREFUSED))
+
+	c, err = b.Dial("tcp", "a:8080")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "10.0.0.2", c.LocalAddr().(*net.TCPAddr).IP.String())
+	c.Close()
+}
+
+// TestHost_Resolve tests the Resolve*Addr functions.
+func TestHost_Resolve(t *testing.T) {
+	nw := NewNetwork()
+	h := nw.NewHost("web", "10.0.0.1", "fd00::1")
+
+	ta, err := h.ResolveTCPAddr("tcp", "web:https")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "10.0.0.1:443", ta.String())
+
+	ta, err = h.ResolveTCPAddr("tcp6", "web:80")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "[fd00::1]:80", ta.String())
+
+	ua, err := h.ResolveUDPAddr("udp", ":53")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ":53", ua.String())
+
+	ia, err := h.ResolveIPAddr("ip4", "web")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "10.0.0.1", ia.String())
+
+	xa, err := h.ResolveUnixAddr("unix", "/run/app.sock")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/run/app.sock", xa.Name)
+
+	_, err = h.ResolveTCPAddr("udp", "web:80")
+	testutil.AssertEqual(t, net.UnknownNetworkError("udp"), err)
+}
+
+// TestHost_Interfaces tests the simulated interfaces.
+func TestHost_Interfaces(t *testing.T) {
+	h := New("192.168.1.10")
+
+	ifs, err := h.Interfaces()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 2, len(ifs))
+	testutil.AssertEqual(t, "lo", ifs[0].Name)
+	testutil.AssertEqual(t, "02:00:c0:a8:01:0a", ifs[1].HardwareAddr.String())
+
+	addrs, err := h.InterfaceAddrs()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "127.0.0.1/8", addrs[0].String())
+	testutil.AssertEqual(t, "192.168.1.10/24", addrs[2].String())
+
+	ifi, err := h.InterfaceByName("eth0")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 2, ifi.Index)
+
+	_, err = h.InterfaceByName("wlan0")
+	assertOpError(t, err, "route ip+net: no such network interface")
+
+	_, err = h.InterfaceByIndex(0)
+	assertOpError(t, err, "route ip+net: invalid network interface index")
+}
+
+// TestHost_Unsupported tests the descriptor-based functions.
+func TestHost_Unsupported(t *testing.T) {
+	h := New()
+
+	_, err := h.FileConn(os.Stdin)
+	testutil.AssertEqual(t, true, errors.Is(err, errors.ErrUnsupported))
+
+	_, err = h.DialIP("ip4:icmp", nil, &net.IPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EPERM))
+}
+
+// TestHost_DialTCP tests the typed dial and listen functions.
+func TestHost_DialTCP(t *testing.T) {
+	h := New("10.0.0.1")
+
+	l, err := h.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000})
+	testutil.AssertNil(t, err)
+	defer l.Close()
+
+	c, err := h.DialTCP("tcp", &net.TCPAddr{Port: 40000}, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000})
+	testutil.AssertNil(t, err)
+	defer c.Close()
+	testutil.AssertEqual(t, "10.0.0.1:40000", c.LocalAddr().String())
+
+	s, err := l.AcceptTCP()
+	testutil.AssertNil(t, err)
+	defer s.Close()
+	testutil.AssertEqual(t, "10.0.0.1:40000", s.RemoteAddr().String())
+
+	_, err = h.DialTCP("tcp", nil, nil)
+	assertOpError(t, err, "dial tcp: missing address")
+}
@@ -0,0 +1,383 @@
+package fake_net
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// segmentSize is the size of the pieces a write is split into on a
+// link with limited bandwidth, so that the reader sees the data
+// arrive gradually.
+const segmentSize = 1460
+
+// Link is the path between two hosts, or from a host to itself.  It
+// carries every connection and datagram between them and can be made
+// to misbehave: conditions apply to traffic sent after they are set,
+// and faults such as Partition and Reset can be injected while a test
+// is running.
+//
+// A zero Link, which is what every pair of hosts starts with, delivers
+// traffic instantly and never loses any.
+type Link struct {
+	a, b *Host
+
+	mu          sync.Mutex
+	latency     time.Duration
+	bandwidth   int
+	loss        float64
+	resetAfter  int64
+	partitioned bool
+	busy        [2]time.Time
+	rng         *rand.Rand
+	flows       map[*flow]struct{}
+
+	// changed is closed and replaced whenever the link is healed or
+	// reconfigured, waking anything waiting on the old state.
+	changed chan struct{}
+}
+
+// Link returns the link between hosts a and b.  It is the same Link
+// whichever order the hosts are given in.  Passing the same host
+// twice returns the link that host's loopback traffic uses.
+func (nw *Network) Link(a, b *Host) *Link {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	return nw.link(a, b)
+}
+
+// link returns the link between a and b, creating it on first use.
+// It must be called with nw.mu held.
+func (nw *Network) link(a, b *Host) *Link {
+	if b.id < a.id {
+		a, b = b, a
+	}
+
+	var key = [2]*Host{a, b}
+	if l := nw.links[key]; l != nil {
+		return l
+	}
+
+	var l = &Link{
+		a:       a,
+		b:       b,
+		rng:     rand.New(rand.NewPCG(nw.seed, uint64(len(nw.links)))),
+		flows:   map[*flow]struct{}{},
+		changed: make(chan struct{}),
+	}
+	nw.links[key] = l
+
+	return l
+}
+
+// dir returns the index of the direction of traffic sent by from.
+func (l *Link) dir(from *Host) int {
+	if from == l.a {
+		return 0
+	}
+
+	return 1
+}
+
+// notify wakes everything waiting on the link.  It must be called with
+// mu held.
+func (l *Link) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// SetLatency sets the one-way delay added to everything sent across
+// the link.  Connecting takes a round trip, twice the latency.
+func (l *Link) SetLatency(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.latency = d
+	l.notify()
+}
+
+// SetBandwidth limits each direction of the link to the given number
+// of bytes per second, shared by all the traffic crossing it.  Zero
+// removes the limit.
+func (l *Link) SetBandwidth(bytesPerSecond int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.bandwidth = bytesPerSecond
+	l.notify()
+}
+
+// SetLoss sets the probability, from 0 to 1, that a datagram sent
+// across the link is dropped.  Stream connections are reliable and
+// are not affected.  The choice is pseudo-random but repeatable for a
+// given network seed; see WithSeed.
+func (l *Link) SetLoss(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.loss = rate
+}
+
+// ResetAfter makes connections opened across the link from now on be
+// reset once n bytes have been written to them, counting both
+// directions.  The write that reaches the limit sends the bytes up to
+// it and then fails; both ends read the data sent before the reset
+// followed by ECONNRESET.  Zero or less removes the limit.
+func (l *Link) ResetAfter(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.resetAfter = n
+}
+
+// Partition cuts the link.  Data already sent and anything sent while
+// the link is partitioned is held back until Heal is called, so reads
+// block (and time out if they have a deadline); datagrams are dropped;
+// and new connections wait to be established.
+func (l *Link) Partition() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partitioned = true
+	l.notify()
+}
+
+// Heal restores a partitioned link.
+func (l *Link) Heal() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partitioned = false
+	l.notify()
+}
+
+// Partitioned reports whether the link is partitioned.
+func (l *Link) Partitioned() bool {
+	if l == nil {
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.partitioned
+}
+
+// Reset resets every connection currently open across the link.  Both
+// ends read any data sent before the reset followed by ECONNRESET,
+// and their writes fail with ECONNRESET.
+func (l *Link) Reset() {
+	for _, f := range l.openFlows() {
+		f.reset()
+	}
+}
+
+// HalfClose ends the data flowing from host from on every connection
+// currently open across the link, as if that end had called
+// CloseWrite: the other end reads io.EOF after the data already sent,
+// but can keep writing.  On a host's link to itself it is the
+// accepted end of each connection that is closed.
+func (l *Link) HalfClose(from *Host) {
+	for _, f := range l.openFlows() {
+		f.halfClose(from)
+	}
+}
+
+func (l *Link) openFlows() []*flow {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var flows = make([]*flow, 0, len(l.flows))
+	for f := range l.flows {
+		flows = append(flows, f)
+	}
+
+	return flows
+}
+
+// state reports whether the link is partitioned, and returns a
+// channel closed the next time that may change.
+func (l *Link) state() (bool, <-chan struct{}) {
+	if l == nil {
+		return false, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.partitioned, l.changed
+}
+
+// schedule reserves the link for n bytes sent now by from and returns
+// when they arrive.
+func (l *Link) schedule(from *Host, n int) time.Time {
+	var now = time.Now()
+	if l == nil {
+		return now
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var t = now
+	if l.bandwidth > 0 {
+		var d = l.dir(from)
+		if l.busy[d].After(t) {
+			t = l.busy[d]
+		}
+		t = t.Add(time.Duration(n) * time.Second / time.Duration(l.bandwidth))
+		l.busy[d] = t
+	}
+
+	return t.Add(l.latency)
+}
+
+// chunk returns how many bytes of a write to send at once.
+func (l *Link) chunk(n int) int {
+	if l == nil {
+		return n
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.bandwidth > 0 && n > segmentSize {
+		return segmentSize
+	}
+
+	return n
+}
+
+// drop decides whether to lose a datagram.
+func (l *Link) drop() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.partitioned || (l.loss > 0 && l.rng.Float64() < l.loss)
+}
+
+// connect waits for the round trip needed to open a connection,
+// including any time the link spends partitioned.
+func (l *Link) connect(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		var partitioned, rtt, changed = l.partitioned, 2 * l.latency, l.changed
+		l.mu.Unlock()
+
+		if !partitioned && rtt == 0 {
+			return ctx.Err()
+		}
+
+		if partitioned {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-changed:
+			}
+			continue
+		}
+
+		var t = time.NewTimer(rtt)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-changed:
+			t.Stop()
+		case <-t.C:
+			return nil
+		}
+	}
+}
+
+// open registers a new connection between the ends c (on host ch) and
+// s (on host sh).
+func (l *Link) open(c *streamConn, ch *Host, s *streamConn, sh *Host) {
+	var f = &flow{link: l, ends: [2]*streamConn{c, s}, hosts: [2]*Host{ch, sh}}
+
+	l.mu.Lock()
+	f.budget = l.resetAfter
+	l.flows[f] = struct{}{}
+	l.mu.Unlock()
+
+	c.flow, s.flow = f, f
+	for _, st := range []*stream{c.tx, s.tx} {
+		st.link, st.flow = l, f
+	}
+	c.tx.from, s.tx.from = ch, sh
+}
+
+// flow is a connection crossing a link.
+type flow struct {
+	link  *Link
+	ends  [2]*streamConn
+	hosts [2]*Host
+
+	mu     sync.Mutex
+	budget int64
+	spent  int64
+	closed int
+}
+
+// take accounts for n bytes about to be written and returns how many
+// may be sent before the connection is reset.
+func (f *flow) take(n int) (int, bool) {
+	if f == nil {
+		return n, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.budget <= 0 {
+		return n, false
+	}
+
+	var left = f.budget - f.spent
+	if int64(n) < left {
+		f.spent += int64(n)
+		return n, false
+	}
+	f.spent = f.budget
+
+	return int(left), true
+}
+
+// reset resets both directions of the connection.
+func (f *flow) reset() {
+	for _, end := range f.ends {
+		end.tx.reset()
+	}
+}
+
+func (f *flow) halfClose(from *Host) {
+	var i = 0
+	if f.hosts[1] == from {
+		i = 1
+	} else if f.hosts[0] != from {
+		return
+	}
+
+	f.ends[i].tx.shutdown()
+}
+
+// done records that one end has closed, forgetting the connection
+// once both have.
+func (f *flow) done() {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	f.closed++
+	var gone = f.closed == 2
+	f.mu.Unlock()
+
+	if gone {
+		f.link.mu.Lock()
+		delete(f.link.flows, f)
+		f.link.mu.Unlock()
+	}
+}
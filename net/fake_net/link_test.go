@@ -0,0 +1,310 @@
+package fake_net
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// linkPair returns a network of two hosts and a connection between
+// them.
+func linkPair(t *testing.T) (*Network, *Host, *Host, net.Conn, net.Conn) {
+	t.Helper()
+
+	nw := NewNetwork()
+	client := nw.NewHost("client", "10.0.0.2")
+	server := nw.NewHost("server", "10.0.0.1")
+
+	l, err := server.Listen("tcp", ":80")
+	testutil.AssertNil(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	c, err := client.Dial("tcp", "server:80")
+	testutil.AssertNil(t, err)
+	s, err := l.Accept()
+	testutil.AssertNil(t, err)
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+
+	return nw, client, server, c, s
+}
+
+// TestLink_Same tests that a link is shared by both directions.
+func TestLink_Same(t *testing.T) {
+	nw := NewNetwork()
+	a := nw.NewHost("a", "10.0.0.1")
+	b := nw.NewHost("b", "10.0.0.2")
+
+	testutil.AssertEqual(t, nw.Link(a, b), nw.Link(b, a))
+	testutil.AssertNotEqual(t, nw.Link(a, b), nw.Link(a, a))
+}
+
+// TestLink_Latency tests delaying connections and data.
+func TestLink_Latency(t *testing.T) {
+	nw := NewNetwork()
+	client := nw.NewHost("client", "10.0.0.2")
+	server := nw.NewHost("server", "10.0.0.1")
+	nw.Link(client, server).SetLatency(20 * time.Millisecond)
+
+	l, _ := server.Listen("tcp", ":80")
+	defer l.Close()
+
+	start := time.Now()
+	c, err := client.Dial("tcp", "server:80")
+	testutil.AssertNil(t, err)
+	defer c.Close()
+	testutil.AssertEqual(t, true, time.Since(start) >= 40*time.Millisecond)
+
+	s, _ := l.Accept()
+	defer s.Close()
+
+	start = time.Now()
+	c.Write([]byte("ping"))
+	b := make([]byte, 4)
+	_, err = io.ReadFull(s, b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "ping", string(b))
+	testutil.AssertEqual(t, true, time.Since(start) >= 20*time.Millisecond)
+}
+
+// TestLink_Bandwidth tests limiting how fast data arrives.
+func TestLink_Bandwidth(t *testing.T) {
+	nw, client, server, c, s := linkPair(t)
+	nw.Link(client, server).SetBandwidth(100000)
+
+	start := time.Now()
+	go c.Write(make([]byte, 10000))
+
+	_, err := io.ReadFull(s, make([]byte, 10000))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, time.Since(start) >= 90*time.Millisecond)
+}
+
+// TestLink_WriteDeadline tests a write blocking on a slow link until
+// its deadline.
+func TestLink_WriteDeadline(t *testing.T) {
+	nw, client, server, c, _ := linkPair(t)
+	nw.Link(client, server).SetBandwidth(1000)
+	testutil.AssertNil(t, c.(*TCPConn).SetWriteBuffer(100))
+
+	c.SetWriteDeadline(time.Now().Add(20 * time.Millisecond))
+	n, err := c.Write(make([]byte, 1000))
+	testutil.AssertEqual(t, true, n >= 100 && n < 1000)
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+
+	var ne net.Error
+	testutil.AssertEqual(t, true, errors.As(err, &ne) && ne.Timeout())
+}
+
+// TestLink_Partition tests cutting and healing a link under an open
+// connection.
+func TestLink_Partition(t *testing.T) {
+	nw, client, server, c, s := linkPair(t)
+	link := nw.Link(client, server)
+
+	link.Partition()
+	testutil.AssertEqual(t, true, link.Partitioned())
+
+	_, err := c.Write([]byte("held"))
+	testutil.AssertNil(t, err)
+
+	s.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	b := make([]byte, 4)
+	_, err = s.Read(b)
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+	assertOpError(t, err, "read tcp 10.0.0.1:80->"+c.LocalAddr().String()+": i/o timeout")
+
+	s.SetReadDeadline(time.Time{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		link.Heal()
+	}()
+
+	n, err := s.Read(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "held", string(b[:n]))
+	testutil.AssertEqual(t, false, link.Partitioned())
+}
+
+// TestLink_PartitionDial tests dialing across a partitioned link.
+func TestLink_PartitionDial(t *testing.T) {
+	nw := NewNetwork()
+	client := nw.NewHost("client", "10.0.0.2")
+	server := nw.NewHost("server", "10.0.0.1")
+	l, _ := server.Listen("tcp", ":80")
+	defer l.Close()
+
+	link := nw.Link(client, server)
+	link.Partition()
+
+	_, err := client.DialTimeout("tcp", "10.0.0.1:80", 20*time.Millisecond)
+	assertOpError(t, err, "dial tcp 10.0.0.1:80: i/o timeout")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		link.Heal()
+	}()
+
+	c, err := client.Dial("tcp", "10.0.0.1:80")
+	testutil.AssertNil(t, err)
+	c.Close()
+}
+
+// TestLink_PartitionUDP tests that datagrams are lost across a
+// partitioned link.
+func TestLink_PartitionUDP(t *testing.T) {
+	nw := NewNetwork()
+	client := nw.NewHost("client", "10.0.0.2")
+	server := nw.NewHost("server", "10.0.0.1")
+
+	s, _ := server.ListenUDP("udp", &net.UDPAddr{Port: 53})
+	defer s.Close()
+	c, _ := client.Dial("udp", "10.0.0.1:53")
+	defer c.Close()
+
+	nw.Link(client, server).Partition()
+	_, err := c.Write([]byte("lost"))
+	testutil.AssertNil(t, err)
+
+	nw.Link(client, server).Heal()
+	c.Write([]byte("found"))
+
+	b := make([]byte, 10)
+	n, _, err := s.ReadFrom(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "found", string(b[:n]))
+}
+
+// TestLink_Loss tests that a lossy link drops some datagrams, the same
+// ones for the same seed.
+func TestLink_Loss(t *testing.T) {
+	received := func() int {
+		nw := NewNetwork(WithSeed(42))
+		client := nw.NewHost("client", "10.0.0.2")
+		server := nw.NewHost("server", "10.0.0.1")
+		nw.Link(client, server).SetLoss(0.5)
+
+		s, _ := server.ListenUDP("udp", &net.UDPAddr{Port: 53})
+		defer s.Close()
+		c, _ := client.Dial("udp", "10.0.0.1:53")
+		defer c.Close()
+
+		for range 100 {
+			c.Write([]byte("x"))
+		}
+
+		s.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		var n int
+		for {
+			if _, _, err := s.ReadFrom(make([]byte, 1)); err != nil {
+				return n
+			}
+			n++
+		}
+	}
+
+	n := received()
+	testutil.AssertEqual(t, true, n > 20 && n < 80)
+	testutil.AssertEqual(t, n, received())
+}
+
+// TestLink_LatencyUDP tests a datagram read timing out before a
+// delayed datagram arrives.
+func TestLink_LatencyUDP(t *testing.T) {
+	nw := NewNetwork()
+	client := nw.NewHost("client", "10.0.0.2")
+	server := nw.NewHost("server", "10.0.0.1")
+	nw.Link(client, server).SetLatency(30 * time.Millisecond)
+
+	s, _ := server.ListenUDP("udp", &net.UDPAddr{Port: 53})
+	defer s.Close()
+	c, _ := client.Dial("udp", "10.0.0.1:53")
+	defer c.Close()
+
+	c.Write([]byte("slow"))
+
+	s.SetReadDeadline(time.Now().Add(5 * time.Millisecond))
+	b := make([]byte, 10)
+	_, _, err := s.ReadFrom(b)
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+
+	s.SetReadDeadline(time.Time{})
+	n, _, err := s.ReadFrom(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "slow", string(b[:n]))
+}
+
+// TestLink_Reset tests resetting the connections crossing a link.
+func TestLink_Reset(t *testing.T) {
+	nw, client, server, c, s := linkPair(t)
+
+	c.Write([]byte("last"))
+	nw.Link(server, client).Reset()
+
+	b := make([]byte, 10)
+	n, err := s.Read(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "last", string(b[:n]))
+
+	_, err = s.Read(b)
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNRESET))
+	_, err = c.Read(b)
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNRESET))
+
+	_, err = c.Write([]byte("x"))
+	assertOpError(t, err, "write tcp "+c.LocalAddr().String()+"->10.0.0.1:80: write: connection reset by peer")
+}
+
+// TestLink_ResetAfter tests resetting connections mid-stream.
+func TestLink_ResetAfter(t *testing.T) {
+	nw := NewNetwork()
+	client := nw.NewHost("client", "10.0.0.2")
+	server := nw.NewHost("server", "10.0.0.1")
+	nw.Link(client, server).ResetAfter(10)
+
+	l, _ := server.Listen("tcp", ":80")
+	defer l.Close()
+	c, _ := client.Dial("tcp", "server:80")
+	defer c.Close()
+	s, _ := l.Accept()
+	defer s.Close()
+
+	n, err := c.Write([]byte("0123456789abcdef"))
+	testutil.AssertEqual(t, 10, n)
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNRESET))
+
+	data, err := io.ReadAll(s)
+	testutil.AssertEqual(t, "0123456789", string(data))
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNRESET))
+}
+
+// TestLink_HalfClose tests ending one direction of the connections
+// crossing a link.
+func TestLink_HalfClose(t *testing.T) {
+	nw, client, server, c, s := linkPair(t)
+
+	s.Write([]byte("response"))
+	nw.Link(client, server).HalfClose(server)
+
+	data, err := io.ReadAll(c)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "response", string(data))
+
+	_, err = c.Write([]byte("more"))
+	testutil.AssertNil(t, err)
+	b := make([]byte, 4)
+	_, err = io.ReadFull(s, b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "more", string(b))
+
+	_, err = s.Write([]byte("x"))
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EPIPE))
+}
@@ -0,0 +1,290 @@
+package fake_net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+)
+
+// listener queues the server ends of connections made to a stream
+// socket until they are accepted.  The queue is unbounded: dials
+// succeed as soon as the listener exists, as they do when the kernel
+// completes the handshake before Accept is called.
+type listener struct {
+	host    *Host
+	network string
+	addr    net.Addr
+	key     sockKey
+	path    string
+	unlink  bool
+
+	mu       sync.Mutex
+	queue    []*streamConn
+	signal   chan struct{}
+	deadline *deadline
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newListener(h *Host, network string, addr net.Addr) *listener {
+	return &listener{
+		host:     h,
+		network:  network,
+		addr:     addr,
+		signal:   make(chan struct{}),
+		deadline: newDeadline(),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (l *listener) opError(op string, err error) error {
+	return opErr(op, l.network, nil, l.addr, err)
+}
+
+// enqueue hands a new connection to the listener.
+func (l *listener) enqueue(c *streamConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c.network = l.network
+	l.queue = append(l.queue, c)
+	close(l.signal)
+	l.signal = make(chan struct{})
+}
+
+// accept waits for a connection, the listener to close or the deadline
+// to pass.
+func (l *listener) accept() (*streamConn, error) {
+	for {
+		var expire = l.deadline.wait()
+
+		l.mu.Lock()
+		switch {
+		case isClosed(l.closed):
+			l.mu.Unlock()
+			return nil, l.opError("accept", net.ErrClosed)
+		case isClosed(expire):
+			l.mu.Unlock()
+			return nil, l.opError("accept", os.ErrDeadlineExceeded)
+		case len(l.queue) > 0:
+			var c = l.queue[0]
+			l.queue = l.queue[1:]
+			l.mu.Unlock()
+			return c, nil
+		}
+		var signal = l.signal
+		l.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-l.closed:
+		case <-expire:
+		}
+	}
+}
+
+// Close stops the listener.  Connections that were never accepted are
+// reset.
+func (l *listener) Close() error {
+	var err = l.opError("close", net.ErrClosed)
+
+	l.closeOnce.Do(func() {
+		err = nil
+
+		var nw = l.host.nw
+		nw.mu.Lock()
+		if l.key.proto == "" {
+			if l.unlink {
+				delete(l.host.unix, l.path)
+			} else {
+				l.host.unix[l.path] = &unixEntry{}
+			}
+		} else {
+			delete(l.host.streams, l.key)
+		}
+		nw.mu.Unlock()
+
+		l.mu.Lock()
+		close(l.closed)
+		var queue = l.queue
+		l.queue = nil
+		l.mu.Unlock()
+
+		for _, c := range queue {
+			c.shut(true)
+		}
+	})
+
+	return err
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *listener) SetDeadline(t time.Time) error {
+	if isClosed(l.closed) {
+		return l.opError("set", net.ErrClosed)
+	}
+	l.deadline.set(t)
+
+	return nil
+}
+
+// File is not supported: there is no descriptor behind the listener.
+func (l *listener) File() (*os.File, error) {
+	return nil, l.opError("file", errors.ErrUnsupported)
+}
+
+// SyscallConn is not supported: there is no descriptor behind the
+// listener.
+func (l *listener) SyscallConn() (syscall.RawConn, error) {
+	return nil, l.opError("raw-control", errors.ErrUnsupported)
+}
+
+// TCPListener is a simulated TCP listener.
+type TCPListener struct {
+	*listener
+}
+
+var _ neti.TCPListener = (*TCPListener)(nil)
+
+func (l *TCPListener) Accept() (net.Conn, error) {
+	var c, err = l.accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TCPConn{c}, nil
+}
+
+func (l *TCPListener) AcceptTCP() (neti.TCPConn, error) {
+	var c, err = l.accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TCPConn{c}, nil
+}
+
+// listenTCP binds a TCP listener to ap.
+func (h *Host) listenTCP(network string, ap netip.AddrPort) (*TCPListener, error) {
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	var key, err = h.bind("tcp", ap)
+	if err != nil {
+		return nil, err
+	}
+
+	var ip = key.ip
+	if !ip.IsValid() {
+		ip = ap.Addr()
+	}
+
+	var l = newListener(h, network, net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, key.port)))
+	l.key = key
+	h.streams[key] = l
+
+	return &TCPListener{l}, nil
+}
+
+// dialTCP connects to the listener at dst from laddr, which may be nil
+// to pick a source address and port.  Connecting takes a round trip
+// across the link to the peer, and waits for a partitioned link to be
+// healed or for ctx to be done.
+func (h *Host) dialTCP(ctx context.Context, network string, laddr *net.TCPAddr, dst netip.AddrPort) (*TCPConn, error) {
+	var local netip.AddrPort
+	if laddr != nil {
+		local = addrPort(laddr.IP, laddr.Zone, laddr.Port)
+	}
+	dst = loopbackFor(dst)
+
+	h.nw.mu.Lock()
+	var peer = h.nw.route(h, dst.Addr())
+	var link *Link
+	if peer != nil {
+		link = h.nw.link(h, peer)
+	}
+	h.nw.mu.Unlock()
+
+	if link != nil {
+		if err := link.connect(ctx); err != nil {
+			return nil, mapContextErr(err)
+		}
+	}
+
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	var src, port, err = h.source("tcp", local, dst.Addr())
+	if err != nil {
+		return nil, err
+	}
+
+	if peer == nil {
+		return nil, sysErr("connect", syscall.EHOSTUNREACH)
+	}
+
+	var l = peer.lookupStream(dst)
+	if l == nil {
+		return nil, sysErr("connect", syscall.ECONNREFUSED)
+	}
+
+	var client, server = newStreamPair(network,
+		net.TCPAddrFromAddrPort(netip.AddrPortFrom(src, port)),
+		net.TCPAddrFromAddrPort(dst))
+	link.open(client, h, server, peer)
+	l.enqueue(server)
+
+	return &TCPConn{client}, nil
+}
+
+// loopbackFor replaces an unspecified destination with the loopback
+// address of the same family, where connecting to it really goes.
+func loopbackFor(dst netip.AddrPort) netip.AddrPort {
+	switch {
+	case dst.Addr() == ipv4Unspecified:
+		return netip.AddrPortFrom(ipv4Loopback, dst.Port())
+	case dst.Addr().IsUnspecified():
+		return netip.AddrPortFrom(netip.IPv6Loopback(), dst.Port())
+	}
+
+	return dst
+}
+
+// source picks the local address and port of a socket sending to
+// dst, honouring any part of local that is set.  It must be called
+// with nw.mu held.
+func (h *Host) source(proto string, local netip.AddrPort, dst netip.Addr) (netip.Addr, uint16, error) {
+	var src = local.Addr()
+	var port = local.Port()
+
+	if src.IsValid() && !src.IsUnspecified() {
+		if !h.owns(src) {
+			return src, 0, sysErr("bind", syscall.EADDRNOTAVAIL)
+		}
+	} else {
+		var ok bool
+		if src, ok = h.sourceFor(dst); !ok {
+			return src, 0, sysErr("connect", syscall.ENETUNREACH)
+		}
+	}
+
+	if port == 0 {
+		var err error
+		if port, err = h.allocPort(proto, src); err != nil {
+			return src, 0, err
+		}
+	}
+
+	return src, port, nil
+}
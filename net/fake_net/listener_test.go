@@ -0,0 +1,84 @@
+package fake_net
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestListener_Close tests closing a listener.
+func TestListener_Close(t *testing.T) {
+	h := New()
+
+	l, err := h.Listen("tcp", "127.0.0.1:7000")
+	testutil.AssertNil(t, err)
+
+	// A connection that is never accepted is reset.
+	c, err := h.Dial("tcp", "127.0.0.1:7000")
+	testutil.AssertNil(t, err)
+
+	testutil.AssertNil(t, l.Close())
+
+	_, err = c.Read(make([]byte, 1))
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNRESET))
+
+	_, err = l.Accept()
+	assertOpError(t, err, "accept tcp 127.0.0.1:7000: use of closed network connection")
+
+	err = l.Close()
+	assertOpError(t, err, "close tcp 127.0.0.1:7000: use of closed network connection")
+
+	_, err = h.Dial("tcp", "127.0.0.1:7000")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNREFUSED))
+}
+
+// TestListener_CloseWakesAccept tests closing a listener while Accept
+// is blocked.
+func TestListener_CloseWakesAccept(t *testing.T) {
+	l, err := New().Listen("tcp", ":0")
+	testutil.AssertNil(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := l.Accept()
+		done <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	l.Close()
+	testutil.AssertEqual(t, true, errors.Is(<-done, net.ErrClosed))
+}
+
+// TestListener_Deadline tests accept deadlines.
+func TestListener_Deadline(t *testing.T) {
+	l, err := New().ListenTCP("tcp", nil)
+	testutil.AssertNil(t, err)
+	defer l.Close()
+
+	testutil.AssertNil(t, l.SetDeadline(time.Now().Add(10*time.Millisecond)))
+
+	_, err = l.Accept()
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+}
+
+// TestListener_Ephemeral tests that port zero picks a free port.
+func TestListener_Ephemeral(t *testing.T) {
+	h := New()
+
+	l1, err := h.Listen("tcp", "127.0.0.1:0")
+	testutil.AssertNil(t, err)
+	defer l1.Close()
+	l2, err := h.Listen("tcp", "127.0.0.1:0")
+	testutil.AssertNil(t, err)
+	defer l2.Close()
+
+	p1 := l1.Addr().(*net.TCPAddr).Port
+	p2 := l2.Addr().(*net.TCPAddr).Port
+	testutil.AssertNotEqual(t, p1, p2)
+	testutil.AssertEqual(t, true, p1 >= firstEphemeralPort && p1 <= lastEphemeralPort)
+}
@@ -0,0 +1,169 @@
+// Package fake_net provides a virtual network fabric implementing
+// the go-interfaces net.Net, Dialer and ListenConfig interfaces.
+//
+// A Network is an in-process address space shared by one or more
+// Hosts.  Each Host implements net.Net: a listener opened on one host
+// can be reached by dialing its address from any host on the same
+// network, and the two ends of the resulting connection behave like
+// a buffered net.Pipe.  Connection failures are reported with the
+// same *net.OpError values the real net package produces:
+//
+//	nw := fake_net.NewNetwork()
+//	server := nw.NewHost("server", "10.0.0.1")
+//	client := nw.NewHost("client", "10.0.0.2")
+//
+//	l, _ := server.Listen("tcp", "10.0.0.1:80")
+//	c, _ := client.Dial("tcp", "server:80")
+//
+// Every host also has the loopback addresses 127.0.0.0/8 and ::1,
+// which only reach that host.
+//
+// Traffic between two hosts crosses a Link, which can be given
+// latency, a bandwidth limit and datagram loss, or be partitioned and
+// healed, and can reset or half-close the connections crossing it:
+//
+//	nw.Link(client, server).SetLatency(50 * time.Millisecond)
+//	nw.Link(client, server).Partition()
+package fake_net
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// Network is a virtual network connecting Hosts.
+type Network struct {
+	mu       sync.Mutex
+	hosts    []*Host
+	byIP     map[netip.Addr]*Host
+	links    map[[2]*Host]*Link
+	seed     uint64
+	resolver *Resolver
+}
+
+// Option configures a Network.
+type Option func(*Network)
+
+// WithSeed seeds the pseudo-random choices the network makes, such as
+// which datagrams a lossy link drops.  Networks with the same seed,
+// hosts and traffic make the same choices.  The default seed is 0.
+func WithSeed(seed uint64) Option {
+	return func(nw *Network) {
+		nw.seed = seed
+	}
+}
+
+// NewNetwork returns an empty network.
+func NewNetwork(options ...Option) *Network {
+	var nw = &Network{
+		byIP:  map[netip.Addr]*Host{},
+		links: map[[2]*Host]*Link{},
+	}
+	for _, opt := range options {
+		opt(nw)
+	}
+	nw.resolver = newResolver(nw)
+
+	return nw
+}
+
+// New returns a single host with the given addresses on a network of
+// its own.  It is a shortcut for tests that only need loopback or a
+// client and server on the same machine.
+func New(addrs ...string) *Host {
+	return NewNetwork().NewHost("localhost", addrs...)
+}
+
+// NewHost adds a host to the network.  Its name resolves to addrs
+// from every host on the network.  It panics if an address is not a
+// valid IP or is already in use, since that is a mistake in the test.
+func (nw *Network) NewHost(name string, addrs ...string) *Host {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	var h = &Host{
+		nw:       nw,
+		id:       len(nw.hosts),
+		name:     name,
+		streams:  map[sockKey]*listener{},
+		packets:  map[sockKey]*packetSocket{},
+		nextPort: firstEphemeralPort,
+		unix:     map[string]*unixEntry{},
+	}
+
+	for _, s := range addrs {
+		var ip, err = netip.ParseAddr(s)
+		if err != nil {
+			panic(fmt.Sprintf("fake_net: invalid host address %q: %v", s, err))
+		}
+		ip = ip.Unmap()
+		if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() {
+			panic(fmt.Sprintf("fake_net: %s cannot be assigned to a host", ip))
+		}
+		if other := nw.byIP[ip]; other != nil {
+			panic(fmt.Sprintf("fake_net: address %s already belongs to host %q", ip, other.name))
+		}
+		nw.byIP[ip] = h
+		h.addrs = append(h.addrs, ip)
+	}
+
+	nw.hosts = append(nw.hosts, h)
+
+	return h
+}
+
+// Resolver returns the resolver shared by every host on the network.
+func (nw *Network) Resolver() *Resolver {
+	return nw.resolver
+}
+
+// hostByName returns the addresses of the named host.
+func (nw *Network) hostByName(name string) []netip.Addr {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	for _, h := range nw.hosts {
+		if h.name == name {
+			return append([]netip.Addr{}, h.addrs...)
+		}
+	}
+
+	return nil
+}
+
+// hostByAddr returns the name of the host owning ip.
+func (nw *Network) hostByAddr(ip netip.Addr) (string, bool) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	var h = nw.byIP[ip.Unmap()]
+	if h == nil {
+		return "", false
+	}
+
+	return h.name, true
+}
+
+// route finds the host that receives traffic sent by from to ip.  It
+// must be called with mu held.
+func (nw *Network) route(from *Host, ip netip.Addr) *Host {
+	ip = ip.Unmap()
+	if ip.IsLoopback() {
+		return from
+	}
+
+	return nw.byIP[ip]
+}
+
+// addrFor turns an address and port into the net.Addr type used by
+// the given protocol.
+func addrFor(proto string, ap netip.AddrPort) net.Addr {
+	switch proto {
+	case "udp":
+		return net.UDPAddrFromAddrPort(ap)
+	}
+
+	return net.TCPAddrFromAddrPort(ap)
+}
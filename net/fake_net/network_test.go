@@ -0,0 +1,81 @@
+package fake_net
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// assertOpError checks that err is a *net.OpError with the message
+// want, and returns it.
+func assertOpError(t *testing.T, err error, want string) *net.OpError {
+	t.Helper()
+
+	var oe *net.OpError
+	if !errors.As(err, &oe) {
+		t.Fatalf("expected *net.OpError, got %T: %v", err, err)
+	}
+	testutil.AssertEqual(t, want, err.Error())
+
+	return oe
+}
+
+// TestNetwork_TwoHosts tests a connection between two hosts.
+func TestNetwork_TwoHosts(t *testing.T) {
+	nw := NewNetwork()
+	server := nw.NewHost("server", "10.0.0.1")
+	client := nw.NewHost("client", "10.0.0.2")
+
+	l, err := server.Listen("tcp", "10.0.0.1:80")
+	testutil.AssertNil(t, err)
+	defer l.Close()
+
+	c, err := client.Dial("tcp", "server:80")
+	testutil.AssertNil(t, err)
+	defer c.Close()
+
+	s, err := l.Accept()
+	testutil.AssertNil(t, err)
+	defer s.Close()
+
+	testutil.AssertEqual(t, "10.0.0.1:80", c.RemoteAddr().String())
+	testutil.AssertEqual(t, c.LocalAddr().String(), s.RemoteAddr().String())
+	testutil.AssertEqual(t, "10.0.0.1:80", s.LocalAddr().String())
+
+	_, err = c.Write([]byte("ping"))
+	testutil.AssertNil(t, err)
+	c.Close()
+
+	data, err := io.ReadAll(s)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "ping", string(data))
+}
+
+// TestNetwork_NewHostPanics tests rejecting bad host addresses.
+func TestNetwork_NewHostPanics(t *testing.T) {
+	nw := NewNetwork()
+	nw.NewHost("a", "10.0.0.1")
+
+	testutil.AssertPanic(t, func() { nw.NewHost("b", "10.0.0.1") }, "duplicate address")
+	testutil.AssertPanic(t, func() { nw.NewHost("c", "not-an-ip") }, "invalid address")
+	testutil.AssertPanic(t, func() { nw.NewHost("d", "127.0.0.1") }, "loopback address")
+}
+
+// TestNew tests the single host shortcut.
+func TestNew(t *testing.T) {
+	h := New()
+
+	l, err := h.Listen("tcp", "127.0.0.1:0")
+	testutil.AssertNil(t, err)
+	defer l.Close()
+
+	c, err := h.Dial("tcp", l.Addr().String())
+	testutil.AssertNil(t, err)
+	c.Close()
+
+	testutil.AssertEqual(t, "localhost", h.Name())
+	testutil.AssertEqual(t, true, h.Network().Resolver() != nil)
+}
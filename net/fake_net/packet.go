@@ -0,0 +1,544 @@
+package fake_net
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+)
+
+const (
+	// defaultReadBuffer is the receive buffer of a new packet socket,
+	// Linux's default.
+	defaultReadBuffer = 212992
+
+	// maxDatagram is the largest UDP payload that fits in an IPv4
+	// packet.
+	maxDatagram = 65507
+)
+
+// datagram is a message waiting in a packet socket's receive queue.
+// It can be read from time at.
+type datagram struct {
+	b    []byte
+	from net.Addr
+	at   time.Time
+}
+
+// packetSocket is the part of a connection shared by UDP and Unix
+// datagram sockets.  Datagrams that do not fit in the receive buffer
+// are dropped.
+type packetSocket struct {
+	host    *Host
+	network string
+	laddr   net.Addr
+	raddr   net.Addr
+
+	// key is the UDP binding; path the Unix socket name, empty for an
+	// unnamed socket.
+	key   sockKey
+	path  string
+	group *netip.AddrPort
+
+	mu      sync.Mutex
+	queue   []datagram
+	queued  int
+	rcvbuf  int
+	signal  chan struct{}
+	pending error
+
+	readDeadline  *deadline
+	writeDeadline *deadline
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newPacketSocket(h *Host, network string, laddr, raddr net.Addr) *packetSocket {
+	return &packetSocket{
+		host:          h,
+		network:       network,
+		laddr:         laddr,
+		raddr:         raddr,
+		rcvbuf:        defaultReadBuffer,
+		signal:        make(chan struct{}),
+		readDeadline:  newDeadline(),
+		writeDeadline: newDeadline(),
+		closed:        make(chan struct{}),
+	}
+}
+
+func (p *packetSocket) opError(op string, addr net.Addr, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &net.OpError{Op: op, Net: p.network, Source: p.laddr, Addr: addr, Err: err}
+}
+
+// notify wakes every goroutine waiting on the socket.  It must be
+// called with mu held.
+func (p *packetSocket) notify() {
+	close(p.signal)
+	p.signal = make(chan struct{})
+}
+
+// deliver queues a datagram for reading.  A connected socket only
+// accepts datagrams from its peer.
+func (p *packetSocket) deliver(d datagram) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case isClosed(p.closed):
+		return
+	case p.raddr != nil && (d.from == nil || d.from.String() != p.raddr.String()):
+		return
+	case p.queued+len(d.b) > p.rcvbuf:
+		return
+	}
+
+	var i = len(p.queue)
+	for i > 0 && p.queue[i-1].at.After(d.at) {
+		i--
+	}
+	p.queue = slices.Insert(p.queue, i, d)
+	p.queued += len(d.b)
+	p.notify()
+}
+
+// fail records an error reported asynchronously, as an ICMP error is,
+// for the next read to return.
+func (p *packetSocket) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending = err
+	p.notify()
+}
+
+// readFrom waits for a datagram and copies as much of it as fits into
+// b; the rest is discarded.
+func (p *packetSocket) readFrom(b []byte) (int, net.Addr, error) {
+	for {
+		var expire = p.readDeadline.wait()
+
+		p.mu.Lock()
+		switch {
+		case isClosed(p.closed):
+			p.mu.Unlock()
+			return 0, nil, net.ErrClosed
+		case p.pending != nil:
+			var err = p.pending
+			p.pending = nil
+			p.mu.Unlock()
+			return 0, nil, err
+		case isClosed(expire):
+			p.mu.Unlock()
+			return 0, nil, os.ErrDeadlineExceeded
+		case len(p.queue) > 0 && !p.queue[0].at.After(time.Now()):
+			var d = p.queue[0]
+			p.queue = p.queue[1:]
+			p.queued -= len(d.b)
+			p.mu.Unlock()
+			return copy(b, d.b), d.from, nil
+		}
+		var signal = p.signal
+		var timer <-chan time.Time
+		var stop = func() bool { return false }
+		if len(p.queue) > 0 {
+			var t = time.NewTimer(time.Until(p.queue[0].at))
+			timer, stop = t.C, t.Stop
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-timer:
+		case <-p.closed:
+		case <-expire:
+		}
+		stop()
+	}
+}
+
+// writeTo sends b to addr, or to the connected peer if addr is nil.
+func (p *packetSocket) writeTo(b []byte, addr net.Addr) error {
+	switch {
+	case isClosed(p.closed):
+		return net.ErrClosed
+	case isClosed(p.writeDeadline.wait()):
+		return os.ErrDeadlineExceeded
+	}
+
+	var call = "sendto"
+	if addr == nil {
+		if p.raddr == nil {
+			return sysErr("write", syscall.EDESTADDRREQ)
+		}
+		addr, call = p.raddr, "write"
+	}
+
+	switch to := addr.(type) {
+	case *net.UDPAddr:
+		if len(b) > maxDatagram {
+			return sysErr(call, syscall.EMSGSIZE)
+		}
+		return p.host.sendUDP(p, call, b, addrPort(to.IP, to.Zone, to.Port))
+	case *net.UnixAddr:
+		return p.host.sendUnix(p, call, b, to.Name)
+	}
+
+	return sysErr(call, syscall.EINVAL)
+}
+
+func (p *packetSocket) Close() error {
+	var err = p.opError("close", p.raddr, net.ErrClosed)
+
+	p.closeOnce.Do(func() {
+		err = nil
+
+		var nw = p.host.nw
+		nw.mu.Lock()
+		switch {
+		case p.group != nil:
+			p.host.unsubscribe(p)
+		case p.key.proto != "":
+			delete(p.host.packets, p.key)
+		case p.path != "":
+			p.host.unix[p.path] = &unixEntry{}
+		}
+		nw.mu.Unlock()
+
+		p.mu.Lock()
+		close(p.closed)
+		p.queue = nil
+		p.mu.Unlock()
+	})
+
+	return err
+}
+
+func (p *packetSocket) Read(b []byte) (int, error) {
+	var n, _, err = p.readFrom(b)
+	return n, p.opError("read", p.raddr, err)
+}
+
+func (p *packetSocket) Write(b []byte) (int, error) {
+	if err := p.writeTo(b, nil); err != nil {
+		return 0, p.opError("write", p.raddr, err)
+	}
+
+	return len(b), nil
+}
+
+func (p *packetSocket) LocalAddr() net.Addr {
+	return p.laddr
+}
+
+func (p *packetSocket) RemoteAddr() net.Addr {
+	return p.raddr
+}
+
+func (p *packetSocket) SetDeadline(t time.Time) error {
+	if isClosed(p.closed) {
+		return p.opError("set", p.raddr, net.ErrClosed)
+	}
+	p.readDeadline.set(t)
+	p.writeDeadline.set(t)
+
+	return nil
+}
+
+func (p *packetSocket) SetReadDeadline(t time.Time) error {
+	if isClosed(p.closed) {
+		return p.opError("set", p.raddr, net.ErrClosed)
+	}
+	p.readDeadline.set(t)
+
+	return nil
+}
+
+func (p *packetSocket) SetWriteDeadline(t time.Time) error {
+	if isClosed(p.closed) {
+		return p.opError("set", p.raddr, net.ErrClosed)
+	}
+	p.writeDeadline.set(t)
+
+	return nil
+}
+
+// SetReadBuffer sets the number of bytes of datagrams that can be
+// queued before further datagrams are dropped.
+func (p *packetSocket) SetReadBuffer(n int) error {
+	if isClosed(p.closed) {
+		return p.opError("set", p.raddr, net.ErrClosed)
+	}
+
+	p.mu.Lock()
+	p.rcvbuf = n
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *packetSocket) SetWriteBuffer(int) error {
+	if isClosed(p.closed) {
+		return p.opError("set", p.raddr, net.ErrClosed)
+	}
+
+	return nil
+}
+
+// File is not supported: there is no descriptor behind the socket.
+func (p *packetSocket) File() (*os.File, error) {
+	return nil, p.opError("file", p.raddr, errors.ErrUnsupported)
+}
+
+// SyscallConn is not supported: there is no descriptor behind the
+// socket.
+func (p *packetSocket) SyscallConn() (syscall.RawConn, error) {
+	return nil, p.opError("raw-control", p.raddr, errors.ErrUnsupported)
+}
+
+// UDPConn is a simulated UDP socket.
+type UDPConn struct {
+	*packetSocket
+}
+
+var _ neti.UDPConn = (*UDPConn)(nil)
+
+func (c *UDPConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	var n, addr, err = c.ReadFromUDP(b)
+	if addr == nil {
+		return n, nil, err
+	}
+
+	return n, addr, err
+}
+
+func (c *UDPConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	var n, from, err = c.readFrom(b)
+	if err != nil {
+		return n, nil, c.opError("read", c.raddr, err)
+	}
+
+	return n, from.(*net.UDPAddr), nil
+}
+
+func (c *UDPConn) ReadFromUDPAddrPort(b []byte) (int, netip.AddrPort, error) {
+	var n, addr, err = c.ReadFromUDP(b)
+	if err != nil {
+		return n, netip.AddrPort{}, err
+	}
+
+	return n, addr.AddrPort(), nil
+}
+
+// ReadMsgUDP reads a datagram.  No out-of-band data is ever received.
+func (c *UDPConn) ReadMsgUDP(b, oob []byte) (int, int, int, *net.UDPAddr, error) {
+	var n, addr, err = c.ReadFromUDP(b)
+	return n, 0, 0, addr, err
+}
+
+func (c *UDPConn) ReadMsgUDPAddrPort(b, oob []byte) (int, int, int, netip.AddrPort, error) {
+	var n, addr, err = c.ReadFromUDPAddrPort(b)
+	return n, 0, 0, addr, err
+}
+
+func (c *UDPConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	var to, ok = addr.(*net.UDPAddr)
+	if !ok {
+		return 0, c.opError("write", addr, sysErr("sendto", syscall.EINVAL))
+	}
+
+	return c.WriteToUDP(b, to)
+}
+
+func (c *UDPConn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) {
+	switch {
+	case c.raddr != nil:
+		return 0, c.opError("write", opAddr(addr), net.ErrWriteToConnected)
+	case addr == nil:
+		return 0, c.opError("write", nil, errMissingAddress)
+	}
+
+	if err := c.writeTo(b, addr); err != nil {
+		return 0, c.opError("write", addr, err)
+	}
+
+	return len(b), nil
+}
+
+func (c *UDPConn) WriteToUDPAddrPort(b []byte, addr netip.AddrPort) (int, error) {
+	return c.WriteToUDP(b, net.UDPAddrFromAddrPort(addr))
+}
+
+// WriteMsgUDP writes a datagram.  Out-of-band data is accepted and
+// discarded.
+func (c *UDPConn) WriteMsgUDP(b, oob []byte, addr *net.UDPAddr) (int, int, error) {
+	var n int
+	var err error
+	if addr == nil {
+		n, err = c.Write(b)
+	} else {
+		n, err = c.WriteToUDP(b, addr)
+	}
+	if err != nil {
+		return n, 0, err
+	}
+
+	return n, len(oob), nil
+}
+
+func (c *UDPConn) WriteMsgUDPAddrPort(b, oob []byte, addr netip.AddrPort) (int, int, error) {
+	if !addr.IsValid() {
+		return c.WriteMsgUDP(b, oob, nil)
+	}
+
+	return c.WriteMsgUDP(b, oob, net.UDPAddrFromAddrPort(addr))
+}
+
+// listenUDP binds a UDP socket to ap.  If group is set the socket
+// receives datagrams sent to that multicast group instead.
+func (h *Host) listenUDP(network string, ap netip.AddrPort, group *netip.AddrPort) (*UDPConn, error) {
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	if group != nil {
+		var p = newPacketSocket(h, network, net.UDPAddrFromAddrPort(*group), nil)
+		p.key = sockKey{proto: "udp", port: group.Port()}
+		p.group = group
+		h.multicast = append(h.multicast, p)
+		return &UDPConn{p}, nil
+	}
+
+	var key, err = h.bind("udp", ap)
+	if err != nil {
+		return nil, err
+	}
+
+	var ip = key.ip
+	if !ip.IsValid() {
+		ip = ap.Addr()
+	}
+
+	var p = newPacketSocket(h, network, net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip, key.port)), nil)
+	p.key = key
+	h.packets[key] = p
+
+	return &UDPConn{p}, nil
+}
+
+// dialUDP binds a UDP socket to local and connects it to dst.
+func (h *Host) dialUDP(network string, local, dst netip.AddrPort) (*UDPConn, error) {
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	dst = loopbackFor(dst)
+
+	var src, port, err = h.source("udp", local, dst.Addr())
+	if err != nil {
+		return nil, err
+	}
+
+	var key = sockKey{proto: "udp", ip: src, port: port}
+	if local.Addr().IsUnspecified() {
+		key.ip = netip.Addr{}
+	}
+	if h.inUse("udp", key.ip, port) {
+		return nil, sysErr("bind", syscall.EADDRINUSE)
+	}
+
+	var p = newPacketSocket(h, network,
+		net.UDPAddrFromAddrPort(netip.AddrPortFrom(src, port)),
+		net.UDPAddrFromAddrPort(dst))
+	p.key = key
+	h.packets[key] = p
+
+	return &UDPConn{p}, nil
+}
+
+// unsubscribe removes a multicast socket.  It must be called with
+// nw.mu held.
+func (h *Host) unsubscribe(p *packetSocket) {
+	for i, q := range h.multicast {
+		if q == p {
+			h.multicast = append(h.multicast[:i], h.multicast[i+1:]...)
+			return
+		}
+	}
+}
+
+// sendUDP routes a datagram from p to dst.  Like the real network, it
+// silently drops datagrams that cannot be delivered, except that a
+// connected socket learns of a closed port on its next read.
+func (h *Host) sendUDP(p *packetSocket, call string, b []byte, dst netip.AddrPort) error {
+	var nw = h.nw
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+
+	dst = loopbackFor(dst)
+
+	var src, ok = p.key.ip, true
+	if !src.IsValid() {
+		if src, ok = h.sourceFor(dst.Addr()); !ok {
+			return sysErr(call, syscall.ENETUNREACH)
+		}
+	}
+
+	var d = datagram{
+		b:    append([]byte(nil), b...),
+		from: net.UDPAddrFromAddrPort(netip.AddrPortFrom(src, p.key.port)),
+	}
+
+	if dst.Addr().IsMulticast() {
+		for _, peer := range nw.hosts {
+			var d = d
+			if !h.cross(peer, &d) {
+				continue
+			}
+			for _, q := range peer.multicast {
+				if *q.group == dst {
+					q.deliver(d)
+				}
+			}
+		}
+		return nil
+	}
+
+	var peer = nw.route(h, dst.Addr())
+	if peer == nil || !h.cross(peer, &d) {
+		return nil
+	}
+
+	var q = peer.lookupPacket(dst)
+	if q == nil {
+		if p.raddr != nil {
+			p.fail(sysErr("read", syscall.ECONNREFUSED))
+		}
+		return nil
+	}
+	q.deliver(d)
+
+	return nil
+}
+
+// cross sends d across the link from h to peer, setting when it
+// arrives.  It reports false if the link drops it.  It must be called
+// with nw.mu held.
+func (h *Host) cross(peer *Host, d *datagram) bool {
+	var l = h.nw.link(h, peer)
+	if l.drop() {
+		return false
+	}
+	d.at = l.schedule(h, len(d.b))
+
+	return true
+}
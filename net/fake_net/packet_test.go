@@ -0,0 +1,197 @@
+package fake_net
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestUDPConn_Exchange tests datagrams between two hosts.
+func TestUDPConn_Exchange(t *testing.T) {
+	nw := NewNetwork()
+	server := nw.NewHost("server", "10.0.0.1")
+	client := nw.NewHost("client", "10.0.0.2")
+
+	s, err := server.ListenUDP("udp", &net.UDPAddr{Port: 53})
+	testutil.AssertNil(t, err)
+	defer s.Close()
+
+	c, err := client.Dial("udp", "server:domain")
+	testutil.AssertNil(t, err)
+	defer c.Close()
+
+	_, err = c.Write([]byte("query"))
+	testutil.AssertNil(t, err)
+
+	b := make([]byte, 100)
+	n, from, err := s.ReadFromUDP(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "query", string(b[:n]))
+	testutil.AssertEqual(t, c.LocalAddr().String(), from.String())
+
+	_, err = s.WriteToUDP([]byte("answer"), from)
+	testutil.AssertNil(t, err)
+
+	n, err = c.Read(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "answer", string(b[:n]))
+}
+
+// TestUDPConn_Truncate tests reading a datagram into a short buffer.
+func TestUDPConn_Truncate(t *testing.T) {
+	h := New()
+	s, _ := h.ListenPacket("udp", "127.0.0.1:0")
+	defer s.Close()
+	c, _ := h.Dial("udp", s.LocalAddr().String())
+	defer c.Close()
+
+	c.Write([]byte("hello world"))
+	c.Write([]byte("next"))
+
+	b := make([]byte, 5)
+	n, _, err := s.ReadFrom(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "hello", string(b[:n]))
+
+	n, _, err = s.ReadFrom(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "next", string(b[:n]))
+}
+
+// TestUDPConn_Refused tests the error a connected socket gets when
+// nothing listens on the port.
+func TestUDPConn_Refused(t *testing.T) {
+	h := New()
+
+	c, err := h.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1})
+	testutil.AssertNil(t, err)
+	defer c.Close()
+
+	_, err = c.Write([]byte("x"))
+	testutil.AssertNil(t, err)
+
+	_, err = c.Read(make([]byte, 1))
+	assertOpError(t, err, "read udp "+c.LocalAddr().String()+"->127.0.0.1:1: read: connection refused")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNREFUSED))
+}
+
+// TestUDPConn_WriteErrors tests writes that fail synchronously.
+func TestUDPConn_WriteErrors(t *testing.T) {
+	h := New()
+	u, _ := h.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	defer u.Close()
+	c, _ := h.DialUDP("udp", nil, u.LocalAddr().(*net.UDPAddr))
+	defer c.Close()
+
+	_, err := u.Write([]byte("x"))
+	assertOpError(t, err, "write udp "+u.LocalAddr().String()+": write: destination address required")
+
+	_, err = c.WriteTo([]byte("x"), u.LocalAddr())
+	testutil.AssertEqual(t, true, errors.Is(err, net.ErrWriteToConnected))
+
+	_, err = u.WriteToUDP(make([]byte, maxDatagram+1), c.LocalAddr().(*net.UDPAddr))
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EMSGSIZE))
+}
+
+// TestUDPConn_ConnectedFilter tests that a connected socket ignores
+// datagrams from other peers.
+func TestUDPConn_ConnectedFilter(t *testing.T) {
+	h := New()
+	a, _ := h.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	defer a.Close()
+	b, _ := h.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	defer b.Close()
+	c, _ := h.DialUDP("udp", nil, a.LocalAddr().(*net.UDPAddr))
+	defer c.Close()
+
+	b.WriteTo([]byte("stranger"), c.LocalAddr())
+	a.WriteTo([]byte("peer"), c.LocalAddr())
+
+	buf := make([]byte, 10)
+	n, err := c.Read(buf)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "peer", string(buf[:n]))
+}
+
+// TestUDPConn_ReadBuffer tests dropping datagrams when the receive
+// buffer is full.
+func TestUDPConn_ReadBuffer(t *testing.T) {
+	h := New()
+	s, _ := h.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	defer s.Close()
+	testutil.AssertNil(t, s.SetReadBuffer(10))
+
+	c, _ := h.DialUDP("udp", nil, s.LocalAddr().(*net.UDPAddr))
+	defer c.Close()
+	c.Write([]byte("12345678"))
+	c.Write([]byte("12345678"))
+
+	s.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	b := make([]byte, 100)
+	_, err := s.Read(b)
+	testutil.AssertNil(t, err)
+	_, err = s.Read(b)
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrDeadlineExceeded))
+}
+
+// TestUDPConn_Multicast tests delivering to a multicast group.
+func TestUDPConn_Multicast(t *testing.T) {
+	nw := NewNetwork()
+	a := nw.NewHost("a", "10.0.0.1")
+	b := nw.NewHost("b", "10.0.0.2")
+	sender := nw.NewHost("sender", "10.0.0.3")
+
+	group := &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+	ma, err := a.ListenMulticastUDP("udp4", nil, group)
+	testutil.AssertNil(t, err)
+	defer ma.Close()
+	mb, err := b.ListenMulticastUDP("udp4", nil, group)
+	testutil.AssertNil(t, err)
+	defer mb.Close()
+
+	c, _ := sender.ListenUDP("udp4", nil)
+	defer c.Close()
+	_, err = c.WriteToUDPAddrPort([]byte("hello"), netip.MustParseAddrPort("224.0.0.251:5353"))
+	testutil.AssertNil(t, err)
+
+	buf := make([]byte, 10)
+	for _, m := range []interface {
+		ReadFromUDP([]byte) (int, *net.UDPAddr, error)
+	}{ma, mb} {
+		n, from, err := m.ReadFromUDP(buf)
+		testutil.AssertNil(t, err)
+		testutil.AssertEqual(t, "hello", string(buf[:n]))
+		testutil.AssertEqual(t, "10.0.0.3", from.IP.String())
+	}
+
+	_, err = b.ListenMulticastUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1})
+	testutil.AssertNotNil(t, err)
+}
+
+// TestUDPConn_Close tests a closed socket.
+func TestUDPConn_Close(t *testing.T) {
+	h := New()
+	s, _ := h.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9999})
+
+	done := make(chan error)
+	go func() {
+		_, _, err := s.ReadFrom(make([]byte, 1))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	testutil.AssertNil(t, s.Close())
+	testutil.AssertEqual(t, true, errors.Is(<-done, net.ErrClosed))
+	testutil.AssertEqual(t, true, errors.Is(s.Close(), net.ErrClosed))
+
+	// The port is free again.
+	s, err := h.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9999})
+	testutil.AssertNil(t, err)
+	s.Close()
+}
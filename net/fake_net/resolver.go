@@ -0,0 +1,730 @@
+package fake_net
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+)
+
+var (
+	errNoSuchHost          = &notFoundError{"no such host"}
+	errUnknownPort         = &notFoundError{"unknown port"}
+	errServerMisbehaving   = &temporaryError{"server misbehaving"}
+	errUnrecognizedAddress = errors.New("unrecognized address")
+)
+
+// notFoundError is an answer that the name does not exist, as opposed
+// to a failure to get an answer.
+type notFoundError struct{ s string }
+
+func (e *notFoundError) Error() string { return e.s }
+
+// temporaryError is a failure that may not happen if the query is
+// tried again.
+type temporaryError struct{ s string }
+
+func (e *temporaryError) Error() string   { return e.s }
+func (e *temporaryError) Timeout() bool   { return false }
+func (e *temporaryError) Temporary() bool { return true }
+
+// maxCNAMEChain is how many CNAME records a lookup follows before
+// giving up.
+const maxCNAMEChain = 10
+
+// defaultQueryTimeout is how long a query for a name set to Timeout
+// waits before failing, like the default in resolv.conf.
+const defaultQueryTimeout = 5 * time.Second
+
+// Failure is an injected failure to answer queries for a name.
+type Failure int
+
+const (
+	// NoFailure, the zero Failure, answers queries normally.
+	NoFailure Failure = iota
+
+	// NXDomain answers that the name does not exist, even if it has
+	// records or is the name of a host.
+	NXDomain
+
+	// ServFail answers that the server failed, a temporary error.
+	ServFail
+
+	// Timeout never answers: the query fails with a timeout when its
+	// context is done or the query timeout expires.
+	Timeout
+)
+
+// records are the records in the zone for one name.
+type records struct {
+	addrs []netip.Addr
+	cname string
+	mx    []*net.MX
+	ns    []*net.NS
+	srv   []*net.SRV
+	txt   []string
+}
+
+// services is the fallback service table the net package uses when
+// /etc/services is unavailable.
+var services = map[string]map[string]int{
+	"udp": {
+		"domain": 53,
+	},
+	"tcp": {
+		"ftp":         21,
+		"ftps":        990,
+		"gopher":      70,
+		"http":        80,
+		"https":       443,
+		"imap2":       143,
+		"imap3":       220,
+		"imaps":       993,
+		"pop3":        110,
+		"pop3s":       995,
+		"smtp":        25,
+		"submissions": 465,
+		"ssh":         22,
+		"telnet":      23,
+	},
+}
+
+// Resolver answers lookups for the hosts on a Network from a zone
+// table that tests populate with records:
+//
+//	r := nw.Resolver()
+//	r.AddCNAME("www.example.com", "example.com")
+//	r.AddA("example.com", "93.184.216.34")
+//	r.AddMX("example.com", "mail.example.com", 10)
+//	r.Fail("broken.example.com", fake_net.ServFail)
+//
+// A name's records take precedence over a host of the same name.  A
+// host's name resolves to its addresses, and "localhost" to the
+// loopback addresses.  Names are not case sensitive and may be given
+// with or without a trailing dot.
+type Resolver struct {
+	nw *Network
+
+	mu           sync.Mutex
+	zone         map[string]*records
+	ptr          map[netip.Addr][]string
+	failures     map[string]Failure
+	delay        time.Duration
+	queryTimeout time.Duration
+}
+
+var _ neti.Resolver = (*Resolver)(nil)
+
+func newResolver(nw *Network) *Resolver {
+	return &Resolver{
+		nw:           nw,
+		zone:         map[string]*records{},
+		ptr:          map[netip.Addr][]string{},
+		failures:     map[string]Failure{},
+		queryTimeout: defaultQueryTimeout,
+	}
+}
+
+// records returns the records for name, creating them if needed.  It
+// must be called with mu held.
+func (r *Resolver) records(name string) *records {
+	name = canonical(name)
+
+	var recs = r.zone[name]
+	if recs == nil {
+		recs = &records{}
+		r.zone[name] = recs
+	}
+
+	return recs
+}
+
+// fqdn returns name in the fully qualified form answers use.
+func fqdn(name string) string {
+	return canonical(name) + "."
+}
+
+// parseAddrs parses the addresses of an A or AAAA record, panicking
+// on one that is invalid or of the wrong family.
+func parseAddrs(rtype string, addrs []string, is4 bool) []netip.Addr {
+	var out = make([]netip.Addr, len(addrs))
+	for i, s := range addrs {
+		var ip, err = netip.ParseAddr(s)
+		if err != nil || ip.Unmap().Is4() != is4 {
+			panic(fmt.Sprintf("fake_net: invalid %s record address %q", rtype, s))
+		}
+		out[i] = ip.Unmap()
+	}
+
+	return out
+}
+
+// AddA adds IPv4 address records for name.  It panics if an address
+// is not a valid IPv4 address.
+func (r *Resolver) AddA(name string, addrs ...string) {
+	var ips = parseAddrs("A", addrs, true)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recs = r.records(name)
+	recs.addrs = append(slices.Clip(recs.addrs), ips...)
+}
+
+// AddAAAA adds IPv6 address records for name.  It panics if an
+// address is not a valid IPv6 address.
+func (r *Resolver) AddAAAA(name string, addrs ...string) {
+	var ips = parseAddrs("AAAA", addrs, false)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recs = r.records(name)
+	recs.addrs = append(slices.Clip(recs.addrs), ips...)
+}
+
+// AddCNAME makes name an alias of target, replacing any previous
+// alias.  Lookups of name follow a chain of aliases to the records of
+// the name at its end.
+func (r *Resolver) AddCNAME(name, target string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records(name).cname = canonical(target)
+}
+
+// AddMX adds a mail exchanger record for name.  LookupMX returns them
+// in order of preference.
+func (r *Resolver) AddMX(name, host string, pref uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recs = r.records(name)
+	recs.mx = append(slices.Clip(recs.mx), &net.MX{Host: fqdn(host), Pref: pref})
+	slices.SortStableFunc(recs.mx, func(a, b *net.MX) int {
+		return cmp.Compare(a.Pref, b.Pref)
+	})
+}
+
+// AddNS adds a name server record for name.
+func (r *Resolver) AddNS(name, host string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recs = r.records(name)
+	recs.ns = append(slices.Clip(recs.ns), &net.NS{Host: fqdn(host)})
+}
+
+// AddSRV adds a service record for the service and protocol at name,
+// which is stored under "_service._proto.name".  LookupSRV returns
+// them in order of priority.
+func (r *Resolver) AddSRV(service, proto, name, target string, port, priority, weight uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recs = r.records(srvName(service, proto, name))
+	recs.srv = append(slices.Clip(recs.srv), &net.SRV{Target: fqdn(target), Port: port, Priority: priority, Weight: weight})
+	slices.SortStableFunc(recs.srv, func(a, b *net.SRV) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+}
+
+// AddTXT adds a text record for name.
+func (r *Resolver) AddTXT(name string, txt ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var recs = r.records(name)
+	recs.txt = append(slices.Clip(recs.txt), txt...)
+}
+
+// AddPTR adds a pointer record mapping addr back to name, which
+// LookupAddr returns ahead of any host with the address.  It panics if
+// addr is not a valid IP address.
+func (r *Resolver) AddPTR(addr, name string) {
+	var ip, err = netip.ParseAddr(addr)
+	if err != nil {
+		panic(fmt.Sprintf("fake_net: invalid PTR record address %q", addr))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ip = ip.Unmap()
+	r.ptr[ip] = append(slices.Clip(r.ptr[ip]), fqdn(name))
+}
+
+// Remove deletes every record for name, including its PTR records.
+func (r *Resolver) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var fq = fqdn(name)
+	delete(r.zone, canonical(name))
+	for ip, names := range r.ptr {
+		if names = slices.DeleteFunc(slices.Clone(names), func(n string) bool { return n == fq }); len(names) == 0 {
+			delete(r.ptr, ip)
+		} else {
+			r.ptr[ip] = names
+		}
+	}
+}
+
+// Fail makes queries for name fail, or answer normally again if f is
+// NoFailure.  A failure applies wherever the name is met, including
+// part way along a CNAME chain.  The name may also be an IP address,
+// to make LookupAddr of that address fail.
+func (r *Resolver) Fail(name string, f Failure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f == NoFailure {
+		delete(r.failures, canonical(name))
+	} else {
+		r.failures[canonical(name)] = f
+	}
+}
+
+// SetDelay makes every query take at least d to answer, failing
+// early if its context is done first.
+func (r *Resolver) SetDelay(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.delay = d
+}
+
+// SetQueryTimeout sets how long a query for a name set to Timeout
+// waits before failing when its context has no earlier deadline.  The
+// default is five seconds.
+func (r *Resolver) SetQueryTimeout(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queryTimeout = d
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	var t = time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// query looks host up in the zone, following CNAME records.  It
+// returns the name at the end of the chain and its records, which are
+// nil if the zone has none.  Errors are *net.DNSErrors for host.
+func (r *Resolver) query(ctx context.Context, host string) (string, records, error) {
+	r.mu.Lock()
+	var delay, timeout = r.delay, r.queryTimeout
+	r.mu.Unlock()
+
+	if err := sleep(ctx, delay); err != nil {
+		return "", records{}, newDNSError(mapContextErr(err), host)
+	}
+
+	var name, recs, found, f = r.follow(host)
+
+	switch f {
+	case NXDomain:
+		return "", records{}, newDNSError(errNoSuchHost, host)
+	case ServFail:
+		return "", records{}, newDNSError(errServerMisbehaving, host)
+	case Timeout:
+		var err = sleep(ctx, timeout)
+		if err == nil {
+			err = context.DeadlineExceeded
+		}
+		return "", records{}, newDNSError(mapContextErr(err), host)
+	}
+
+	if !found {
+		return name, records{}, nil
+	}
+
+	return name, recs, nil
+}
+
+// follow walks the CNAME chain from host.  A chain that is too long
+// or loops is reported as a server failure.
+func (r *Resolver) follow(host string) (string, records, bool, Failure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var name = canonical(host)
+	for range maxCNAMEChain {
+		if f := r.failures[name]; f != NoFailure {
+			return name, records{}, false, f
+		}
+
+		var recs = r.zone[name]
+		switch {
+		case recs == nil:
+			return name, records{}, false, NoFailure
+		case recs.cname == "":
+			return name, *recs, true, NoFailure
+		}
+		name = recs.cname
+	}
+
+	return name, records{}, false, ServFail
+}
+
+// newDNSError builds the error the net package returns for a failed
+// lookup of name.
+func newDNSError(err error, name string) *net.DNSError {
+	var isTimeout, isTemporary bool
+	if ne, ok := err.(net.Error); ok {
+		isTimeout = ne.Timeout()
+		isTemporary = ne.Temporary()
+	}
+
+	var unwrapErr error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		unwrapErr = err
+	}
+
+	var _, isNotFound = err.(*notFoundError)
+
+	return &net.DNSError{
+		UnwrapErr:   unwrapErr,
+		Err:         err.Error(),
+		Name:        name,
+		IsTimeout:   isTimeout,
+		IsTemporary: isTemporary,
+		IsNotFound:  isNotFound,
+	}
+}
+
+// canonical lower-cases name and strips a trailing dot.
+func canonical(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// lookup returns the addresses of host, which may be an IP literal.
+func (r *Resolver) lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{ip.Unmap()}, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, newDNSError(mapContextErr(err), host)
+	}
+
+	var _, addrs, err = r.lookupName(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, newDNSError(errNoSuchHost, host)
+	}
+
+	return addrs, nil
+}
+
+// lookupName resolves host to its canonical name and addresses, which
+// may be empty if the name exists but has no addresses.
+func (r *Resolver) lookupName(ctx context.Context, host string) (string, []netip.Addr, error) {
+	var name, recs, err = r.query(ctx, host)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(recs.addrs) > 0 {
+		return name, slices.Clone(recs.addrs), nil
+	}
+	if name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return name, []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.IPv6Loopback()}, nil
+	}
+	if addrs := r.nw.hostByName(name); len(addrs) > 0 {
+		return name, addrs, nil
+	}
+	if !recs.exists() {
+		return "", nil, newDNSError(errNoSuchHost, host)
+	}
+
+	return name, nil, nil
+}
+
+// exists reports whether there are any records at all.
+func (recs records) exists() bool {
+	return len(recs.addrs) > 0 || recs.cname != "" || len(recs.mx) > 0 ||
+		len(recs.ns) > 0 || len(recs.srv) > 0 || len(recs.txt) > 0
+}
+
+// filter keeps the addresses matching the family of network, which
+// ends in "4" or "6" to select one.
+func filter(network string, addrs []netip.Addr) []netip.Addr {
+	var out []netip.Addr
+	for _, ip := range addrs {
+		switch {
+		case strings.HasSuffix(network, "4") && !ip.Is4():
+		case strings.HasSuffix(network, "6") && !ip.Is6():
+		default:
+			out = append(out, ip)
+		}
+	}
+
+	return out
+}
+
+func (r *Resolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, newDNSError(mapContextErr(err), addr)
+	}
+
+	var ip, err = netip.ParseAddr(addr)
+	if err != nil {
+		return nil, newDNSError(errUnrecognizedAddress, addr)
+	}
+	ip = ip.Unmap()
+
+	if _, _, err = r.query(ctx, ip.String()); err != nil {
+		err.(*net.DNSError).Name = addr
+		return nil, err
+	}
+
+	r.mu.Lock()
+	var names = slices.Clone(r.ptr[ip])
+	r.mu.Unlock()
+
+	switch {
+	case len(names) > 0:
+		return names, nil
+	case ip.IsLoopback():
+		return []string{"localhost"}, nil
+	}
+	if name, ok := r.nw.hostByAddr(ip); ok {
+		return []string{name + "."}, nil
+	}
+
+	return nil, newDNSError(errNoSuchHost, addr)
+}
+
+// LookupCNAME returns the name at the end of host's chain of CNAME
+// records, or host itself if it has none.
+func (r *Resolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", newDNSError(mapContextErr(err), host)
+	}
+
+	var name, _, err = r.lookupName(ctx, host)
+	if err != nil {
+		return "", err
+	}
+
+	return name + ".", nil
+}
+
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	var addrs, err = r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var out = make([]string, len(addrs))
+	for i, ip := range addrs {
+		out[i] = ip.String()
+	}
+
+	return out, nil
+}
+
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	var addrs, err = r.LookupNetIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var out = make([]net.IP, len(addrs))
+	for i, ip := range addrs {
+		out[i] = net.IP(ip.AsSlice())
+	}
+
+	return out, nil
+}
+
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var addrs, err = r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var out = make([]net.IPAddr, len(addrs))
+	for i, ip := range addrs {
+		out[i] = net.IPAddr{IP: net.IP(ip.AsSlice()), Zone: ip.Zone()}
+	}
+
+	return out, nil
+}
+
+func (r *Resolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	switch network {
+	case "ip", "ip4", "ip6":
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	var addrs, err = r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if addrs = filter(network, addrs); len(addrs) == 0 {
+		return nil, newDNSError(errNoSuchHost, host)
+	}
+
+	return addrs, nil
+}
+
+// lookupRecords queries name and returns its records, failing as not
+// found if it has none of the kind that pick selects.
+func lookupRecords[T any](ctx context.Context, r *Resolver, name string, pick func(records) []T) (string, []T, error) {
+	if err := ctx.Err(); err != nil {
+		return "", nil, newDNSError(mapContextErr(err), name)
+	}
+
+	var cname, recs, err = r.query(ctx, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var out = pick(recs)
+	if len(out) == 0 {
+		return "", nil, newDNSError(errNoSuchHost, name)
+	}
+
+	return cname + ".", out, nil
+}
+
+// LookupMX returns the mail exchanger records of name in order of
+// preference.
+func (r *Resolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	var _, mx, err = lookupRecords(ctx, r, name, func(recs records) []*net.MX {
+		var out = make([]*net.MX, len(recs.mx))
+		for i, mx := range recs.mx {
+			out[i] = &net.MX{Host: mx.Host, Pref: mx.Pref}
+		}
+		return out
+	})
+
+	return mx, err
+}
+
+// LookupNS returns the name server records of name.
+func (r *Resolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	var _, ns, err = lookupRecords(ctx, r, name, func(recs records) []*net.NS {
+		var out = make([]*net.NS, len(recs.ns))
+		for i, ns := range recs.ns {
+			out[i] = &net.NS{Host: ns.Host}
+		}
+		return out
+	})
+
+	return ns, err
+}
+
+// LookupPort answers from the net package's fallback service table.
+func (r *Resolver) LookupPort(ctx context.Context, network, service string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, newDNSError(mapContextErr(err), network+"/"+service)
+	}
+
+	return lookupPort(network, service)
+}
+
+// lookupPort parses a port number or looks up a service name.
+func lookupPort(network, service string) (int, error) {
+	var proto string
+	switch network {
+	case "", "ip":
+		proto = ""
+	case "tcp", "tcp4", "tcp6":
+		proto = "tcp"
+	case "udp", "udp4", "udp6":
+		proto = "udp"
+	default:
+		return 0, net.UnknownNetworkError(network)
+	}
+
+	if service == "" {
+		return 0, nil
+	}
+	if strings.Trim(service, "0123456789") == "" {
+		var n, err = strconv.Atoi(service)
+		if err != nil || n > 0xFFFF {
+			return 0, &net.AddrError{Err: "invalid port", Addr: service}
+		}
+		return n, nil
+	}
+
+	var svc = strings.ToLower(service)
+	for _, p := range []string{"tcp", "udp"} {
+		if proto != "" && proto != p {
+			continue
+		}
+		if port, ok := services[p][svc]; ok {
+			return port, nil
+		}
+	}
+
+	return 0, newDNSError(errUnknownPort, network+"/"+service)
+}
+
+// srvName returns the name SRV records for a service are stored under.
+// If service and proto are empty, name is used directly.
+func srvName(service, proto, name string) string {
+	if service == "" && proto == "" {
+		return name
+	}
+
+	return "_" + service + "._" + proto + "." + name
+}
+
+// LookupSRV returns the service records for the service and protocol
+// at name in order of priority, along with the canonical name they
+// were found under.
+func (r *Resolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return lookupRecords(ctx, r, srvName(service, proto, name), func(recs records) []*net.SRV {
+		var out = make([]*net.SRV, len(recs.srv))
+		for i, srv := range recs.srv {
+			var cp = *srv
+			out[i] = &cp
+		}
+		return out
+	})
+}
+
+// LookupTXT returns the text records of name.
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	var _, txt, err = lookupRecords(ctx, r, name, func(recs records) []string {
+		return slices.Clone(recs.txt)
+	})
+
+	return txt, err
+}
+
+// GetUnderlyingResolver returns nil: there is no *net.Resolver behind
+// the fake.
+func (r *Resolver) GetUnderlyingResolver() *net.Resolver {
+	return nil
+}
@@ -0,0 +1,251 @@
+package fake_net
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestResolver_Hosts tests looking up host names.
+func TestResolver_Hosts(t *testing.T) {
+	nw := NewNetwork()
+	nw.NewHost("db", "10.0.0.5", "fd00::5")
+	r := nw.Resolver()
+	ctx := context.Background()
+
+	addrs, err := r.LookupHost(ctx, "DB.")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "10.0.0.5 fd00::5", strings.Join(addrs, " "))
+
+	ips, err := r.LookupIP(ctx, "ip6", "db")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "fd00::5", ips[0].String())
+
+	names, err := r.LookupAddr(ctx, "10.0.0.5")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "db.", strings.Join(names, " "))
+
+	cname, err := r.LookupCNAME(ctx, "db")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "db.", cname)
+
+	addrs, err = r.LookupHost(ctx, "localhost")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "127.0.0.1 ::1", strings.Join(addrs, " "))
+}
+
+// TestResolver_NotFound tests the error for unknown names.
+func TestResolver_NotFound(t *testing.T) {
+	r := NewNetwork().Resolver()
+
+	_, err := r.LookupHost(context.Background(), "nosuch")
+
+	var de *net.DNSError
+	testutil.AssertEqual(t, true, errors.As(err, &de))
+	testutil.AssertEqual(t, true, de.IsNotFound)
+	testutil.AssertEqual(t, "lookup nosuch: no such host", err.Error())
+
+	_, err = r.LookupMX(context.Background(), "example.com")
+	testutil.AssertEqual(t, true, errors.As(err, &de) && de.IsNotFound)
+}
+
+// TestResolver_Canceled tests lookups with a canceled context.
+func TestResolver_Canceled(t *testing.T) {
+	r := NewNetwork().Resolver()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := r.LookupHost(ctx, "localhost")
+	testutil.AssertEqual(t, true, errors.Is(err, context.Canceled))
+	testutil.AssertEqual(t, "lookup localhost: operation was canceled", err.Error())
+}
+
+// TestResolver_LookupPort tests service name lookup.
+func TestResolver_LookupPort(t *testing.T) {
+	h := New()
+
+	port, err := h.LookupPort("tcp", "https")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 443, port)
+
+	port, err = h.LookupPort("udp", "domain")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 53, port)
+
+	port, err = h.LookupPort("tcp", "8080")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 8080, port)
+
+	_, err = h.LookupPort("tcp", "99999")
+	testutil.AssertEqual(t, "address 99999: invalid port", err.Error())
+
+	_, err = h.LookupPort("tcp", "nosuchsvc")
+	testutil.AssertEqual(t, "lookup tcp/nosuchsvc: unknown port", err.Error())
+}
+
+// TestResolver_Zone tests address records and CNAME chains.
+func TestResolver_Zone(t *testing.T) {
+	r := NewNetwork().Resolver()
+	r.AddCNAME("www.example.com", "web.example.com.")
+	r.AddCNAME("web.example.com", "Example.com")
+	r.AddA("example.com", "93.184.216.34")
+	r.AddAAAA("example.com", "2606:2800:220:1::")
+	ctx := context.Background()
+
+	addrs, err := r.LookupHost(ctx, "www.example.com")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "93.184.216.34 2606:2800:220:1::", strings.Join(addrs, " "))
+
+	cname, err := r.LookupCNAME(ctx, "WWW.example.com.")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "example.com.", cname)
+
+	ips, err := r.LookupNetIP(ctx, "ip6", "www.example.com")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "2606:2800:220:1::", ips[0].String())
+
+	testutil.AssertPanic(t, func() { r.AddA("bad", "::1") }, "IPv6 address in an A record")
+}
+
+// TestResolver_ZoneHost tests that zone records can point at hosts.
+func TestResolver_ZoneHost(t *testing.T) {
+	nw := NewNetwork()
+	server := nw.NewHost("server", "10.0.0.1")
+	client := nw.NewHost("client", "10.0.0.2")
+	nw.Resolver().AddCNAME("api.example.com", "server")
+
+	l, _ := server.Listen("tcp", ":443")
+	defer l.Close()
+
+	c, err := client.Dial("tcp", "api.example.com:https")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "10.0.0.1:443", c.RemoteAddr().String())
+	c.Close()
+}
+
+// TestResolver_Records tests the other record types.
+func TestResolver_Records(t *testing.T) {
+	r := NewNetwork().Resolver()
+	r.AddMX("example.com", "backup.example.com", 20)
+	r.AddMX("example.com", "mail.example.com", 10)
+	r.AddNS("example.com", "ns1.example.com")
+	r.AddTXT("example.com", "v=spf1 -all", "hello")
+	r.AddSRV("ldap", "tcp", "example.com", "dc1.example.com", 389, 0, 100)
+	r.AddCNAME("_ldap._tcp.corp.example.com", "_ldap._tcp.example.com")
+	r.AddPTR("93.184.216.34", "example.com")
+	ctx := context.Background()
+
+	mx, err := r.LookupMX(ctx, "example.com")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 2, len(mx))
+	testutil.AssertEqual(t, "mail.example.com.", mx[0].Host)
+	testutil.AssertEqual(t, uint16(20), mx[1].Pref)
+
+	ns, err := r.LookupNS(ctx, "example.com")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "ns1.example.com.", ns[0].Host)
+
+	txt, err := r.LookupTXT(ctx, "example.com")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "v=spf1 -all|hello", strings.Join(txt, "|"))
+
+	cname, srv, err := r.LookupSRV(ctx, "ldap", "tcp", "corp.example.com")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "_ldap._tcp.example.com.", cname)
+	testutil.AssertEqual(t, "dc1.example.com.", srv[0].Target)
+	testutil.AssertEqual(t, uint16(389), srv[0].Port)
+
+	names, err := r.LookupAddr(ctx, "93.184.216.34")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "example.com.", strings.Join(names, " "))
+
+	// The name exists but has no addresses.
+	_, err = r.LookupHost(ctx, "example.com")
+	var de *net.DNSError
+	testutil.AssertEqual(t, true, errors.As(err, &de) && de.IsNotFound)
+
+	r.Remove("example.com")
+	_, err = r.LookupMX(ctx, "example.com")
+	testutil.AssertEqual(t, true, errors.As(err, &de) && de.IsNotFound)
+	_, err = r.LookupAddr(ctx, "93.184.216.34")
+	testutil.AssertEqual(t, true, errors.As(err, &de) && de.IsNotFound)
+}
+
+// TestResolver_Failures tests injected NXDOMAIN and SERVFAIL answers.
+func TestResolver_Failures(t *testing.T) {
+	nw := NewNetwork()
+	nw.NewHost("db", "10.0.0.5")
+	r := nw.Resolver()
+	ctx := context.Background()
+
+	r.Fail("db", NXDomain)
+	_, err := r.LookupHost(ctx, "db")
+	var de *net.DNSError
+	testutil.AssertEqual(t, true, errors.As(err, &de) && de.IsNotFound)
+
+	r.Fail("db", ServFail)
+	_, err = r.LookupIP(ctx, "ip", "db")
+	testutil.AssertEqual(t, "lookup db: server misbehaving", err.Error())
+	testutil.AssertEqual(t, true, errors.As(err, &de))
+	testutil.AssertEqual(t, false, de.IsNotFound)
+	testutil.AssertEqual(t, true, de.IsTemporary)
+
+	r.AddCNAME("alias", "db")
+	_, err = r.LookupHost(ctx, "alias")
+	testutil.AssertEqual(t, "lookup alias: server misbehaving", err.Error())
+
+	r.Fail("db", NoFailure)
+	_, err = r.LookupHost(ctx, "alias")
+	testutil.AssertNil(t, err)
+
+	r.Fail("10.0.0.5", ServFail)
+	_, err = r.LookupAddr(ctx, "10.0.0.5")
+	testutil.AssertEqual(t, "lookup 10.0.0.5: server misbehaving", err.Error())
+
+	r.AddCNAME("loop1", "loop2")
+	r.AddCNAME("loop2", "loop1")
+	_, err = r.LookupHost(ctx, "loop1")
+	testutil.AssertEqual(t, "lookup loop1: server misbehaving", err.Error())
+}
+
+// TestResolver_Timeout tests queries that never get an answer.
+func TestResolver_Timeout(t *testing.T) {
+	r := NewNetwork().Resolver()
+	r.AddA("slow.example.com", "10.0.0.9")
+	r.Fail("slow.example.com", Timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := r.LookupHost(ctx, "slow.example.com")
+	var de *net.DNSError
+	testutil.AssertEqual(t, true, errors.As(err, &de))
+	testutil.AssertEqual(t, true, de.IsTimeout)
+	testutil.AssertEqual(t, "lookup slow.example.com: i/o timeout", err.Error())
+	testutil.AssertEqual(t, true, errors.Is(err, context.DeadlineExceeded))
+
+	r.SetQueryTimeout(10 * time.Millisecond)
+	_, err = r.LookupTXT(context.Background(), "slow.example.com")
+	testutil.AssertEqual(t, true, errors.As(err, &de) && de.IsTimeout)
+}
+
+// TestResolver_Delay tests canceling a slow query.
+func TestResolver_Delay(t *testing.T) {
+	r := NewNetwork().Resolver()
+	r.SetDelay(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := r.LookupHost(ctx, "localhost")
+	testutil.AssertEqual(t, true, errors.Is(err, context.Canceled))
+	testutil.AssertEqual(t, "lookup localhost: operation was canceled", err.Error())
+}
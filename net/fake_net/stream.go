@@ -0,0 +1,287 @@
+package fake_net
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultWriteBuffer is the number of bytes a stream lets be in
+// flight across its link before writes block.
+const defaultWriteBuffer = 212992
+
+// segment is a piece of a stream on its way to the reader.  It
+// becomes readable at time at.  A segment with a non-nil end marks
+// the end of the stream: io.EOF or ECONNRESET.
+type segment struct {
+	b   []byte
+	at  time.Time
+	end error
+}
+
+// stream carries the bytes flowing in one direction of a connection.
+// Data that has arrived is buffered without limit, like a socket with
+// a very large receive buffer, so writes only block while the link
+// the stream crosses holds too much in flight.
+type stream struct {
+	mu     sync.Mutex
+	segs   []segment
+	signal chan struct{}
+	wbuf   int
+
+	// link is the link the stream crosses and from the host writing
+	// to it; flow is the connection it belongs to.  They are nil for
+	// streams that do not cross a link, such as Unix sockets.
+	link *Link
+	from *Host
+	flow *flow
+
+	// shut is set when the writer shuts down its side (Close or
+	// CloseWrite); the reader drains the data then sees io.EOF.
+	shut bool
+
+	// broken is set when the connection is reset; further writes fail
+	// with ECONNRESET.
+	broken bool
+
+	// gone is set when the reader will never read again; further
+	// writes fail with EPIPE.
+	gone bool
+}
+
+func newStream() *stream {
+	return &stream{signal: make(chan struct{}), wbuf: defaultWriteBuffer}
+}
+
+// notify wakes every goroutine waiting on the stream.  It must be
+// called with mu held.
+func (s *stream) notify() {
+	close(s.signal)
+	s.signal = make(chan struct{})
+}
+
+// push appends a segment of n bytes, or an end marker, arriving after
+// everything already sent.  It must be called with mu held.
+func (s *stream) push(seg segment) {
+	seg.at = s.link.schedule(s.from, len(seg.b))
+	if n := len(s.segs); n > 0 && s.segs[n-1].at.After(seg.at) {
+		seg.at = s.segs[n-1].at
+	}
+
+	s.segs = append(s.segs, seg)
+	s.notify()
+}
+
+// arrived reports whether seg can be read at time now.
+func arrived(seg segment, now time.Time, partitioned bool) bool {
+	return !partitioned && !seg.at.After(now)
+}
+
+// next returns a channel that fires when the first segment still in
+// flight arrives, or nil if there is no such segment.  It must be
+// called with mu held.
+func (s *stream) next(now time.Time, partitioned bool) (<-chan time.Time, func() bool) {
+	if partitioned {
+		return nil, func() bool { return false }
+	}
+
+	for _, seg := range s.segs {
+		if seg.at.After(now) {
+			var t = time.NewTimer(seg.at.Sub(now))
+			return t.C, t.Stop
+		}
+	}
+
+	return nil, func() bool { return false }
+}
+
+// read blocks until data is available, the stream ends, or one of
+// the channels is closed.  closed is the local connection's close
+// channel and expire its read deadline.
+func (s *stream) read(b []byte, closed, expire <-chan struct{}) (int, error) {
+	for {
+		var partitioned, changed = s.link.state()
+
+		s.mu.Lock()
+		var now = time.Now()
+		switch {
+		case isClosed(closed):
+			s.mu.Unlock()
+			return 0, net.ErrClosed
+		case isClosed(expire):
+			s.mu.Unlock()
+			return 0, os.ErrDeadlineExceeded
+		case len(s.segs) > 0 && arrived(s.segs[0], now, partitioned):
+			var n, err = s.take(b, now, partitioned)
+			s.mu.Unlock()
+			return n, err
+		case s.gone:
+			s.mu.Unlock()
+			return 0, io.EOF
+		case len(b) == 0:
+			s.mu.Unlock()
+			return 0, nil
+		}
+		var signal = s.signal
+		var timer, stop = s.next(now, partitioned)
+		s.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-changed:
+		case <-timer:
+		case <-closed:
+		case <-expire:
+		}
+		stop()
+	}
+}
+
+// take reads as much arrived data into b as will fit.  An end marker
+// is only returned once there is no data before it, and stays in
+// place so that it is returned again.  It must be called with mu held.
+func (s *stream) take(b []byte, now time.Time, partitioned bool) (int, error) {
+	if err := s.segs[0].end; err != nil {
+		return 0, err
+	}
+
+	var n int
+	for n < len(b) && len(s.segs) > 0 && s.segs[0].end == nil && arrived(s.segs[0], now, partitioned) {
+		var m = copy(b[n:], s.segs[0].b)
+		n += m
+		if m == len(s.segs[0].b) {
+			s.segs = s.segs[1:]
+		} else {
+			s.segs[0].b = s.segs[0].b[m:]
+		}
+	}
+
+	return n, nil
+}
+
+// inFlight returns how many bytes written to the stream have not yet
+// arrived.  It must be called with mu held.
+func (s *stream) inFlight(now time.Time, partitioned bool) int {
+	var n int
+	for i := len(s.segs) - 1; i >= 0 && !arrived(s.segs[i], now, partitioned); i-- {
+		n += len(s.segs[i].b)
+	}
+
+	return n
+}
+
+// write sends b, blocking while the write buffer is full of data in
+// flight.  closed is the local connection's close channel and expire
+// its write deadline.
+func (s *stream) write(b []byte, closed, expire <-chan struct{}) (int, error) {
+	var n int
+
+	for {
+		var partitioned, changed = s.link.state()
+
+		s.mu.Lock()
+		switch {
+		case isClosed(closed):
+			s.mu.Unlock()
+			return n, net.ErrClosed
+		case isClosed(expire):
+			s.mu.Unlock()
+			return n, os.ErrDeadlineExceeded
+		case s.broken:
+			s.mu.Unlock()
+			return n, syscall.ECONNRESET
+		case s.shut || s.gone:
+			s.mu.Unlock()
+			return n, syscall.EPIPE
+		case n == len(b):
+			s.mu.Unlock()
+			return n, nil
+		}
+
+		var now = time.Now()
+		if room := s.wbuf - s.inFlight(now, partitioned); room > 0 {
+			var m, reset = s.flow.take(min(len(b)-n, room, s.link.chunk(len(b)-n)))
+			if m > 0 {
+				s.push(segment{b: append([]byte(nil), b[n:n+m]...)})
+				n += m
+			}
+			s.mu.Unlock()
+
+			if reset {
+				s.flow.reset()
+				return n, syscall.ECONNRESET
+			}
+			continue
+		}
+		var signal = s.signal
+		var timer, stop = s.next(now, partitioned)
+		s.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-changed:
+		case <-timer:
+		case <-closed:
+		case <-expire:
+		}
+		stop()
+	}
+}
+
+// setWriteBuffer sets how many bytes may be in flight.
+func (s *stream) setWriteBuffer(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.wbuf = max(n, 1)
+	s.notify()
+}
+
+// shutdown marks the end of the data written to the stream.
+func (s *stream) shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.shut && !s.broken {
+		s.shut = true
+		s.push(segment{end: io.EOF})
+	}
+}
+
+// abandon records that the reader has gone away.
+func (s *stream) abandon() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gone = true
+	s.segs = nil
+	s.notify()
+}
+
+// reset resets the connection as seen by the stream's reader, who
+// reads the data already sent followed by ECONNRESET, and fails
+// further writes.
+func (s *stream) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.broken {
+		s.broken = true
+		s.push(segment{end: syscall.ECONNRESET})
+	}
+}
+
+// abort fails further writes with ECONNRESET and discards anything
+// unread, for the direction leading to an end that has reset the
+// connection.
+func (s *stream) abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.broken = true
+	s.segs = nil
+	s.notify()
+}
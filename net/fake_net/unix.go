@@ -0,0 +1,294 @@
+package fake_net
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	neti "github.com/pdutton/go-interfaces/net"
+)
+
+// socket is the set of methods shared by stream and packet sockets.
+type socket interface {
+	Read(b []byte) (int, error)
+	Write(b []byte) (int, error)
+	Close() error
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetReadBuffer(bytes int) error
+	SetWriteBuffer(bytes int) error
+	File() (*os.File, error)
+	SyscallConn() (syscall.RawConn, error)
+}
+
+// UnixConn is a simulated Unix domain socket.  Sockets of type "unix"
+// and "unixpacket" are connected streams; "unixpacket" does not
+// preserve message boundaries.  Sockets of type "unixgram" carry
+// datagrams.
+type UnixConn struct {
+	socket
+
+	stream *streamConn
+	packet *packetSocket
+}
+
+var _ neti.UnixConn = (*UnixConn)(nil)
+
+func newUnixStream(c *streamConn) *UnixConn {
+	return &UnixConn{socket: c, stream: c}
+}
+
+func newUnixgram(p *packetSocket) *UnixConn {
+	return &UnixConn{socket: p, packet: p}
+}
+
+func (c *UnixConn) CloseRead() error {
+	if c.stream != nil {
+		return c.stream.CloseRead()
+	}
+	if isClosed(c.packet.closed) {
+		return c.packet.opError("close", c.packet.raddr, net.ErrClosed)
+	}
+
+	return nil
+}
+
+func (c *UnixConn) CloseWrite() error {
+	if c.stream != nil {
+		return c.stream.CloseWrite()
+	}
+	if isClosed(c.packet.closed) {
+		return c.packet.opError("close", c.packet.raddr, net.ErrClosed)
+	}
+
+	return nil
+}
+
+func (c *UnixConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	var n, addr, err = c.ReadFromUnix(b)
+	if addr == nil {
+		return n, nil, err
+	}
+
+	return n, addr, err
+}
+
+// ReadFromUnix reads from the socket.  The address is nil for a
+// stream socket and for datagrams sent from an unnamed socket.
+func (c *UnixConn) ReadFromUnix(b []byte) (int, *net.UnixAddr, error) {
+	if c.stream != nil {
+		var n, err = c.stream.Read(b)
+		return n, nil, err
+	}
+
+	var n, from, err = c.packet.readFrom(b)
+	if err != nil {
+		return n, nil, c.packet.opError("read", c.packet.raddr, err)
+	}
+	if from == nil {
+		return n, nil, nil
+	}
+
+	return n, from.(*net.UnixAddr), nil
+}
+
+// ReadMsgUnix reads from the socket.  No out-of-band data is ever
+// received.
+func (c *UnixConn) ReadMsgUnix(b, oob []byte) (int, int, int, *net.UnixAddr, error) {
+	var n, addr, err = c.ReadFromUnix(b)
+	return n, 0, 0, addr, err
+}
+
+func (c *UnixConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	var to, ok = addr.(*net.UnixAddr)
+	if !ok {
+		return 0, opErr("write", c.network(), c.LocalAddr(), addr, sysErr("sendto", syscall.EINVAL))
+	}
+
+	return c.WriteToUnix(b, to)
+}
+
+func (c *UnixConn) WriteToUnix(b []byte, addr *net.UnixAddr) (int, error) {
+	switch {
+	case c.stream != nil:
+		return 0, c.stream.opError("write", net.ErrWriteToConnected)
+	case c.packet.raddr != nil:
+		return 0, c.packet.opError("write", opAddr(addr), net.ErrWriteToConnected)
+	case addr == nil:
+		return 0, c.packet.opError("write", nil, errMissingAddress)
+	}
+
+	if err := c.packet.writeTo(b, addr); err != nil {
+		return 0, c.packet.opError("write", addr, err)
+	}
+
+	return len(b), nil
+}
+
+// WriteMsgUnix writes to the socket.  Out-of-band data is accepted and
+// discarded.
+func (c *UnixConn) WriteMsgUnix(b, oob []byte, addr *net.UnixAddr) (int, int, error) {
+	var n int
+	var err error
+	if addr == nil {
+		n, err = c.Write(b)
+	} else {
+		n, err = c.WriteToUnix(b, addr)
+	}
+	if err != nil {
+		return n, 0, err
+	}
+
+	return n, len(oob), nil
+}
+
+func (c *UnixConn) network() string {
+	if c.stream != nil {
+		return c.stream.network
+	}
+
+	return c.packet.network
+}
+
+// UnixListener is a simulated Unix domain socket listener.
+type UnixListener struct {
+	*listener
+}
+
+var _ neti.UnixListener = (*UnixListener)(nil)
+
+func (l *UnixListener) Accept() (net.Conn, error) {
+	var c, err = l.accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newUnixStream(c), nil
+}
+
+func (l *UnixListener) AcceptUnix() (neti.UnixConn, error) {
+	var c, err = l.accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newUnixStream(c), nil
+}
+
+// SetUnlinkOnClose sets whether closing the listener frees its name.
+// If not, later attempts to bind the name fail as if the socket file
+// were still there, and connecting to it is refused.
+func (l *UnixListener) SetUnlinkOnClose(unlink bool) {
+	l.host.nw.mu.Lock()
+	defer l.host.nw.mu.Unlock()
+
+	l.unlink = unlink
+}
+
+// listenUnix binds a Unix stream listener to laddr.
+func (h *Host) listenUnix(network string, laddr *net.UnixAddr) (*UnixListener, error) {
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	if h.unix[laddr.Name] != nil {
+		return nil, sysErr("bind", syscall.EADDRINUSE)
+	}
+
+	var l = newListener(h, network, &net.UnixAddr{Name: laddr.Name, Net: network})
+	l.path = laddr.Name
+	l.unlink = true
+	h.unix[laddr.Name] = &unixEntry{stream: l}
+
+	return &UnixListener{l}, nil
+}
+
+// listenUnixgram binds a Unix datagram socket to laddr.
+func (h *Host) listenUnixgram(laddr *net.UnixAddr) (*UnixConn, error) {
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	if h.unix[laddr.Name] != nil {
+		return nil, sysErr("bind", syscall.EADDRINUSE)
+	}
+
+	var p = newPacketSocket(h, "unixgram", &net.UnixAddr{Name: laddr.Name, Net: "unixgram"}, nil)
+	p.path = laddr.Name
+	h.unix[laddr.Name] = &unixEntry{packet: p}
+
+	return newUnixgram(p), nil
+}
+
+// dialUnix connects a Unix socket to the one named by raddr, first
+// binding it to laddr if that is set.
+func (h *Host) dialUnix(network string, laddr, raddr *net.UnixAddr) (*UnixConn, error) {
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	var entry = h.unix[raddr.Name]
+	switch {
+	case entry == nil:
+		return nil, sysErr("connect", syscall.ENOENT)
+	case entry.stream == nil && entry.packet == nil:
+		return nil, sysErr("connect", syscall.ECONNREFUSED)
+	case (network == "unixgram") != (entry.packet != nil):
+		return nil, sysErr("connect", syscall.EPROTOTYPE)
+	case entry.stream != nil && entry.stream.network != network:
+		return nil, sysErr("connect", syscall.EPROTOTYPE)
+	}
+
+	var local = &net.UnixAddr{Net: network}
+	if laddr != nil && laddr.Name != "" {
+		if h.unix[laddr.Name] != nil {
+			return nil, sysErr("bind", syscall.EADDRINUSE)
+		}
+		local.Name = laddr.Name
+	}
+	var remote = &net.UnixAddr{Name: raddr.Name, Net: network}
+
+	if network == "unixgram" {
+		var p = newPacketSocket(h, network, local, remote)
+		if p.path = local.Name; p.path != "" {
+			h.unix[p.path] = &unixEntry{packet: p}
+		}
+		return newUnixgram(p), nil
+	}
+
+	if local.Name != "" {
+		// A bound client's socket file outlives it; nothing listens on
+		// it.
+		h.unix[local.Name] = &unixEntry{}
+	}
+
+	var client, server = newStreamPair(network, local, remote)
+	entry.stream.enqueue(server)
+
+	return newUnixStream(client), nil
+}
+
+// sendUnix delivers a datagram from p to the socket named name.
+func (h *Host) sendUnix(p *packetSocket, call string, b []byte, name string) error {
+	h.nw.mu.Lock()
+	defer h.nw.mu.Unlock()
+
+	var entry = h.unix[name]
+	switch {
+	case entry == nil:
+		return sysErr(call, syscall.ENOENT)
+	case entry.packet == nil && entry.stream == nil:
+		return sysErr(call, syscall.ECONNREFUSED)
+	case entry.packet == nil:
+		return sysErr(call, syscall.EPROTOTYPE)
+	}
+
+	var d = datagram{b: append([]byte(nil), b...)}
+	if p.path != "" {
+		d.from = p.laddr
+	}
+	entry.packet.deliver(d)
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package fake_net
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestUnixConn_Stream tests a Unix stream connection.
+func TestUnixConn_Stream(t *testing.T) {
+	h := New()
+
+	l, err := h.Listen("unix", "/run/app.sock")
+	testutil.AssertNil(t, err)
+	defer l.Close()
+
+	c, err := h.Dial("unix", "/run/app.sock")
+	testutil.AssertNil(t, err)
+	defer c.Close()
+
+	s, err := l.Accept()
+	testutil.AssertNil(t, err)
+	defer s.Close()
+	testutil.AssertEqual(t, "/run/app.sock", s.LocalAddr().String())
+
+	c.Write([]byte("hi"))
+	c.(*UnixConn).CloseWrite()
+	data, err := io.ReadAll(s)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "hi", string(data))
+
+	_, err = c.(*UnixConn).WriteToUnix([]byte("x"), &net.UnixAddr{Name: "/x", Net: "unix"})
+	testutil.AssertEqual(t, true, errors.Is(err, net.ErrWriteToConnected))
+}
+
+// TestUnixConn_DialErrors tests dialing missing and stale sockets.
+func TestUnixConn_DialErrors(t *testing.T) {
+	h := New()
+
+	_, err := h.Dial("unix", "/nonexistent/sock")
+	assertOpError(t, err, "dial unix /nonexistent/sock: connect: no such file or directory")
+
+	l, err := h.ListenUnix("unix", &net.UnixAddr{Name: "/tmp/s", Net: "unix"})
+	testutil.AssertNil(t, err)
+	l.SetUnlinkOnClose(false)
+	l.Close()
+
+	_, err = h.Dial("unix", "/tmp/s")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ECONNREFUSED))
+
+	_, err = h.Listen("unix", "/tmp/s")
+	assertOpError(t, err, "listen unix /tmp/s: bind: address already in use")
+
+	_, err = h.ListenUnix("unix", nil)
+	assertOpError(t, err, "listen unix: missing address")
+
+	g, _ := h.ListenPacket("unixgram", "/tmp/g")
+	defer g.Close()
+	_, err = h.Dial("unix", "/tmp/g")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EPROTOTYPE))
+}
+
+// TestUnixConn_Unlink tests that closing a listener frees its name.
+func TestUnixConn_Unlink(t *testing.T) {
+	h := New()
+
+	l, _ := h.Listen("unix", "/tmp/s")
+	l.Close()
+
+	l, err := h.Listen("unix", "/tmp/s")
+	testutil.AssertNil(t, err)
+	l.Close()
+}
+
+// TestUnixConn_Datagram tests Unix datagram sockets.
+func TestUnixConn_Datagram(t *testing.T) {
+	h := New()
+
+	s, err := h.ListenUnixgram("unixgram", &net.UnixAddr{Name: "/tmp/server", Net: "unixgram"})
+	testutil.AssertNil(t, err)
+	defer s.Close()
+
+	c, err := h.ListenUnixgram("unixgram", &net.UnixAddr{Name: "/tmp/client", Net: "unixgram"})
+	testutil.AssertNil(t, err)
+	defer c.Close()
+
+	_, err = c.WriteToUnix([]byte("ping"), &net.UnixAddr{Name: "/tmp/server", Net: "unixgram"})
+	testutil.AssertNil(t, err)
+
+	b := make([]byte, 10)
+	n, from, err := s.ReadFromUnix(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "ping", string(b[:n]))
+	testutil.AssertEqual(t, "/tmp/client", from.Name)
+
+	_, _, err = s.WriteMsgUnix([]byte("pong"), nil, from)
+	testutil.AssertNil(t, err)
+	n, _, _, _, err = c.ReadMsgUnix(b, nil)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "pong", string(b[:n]))
+
+	_, err = c.WriteToUnix([]byte("x"), &net.UnixAddr{Name: "/tmp/nobody", Net: "unixgram"})
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.ENOENT))
+}
+
+// TestUnixConn_DialUnixgram tests a connected Unix datagram socket.
+func TestUnixConn_DialUnixgram(t *testing.T) {
+	h := New()
+
+	s, _ := h.ListenUnixgram("unixgram", &net.UnixAddr{Name: "/tmp/log", Net: "unixgram"})
+	defer s.Close()
+
+	c, err := h.DialUnix("unixgram", nil, &net.UnixAddr{Name: "/tmp/log", Net: "unixgram"})
+	testutil.AssertNil(t, err)
+	defer c.Close()
+
+	_, err = c.Write([]byte("entry"))
+	testutil.AssertNil(t, err)
+
+	b := make([]byte, 10)
+	n, from, err := s.ReadFrom(b)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "entry", string(b[:n]))
+	testutil.AssertNil(t, from)
+}
@@ -0,0 +1,493 @@
+package fake_client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+)
+
+// redacted replaces the values of redacted headers.
+const redacted = "REDACTED"
+
+// ErrNoRecording is wrapped by the error a replaying Cassette returns
+// for a request it has no recorded response for.
+var ErrNoRecording = errors.New("no recorded response")
+
+// Mode is what a Cassette does with requests.
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette file without
+	// sending them anywhere.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends requests upstream and records each exchange,
+	// to be written to the cassette file by Save.
+	ModeRecord
+
+	// ModePassthrough sends requests upstream without recording them.
+	ModePassthrough
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModePassthrough:
+		return "passthrough"
+	}
+	return "Mode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// Cassette is an http.RoundTripper that records the exchanges a client
+// makes to a file, and later replays them without any network:
+//
+//	cas, err := fake_client.NewCassette("testdata/users.json", fake_client.ModeReplay)
+//	client := cas.Client()
+//	// ...
+//	err = cas.Save()
+//
+// Run the test once in ModeRecord, against a local stub server, to
+// write the file, and from then on in ModeReplay.
+//
+// Replayed requests are matched against the recorded ones with the
+// cassette's Matchers, by default on method and URL.  Each recorded
+// exchange is replayed once, in order; when all the matching ones
+// have been used, the last of them is replayed again.
+//
+// Secret headers are redacted before a request is recorded or
+// matched, so the cassette file can be checked in.
+type Cassette struct {
+	path     string
+	mode     Mode
+	upstream http.RoundTripper
+	matchers []Matcher
+	redact   []string
+
+	mu           sync.Mutex
+	interactions []interaction
+	used         []bool
+}
+
+var _ http.RoundTripper = (*Cassette)(nil)
+
+// CassetteOption configures a Cassette.
+type CassetteOption func(*Cassette)
+
+// WithUpstream sets where recording and passthrough cassettes send
+// requests, by default http.DefaultTransport.  A Transport serving a
+// stub handler records without any network.
+func WithUpstream(rt http.RoundTripper) CassetteOption {
+	return func(c *Cassette) {
+		c.upstream = rt
+	}
+}
+
+// WithMatchers sets the matchers a replayed request must satisfy to
+// be answered by a recorded exchange.
+func WithMatchers(matchers ...Matcher) CassetteOption {
+	return func(c *Cassette) {
+		c.matchers = matchers
+	}
+}
+
+// WithRedactedHeaders adds headers whose values are replaced with
+// "REDACTED" in requests and responses.  Authorization,
+// Proxy-Authorization, Cookie and Set-Cookie are always redacted.
+func WithRedactedHeaders(keys ...string) CassetteOption {
+	return func(c *Cassette) {
+		for _, k := range keys {
+			c.redact = append(c.redact, http.CanonicalHeaderKey(k))
+		}
+	}
+}
+
+// NewCassette returns a Cassette for the file at path in the given
+// mode.  In ModeReplay the file is read, and must exist; in ModeRecord
+// it is written by Save.
+func NewCassette(path string, mode Mode, options ...CassetteOption) (*Cassette, error) {
+	var c = &Cassette{
+		path:     path,
+		mode:     mode,
+		upstream: http.DefaultTransport,
+		matchers: []Matcher{MatchMethod, MatchURL},
+		redact:   []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+
+	switch mode {
+	case ModeReplay:
+		if err := c.load(); err != nil {
+			return nil, err
+		}
+	case ModeRecord, ModePassthrough:
+	default:
+		return nil, fmt.Errorf("fake_client: cassette %s: unknown mode %v", path, mode)
+	}
+
+	return c, nil
+}
+
+// Client returns a Client whose requests go through the cassette.
+// The options configure the underlying *http.Client as they do for a
+// real client, except that the transport is always the cassette.
+func (c *Cassette) Client(options ...clienti.ClientOption) *Client {
+	var cl http.Client
+	for _, opt := range options {
+		opt(&cl)
+	}
+	cl.Transport = c
+
+	return &Client{Client: clienti.WrapClient(&cl)}
+}
+
+// Mode returns the cassette's mode.
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Len returns the number of exchanges in the cassette.
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.interactions)
+}
+
+// Save writes the recorded exchanges to the cassette file, creating
+// its directory if need be.  It does nothing unless the cassette is
+// recording.
+func (c *Cassette) Save() error {
+	if c.mode != ModeRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	var file = cassetteFile{Interactions: slices.Clone(c.interactions)}
+	c.mu.Unlock()
+
+	if file.Interactions == nil {
+		file.Interactions = []interaction{}
+	}
+
+	var data, err = json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fake_client: cassette %s: %w", c.path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("fake_client: cassette %s: %w", c.path, err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("fake_client: cassette %s: %w", c.path, err)
+	}
+
+	return nil
+}
+
+func (c *Cassette) load() error {
+	var data, err = os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("fake_client: cassette %s: %w (record it first with ModeRecord)", c.path, err)
+	}
+
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("fake_client: cassette %s: %w", c.path, err)
+	}
+
+	c.interactions = file.Interactions
+	c.used = make([]bool, len(file.Interactions))
+
+	return nil
+}
+
+// RoundTrip replays, records or passes through req according to the
+// cassette's mode.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch c.mode {
+	case ModePassthrough:
+		return c.upstream.RoundTrip(req)
+	case ModeRecord:
+		return c.record(req)
+	}
+
+	return c.replay(req)
+}
+
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	var rec, err = c.capture(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var found = -1
+	for i, in := range c.interactions {
+		if !c.matches(rec, in.Request.recorded()) {
+			continue
+		}
+		found = i
+		if !c.used[i] {
+			break
+		}
+	}
+
+	if found < 0 {
+		return nil, fmt.Errorf("fake_client: cassette %s: %w for %s %s", c.path, ErrNoRecording, req.Method, req.URL)
+	}
+	c.used[found] = true
+
+	return c.interactions[found].Response.response(req), nil
+}
+
+func (c *Cassette) matches(req, recorded *RecordedRequest) bool {
+	for _, m := range c.matchers {
+		if !m(req, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	var rec, err = c.capture(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out = req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		out.Body = io.NopCloser(bytes.NewReader(rec.Body))
+	}
+
+	resp, err := c.upstream.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	var header = c.redacted(resp.Header)
+	var in = interaction{
+		Request: wireRequest{
+			Method: rec.Method,
+			URL:    rec.URL,
+			Header: rec.Header,
+			Body:   wireBody(rec.Body),
+		},
+		Response: wireResponse{
+			Status:        resp.Status,
+			StatusCode:    resp.StatusCode,
+			Proto:         resp.Proto,
+			Header:        header,
+			ContentLength: resp.ContentLength,
+			Body:          wireBody(data),
+			Trailer:       c.redacted(resp.Trailer),
+		},
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, in)
+	c.used = append(c.used, true)
+	c.mu.Unlock()
+
+	return resp, nil
+}
+
+// capture reads req, closing its body, into a redacted RecordedRequest.
+func (c *Cassette) capture(req *http.Request) (*RecordedRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: c.redacted(req.Header),
+		Body:   body,
+	}, nil
+}
+
+// redacted returns a copy of h with the secret headers redacted.
+func (c *Cassette) redacted(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	h = h.Clone()
+	for _, k := range c.redact {
+		if vs, ok := h[k]; ok {
+			for i := range vs {
+				vs[i] = redacted
+			}
+		}
+	}
+
+	return h
+}
+
+// RecordedRequest is a request as stored in a cassette, with secret
+// headers redacted.
+type RecordedRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Matcher reports whether a request being replayed matches a recorded
+// one.
+type Matcher func(req, recorded *RecordedRequest) bool
+
+// MatchMethod matches requests with the same method.
+func MatchMethod(req, recorded *RecordedRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchURL matches requests with the same URL.
+func MatchURL(req, recorded *RecordedRequest) bool {
+	return req.URL == recorded.URL
+}
+
+// MatchBody matches requests with the same body.
+func MatchBody(req, recorded *RecordedRequest) bool {
+	return bytes.Equal(req.Body, recorded.Body)
+}
+
+// MatchHeaders returns a Matcher for requests with the same values for
+// each of the headers.
+func MatchHeaders(keys ...string) Matcher {
+	return func(req, recorded *RecordedRequest) bool {
+		for _, k := range keys {
+			if !slices.Equal(req.Header.Values(k), recorded.Header.Values(k)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// cassetteFile is the JSON form of a cassette.
+type cassetteFile struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request  wireRequest  `json:"request"`
+	Response wireResponse `json:"response"`
+}
+
+type wireRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   wireBody    `json:"body,omitempty"`
+}
+
+func (r wireRequest) recorded() *RecordedRequest {
+	return &RecordedRequest{
+		Method: r.Method,
+		URL:    r.URL,
+		Header: r.Header,
+		Body:   r.Body,
+	}
+}
+
+type wireResponse struct {
+	Status        string      `json:"status"`
+	StatusCode    int         `json:"status_code"`
+	Proto         string      `json:"proto"`
+	Header        http.Header `json:"header,omitempty"`
+	ContentLength int64       `json:"content_length"`
+	Body          wireBody    `json:"body,omitempty"`
+	Trailer       http.Header `json:"trailer,omitempty"`
+}
+
+// response returns a fresh response to req.
+func (r wireResponse) response(req *http.Request) *http.Response {
+	var major, minor, ok = http.ParseHTTPVersion(r.Proto)
+	if !ok {
+		major, minor = 1, 1
+	}
+
+	return &http.Response{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         r.Proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: r.ContentLength,
+		Trailer:       r.Trailer.Clone(),
+		Request:       req,
+	}
+}
+
+// wireBody is a body in a cassette file: a string if it is valid
+// UTF-8, so text is readable in the file, and otherwise an object
+// holding it in base64.
+type wireBody []byte
+
+type base64Body struct {
+	Base64 []byte `json:"base64"`
+}
+
+func (b wireBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(base64Body{Base64: b})
+}
+
+func (b *wireBody) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = wireBody(s)
+		return nil
+	}
+
+	var v base64Body
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*b = v.Base64
+
+	return nil
+}
@@ -0,0 +1,273 @@
+package fake_client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// stubUpstream returns a Transport for a stub server that counts the
+// requests it serves.
+func stubUpstream(calls *int) *Transport {
+	rt := NewRouter()
+	rt.HandleFunc("GET /users/*", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Session", "s3cr3t")
+		io.WriteString(w, `{"name":"`+strings.TrimPrefix(r.URL.Path, "/users/")+`"}`)
+	})
+	rt.HandleFunc("POST /echo", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		io.Copy(w, r.Body)
+	})
+	rt.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "c00kie"})
+	})
+	rt.HandleFunc("GET /binary", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Write([]byte{0xff, 0x00, 0xfe})
+	})
+
+	return NewTransport(rt)
+}
+
+// readBody returns the body of resp, closing it.
+func readBody(t *testing.T, resp clienti.Response) string {
+	t.Helper()
+
+	defer resp.Body().Close()
+	data, err := io.ReadAll(resp.Body())
+	testutil.AssertNil(t, err)
+
+	return string(data)
+}
+
+// TestCassette_RecordReplay tests recording exchanges and replaying
+// them without the upstream.
+func TestCassette_RecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "users.json")
+
+	var calls int
+	rec, err := NewCassette(path, ModeRecord, WithUpstream(stubUpstream(&calls)))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ModeRecord, rec.Mode())
+
+	client := rec.Client()
+	resp, err := client.Get("http://api.test/users/ann")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `{"name":"ann"}`, readBody(t, resp))
+
+	resp, err = client.Post("http://api.test/echo", "text/plain", strings.NewReader("hello"))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "hello", readBody(t, resp))
+
+	resp, err = client.Get("http://api.test/binary")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "\xff\x00\xfe", readBody(t, resp))
+
+	testutil.AssertEqual(t, 3, calls)
+	testutil.AssertEqual(t, 3, rec.Len())
+	testutil.AssertNil(t, rec.Save())
+
+	play, err := NewCassette(path, ModeReplay)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 3, play.Len())
+
+	client = play.Client()
+	resp, err = client.Get("http://api.test/users/ann")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 200, resp.StatusCode())
+	testutil.AssertEqual(t, "application/json", resp.Header().Get("Content-Type"))
+	testutil.AssertEqual(t, `{"name":"ann"}`, readBody(t, resp))
+
+	resp, err = client.Post("http://api.test/echo", "text/plain", strings.NewReader("ignored"))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "hello", readBody(t, resp))
+
+	resp, err = client.Get("http://api.test/binary")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "\xff\x00\xfe", readBody(t, resp))
+
+	testutil.AssertEqual(t, 3, calls)
+}
+
+// TestCassette_NoRecording tests the errors for a missing cassette and
+// an unrecorded request.
+func TestCassette_NoRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.json")
+
+	_, err := NewCassette(path, ModeReplay)
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrNotExist))
+	testutil.AssertEqual(t, "fake_client: cassette "+path+": open "+path+": no such file or directory (record it first with ModeRecord)", err.Error())
+
+	rec, _ := NewCassette(path, ModeRecord)
+	testutil.AssertNil(t, rec.Save())
+
+	play, err := NewCassette(path, ModeReplay)
+	testutil.AssertNil(t, err)
+
+	_, err = play.Client().Get("http://api.test/users/bob")
+	testutil.AssertEqual(t, true, errors.Is(err, ErrNoRecording))
+	testutil.AssertEqual(t, `Get "http://api.test/users/bob": fake_client: cassette `+path+`: no recorded response for GET http://api.test/users/bob`, err.Error())
+
+	_, err = NewCassette(path, Mode(7))
+	testutil.AssertEqual(t, "fake_client: cassette "+path+": unknown mode Mode(7)", err.Error())
+}
+
+// TestCassette_Order tests replaying repeated requests in order.
+func TestCassette_Order(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "poll.json")
+
+	n := 0
+	rt := NewRouter()
+	rt.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		n++
+		io.WriteString(w, []string{"pending", "running", "done"}[n-1])
+	})
+
+	rec, _ := NewCassette(path, ModeRecord, WithUpstream(NewTransport(rt)))
+	for range 3 {
+		resp, err := rec.Client().Get("http://api.test/status")
+		testutil.AssertNil(t, err)
+		resp.Body().Close()
+	}
+	testutil.AssertNil(t, rec.Save())
+
+	play, _ := NewCassette(path, ModeReplay)
+	var got []string
+	for range 4 {
+		resp, err := play.Client().Get("http://api.test/status")
+		testutil.AssertNil(t, err)
+		got = append(got, readBody(t, resp))
+	}
+	testutil.AssertEqual(t, "pending running done done", strings.Join(got, " "))
+}
+
+// TestCassette_Matchers tests replaying with custom matchers.
+func TestCassette_Matchers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "echo.json")
+
+	var calls int
+	rec, _ := NewCassette(path, ModeRecord, WithUpstream(stubUpstream(&calls)))
+	for _, body := range []string{"one", "two"} {
+		req, _ := clienti.NewHTTP().NewRequest("POST", "http://api.test/echo", strings.NewReader(body),
+			clienti.WithHeader("X-Tenant", body))
+		resp, err := rec.Client().Do(req)
+		testutil.AssertNil(t, err)
+		resp.Body().Close()
+	}
+	testutil.AssertNil(t, rec.Save())
+
+	play, _ := NewCassette(path, ModeReplay, WithMatchers(MatchMethod, MatchURL, MatchBody))
+	resp, err := play.Client().Post("http://api.test/echo", "text/plain", strings.NewReader("two"))
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "two", readBody(t, resp))
+
+	_, err = play.Client().Post("http://api.test/echo", "text/plain", strings.NewReader("three"))
+	testutil.AssertEqual(t, true, errors.Is(err, ErrNoRecording))
+
+	play, _ = NewCassette(path, ModeReplay, WithMatchers(MatchHeaders("X-Tenant")))
+	req, _ := clienti.NewHTTP().NewRequest("GET", "http://other.test/", nil, clienti.WithHeader("X-Tenant", "two"))
+	resp, err = play.Client().Do(req)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "two", readBody(t, resp))
+}
+
+// TestCassette_Redaction tests that secret headers never reach the
+// cassette file.
+func TestCassette_Redaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.json")
+
+	var calls int
+	rec, _ := NewCassette(path, ModeRecord, WithUpstream(stubUpstream(&calls)), WithRedactedHeaders("x-session"))
+	req, _ := clienti.NewHTTP().NewRequest("GET", "http://api.test/users/ann", nil,
+		clienti.WithHeader("Authorization", "Bearer t0ken"))
+	resp, err := rec.Client().Do(req)
+	testutil.AssertNil(t, err)
+
+	// The live response is not redacted.
+	testutil.AssertEqual(t, "s3cr3t", resp.Header().Get("X-Session"))
+	resp.Body().Close()
+	testutil.AssertNil(t, rec.Save())
+
+	data, _ := os.ReadFile(path)
+	testutil.AssertEqual(t, false, strings.Contains(string(data), "t0ken"))
+	testutil.AssertEqual(t, false, strings.Contains(string(data), "s3cr3t"))
+
+	var file struct {
+		Interactions []struct {
+			Request struct {
+				Header http.Header
+			}
+		}
+	}
+	testutil.AssertNil(t, json.Unmarshal(data, &file))
+	testutil.AssertEqual(t, "REDACTED", file.Interactions[0].Request.Header.Get("Authorization"))
+
+	play, _ := NewCassette(path, ModeReplay, WithRedactedHeaders("X-Session"),
+		WithMatchers(MatchURL, MatchHeaders("Authorization")))
+	req, _ = clienti.NewHTTP().NewRequest("GET", "http://api.test/users/ann", nil,
+		clienti.WithHeader("Authorization", "Bearer other"))
+	resp, err = play.Client().Do(req)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "REDACTED", resp.Header().Get("X-Session"))
+	resp.Body().Close()
+}
+
+// TestCassette_SetCookie tests that cookies set by a response are
+// redacted by default.
+func TestCassette_SetCookie(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "login.json")
+
+	var calls int
+	rec, _ := NewCassette(path, ModeRecord, WithUpstream(stubUpstream(&calls)))
+	req, _ := clienti.NewHTTP().NewRequest("POST", "http://api.test/login", nil)
+	resp, err := rec.Client().Do(req)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "session=c00kie", resp.Header().Get("Set-Cookie"))
+	resp.Body().Close()
+	testutil.AssertNil(t, rec.Save())
+
+	data, _ := os.ReadFile(path)
+	testutil.AssertEqual(t, false, strings.Contains(string(data), "c00kie"))
+
+	var file struct {
+		Interactions []struct {
+			Response struct {
+				Header http.Header
+			}
+		}
+	}
+	testutil.AssertNil(t, json.Unmarshal(data, &file))
+	testutil.AssertEqual(t, "REDACTED", file.Interactions[0].Response.Header.Get("Set-Cookie"))
+}
+
+// TestCassette_Passthrough tests sending requests upstream without
+// recording.
+func TestCassette_Passthrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pass.json")
+
+	var calls int
+	pass, err := NewCassette(path, ModePassthrough, WithUpstream(stubUpstream(&calls)))
+	testutil.AssertNil(t, err)
+
+	resp, err := pass.Client().Get("http://api.test/users/ann")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `{"name":"ann"}`, readBody(t, resp))
+	testutil.AssertEqual(t, 1, calls)
+	testutil.AssertEqual(t, 0, pass.Len())
+
+	testutil.AssertNil(t, pass.Save())
+	_, err = os.Stat(path)
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrNotExist))
+}
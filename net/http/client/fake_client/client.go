@@ -0,0 +1,97 @@
+// Package fake_client provides an in-process implementation of the
+// go-interfaces http client Client and HTTP interfaces.
+//
+// Requests are served by an http.Handler, such as a Router, called
+// directly instead of over the network.  The client is a real
+// *http.Client, so redirects, CheckRedirect, cookie jars, timeouts and
+// context cancellation all behave as they do in production, and the
+// responses are real *http.Response values with headers, cookies and
+// trailers:
+//
+//	rt := fake_client.NewRouter()
+//	rt.HandleFunc("GET api.example.com/users/*", func(w http.ResponseWriter, r *http.Request) {
+//		w.Header().Set("Content-Type", "application/json")
+//		io.WriteString(w, `{"name":"gopher"}`)
+//	})
+//
+//	var client = fake_client.New(rt)
+//	resp, err := client.Get("https://api.example.com/users/1")
+package fake_client
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+)
+
+// Client is a fake implementation of the go-interfaces http Client
+// interface that serves every request with a handler.
+type Client struct {
+	clienti.Client
+}
+
+var _ clienti.Client = (*Client)(nil)
+
+// New returns a Client whose requests are served by h.  The options
+// configure the underlying *http.Client as they do for a real client,
+// except that the transport is always a Transport calling h.
+func New(h http.Handler, options ...clienti.ClientOption) *Client {
+	var cl http.Client
+	for _, opt := range options {
+		opt(&cl)
+	}
+	cl.Transport = NewTransport(h)
+
+	return &Client{Client: clienti.WrapClient(&cl)}
+}
+
+// HTTP is a fake implementation of the go-interfaces http HTTP
+// interface.  Clients it creates, and its package-level Get, Head,
+// Post and PostForm, send their requests to its handler; the other
+// functions are those of the net/http package.
+type HTTP struct {
+	clienti.HTTP
+
+	handler http.Handler
+	client  *Client
+}
+
+var _ clienti.HTTP = (*HTTP)(nil)
+
+// NewHTTP returns an HTTP whose requests are served by h.
+func NewHTTP(h http.Handler) *HTTP {
+	return &HTTP{
+		HTTP:    clienti.NewHTTP(),
+		handler: h,
+		client:  New(h),
+	}
+}
+
+// NewClient returns a Client whose requests are served by the
+// handler.
+func (f *HTTP) NewClient(options ...clienti.ClientOption) clienti.Client {
+	return New(f.handler, options...)
+}
+
+// Get issues a GET with the default client, like http.Get.
+func (f *HTTP) Get(url string) (clienti.Response, error) {
+	return f.client.Get(url)
+}
+
+// Head issues a HEAD with the default client, like http.Head.
+func (f *HTTP) Head(url string) (clienti.Response, error) {
+	return f.client.Head(url)
+}
+
+// Post issues a POST with the default client, like http.Post.
+func (f *HTTP) Post(url, contentType string, body io.Reader) (clienti.Response, error) {
+	return f.client.Post(url, contentType, body)
+}
+
+// PostForm issues a form POST with the default client, like
+// http.PostForm.
+func (f *HTTP) PostForm(url string, data url.Values) (clienti.Response, error) {
+	return f.client.PostForm(url, data)
+}
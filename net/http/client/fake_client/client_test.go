@@ -0,0 +1,169 @@
+package fake_client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestClient_Get tests a simple request and response.
+func TestClient_Get(t *testing.T) {
+	c := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		testutil.AssertEqual(t, "/hello?x=1", r.RequestURI)
+		testutil.AssertEqual(t, "example.com", r.Host)
+		w.Header().Set("X-Test", "yes")
+		io.WriteString(w, "hello, world")
+	}))
+
+	resp, err := c.Get("http://example.com/hello?x=1")
+	testutil.AssertNil(t, err)
+	defer resp.Body().Close()
+
+	testutil.AssertEqual(t, 200, resp.StatusCode())
+	testutil.AssertEqual(t, "200 OK", resp.Status())
+	testutil.AssertEqual(t, "yes", resp.Header().Get("X-Test"))
+	testutil.AssertEqual(t, int64(12), resp.ContentLength())
+
+	data, err := io.ReadAll(resp.Body())
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "hello, world", string(data))
+}
+
+// TestClient_Post tests that the handler reads the request body.
+func TestClient_Post(t *testing.T) {
+	c := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		io.WriteString(w, r.Method+" "+r.PostForm.Get("name"))
+	}))
+
+	resp, err := c.PostForm("http://example.com/form", url.Values{"name": {"gopher"}})
+	testutil.AssertNil(t, err)
+	data, _ := io.ReadAll(resp.Body())
+	resp.Body().Close()
+	testutil.AssertEqual(t, "POST gopher", string(data))
+}
+
+// TestClient_Redirect tests following redirects and CheckRedirect.
+func TestClient_Redirect(t *testing.T) {
+	rt := NewRouter()
+	rt.Handle("/old", http.RedirectHandler("/new", http.StatusMovedPermanently))
+	rt.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "moved")
+	})
+
+	resp, err := New(rt).Get("http://example.com/old")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/new", resp.Request().RealRequest().URL.Path)
+	data, _ := io.ReadAll(resp.Body())
+	testutil.AssertEqual(t, "moved", string(data))
+
+	var via int
+	c := New(rt, clienti.WithCheckRedirect(func(req *http.Request, prior []*http.Request) error {
+		via = len(prior)
+		return http.ErrUseLastResponse
+	}))
+	resp, err = c.Get("http://example.com/old")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 1, via)
+	testutil.AssertEqual(t, http.StatusMovedPermanently, resp.StatusCode())
+
+	loc, err := resp.Location()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "http://example.com/new", loc.String())
+}
+
+// TestClient_Cookies tests cookies set by the handler and sent back
+// through a jar.
+func TestClient_Cookies(t *testing.T) {
+	rt := NewRouter()
+	rt.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+	})
+	rt.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("session")
+		if err != nil {
+			http.Error(w, "no session", http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, c.Value)
+	})
+
+	jar, _ := cookiejar.New(nil)
+	c := New(rt, clienti.WithCookieJar(jar))
+
+	resp, err := c.Get("http://example.com/login")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "session", resp.Cookies()[0].Name)
+
+	resp, err = c.Get("http://example.com/me")
+	testutil.AssertNil(t, err)
+	data, _ := io.ReadAll(resp.Body())
+	testutil.AssertEqual(t, "abc", string(data))
+}
+
+// TestClient_Cancel tests canceling a request while the handler runs.
+func TestClient_Cancel(t *testing.T) {
+	done := make(chan error, 1)
+	c := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		done <- r.Context().Err()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := clienti.NewHTTP().NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+	testutil.AssertNil(t, err)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err = c.Do(req)
+	testutil.AssertEqual(t, true, errors.Is(err, context.Canceled))
+	testutil.AssertEqual(t, `Get "http://example.com/": context canceled`, err.Error())
+	testutil.AssertEqual(t, context.Canceled, <-done)
+}
+
+// TestClient_Timeout tests the client timeout.
+func TestClient_Timeout(t *testing.T) {
+	c := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}), clienti.WithTimeout(10*time.Millisecond))
+
+	// Whether the message mentions Client.Timeout depends on which of
+	// the client's timer and the request's deadline fires first, as
+	// with a real transport.
+	_, err := c.Get("http://example.com/")
+	testutil.AssertEqual(t, true, errors.Is(err, context.DeadlineExceeded))
+
+	var ue *url.Error
+	testutil.AssertEqual(t, true, errors.As(err, &ue) && ue.Timeout())
+}
+
+// TestHTTP tests the package-level functions of the fake HTTP.
+func TestHTTP(t *testing.T) {
+	h := NewHTTP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method)
+	}))
+
+	resp, err := h.Post("http://example.com/", "text/plain", strings.NewReader("x"))
+	testutil.AssertNil(t, err)
+	data, _ := io.ReadAll(resp.Body())
+	testutil.AssertEqual(t, "POST", string(data))
+
+	resp, err = h.NewClient().Head("http://example.com/")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 200, resp.StatusCode())
+
+	testutil.AssertEqual(t, "Not Found", h.StatusText(404))
+}
@@ -0,0 +1,156 @@
+package fake_client
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pdutton/go-mocks/internal/pathpattern"
+)
+
+// Router is an http.Handler that dispatches requests through a table
+// of routes matched on method, host, path and query.
+type Router struct {
+	mu     sync.Mutex
+	routes []route
+}
+
+var _ http.Handler = (*Router)(nil)
+
+type route struct {
+	method  string
+	host    string
+	path    string
+	query   url.Values
+	handler http.Handler
+}
+
+// NewRouter returns a Router with no routes.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle adds a route for pattern, which has the form
+//
+//	[METHOD ][HOST]/PATH[?QUERY]
+//
+// like an http.ServeMux pattern with an optional query.  Without a
+// method or host the route matches any.  The host is matched against
+// the request's Host, including any port.  The path is matched with
+// path.Match, so "*" matches one path segment, and a final "/**"
+// matches the rest of the path.  Each query parameter in the pattern
+// must be present in the request with the same values, in any order;
+// the request may have other parameters too.
+//
+// Later routes take precedence over earlier ones, so a test can
+// override a route installed by a shared helper.  It panics if the
+// pattern cannot be parsed.
+func (rt *Router) Handle(pattern string, h http.Handler) {
+	var r = parsePattern(pattern)
+	r.handler = h
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.routes = append(rt.routes, r)
+}
+
+// HandleFunc adds a route for pattern served by f.
+func (rt *Router) HandleFunc(pattern string, f func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(pattern, http.HandlerFunc(f))
+}
+
+func parsePattern(pattern string) route {
+	var r route
+
+	var rest = pattern
+	if method, after, ok := strings.Cut(rest, " "); ok {
+		r.method = method
+		rest = strings.TrimLeft(after, " ")
+	}
+
+	var i = strings.Index(rest, "/")
+	if i < 0 {
+		panic("fake_client: pattern " + pattern + " has no path")
+	}
+	r.host, rest = rest[:i], rest[i:]
+
+	if p, q, ok := strings.Cut(rest, "?"); ok {
+		var values, err = url.ParseQuery(q)
+		if err != nil {
+			panic("fake_client: pattern " + pattern + ": " + err.Error())
+		}
+		rest, r.query = p, values
+	}
+
+	if err := pathpattern.Validate(rest); err != nil {
+		panic("fake_client: pattern " + pattern + ": " + err.Error())
+	}
+	r.path = rest
+
+	return r
+}
+
+func (r route) matchesHost(host string) bool {
+	return r.host == "" || strings.EqualFold(r.host, host)
+}
+
+func (r route) matchesPath(p string) bool {
+	return pathpattern.Match(r.path, p)
+}
+
+func (r route) matchesQuery(query url.Values) bool {
+	for k, want := range r.query {
+		var got = slices.Clone(query[k])
+		for _, v := range want {
+			var i = slices.Index(got, v)
+			if i < 0 {
+				return false
+			}
+			got = slices.Delete(got, i, i+1)
+		}
+	}
+
+	return true
+}
+
+// ServeHTTP serves req with the most recently added matching route.
+// If routes match everything but the method it responds 405 Method
+// Not Allowed, listing the allowed methods, and otherwise 404 Not
+// Found.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rt.mu.Lock()
+	var routes = slices.Clone(rt.routes)
+	rt.mu.Unlock()
+
+	var allow []string
+	for _, r := range slices.Backward(routes) {
+		if !r.matchesHost(req.Host) || !r.matchesPath(req.URL.Path) || !r.matchesQuery(req.URL.Query()) {
+			continue
+		}
+
+		switch {
+		case r.method == "" || r.method == req.Method:
+			r.handler.ServeHTTP(w, req)
+			return
+		case r.method == http.MethodGet && req.Method == http.MethodHead:
+			r.handler.ServeHTTP(w, req)
+			return
+		}
+
+		if !slices.Contains(allow, r.method) {
+			allow = append(allow, r.method)
+		}
+	}
+
+	if len(allow) > 0 {
+		slices.Sort(allow)
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	http.NotFound(w, req)
+}
@@ -0,0 +1,82 @@
+package fake_client
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// serve sends a request through rt and returns the status and body.
+func serve(t *testing.T, rt *Router, method, target string) (int, string) {
+	t.Helper()
+
+	req, _ := http.NewRequest(method, target, nil)
+	resp, err := NewTransport(rt).RoundTrip(req)
+	testutil.AssertNil(t, err)
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// respond returns a handler writing s.
+func respond(s string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, s)
+	}
+}
+
+// TestRouter_Match tests matching on method, host, path and query.
+func TestRouter_Match(t *testing.T) {
+	rt := NewRouter()
+	rt.Handle("/users/*", respond("user"))
+	rt.Handle("POST /users/*", respond("update"))
+	rt.Handle("api.example.com/users/*", respond("api user"))
+	rt.Handle("/users/*?fields=name&fields=id", respond("fields"))
+	rt.Handle("GET /static/**", respond("static"))
+
+	for _, tc := range []struct{ method, target, want string }{
+		{"GET", "http://example.com/users/1", "user"},
+		{"POST", "http://example.com/users/1", "update"},
+		{"GET", "http://api.example.com/users/1", "api user"},
+		{"GET", "http://example.com/users/1?fields=id&x=y&fields=name", "fields"},
+		{"GET", "http://example.com/users/1?fields=id", "user"},
+		{"GET", "http://example.com/static/css/site.css", "static"},
+	} {
+		status, body := serve(t, rt, tc.method, tc.target)
+		testutil.AssertEqual(t, 200, status)
+		testutil.AssertEqual(t, tc.want, body)
+	}
+}
+
+// TestRouter_NoMatch tests the responses when no route matches.
+func TestRouter_NoMatch(t *testing.T) {
+	rt := NewRouter()
+	rt.Handle("GET /things", respond("things"))
+	rt.Handle("DELETE /things", respond("deleted"))
+
+	status, _ := serve(t, rt, "GET", "http://example.com/other")
+	testutil.AssertEqual(t, http.StatusNotFound, status)
+
+	req, _ := http.NewRequest("PUT", "http://example.com/things", nil)
+	resp, _ := NewTransport(rt).RoundTrip(req)
+	testutil.AssertEqual(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	testutil.AssertEqual(t, "DELETE, GET", resp.Header.Get("Allow"))
+
+	status, _ = serve(t, rt, "HEAD", "http://example.com/things")
+	testutil.AssertEqual(t, http.StatusOK, status)
+}
+
+// TestRouter_Override tests that later routes take precedence.
+func TestRouter_Override(t *testing.T) {
+	rt := NewRouter()
+	rt.Handle("/", respond("old"))
+	rt.Handle("/", respond("new"))
+
+	_, body := serve(t, rt, "GET", "http://example.com/")
+	testutil.AssertEqual(t, "new", body)
+
+	testutil.AssertPanic(t, func() { rt.Handle("example.com", respond("")) }, "pattern without a path")
+}
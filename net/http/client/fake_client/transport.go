@@ -0,0 +1,508 @@
+package fake_client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bufferBeforeChunking is how much of a response body the handler
+// can write before the response is sent with chunked encoding, as in
+// the net/http server.
+const bufferBeforeChunking = 2048
+
+// remoteAddr is the client address handlers see, from the range
+// reserved for documentation.
+const remoteAddr = "192.0.2.1:1234"
+
+var errReadOnClosedBody = errors.New("http: read on closed response body")
+
+// Transport is an http.RoundTripper that serves each request by
+// calling a handler in-process, the way a server would.  The handler
+// runs in its own goroutine and sees a server-side request: RequestURI,
+// RemoteAddr and Host are set, and TLS is set for https URLs.
+//
+// The response is returned as soon as the handler sends its header,
+// either by flushing or by writing more than a small buffer, or when
+// it returns; the body then streams until the handler returns.  A
+// response completed before anything was sent gets a Content-Length
+// and, like a real server, a sniffed Content-Type if the handler did
+// not set one.  Trailers declared in the "Trailer" header or set with
+// the http.TrailerPrefix are filled in when the body reaches io.EOF.
+//
+// Cancelling the request's context fails the round trip, or any read
+// of the body that follows, with the context's error, and is visible
+// to the handler through the request's context.
+type Transport struct {
+	handler http.Handler
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// NewTransport returns a Transport that serves requests with h.
+func NewTransport(h http.Handler) *Transport {
+	return &Transport{handler: h}
+}
+
+// RoundTrip serves req with the transport's handler.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var ctx = req.Context()
+	if err := ctx.Err(); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	var sreq, err = serverRequest(req)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	var w = newResponseWriter(req)
+	go w.serve(t.handler, sreq)
+
+	select {
+	case <-w.ready:
+		if w.err != nil {
+			return nil, w.err
+		}
+		return w.resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// CloseIdleConnections does nothing: there are no connections.
+func (t *Transport) CloseIdleConnections() {}
+
+// serverRequest returns req as a handler would receive it.
+func serverRequest(req *http.Request) (*http.Request, error) {
+	if req.URL == nil {
+		return nil, errors.New("http: nil Request.URL")
+	}
+
+	var sreq = req.Clone(req.Context())
+
+	sreq.RequestURI = req.URL.RequestURI()
+	var u, err = url.ParseRequestURI(sreq.RequestURI)
+	if err != nil {
+		return nil, err
+	}
+	sreq.URL = u
+
+	if sreq.Host == "" {
+		sreq.Host = req.URL.Host
+	}
+	if sreq.Method == "" {
+		sreq.Method = http.MethodGet
+	}
+	sreq.Proto, sreq.ProtoMajor, sreq.ProtoMinor = "HTTP/1.1", 1, 1
+	sreq.RemoteAddr = remoteAddr
+
+	if sreq.Header == nil {
+		sreq.Header = http.Header{}
+	}
+	if _, ok := sreq.Header["User-Agent"]; !ok {
+		sreq.Header.Set("User-Agent", "Go-http-client/1.1")
+	}
+
+	// The handler reads the client's body directly.
+	sreq.Body = req.Body
+	if sreq.Body == nil {
+		sreq.Body = http.NoBody
+	}
+
+	if req.URL.Scheme == "https" {
+		var host = req.URL.Hostname()
+		sreq.TLS = &tls.ConnectionState{
+			Version:           tls.VersionTLS13,
+			HandshakeComplete: true,
+			ServerName:        host,
+		}
+	}
+
+	return sreq, nil
+}
+
+// responseWriter is the http.ResponseWriter a handler writes to.
+type responseWriter struct {
+	req    *http.Request
+	header http.Header
+
+	mu          sync.Mutex
+	wroteHeader bool
+	status      int
+	buf         bytes.Buffer
+	committed   bool
+	body        *body
+
+	// resp and err are set before ready is closed.
+	resp  *http.Response
+	err   error
+	ready chan struct{}
+}
+
+var _ http.Flusher = (*responseWriter)(nil)
+
+func newResponseWriter(req *http.Request) *responseWriter {
+	return &responseWriter{
+		req:    req,
+		header: http.Header{},
+		body:   newBody(req.Context()),
+		ready:  make(chan struct{}),
+	}
+}
+
+// serve runs the handler and completes the response.  Like the
+// net/http server, it recovers from a panic in the handler by
+// aborting the response, logging the panic unless it is
+// http.ErrAbortHandler.
+func (w *responseWriter) serve(h http.Handler, sreq *http.Request) {
+	defer sreq.Body.Close()
+
+	defer func() {
+		if v := recover(); v != nil {
+			if v != http.ErrAbortHandler {
+				var stack = make([]byte, 64<<10)
+				stack = stack[:runtime.Stack(stack, false)]
+				log.Printf("http: panic serving %s: %v\n%s", remoteAddr, v, stack)
+			}
+			w.abort()
+			return
+		}
+		w.finish()
+	}()
+
+	h.ServeHTTP(w, sreq)
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code < 100 || code > 999 {
+		panic(fmt.Sprintf("invalid WriteHeader code %v", code))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.wroteHeader {
+		return
+	}
+
+	// Informational responses are not passed on to the client.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !bodyAllowed(w.status) {
+		return 0, http.ErrBodyNotAllowed
+	}
+
+	// A HEAD response has no body, but the handler is not told so.
+	if w.committed && w.req.Method == http.MethodHead {
+		return len(p), nil
+	}
+	if w.committed {
+		return w.body.write(p), nil
+	}
+
+	w.buf.Write(p)
+	if w.buf.Len() > bufferBeforeChunking {
+		w.commit(false)
+	}
+
+	return len(p), nil
+}
+
+// Flush sends the header and any buffered body to the client.
+func (w *responseWriter) Flush() {
+	w.WriteHeader(http.StatusOK)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.committed {
+		w.commit(false)
+	}
+}
+
+// bodyAllowed reports whether a response with the status can have a
+// body.
+func bodyAllowed(status int) bool {
+	return !(status >= 100 && status <= 199 || status == http.StatusNoContent || status == http.StatusNotModified)
+}
+
+// statusLine returns the status text a client sees for code.
+func statusLine(code int) string {
+	if text := http.StatusText(code); text != "" {
+		return strconv.Itoa(code) + " " + text
+	}
+
+	return fmt.Sprintf("%03d status code %d", code, code)
+}
+
+// commit sends the response header to the client, along with the body
+// buffered so far.  If final is set, the handler has returned and the
+// whole body is known.  It must be called with mu held.
+func (w *responseWriter) commit(final bool) {
+	w.committed = true
+
+	var header = w.header.Clone()
+	var trailer http.Header
+	for _, v := range header.Values("Trailer") {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				if trailer == nil {
+					trailer = http.Header{}
+				}
+				trailer[http.CanonicalHeaderKey(k)] = nil
+			}
+		}
+	}
+	header.Del("Trailer")
+	for k := range header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			delete(header, k)
+		}
+	}
+
+	if header.Get("Date") == "" {
+		header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+
+	var allowed = bodyAllowed(w.status)
+	if _, ok := header["Content-Type"]; !ok && allowed && w.buf.Len() > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf.Bytes()))
+	}
+
+	var length int64 = -1
+	var encoding []string
+	if cl := header.Get("Content-Length"); cl != "" {
+		length, _ = strconv.ParseInt(cl, 10, 64)
+	} else if !allowed {
+		length = 0
+	} else if final && trailer == nil {
+		length = int64(w.buf.Len())
+		header.Set("Content-Length", strconv.FormatInt(length, 10))
+	} else {
+		encoding = []string{"chunked"}
+	}
+
+	if w.req.Method == http.MethodHead {
+		w.buf.Reset()
+	}
+
+	var resp = &http.Response{
+		Status:           statusLine(w.status),
+		StatusCode:       w.status,
+		Proto:            "HTTP/1.1",
+		ProtoMajor:       1,
+		ProtoMinor:       1,
+		Header:           header,
+		Body:             w.body,
+		ContentLength:    length,
+		TransferEncoding: encoding,
+		Trailer:          trailer,
+		Request:          w.req,
+	}
+	if w.req.URL.Scheme == "https" {
+		resp.TLS = &tls.ConnectionState{
+			Version:           tls.VersionTLS13,
+			HandshakeComplete: true,
+			ServerName:        w.req.URL.Hostname(),
+		}
+	}
+
+	w.body.resp = resp
+	w.body.write(w.buf.Bytes())
+	w.buf.Reset()
+
+	w.resp = resp
+	close(w.ready)
+}
+
+// finish completes the response after the handler returns.
+func (w *responseWriter) finish() {
+	w.WriteHeader(http.StatusOK)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.committed {
+		w.commit(true)
+	}
+
+	var trailer = http.Header{}
+	for k := range w.resp.Trailer {
+		trailer[k] = w.header[k]
+	}
+	for k, v := range w.header {
+		if name, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+			trailer[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+
+	w.body.finish(nil, trailer)
+}
+
+// abort ends the response after the handler panicked: a client still
+// waiting for the header sees the connection close, and one reading
+// the body sees it cut short.
+func (w *responseWriter) abort() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.committed {
+		w.committed = true
+		w.err = io.EOF
+		close(w.ready)
+		return
+	}
+
+	w.body.finish(io.ErrUnexpectedEOF, nil)
+}
+
+// body is a response body streaming from a handler to the client.
+// The handler never blocks writing to it.
+type body struct {
+	ctx  context.Context
+	resp *http.Response
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	done    bool
+	err     error
+	trailer http.Header
+	closed  bool
+	signal  chan struct{}
+}
+
+func newBody(ctx context.Context) *body {
+	return &body{ctx: ctx, signal: make(chan struct{})}
+}
+
+// notify wakes a waiting reader.  It must be called with mu held.
+func (b *body) notify() {
+	close(b.signal)
+	b.signal = make(chan struct{})
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	for {
+		b.mu.Lock()
+		switch {
+		case b.closed:
+			b.mu.Unlock()
+			return 0, errReadOnClosedBody
+		case b.ctx.Err() != nil:
+			b.mu.Unlock()
+			return 0, b.ctx.Err()
+		case b.buf.Len() > 0:
+			var n, _ = b.buf.Read(p)
+			b.mu.Unlock()
+			return n, nil
+		case b.done:
+			var err = b.err
+			b.mergeTrailer()
+			b.mu.Unlock()
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		case len(p) == 0:
+			b.mu.Unlock()
+			return 0, nil
+		}
+		var signal = b.signal
+		b.mu.Unlock()
+
+		select {
+		case <-signal:
+		case <-b.ctx.Done():
+		}
+	}
+}
+
+// Close discards the rest of the body.
+func (b *body) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	b.buf.Reset()
+	b.notify()
+
+	return nil
+}
+
+// write adds data from the handler, which is discarded once the
+// client has closed the body.
+func (b *body) write(p []byte) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed && len(p) > 0 {
+		b.buf.Write(p)
+		b.notify()
+	}
+
+	return len(p)
+}
+
+// finish marks the end of the body; err is returned after the data
+// instead of io.EOF if it is not nil.  The trailer is added to the
+// response when the reader reaches the end.
+func (b *body) finish(err error, trailer http.Header) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done = true
+	b.err = err
+	b.trailer = trailer
+	b.notify()
+}
+
+// mergeTrailer adds the trailer to the response, in the reader's
+// goroutine, as the net/http client does.  It must be called with mu
+// held.
+func (b *body) mergeTrailer() {
+	if len(b.trailer) == 0 {
+		return
+	}
+
+	if b.resp.Trailer == nil {
+		b.resp.Trailer = http.Header{}
+	}
+	for k, v := range b.trailer {
+		b.resp.Trailer[k] = v
+	}
+	b.trailer = nil
+}
@@ -0,0 +1,172 @@
+package fake_client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// get sends a GET through a Transport serving h.
+func get(t *testing.T, h http.HandlerFunc, target string) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", target, nil)
+	resp, err := NewTransport(h).RoundTrip(req)
+	testutil.AssertNil(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+// TestTransport_Sniff tests the headers added to a short response.
+func TestTransport_Sniff(t *testing.T) {
+	resp := get(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>hi</body></html>")
+	}, "http://example.com/")
+
+	testutil.AssertEqual(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	testutil.AssertEqual(t, "28", resp.Header.Get("Content-Length"))
+	testutil.AssertNotEqual(t, "", resp.Header.Get("Date"))
+	testutil.AssertEqual(t, "HTTP/1.1", resp.Proto)
+}
+
+// TestTransport_Stream tests reading a response while the handler is
+// still writing it.
+func TestTransport_Stream(t *testing.T) {
+	next := make(chan struct{})
+	resp := get(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "first\n")
+		w.(http.Flusher).Flush()
+		<-next
+		io.WriteString(w, "second\n")
+	}, "http://example.com/")
+
+	testutil.AssertEqual(t, int64(-1), resp.ContentLength)
+	testutil.AssertEqual(t, "chunked", strings.Join(resp.TransferEncoding, ","))
+
+	br := bufio.NewReader(resp.Body)
+	line, err := br.ReadString('\n')
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "first\n", line)
+
+	close(next)
+	rest, err := io.ReadAll(br)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "second\n", string(rest))
+}
+
+// TestTransport_Trailers tests declared and prefixed trailers.
+func TestTransport_Trailers(t *testing.T) {
+	resp := get(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		io.WriteString(w, "data")
+		w.Header().Set("X-Checksum", "1234")
+		w.Header().Set(http.TrailerPrefix+"X-Late", "yes")
+	}, "http://example.com/")
+
+	_, declared := resp.Trailer["X-Checksum"]
+	testutil.AssertEqual(t, true, declared)
+	testutil.AssertEqual(t, "", resp.Trailer.Get("X-Checksum"))
+
+	io.ReadAll(resp.Body)
+	testutil.AssertEqual(t, "1234", resp.Trailer.Get("X-Checksum"))
+	testutil.AssertEqual(t, "yes", resp.Trailer.Get("X-Late"))
+	testutil.AssertEqual(t, "", resp.Header.Get("X-Late"))
+}
+
+// TestTransport_Status tests status codes without bodies and unknown
+// codes.
+func TestTransport_Status(t *testing.T) {
+	resp := get(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+		_, err := w.Write([]byte("x"))
+		testutil.AssertEqual(t, http.ErrBodyNotAllowed, err)
+	}, "http://example.com/")
+	testutil.AssertEqual(t, "204 No Content", resp.Status)
+	testutil.AssertEqual(t, int64(0), resp.ContentLength)
+
+	resp = get(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(599)
+	}, "https://example.com/")
+	testutil.AssertEqual(t, "599 status code 599", resp.Status)
+	testutil.AssertEqual(t, "example.com", resp.TLS.ServerName)
+}
+
+// TestTransport_Head tests that a HEAD response has no body.
+func TestTransport_Head(t *testing.T) {
+	req, _ := http.NewRequest("HEAD", "http://example.com/", nil)
+	resp, err := NewTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "body")
+	})).RoundTrip(req)
+	testutil.AssertNil(t, err)
+
+	data, _ := io.ReadAll(resp.Body)
+	testutil.AssertEqual(t, "", string(data))
+	testutil.AssertEqual(t, int64(4), resp.ContentLength)
+}
+
+// TestTransport_HeadFlush tests that a HEAD response stays empty when
+// the handler writes after flushing.
+func TestTransport_HeadFlush(t *testing.T) {
+	req, _ := http.NewRequest("HEAD", "http://example.com/", nil)
+	resp, err := NewTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "before")
+		w.(http.Flusher).Flush()
+		n, err := io.WriteString(w, "after")
+		testutil.AssertNil(t, err)
+		testutil.AssertEqual(t, 5, n)
+		w.Write(make([]byte, 2*bufferBeforeChunking))
+	})).RoundTrip(req)
+	testutil.AssertNil(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "", string(data))
+}
+
+// TestTransport_Panic tests a handler that panics.
+func TestTransport_Panic(t *testing.T) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	_, err := NewTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).RoundTrip(req)
+	testutil.AssertEqual(t, io.EOF, err)
+
+	resp := get(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		panic("boom")
+	}, "http://example.com/")
+	_, err = io.ReadAll(resp.Body)
+	testutil.AssertEqual(t, io.ErrUnexpectedEOF, err)
+}
+
+// TestTransport_BodyCancel tests canceling while reading the body.
+func TestTransport_BodyCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/", nil)
+	resp, err := NewTransport(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})).RoundTrip(req)
+	testutil.AssertNil(t, err)
+
+	cancel()
+	_, err = resp.Body.Read(make([]byte, 1))
+	testutil.AssertEqual(t, true, errors.Is(err, context.Canceled))
+
+	resp.Body.Close()
+	_, err = resp.Body.Read(make([]byte, 1))
+	testutil.AssertEqual(t, "http: read on closed response body", err.Error())
+}
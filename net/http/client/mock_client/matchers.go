@@ -0,0 +1,495 @@
+package mock_http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+	"github.com/pdutton/go-mocks/internal/pathpattern"
+	"go.uber.org/mock/gomock"
+)
+
+// maxFormMemory is the memory limit for parsing multipart forms, as
+// for http.Request.FormValue.
+const maxFormMemory = 32 << 20
+
+// RequestMatcher is a gomock.Matcher for the go-interfaces http Request
+// passed to Client.Do, built up from the parts of the request a test
+// cares about:
+//
+//	client.EXPECT().Do(mock_http.MatchRequest().
+//		Method("POST").
+//		Path("/users/*").
+//		Header("Authorization", "Bearer token").
+//		JSONBody(`{"name":"gopher"}`, mock_http.IgnoreFields("id")).
+//		Deadline(true))
+//
+// It also matches a *http.Request.  When a call does not match, gomock
+// reports each part of the request the matcher checks, with "-" lines
+// for what was expected and "+" lines for what was sent.
+//
+// Matching reads the request body, which is then replaced with a copy
+// so the request can still be sent.
+type RequestMatcher struct {
+	checks []check
+}
+
+var (
+	_ gomock.Matcher      = (*RequestMatcher)(nil)
+	_ gomock.GotFormatter = (*RequestMatcher)(nil)
+)
+
+// check is one part of a RequestMatcher.
+type check struct {
+	name string
+	want string
+	eval func(v *requestView) result
+}
+
+// result is the outcome of a check: the value found in the request,
+// whether it matches, and optionally lines detailing the difference.
+type result struct {
+	got    string
+	ok     bool
+	detail []string
+}
+
+// MatchRequest returns a RequestMatcher that matches any request.
+func MatchRequest() *RequestMatcher {
+	return &RequestMatcher{}
+}
+
+func (m *RequestMatcher) add(name, want string, eval func(v *requestView) result) *RequestMatcher {
+	m.checks = append(m.checks, check{name: name, want: want, eval: eval})
+
+	return m
+}
+
+// Method matches the request method.
+func (m *RequestMatcher) Method(method string) *RequestMatcher {
+	return m.add("method", method, func(v *requestView) result {
+		return result{got: v.req.Method, ok: v.req.Method == method}
+	})
+}
+
+// URL matches the whole request URL.
+func (m *RequestMatcher) URL(u string) *RequestMatcher {
+	return m.add("url", u, func(v *requestView) result {
+		var got = v.req.URL.String()
+		return result{got: got, ok: got == u}
+	})
+}
+
+// Path matches the URL path against pattern with path.Match, so "*"
+// matches one path segment, and a final "/**" matches the rest of the
+// path.  It panics if the pattern is malformed.
+func (m *RequestMatcher) Path(pattern string) *RequestMatcher {
+	if err := pathpattern.Validate(pattern); err != nil {
+		panic("mock_http: path pattern " + pattern + ": " + err.Error())
+	}
+
+	return m.add("path", "matching "+pattern, func(v *requestView) result {
+		return result{got: v.req.URL.Path, ok: pathpattern.Match(pattern, v.req.URL.Path)}
+	})
+}
+
+// Query matches a query parameter, which must have the given values
+// in any order, and possibly others.  With no values the parameter
+// need only be present.
+func (m *RequestMatcher) Query(key string, values ...string) *RequestMatcher {
+	return m.add("query "+key, describeValues(values), func(v *requestView) result {
+		var got, ok = v.req.URL.Query()[key]
+		return compareValues(got, ok, values)
+	})
+}
+
+// Header matches a header, which must have the given values in any
+// order, and possibly others.  With no values the header need only be
+// present.
+func (m *RequestMatcher) Header(key string, values ...string) *RequestMatcher {
+	key = http.CanonicalHeaderKey(key)
+
+	return m.add("header "+key, describeValues(values), func(v *requestView) result {
+		var got, ok = v.req.Header[key]
+		return compareValues(got, ok, values)
+	})
+}
+
+// Form matches a form value, from either the query or a URL-encoded
+// or multipart body, as http.Request.Form does.  The value must have
+// the given values in any order, and possibly others.  With no values
+// it need only be present.
+func (m *RequestMatcher) Form(key string, values ...string) *RequestMatcher {
+	return m.add("form "+key, describeValues(values), func(v *requestView) result {
+		var form, err = v.form()
+		if err != nil {
+			return result{got: err.Error()}
+		}
+
+		var got, ok = form[key]
+		return compareValues(got, ok, values)
+	})
+}
+
+// Deadline matches whether the request's context has a deadline, so
+// a test can check that the code under test sets a timeout.
+func (m *RequestMatcher) Deadline(present bool) *RequestMatcher {
+	return m.add("deadline", describeDeadline(present), func(v *requestView) result {
+		var _, ok = v.req.Context().Deadline()
+		return result{got: describeDeadline(ok), ok: ok == present}
+	})
+}
+
+func describeDeadline(present bool) string {
+	if present {
+		return "set"
+	}
+	return "none"
+}
+
+// JSONOption configures how JSONBody compares bodies.
+type JSONOption func(*jsonMatch)
+
+type jsonMatch struct {
+	ignore [][]string
+}
+
+// IgnoreFields leaves fields out of the comparison.  Each field is a
+// dot-separated path, such as "user.id", in which "*" matches any
+// object key or array element, as in "items.*.created".
+func IgnoreFields(fields ...string) JSONOption {
+	return func(j *jsonMatch) {
+		for _, f := range fields {
+			j.ignore = append(j.ignore, strings.Split(f, "."))
+		}
+	}
+}
+
+// JSONBody matches a body that is JSON equal to want: the same values
+// regardless of formatting and key order.  Want is raw JSON if it is a
+// string, []byte or json.RawMessage, and otherwise a value encoded as
+// JSON.  It panics if want is not valid JSON or cannot be encoded.
+func (m *RequestMatcher) JSONBody(want any, options ...JSONOption) *RequestMatcher {
+	var j jsonMatch
+	for _, opt := range options {
+		opt(&j)
+	}
+
+	var raw []byte
+	switch w := want.(type) {
+	case string:
+		raw = []byte(w)
+	case []byte:
+		raw = w
+	case json.RawMessage:
+		raw = w
+	default:
+		var err error
+		if raw, err = json.Marshal(w); err != nil {
+			panic("mock_http: encoding JSON body: " + err.Error())
+		}
+	}
+
+	var expected any
+	if err := json.Unmarshal(raw, &expected); err != nil {
+		panic("mock_http: JSON body " + strconv.Quote(string(raw)) + ": " + err.Error())
+	}
+	expected = j.strip(expected)
+
+	return m.add("body", encodeJSON(expected), func(v *requestView) result {
+		var body, err = v.body()
+		if err != nil {
+			return result{got: err.Error()}
+		}
+
+		var got any
+		if err := json.Unmarshal(body, &got); err != nil {
+			return result{got: fmt.Sprintf("%q (%v)", body, err)}
+		}
+		got = j.strip(got)
+
+		if reflect.DeepEqual(expected, got) {
+			return result{got: encodeJSON(got), ok: true}
+		}
+
+		var r = result{got: encodeJSON(got)}
+		diffJSON("body", expected, got, &r.detail)
+		return r
+	})
+}
+
+// strip removes the ignored fields from v.
+func (j *jsonMatch) strip(v any) any {
+	for _, p := range j.ignore {
+		v = removeField(v, p)
+	}
+	return v
+}
+
+func removeField(v any, p []string) any {
+	if len(p) == 0 {
+		return v
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if p[0] != "*" && p[0] != k {
+				continue
+			}
+			if len(p) == 1 {
+				delete(v, k)
+			} else {
+				v[k] = removeField(e, p[1:])
+			}
+		}
+	case []any:
+		if len(p) == 1 {
+			if p[0] == "*" {
+				return []any{}
+			}
+			return v
+		}
+		for i, e := range v {
+			if p[0] == "*" || p[0] == strconv.Itoa(i) {
+				v[i] = removeField(e, p[1:])
+			}
+		}
+	}
+
+	return v
+}
+
+// diffJSON appends a line for each difference between want and got to
+// lines, identifying values by their path from name.
+func diffJSON(name string, want, got any, lines *[]string) {
+	switch w := want.(type) {
+	case map[string]any:
+		if g, ok := got.(map[string]any); ok {
+			var keys []string
+			for k := range w {
+				keys = append(keys, k)
+			}
+			for k := range g {
+				if _, ok := w[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			slices.Sort(keys)
+
+			for _, k := range keys {
+				var we, wok = w[k]
+				var ge, gok = g[k]
+				switch {
+				case !gok:
+					*lines = append(*lines, "- "+name+"."+k+": "+encodeJSON(we))
+				case !wok:
+					*lines = append(*lines, "+ "+name+"."+k+": "+encodeJSON(ge))
+				default:
+					diffJSON(name+"."+k, we, ge, lines)
+				}
+			}
+			return
+		}
+	case []any:
+		if g, ok := got.([]any); ok && len(g) == len(w) {
+			for i := range w {
+				diffJSON(name+"."+strconv.Itoa(i), w[i], g[i], lines)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		*lines = append(*lines,
+			"- "+name+": "+encodeJSON(want),
+			"+ "+name+": "+encodeJSON(got))
+	}
+}
+
+// encodeJSON returns v as compact JSON with sorted object keys.
+func encodeJSON(v any) string {
+	var data, _ = json.Marshal(v)
+	return string(data)
+}
+
+func describeValues(values []string) string {
+	if len(values) == 0 {
+		return "present"
+	}
+	return fmt.Sprintf("%q", values)
+}
+
+// compareValues reports whether got, which is present if ok, contains
+// want.
+func compareValues(got []string, ok bool, want []string) result {
+	if !ok {
+		return result{got: "absent"}
+	}
+
+	var r = result{got: fmt.Sprintf("%q", got), ok: true}
+	var rest = slices.Clone(got)
+	for _, v := range want {
+		var i = slices.Index(rest, v)
+		if i < 0 {
+			r.ok = false
+			break
+		}
+		rest = slices.Delete(rest, i, i+1)
+	}
+
+	return r
+}
+
+// Matches reports whether x is a request matching every part of m.
+func (m *RequestMatcher) Matches(x any) bool {
+	var v = newRequestView(x)
+	if v == nil {
+		return false
+	}
+
+	for _, c := range m.checks {
+		if !c.eval(v).ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String describes the requests m matches.
+func (m *RequestMatcher) String() string {
+	if len(m.checks) == 0 {
+		return "any request"
+	}
+
+	var parts []string
+	for _, c := range m.checks {
+		parts = append(parts, c.name+" "+c.want)
+	}
+
+	return "request with " + strings.Join(parts, ", ")
+}
+
+// Got describes x as a diff against the expected request.
+func (m *RequestMatcher) Got(x any) string {
+	var v = newRequestView(x)
+	if v == nil {
+		return fmt.Sprintf("%v (%T), not a request", x, x)
+	}
+
+	var lines = []string{v.req.Method + " " + v.req.URL.String()}
+	for _, c := range m.checks {
+		var r = c.eval(v)
+		switch {
+		case r.ok:
+			lines = append(lines, "  "+c.name+": "+r.got)
+		case r.detail != nil:
+			lines = append(lines, r.detail...)
+		default:
+			lines = append(lines, "- "+c.name+": "+c.want, "+ "+c.name+": "+r.got)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// requestView is a request being matched, with its body and form read
+// at most once.
+type requestView struct {
+	req *http.Request
+
+	data    []byte
+	dataErr error
+	read    bool
+
+	values    url.Values
+	valuesErr error
+	parsed    bool
+}
+
+func newRequestView(x any) *requestView {
+	var req *http.Request
+	switch r := x.(type) {
+	case clienti.Request:
+		if r != nil {
+			req = r.RealRequest()
+		}
+	case *http.Request:
+		req = r
+	}
+
+	if req == nil {
+		return nil
+	}
+
+	return &requestView{req: req}
+}
+
+// body returns the request body.  It uses GetBody if it can, and
+// otherwise reads the body and replaces it with a copy.
+func (v *requestView) body() ([]byte, error) {
+	if v.read {
+		return v.data, v.dataErr
+	}
+	v.read = true
+
+	var req = v.req
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case req.GetBody != nil:
+		var rc, err = req.GetBody()
+		if err != nil {
+			v.dataErr = err
+			break
+		}
+		v.data, v.dataErr = io.ReadAll(rc)
+		rc.Close()
+	default:
+		v.data, v.dataErr = io.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(v.data))
+	}
+
+	if v.dataErr != nil {
+		v.dataErr = fmt.Errorf("reading body: %w", v.dataErr)
+	}
+
+	return v.data, v.dataErr
+}
+
+// form returns the form values from the query and body.
+func (v *requestView) form() (url.Values, error) {
+	if v.parsed {
+		return v.values, v.valuesErr
+	}
+	v.parsed = true
+
+	var data, err = v.body()
+	if err != nil {
+		v.valuesErr = err
+		return nil, err
+	}
+
+	var r = v.req.Clone(v.req.Context())
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	r.Form, r.PostForm, r.MultipartForm = nil, nil, nil
+
+	err = r.ParseMultipartForm(maxFormMemory)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		v.valuesErr = fmt.Errorf("parsing form: %w", err)
+		return nil, v.valuesErr
+	}
+	v.values = r.Form
+
+	return v.values, nil
+}
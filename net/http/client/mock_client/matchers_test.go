@@ -0,0 +1,181 @@
+package mock_http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+	"go.uber.org/mock/gomock"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// newRequest returns a request with a JSON body for the tests.
+func newRequest(t *testing.T, method, u, body string, options ...clienti.RequestOption) clienti.Request {
+	t.Helper()
+
+	req, err := clienti.NewHTTP().NewRequest(method, u, strings.NewReader(body), options...)
+	testutil.AssertNil(t, err)
+
+	return req
+}
+
+// TestMatchRequest_Any tests a matcher with no parts.
+func TestMatchRequest_Any(t *testing.T) {
+	m := MatchRequest()
+
+	testutil.AssertEqual(t, true, m.Matches(newRequest(t, "GET", "https://example.com/", "")))
+	testutil.AssertEqual(t, false, m.Matches(nil))
+	testutil.AssertEqual(t, false, m.Matches("GET /"))
+	testutil.AssertEqual(t, "any request", m.String())
+}
+
+// TestMatchRequest_URL tests matching the method, URL, path and query.
+func TestMatchRequest_URL(t *testing.T) {
+	req := newRequest(t, "GET", "https://example.com/users/42/posts?page=2&tag=a&tag=b", "")
+
+	testutil.AssertEqual(t, true, MatchRequest().Method("GET").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Method("POST").Matches(req))
+
+	testutil.AssertEqual(t, true, MatchRequest().URL("https://example.com/users/42/posts?page=2&tag=a&tag=b").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().URL("https://example.com/users/42/posts").Matches(req))
+
+	testutil.AssertEqual(t, true, MatchRequest().Path("/users/*/posts").Matches(req))
+	testutil.AssertEqual(t, true, MatchRequest().Path("/users/**").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Path("/users/*").Matches(req))
+
+	testutil.AssertEqual(t, true, MatchRequest().Query("page", "2").Matches(req))
+	testutil.AssertEqual(t, true, MatchRequest().Query("tag", "b", "a").Matches(req))
+	testutil.AssertEqual(t, true, MatchRequest().Query("tag").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Query("tag", "c").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Query("sort").Matches(req))
+
+	testutil.AssertPanic(t, func() { MatchRequest().Path("/[") }, "malformed path pattern")
+}
+
+// TestMatchRequest_Header tests matching headers.
+func TestMatchRequest_Header(t *testing.T) {
+	req := newRequest(t, "GET", "https://example.com/", "",
+		clienti.WithHeader("Authorization", "Bearer token"),
+		clienti.WithHeader("Accept", "text/html", "application/json"))
+
+	testutil.AssertEqual(t, true, MatchRequest().Header("authorization", "Bearer token").Matches(req))
+	testutil.AssertEqual(t, true, MatchRequest().Header("Accept", "application/json").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Header("Authorization", "Bearer other").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Header("X-Api-Key").Matches(req))
+}
+
+// TestMatchRequest_JSONBody tests matching JSON bodies.
+func TestMatchRequest_JSONBody(t *testing.T) {
+	req := newRequest(t, "POST", "https://example.com/users",
+		`{"name": "gopher", "id": 7, "tags": [{"name": "a", "at": 1}, {"name": "b", "at": 2}]}`)
+
+	testutil.AssertEqual(t, true, MatchRequest().JSONBody(
+		`{"tags":[{"at":1,"name":"a"},{"at":2,"name":"b"}],"id":7,"name":"gopher"}`).Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().JSONBody(`{"name":"gopher"}`).Matches(req))
+	testutil.AssertEqual(t, true, MatchRequest().JSONBody(
+		map[string]any{"name": "gopher", "tags": []map[string]string{{"name": "a"}, {"name": "b"}}},
+		IgnoreFields("id", "tags.*.at")).Matches(req))
+	testutil.AssertEqual(t, true, MatchRequest().JSONBody(`{"name":"gopher"}`, IgnoreFields("id", "tags")).Matches(req))
+
+	testutil.AssertEqual(t, false, MatchRequest().JSONBody(`{}`).Matches(newRequest(t, "POST", "https://example.com/", "not json")))
+
+	testutil.AssertPanic(t, func() { MatchRequest().JSONBody(`{`) }, "invalid JSON")
+}
+
+// TestMatchRequest_Body tests that matching leaves the body to be
+// read by the code under test.
+func TestMatchRequest_Body(t *testing.T) {
+	req := newRequest(t, "POST", "https://example.com/", `{"a":1}`,
+		clienti.WithBody(io.NopCloser(bytes.NewBufferString(`{"a":1}`))),
+		clienti.WithGetBody(nil))
+
+	m := MatchRequest().JSONBody(`{"a":1}`)
+	testutil.AssertEqual(t, true, m.Matches(req))
+	testutil.AssertEqual(t, true, m.Matches(req))
+
+	data, err := io.ReadAll(req.RealRequest().Body)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `{"a":1}`, string(data))
+}
+
+// TestMatchRequest_Form tests matching URL-encoded and multipart form
+// values.
+func TestMatchRequest_Form(t *testing.T) {
+	form := url.Values{"user": {"gopher"}, "role": {"admin", "dev"}}
+	req := newRequest(t, "POST", "https://example.com/login?next=/home", form.Encode(),
+		clienti.WithHeader("Content-Type", "application/x-www-form-urlencoded"))
+
+	testutil.AssertEqual(t, true, MatchRequest().Form("user", "gopher").Form("role", "dev").Form("next", "/home").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Form("user", "other").Matches(req))
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	w.WriteField("user", "gopher")
+	w.Close()
+	req = newRequest(t, "POST", "https://example.com/upload", buf.String(),
+		clienti.WithHeader("Content-Type", w.FormDataContentType()))
+
+	testutil.AssertEqual(t, true, MatchRequest().Form("user", "gopher").Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Form("file").Matches(req))
+}
+
+// TestMatchRequest_Deadline tests matching the presence of a context
+// deadline.
+func TestMatchRequest_Deadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	req, _ := clienti.NewHTTP().NewRequestWithContext(ctx, "GET", "https://example.com/", nil)
+	testutil.AssertEqual(t, true, MatchRequest().Deadline(true).Matches(req))
+	testutil.AssertEqual(t, false, MatchRequest().Deadline(false).Matches(req))
+
+	req, _ = clienti.NewHTTP().NewRequest("GET", "https://example.com/", nil)
+	testutil.AssertEqual(t, false, MatchRequest().Deadline(true).Matches(req))
+	testutil.AssertEqual(t, true, MatchRequest().Deadline(false).Matches(req.RealRequest()))
+}
+
+// TestMatchRequest_Messages tests the descriptions gomock reports for
+// a mismatch.
+func TestMatchRequest_Messages(t *testing.T) {
+	m := MatchRequest().
+		Method("POST").
+		Path("/users/*").
+		Header("X-Api-Key").
+		JSONBody(`{"name":"gopher","admin":false}`)
+	req := newRequest(t, "POST", "https://example.com/users/1", `{"name":"bob","extra":1}`)
+
+	testutil.AssertEqual(t, `request with method POST, path matching /users/*, header X-Api-Key present, body {"admin":false,"name":"gopher"}`, m.String())
+	testutil.AssertEqual(t, strings.Join([]string{
+		"POST https://example.com/users/1",
+		"  method: POST",
+		"  path: /users/1",
+		"- header X-Api-Key: present",
+		"+ header X-Api-Key: absent",
+		"- body.admin: false",
+		"+ body.extra: 1",
+		"- body.name: \"gopher\"",
+		"+ body.name: \"bob\"",
+	}, "\n"), m.Got(req))
+}
+
+// TestMatchRequest_Mock tests the matcher with a MockClient.
+func TestMatchRequest_Mock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	client := NewMockClient(ctrl)
+
+	client.EXPECT().Do(MatchRequest().Method("DELETE").Path("/users/*")).Return(nil, http.ErrHandlerTimeout)
+	client.EXPECT().Do(MatchRequest().Method("GET")).Return(nil, nil)
+
+	_, err := client.Do(newRequest(t, "GET", "https://example.com/users/1", ""))
+	testutil.AssertNil(t, err)
+	_, err = client.Do(newRequest(t, "DELETE", "https://example.com/users/1", ""))
+	testutil.AssertEqual(t, http.ErrHandlerTimeout, err)
+}
@@ -0,0 +1,279 @@
+package mock_http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+	"go.uber.org/mock/gomock"
+)
+
+var errReadOnClosedBody = errors.New("http: read on closed response body")
+
+// ResponseBuilder builds a MockResponse whose accessors all return
+// fixed values, instead of setting up an expectation for each one:
+//
+//	resp := mock_http.NewResponse(ctrl).
+//		Status(404).
+//		JSON(map[string]string{"error": "not found"}).
+//		Header("X-Request-Id", "abc").
+//		Build()
+//
+// The response defaults to 200 OK over HTTP/1.1 with an empty body.
+type ResponseBuilder struct {
+	ctrl *gomock.Controller
+	resp http.Response
+	body []byte
+
+	contentLength *int64
+}
+
+// NewResponse returns a builder for a MockResponse on ctrl.
+func NewResponse(ctrl *gomock.Controller) *ResponseBuilder {
+	return &ResponseBuilder{
+		ctrl: ctrl,
+		resp: http.Response{
+			Status:     "200 OK",
+			StatusCode: http.StatusOK,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{},
+		},
+	}
+}
+
+// Status sets the status code, and the status text to match.
+func (b *ResponseBuilder) Status(code int) *ResponseBuilder {
+	b.resp.StatusCode = code
+	if text := http.StatusText(code); text != "" {
+		b.resp.Status = strconv.Itoa(code) + " " + text
+	} else {
+		b.resp.Status = fmt.Sprintf("%03d status code %d", code, code)
+	}
+
+	return b
+}
+
+// Header adds a header value.
+func (b *ResponseBuilder) Header(key, value string) *ResponseBuilder {
+	b.resp.Header.Add(key, value)
+
+	return b
+}
+
+// Cookie adds a Set-Cookie header for c.
+func (b *ResponseBuilder) Cookie(c *http.Cookie) *ResponseBuilder {
+	return b.Header("Set-Cookie", c.String())
+}
+
+// Trailer adds a trailer value.
+func (b *ResponseBuilder) Trailer(key, value string) *ResponseBuilder {
+	if b.resp.Trailer == nil {
+		b.resp.Trailer = http.Header{}
+	}
+	b.resp.Trailer.Add(key, value)
+
+	return b
+}
+
+// Body sets the body.
+func (b *ResponseBuilder) Body(body []byte) *ResponseBuilder {
+	b.body = bytes.Clone(body)
+
+	return b
+}
+
+// String sets the body to s.
+func (b *ResponseBuilder) String(s string) *ResponseBuilder {
+	return b.Body([]byte(s))
+}
+
+// JSON sets the body to v encoded as JSON, and the Content-Type to
+// application/json unless it has already been set.  It fails the test
+// if v cannot be encoded.
+func (b *ResponseBuilder) JSON(v any) *ResponseBuilder {
+	var data, err = json.Marshal(v)
+	if err != nil {
+		b.ctrl.T.Helper()
+		b.ctrl.T.Fatalf("mock_http: encoding response body: %v", err)
+		return b
+	}
+
+	if b.resp.Header.Get("Content-Type") == "" {
+		b.resp.Header.Set("Content-Type", "application/json")
+	}
+
+	return b.Body(data)
+}
+
+// ContentLength overrides the content length, which is otherwise the
+// length of the body.
+func (b *ResponseBuilder) ContentLength(n int64) *ResponseBuilder {
+	b.contentLength = &n
+
+	return b
+}
+
+// Proto sets the protocol version, such as 2, 0 for HTTP/2.0.
+func (b *ResponseBuilder) Proto(major, minor int) *ResponseBuilder {
+	b.resp.ProtoMajor, b.resp.ProtoMinor = major, minor
+	b.resp.Proto = "HTTP/" + strconv.Itoa(major) + "." + strconv.Itoa(minor)
+
+	return b
+}
+
+// Close sets whether the connection is to be closed after the
+// response.
+func (b *ResponseBuilder) Close(v bool) *ResponseBuilder {
+	b.resp.Close = v
+
+	return b
+}
+
+// TransferEncoding sets the transfer encodings.
+func (b *ResponseBuilder) TransferEncoding(encodings ...string) *ResponseBuilder {
+	b.resp.TransferEncoding = encodings
+
+	return b
+}
+
+// Uncompressed sets whether the body was transparently decompressed.
+func (b *ResponseBuilder) Uncompressed(v bool) *ResponseBuilder {
+	b.resp.Uncompressed = v
+
+	return b
+}
+
+// Request sets the request the response answers, which Location uses
+// to resolve relative redirects.
+func (b *ResponseBuilder) Request(req clienti.Request) *ResponseBuilder {
+	b.resp.Request = req.RealRequest()
+
+	return b
+}
+
+// TLS sets the TLS connection state.
+func (b *ResponseBuilder) TLS(state *tls.ConnectionState) *ResponseBuilder {
+	b.resp.TLS = state
+
+	return b
+}
+
+// Build returns a MockResponse with an AnyTimes expectation for every
+// method.  Every call to Body returns the same stream, which must be
+// closed by the end of the test: if the controller's test reporter
+// supports Cleanup, as *testing.T does, the test fails otherwise.
+func (b *ResponseBuilder) Build() *MockResponse {
+	var m = NewMockResponse(b.ctrl)
+	var resp = b.resp
+	var data = b.body
+	var body = &responseBody{r: bytes.NewReader(data)}
+
+	var length = int64(len(data))
+	if b.contentLength != nil {
+		length = *b.contentLength
+	}
+	resp.ContentLength = length
+
+	var request clienti.Request
+	if resp.Request != nil {
+		request = wrappedRequest{resp.Request}
+	}
+
+	// real returns a fresh *http.Response for the methods that read
+	// the body or might change the response.
+	var real = func() *http.Response {
+		var r = resp
+		r.Header = resp.Header.Clone()
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		return &r
+	}
+
+	var e = m.EXPECT()
+	e.Body().Return(body).AnyTimes()
+	e.Close().Return(resp.Close).AnyTimes()
+	e.ContentLength().Return(length).AnyTimes()
+	e.Cookies().DoAndReturn(func() []*http.Cookie { return real().Cookies() }).AnyTimes()
+	e.Header().Return(resp.Header).AnyTimes()
+	e.Location().DoAndReturn(func() (*url.URL, error) { return real().Location() }).AnyTimes()
+	e.Proto().Return(resp.Proto).AnyTimes()
+	e.ProtoAtLeast(gomock.Any(), gomock.Any()).DoAndReturn(func(major, minor int) bool {
+		return resp.ProtoAtLeast(major, minor)
+	}).AnyTimes()
+	e.ProtoMajor().Return(resp.ProtoMajor).AnyTimes()
+	e.ProtoMinor().Return(resp.ProtoMinor).AnyTimes()
+	e.Request().Return(request).AnyTimes()
+	e.Status().Return(resp.Status).AnyTimes()
+	e.StatusCode().Return(resp.StatusCode).AnyTimes()
+	e.TLS().Return(resp.TLS).AnyTimes()
+	e.Trailer().Return(resp.Trailer).AnyTimes()
+	e.TransferEncoding().Return(resp.TransferEncoding).AnyTimes()
+	e.Uncompressed().Return(resp.Uncompressed).AnyTimes()
+	e.Write(gomock.Any()).DoAndReturn(func(w io.Writer) error { return real().Write(w) }).AnyTimes()
+
+	if c, ok := b.ctrl.T.(interface{ Cleanup(func()) }); ok {
+		var t = b.ctrl.T
+		c.Cleanup(func() {
+			t.Helper()
+			if !body.isClosed() {
+				t.Errorf("mock_http: response body (status %q) was not closed", resp.Status)
+			}
+		})
+	}
+
+	return m
+}
+
+// wrappedRequest is a clienti.Request for the request a built response
+// answers.
+type wrappedRequest struct {
+	req *http.Request
+}
+
+func (r wrappedRequest) Write(w io.Writer) error      { return r.req.Write(w) }
+func (r wrappedRequest) WriteProxy(w io.Writer) error { return r.req.WriteProxy(w) }
+func (r wrappedRequest) RealRequest() *http.Request   { return r.req }
+
+// responseBody is the body of a built response.  Reading it after
+// Close fails, as it does for a real response body.
+type responseBody struct {
+	mu     sync.Mutex
+	r      *bytes.Reader
+	closed bool
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, errReadOnClosedBody
+	}
+
+	return b.r.Read(p)
+}
+
+func (b *responseBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	return nil
+}
+
+func (b *responseBody) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.closed
+}
@@ -0,0 +1,124 @@
+package mock_http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	clienti "github.com/pdutton/go-interfaces/net/http/client"
+	"go.uber.org/mock/gomock"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// reporter is a gomock test reporter that records failures and
+// cleanups instead of acting on them.
+type reporter struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (r *reporter) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+func (r *reporter) Fatalf(format string, args ...any) { r.Errorf(format, args...) }
+func (r *reporter) Helper()                           {}
+func (r *reporter) Cleanup(f func())                  { r.cleanups = append(r.cleanups, f) }
+
+func (r *reporter) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+// TestNewResponse_Defaults tests an unconfigured response.
+func TestNewResponse_Defaults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	resp := NewResponse(ctrl).Build()
+	defer resp.Body().Close()
+
+	testutil.AssertEqual(t, 200, resp.StatusCode())
+	testutil.AssertEqual(t, "200 OK", resp.Status())
+	testutil.AssertEqual(t, "HTTP/1.1", resp.Proto())
+	testutil.AssertEqual(t, true, resp.ProtoAtLeast(1, 0))
+	testutil.AssertEqual(t, int64(0), resp.ContentLength())
+	testutil.AssertNil(t, resp.Request())
+}
+
+// TestNewResponse_JSON tests a JSON error response.
+func TestNewResponse_JSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	resp := NewResponse(ctrl).
+		Status(404).
+		JSON(map[string]string{"error": "not found"}).
+		Header("X", "y").
+		Build()
+
+	testutil.AssertEqual(t, 404, resp.StatusCode())
+	testutil.AssertEqual(t, "404 Not Found", resp.Status())
+	testutil.AssertEqual(t, "y", resp.Header().Get("X"))
+	testutil.AssertEqual(t, "application/json", resp.Header().Get("Content-Type"))
+	testutil.AssertEqual(t, int64(21), resp.ContentLength())
+
+	body := resp.Body()
+	data, err := io.ReadAll(body)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `{"error":"not found"}`, string(data))
+	testutil.AssertNil(t, body.Close())
+
+	_, err = resp.Body().Read(make([]byte, 1))
+	testutil.AssertEqual(t, errReadOnClosedBody, err)
+}
+
+// TestNewResponse_Extras tests cookies, trailers, redirects and Write.
+func TestNewResponse_Extras(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	req, _ := clienti.NewHTTP().NewRequest("GET", "https://example.com/a/b", nil)
+	resp := NewResponse(ctrl).
+		Status(302).
+		Header("Location", "../c").
+		Cookie(&http.Cookie{Name: "id", Value: "42"}).
+		Trailer("X-Sum", "abc").
+		String("moved").
+		Request(req).
+		Build()
+	defer resp.Body().Close()
+
+	testutil.AssertEqual(t, "42", resp.Cookies()[0].Value)
+	testutil.AssertEqual(t, "abc", resp.Trailer().Get("X-Sum"))
+	testutil.AssertEqual(t, "GET", resp.Request().RealRequest().Method)
+
+	loc, err := resp.Location()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "https://example.com/c", loc.String())
+
+	var buf bytes.Buffer
+	testutil.AssertNil(t, resp.Write(&buf))
+	testutil.AssertEqual(t, true, strings.HasPrefix(buf.String(), "HTTP/1.1 302 Found\r\n"))
+	testutil.AssertEqual(t, true, strings.Contains(buf.String(), "moved"))
+
+	// Writing does not consume the body.
+	data, _ := io.ReadAll(resp.Body())
+	testutil.AssertEqual(t, "moved", string(data))
+}
+
+// TestNewResponse_Unclosed tests the check that the body is closed.
+func TestNewResponse_Unclosed(t *testing.T) {
+	r := &reporter{}
+	ctrl := gomock.NewController(r)
+
+	resp := NewResponse(ctrl).Status(500).Build()
+	resp.Body()
+	r.finish()
+	testutil.AssertEqual(t, 1, len(r.errors))
+	testutil.AssertEqual(t, `mock_http: response body (status "500 Internal Server Error") was not closed`, r.errors[0])
+
+	r = &reporter{}
+	ctrl = gomock.NewController(r)
+	NewResponse(ctrl).Build().Body().Close()
+	r.finish()
+	testutil.AssertEqual(t, 0, len(r.errors))
+}
@@ -0,0 +1,270 @@
+// Package fake_server provides an implementation of the go-interfaces
+// http server Server interface whose lifecycle a test can observe.
+//
+// The server is a real *http.Server, so ListenAndServe blocks until
+// Shutdown or Close and then returns http.ErrServerClosed, and Shutdown
+// waits for requests in flight until its context is done, as they do
+// in production.  It listens with a Listener, such as a fake_net Host
+// or Loopback, and reports when it is listening, how many requests are
+// in flight and when the shutdown hooks have run, so graceful shutdown
+// can be tested without sleeping:
+//
+//	srv := fake_server.New(host, serveri.WithAddr(":80"), serveri.WithHandler(h))
+//	go srv.ListenAndServe()
+//	<-srv.Listening()
+//
+//	// start a slow request, then
+//	srv.WaitInFlight(ctx, 1)
+//	err := srv.Shutdown(ctx)
+package fake_server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+
+	serveri "github.com/pdutton/go-interfaces/net/http/server"
+)
+
+// Listener opens the listeners a Server serves on.  A fake_net Host
+// is a Listener.
+type Listener interface {
+	Listen(network, address string) (net.Listener, error)
+}
+
+// Loopback is a Listener on the real loopback interface.
+var Loopback Listener = loopback{}
+
+type loopback struct{}
+
+// Listen listens on address, on 127.0.0.1 if it has no host and on a
+// free port if it is empty.
+func (loopback) Listen(network, address string) (net.Listener, error) {
+	if address == "" {
+		return net.Listen(network, "127.0.0.1:0")
+	}
+
+	var host, port, err = net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	return net.Listen(network, net.JoinHostPort(host, port))
+}
+
+// Server is a fake implementation of the go-interfaces http Server
+// interface.
+type Server struct {
+	server   *http.Server
+	listener Listener
+
+	mu        sync.Mutex
+	addr      net.Addr
+	listening chan struct{}
+	inFlight  int
+	served    int
+	hooks     int
+	hooksRun  int
+	changed   chan struct{}
+}
+
+var _ serveri.Server = (*Server)(nil)
+
+// New returns a Server that listens with l.  The options configure
+// the underlying *http.Server as they do for a real server.  Without
+// an address, ListenAndServe listens on ":http", as a real server
+// does, except on Loopback.
+func New(l Listener, options ...serveri.ServerOption) *Server {
+	var srv http.Server
+	for _, opt := range options {
+		opt(&srv)
+	}
+
+	var s = &Server{
+		server:    &srv,
+		listener:  l,
+		listening: make(chan struct{}),
+		changed:   make(chan struct{}),
+	}
+
+	var h = srv.Handler
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.update(func() { s.inFlight++ })
+		defer s.update(func() {
+			s.inFlight--
+			s.served++
+		})
+
+		h.ServeHTTP(w, r)
+	})
+
+	return s
+}
+
+// update changes the server's state with f and wakes anything waiting
+// on it.
+func (s *Server) update(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f()
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// wait waits until cond, called with the lock held, is true or ctx is
+// done.
+func (s *Server) wait(ctx context.Context, cond func() bool) error {
+	for {
+		s.mu.Lock()
+		var ok, changed = cond(), s.changed
+		s.mu.Unlock()
+
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ListenAndServe listens on the server's address and serves requests
+// until Shutdown or Close, then returns http.ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	var l, err = s.listen()
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// ListenAndServeTLS is like ListenAndServe for HTTPS connections.
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	var l, err = s.listen()
+	if err != nil {
+		return err
+	}
+
+	return s.ServeTLS(l, certFile, keyFile)
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	var addr = s.server.Addr
+	if addr == "" && s.listener != Loopback {
+		addr = ":http"
+	}
+
+	return s.listener.Listen("tcp", addr)
+}
+
+// Serve serves requests on l until Shutdown or Close, then returns
+// http.ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.started(l)
+
+	return s.server.Serve(l)
+}
+
+// ServeTLS is like Serve for HTTPS connections.
+func (s *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
+	s.started(l)
+
+	return s.server.ServeTLS(l, certFile, keyFile)
+}
+
+func (s *Server) started(l net.Listener) {
+	s.update(func() {
+		if s.addr == nil {
+			s.addr = l.Addr()
+			close(s.listening)
+		}
+	})
+}
+
+// Shutdown stops the server gracefully: it closes the listeners,
+// starts the shutdown hooks, and waits for the requests in flight to
+// finish.  If ctx is done first it returns the context's error, and
+// the requests are left running.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// Close stops the server immediately, closing the listeners and every
+// connection.  It does not run the shutdown hooks.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// RegisterOnShutdown adds a hook for Shutdown to start, in its own
+// goroutine.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.update(func() { s.hooks++ })
+
+	s.server.RegisterOnShutdown(func() {
+		defer s.update(func() { s.hooksRun++ })
+
+		f()
+	})
+}
+
+// SetKeepAlivesEnabled controls whether connections are kept alive
+// between requests.
+func (s *Server) SetKeepAlivesEnabled(v bool) {
+	s.server.SetKeepAlivesEnabled(v)
+}
+
+// Listening returns a channel that is closed once the server starts
+// serving on a listener.
+func (s *Server) Listening() <-chan struct{} {
+	return s.listening
+}
+
+// Addr returns the address of the first listener the server served
+// on, or nil if it has not started.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addr
+}
+
+// InFlight returns the number of requests being handled.
+func (s *Server) InFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.inFlight
+}
+
+// Served returns the number of requests that have been handled.
+func (s *Server) Served() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.served
+}
+
+// WaitInFlight waits until exactly n requests are being handled, or
+// ctx is done.
+func (s *Server) WaitInFlight(ctx context.Context, n int) error {
+	return s.wait(ctx, func() bool { return s.inFlight == n })
+}
+
+// WaitShutdownHooks waits until every hook registered with
+// RegisterOnShutdown has returned, or ctx is done.  The hooks only run
+// once Shutdown has been called.
+func (s *Server) WaitShutdownHooks(ctx context.Context) error {
+	return s.wait(ctx, func() bool { return s.hooksRun == s.hooks })
+}
@@ -0,0 +1,168 @@
+package fake_server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	serveri "github.com/pdutton/go-interfaces/net/http/server"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+	"github.com/pdutton/go-mocks/net/fake_net"
+)
+
+// virtualServer returns a Server for h on a virtual host, and a client
+// on another host of the same network.
+func virtualServer(t *testing.T, h http.Handler) (*Server, *http.Client) {
+	t.Helper()
+
+	nw := fake_net.NewNetwork()
+	server := nw.NewHost("server", "10.0.0.1")
+	client := nw.NewHost("client", "10.0.0.2")
+
+	srv := New(server, serveri.WithHandler(h))
+	t.Cleanup(func() { srv.Close() })
+
+	return srv, &http.Client{Transport: &http.Transport{DialContext: client.NewDialer().DialContext}}
+}
+
+// serve runs ListenAndServe in the background, returning a channel for
+// its result once the server is listening.
+func serve(srv *Server) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- srv.ListenAndServe() }()
+	<-srv.Listening()
+
+	return done
+}
+
+// TestServer_ListenAndServe tests serving until Shutdown.
+func TestServer_ListenAndServe(t *testing.T) {
+	srv, client := virtualServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	testutil.AssertNil(t, srv.Addr())
+
+	done := serve(srv)
+	testutil.AssertEqual(t, "[::]:80", srv.Addr().String())
+
+	resp, err := client.Get("http://10.0.0.1/")
+	testutil.AssertNil(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	testutil.AssertEqual(t, "hello", string(data))
+	testutil.AssertEqual(t, 1, srv.Served())
+
+	select {
+	case err := <-done:
+		t.Fatalf("ListenAndServe returned early: %v", err)
+	default:
+	}
+
+	testutil.AssertNil(t, srv.Shutdown(context.Background()))
+	testutil.AssertEqual(t, http.ErrServerClosed, <-done)
+	testutil.AssertEqual(t, http.ErrServerClosed, srv.ListenAndServe())
+}
+
+// TestServer_GracefulShutdown tests Shutdown waiting for a request in
+// flight, and giving up at its deadline.
+func TestServer_GracefulShutdown(t *testing.T) {
+	release := make(chan struct{})
+	srv, client := virtualServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		io.WriteString(w, "finished")
+	}))
+	done := serve(srv)
+
+	result := make(chan string, 1)
+	go func() {
+		resp, err := client.Get("http://10.0.0.1/slow")
+		if err != nil {
+			result <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		result <- string(data)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	testutil.AssertNil(t, srv.WaitInFlight(ctx, 1))
+	testutil.AssertEqual(t, 1, srv.InFlight())
+
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	testutil.AssertEqual(t, context.DeadlineExceeded, srv.Shutdown(short))
+	testutil.AssertEqual(t, http.ErrServerClosed, <-done)
+	testutil.AssertEqual(t, 1, srv.InFlight())
+
+	close(release)
+	testutil.AssertNil(t, srv.WaitInFlight(ctx, 0))
+	testutil.AssertEqual(t, "finished", <-result)
+	testutil.AssertNil(t, srv.Shutdown(ctx))
+	testutil.AssertEqual(t, 1, srv.Served())
+}
+
+// TestServer_Hooks tests running the shutdown hooks.
+func TestServer_Hooks(t *testing.T) {
+	srv, _ := virtualServer(t, http.NotFoundHandler())
+	done := serve(srv)
+
+	ran := make(chan string, 2)
+	srv.RegisterOnShutdown(func() { ran <- "a" })
+	srv.RegisterOnShutdown(func() { ran <- "b" })
+
+	short, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	testutil.AssertEqual(t, context.DeadlineExceeded, srv.WaitShutdownHooks(short))
+
+	testutil.AssertNil(t, srv.Shutdown(context.Background()))
+	testutil.AssertNil(t, srv.WaitShutdownHooks(context.Background()))
+	testutil.AssertEqual(t, 2, len(ran))
+	testutil.AssertEqual(t, http.ErrServerClosed, <-done)
+}
+
+// TestServer_Close tests closing a server with a request in flight.
+func TestServer_Close(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv, client := virtualServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	done := serve(srv)
+
+	failed := make(chan error, 1)
+	go func() {
+		_, err := client.Get("http://10.0.0.1/")
+		failed <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	testutil.AssertNil(t, srv.WaitInFlight(ctx, 1))
+
+	testutil.AssertNil(t, srv.Close())
+	testutil.AssertEqual(t, http.ErrServerClosed, <-done)
+	testutil.AssertNotNil(t, <-failed)
+}
+
+// TestServer_Loopback tests serving on the real loopback interface.
+func TestServer_Loopback(t *testing.T) {
+	srv := New(Loopback, serveri.WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "loopback")
+	})))
+	done := serve(srv)
+
+	resp, err := http.Get("http://" + srv.Addr().String() + "/")
+	testutil.AssertNil(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	testutil.AssertEqual(t, "loopback", string(data))
+
+	testutil.AssertNil(t, srv.Shutdown(context.Background()))
+	testutil.AssertEqual(t, true, errors.Is(<-done, http.ErrServerClosed))
+}
@@ -0,0 +1,395 @@
+package fake_exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+
+	execi "github.com/pdutton/go-interfaces/os/exec"
+
+	"github.com/pdutton/go-mocks/internal/procstate"
+)
+
+// Cmd is a simulated command.  It follows the same rules as
+// exec.Cmd: it can be started once, Wait must be called exactly
+// once, and the pipe methods must be used before Start.
+type Cmd struct {
+	exec *Exec
+	name string
+	path string
+	args []string
+	env  []string
+	dir  string
+	err  error
+
+	mu     sync.Mutex
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	started bool
+	waited  bool
+	done    chan struct{}
+	code    int
+	pid     int
+	state   *os.ProcessState
+
+	// Pipes created by the *Pipe methods.  The parent ends are
+	// closed by Wait, the child ends when the handler returns.
+	parentEnds []io.Closer
+	childEnds  []io.Closer
+
+	// Set by Output so that Wait can fill in ExitError.Stderr.
+	capturedStderr *bytes.Buffer
+}
+
+var _ execi.Cmd = (*Cmd)(nil)
+
+func (c *Cmd) Path() string {
+	return c.path
+}
+
+func (c *Cmd) Args() []string {
+	return c.args
+}
+
+func (c *Cmd) Env() []string {
+	return c.env
+}
+
+func (c *Cmd) Dir() string {
+	return c.dir
+}
+
+func (c *Cmd) Stdin() io.Reader {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stdin
+}
+
+func (c *Cmd) Stdout() io.Writer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stdout
+}
+
+func (c *Cmd) Stderr() io.Writer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stderr
+}
+
+// Process always returns nil; there is no operating system process
+// behind a fake command.
+func (c *Cmd) Process() *os.Process {
+	return nil
+}
+
+// ProcessState returns the state of the simulated process once Wait
+// has returned.
+func (c *Cmd) ProcessState() *os.ProcessState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
+
+// Environ returns the environment the handler will see: Env, or the
+// inherited environment if Env is nil, with PWD set to Dir.
+func (c *Cmd) Environ() []string {
+	var env = c.env
+	if env == nil {
+		env = c.exec.environ()
+	}
+	env = append([]string{}, env...)
+
+	if c.dir != "" && path.IsAbs(c.dir) {
+		env = append(env, "PWD="+c.dir)
+	}
+
+	return dedupEnv(env)
+}
+
+// dedupEnv removes duplicate keys, keeping the last value, as
+// exec.Cmd does.
+func dedupEnv(env []string) []string {
+	var seen = map[string]bool{}
+	var out = make([]string, 0, len(env))
+
+	for i := len(env) - 1; i >= 0; i-- {
+		var k, _, _ = strings.Cut(env[i], "=")
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, env[i])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return out
+}
+
+func (c *Cmd) String() string {
+	var b strings.Builder
+	b.WriteString(c.path)
+	for _, a := range c.args[1:] {
+		b.WriteByte(' ')
+		b.WriteString(a)
+	}
+
+	return b.String()
+}
+
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+
+	return c.Wait()
+}
+
+func (c *Cmd) Output() ([]byte, error) {
+	c.mu.Lock()
+	if c.stdout != nil {
+		c.mu.Unlock()
+		return nil, errors.New("exec: Stdout already set")
+	}
+	var stdout bytes.Buffer
+	c.stdout = &stdout
+	if c.stderr == nil {
+		c.capturedStderr = &bytes.Buffer{}
+		c.stderr = c.capturedStderr
+	}
+	c.mu.Unlock()
+
+	var err = c.Run()
+
+	return stdout.Bytes(), err
+}
+
+func (c *Cmd) CombinedOutput() ([]byte, error) {
+	c.mu.Lock()
+	if c.stdout != nil {
+		c.mu.Unlock()
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.stderr != nil {
+		c.mu.Unlock()
+		return nil, errors.New("exec: Stderr already set")
+	}
+	var out = &syncBuffer{}
+	c.stdout = out
+	c.stderr = out
+	c.mu.Unlock()
+
+	var err = c.Run()
+
+	return out.Bytes(), err
+}
+
+func (c *Cmd) StdinPipe() (io.WriteCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stdin != nil {
+		return nil, errors.New("exec: Stdin already set")
+	}
+	if c.started {
+		return nil, errors.New("exec: StdinPipe after process started")
+	}
+
+	var p = newPipe()
+	c.stdin = p.reader()
+	c.parentEnds = append(c.parentEnds, p.writer())
+
+	return p.writer(), nil
+}
+
+func (c *Cmd) StdoutPipe() (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stdout != nil {
+		return nil, errors.New("exec: Stdout already set")
+	}
+	if c.started {
+		return nil, errors.New("exec: StdoutPipe after process started")
+	}
+
+	var p = newPipe()
+	c.stdout = p.writer()
+	c.childEnds = append(c.childEnds, p.writer())
+	c.parentEnds = append(c.parentEnds, p.reader())
+
+	return p.reader(), nil
+}
+
+func (c *Cmd) StderrPipe() (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stderr != nil {
+		return nil, errors.New("exec: Stderr already set")
+	}
+	if c.started {
+		return nil, errors.New("exec: StderrPipe after process started")
+	}
+
+	var p = newPipe()
+	c.stderr = p.writer()
+	c.childEnds = append(c.childEnds, p.writer())
+	c.parentEnds = append(c.parentEnds, p.reader())
+
+	return p.reader(), nil
+}
+
+// Start runs the registered handler in a new goroutine.
+func (c *Cmd) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.started {
+		return errors.New("exec: already started")
+	}
+	if c.err != nil {
+		c.closeAll()
+		return c.err
+	}
+
+	var handler, err = c.exec.lookup(c.name, c.args[1:])
+	if err != nil {
+		c.closeAll()
+		return err
+	}
+
+	var call, pid = c.exec.start(c)
+	var inv = &Invocation{
+		Pid:    pid,
+		Path:   c.path,
+		Args:   append([]string{}, c.args...),
+		Env:    call.Env,
+		Dir:    c.dir,
+		Stdin:  c.stdin,
+		Stdout: c.stdout,
+		Stderr: c.stderr,
+	}
+	if inv.Stdin == nil {
+		inv.Stdin = strings.NewReader("")
+	}
+	if inv.Stdout == nil {
+		inv.Stdout = io.Discard
+	}
+	if inv.Stderr == nil {
+		inv.Stderr = io.Discard
+	}
+
+	c.started = true
+	c.pid = pid
+	c.done = make(chan struct{})
+
+	go func() {
+		var code = run(handler, inv)
+
+		c.mu.Lock()
+		c.code = code
+		for _, e := range c.childEnds {
+			e.Close()
+		}
+		c.mu.Unlock()
+
+		c.exec.finish(call, code)
+		close(c.done)
+	}()
+
+	return nil
+}
+
+// run calls the handler, turning a panic into the exit status 2 a
+// Go program reports when it panics.
+func run(handler Handler, inv *Invocation) (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(inv.Stderr, "panic: %v\n", r)
+			code = 2
+		}
+	}()
+
+	return handler(inv)
+}
+
+// Wait waits for the handler to return and reports a non-zero exit
+// code as an *exec.ExitError.
+func (c *Cmd) Wait() error {
+	c.mu.Lock()
+	if !c.started {
+		c.mu.Unlock()
+		return errors.New("exec: not started")
+	}
+	if c.waited {
+		c.mu.Unlock()
+		return errors.New("exec: Wait was already called")
+	}
+	c.waited = true
+	var done = c.done
+	c.mu.Unlock()
+
+	<-done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.parentEnds {
+		e.Close()
+	}
+	c.state = procstate.Exited(c.pid, c.code)
+
+	if c.code == 0 {
+		return nil
+	}
+
+	var ee = &exec.ExitError{ProcessState: c.state}
+	if c.capturedStderr != nil {
+		ee.Stderr = c.capturedStderr.Bytes()
+	}
+
+	return ee
+}
+
+func (c *Cmd) closeAll() {
+	for _, e := range append(c.parentEnds, c.childEnds...) {
+		e.Close()
+	}
+}
+
+// syncBuffer is the buffer CombinedOutput shares between stdout and
+// stderr, which a handler may write from different goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Bytes()
+}
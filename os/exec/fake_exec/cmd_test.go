@@ -0,0 +1,170 @@
+package fake_exec
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strings"
+	"testing"
+
+	execi "github.com/pdutton/go-interfaces/os/exec"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// upper copies stdin to stdout in upper case.
+func upper(inv *Invocation) int {
+	data, _ := io.ReadAll(inv.Stdin)
+	inv.Stdout.Write(bytes.ToUpper(data))
+	return 0
+}
+
+// TestCmd_Output tests capturing standard output.
+func TestCmd_Output(t *testing.T) {
+	fe := New()
+	fe.Register("echo", func(inv *Invocation) int {
+		io.WriteString(inv.Stdout, strings.Join(inv.Args[1:], " ")+"\n")
+		return 0
+	})
+
+	out, err := fe.NewCommand("echo", execi.WithArgs("hello", "world")).Output()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "hello world\n", string(out))
+}
+
+// TestCmd_ExitError tests a non-zero exit code.
+func TestCmd_ExitError(t *testing.T) {
+	fe := New()
+	fe.Register("false", Respond("", "boom\n", 3))
+
+	cmd := fe.NewCommand("false")
+	_, err := cmd.Output()
+
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		t.Fatalf("expected *exec.ExitError, got %T", err)
+	}
+	testutil.AssertEqual(t, 3, ee.ExitCode())
+	testutil.AssertEqual(t, "exit status 3", ee.Error())
+	testutil.AssertEqual(t, "boom\n", string(ee.Stderr))
+	testutil.AssertEqual(t, 3, cmd.ProcessState().ExitCode())
+}
+
+// TestCmd_CombinedOutput tests capturing stdout and stderr together.
+func TestCmd_CombinedOutput(t *testing.T) {
+	fe := New()
+	fe.Register("both", Respond("out ", "err", 0))
+
+	out, err := fe.NewCommand("both").CombinedOutput()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "out err", string(out))
+}
+
+// TestCmd_Stdin tests providing input with WithStdin.
+func TestCmd_Stdin(t *testing.T) {
+	fe := New()
+	fe.Register("upper", upper)
+
+	out, err := fe.NewCommand("upper", execi.WithStdin(strings.NewReader("abc"))).Output()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "ABC", string(out))
+}
+
+// TestCmd_Pipes tests StdinPipe and StdoutPipe with Start and Wait.
+func TestCmd_Pipes(t *testing.T) {
+	fe := New()
+	fe.Register("upper", upper)
+
+	cmd := fe.NewCommand("upper")
+	stdin, err := cmd.StdinPipe()
+	testutil.AssertNil(t, err)
+	stdout, err := cmd.StdoutPipe()
+	testutil.AssertNil(t, err)
+
+	testutil.AssertNil(t, cmd.Start())
+	io.WriteString(stdin, "piped")
+	stdin.Close()
+
+	data, err := io.ReadAll(stdout)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "PIPED", string(data))
+	testutil.AssertNil(t, cmd.Wait())
+
+	_, err = stdout.Read(make([]byte, 1))
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrClosed))
+}
+
+// TestCmd_StderrPipe tests streaming standard error.
+func TestCmd_StderrPipe(t *testing.T) {
+	fe := New()
+	fe.Register("warn", Respond("", "line1\nline2\n", 1))
+
+	cmd := fe.NewCommand("warn")
+	stderr, _ := cmd.StderrPipe()
+	testutil.AssertNil(t, cmd.Start())
+
+	var lines []string
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	testutil.AssertEqual(t, 2, len(lines))
+
+	err := cmd.Wait()
+	var ee *exec.ExitError
+	testutil.AssertEqual(t, true, errors.As(err, &ee))
+}
+
+// TestCmd_Misuse tests the errors for using a Cmd out of order.
+func TestCmd_Misuse(t *testing.T) {
+	fe := New()
+	fe.Register("true", Respond("", "", 0))
+
+	cmd := fe.NewCommand("true")
+	testutil.AssertError(t, errors.New("exec: not started"), cmd.Wait())
+
+	testutil.AssertNil(t, cmd.Start())
+	testutil.AssertError(t, errors.New("exec: already started"), cmd.Start())
+	_, err := cmd.StdoutPipe()
+	testutil.AssertError(t, errors.New("exec: StdoutPipe after process started"), err)
+
+	testutil.AssertNil(t, cmd.Wait())
+	testutil.AssertError(t, errors.New("exec: Wait was already called"), cmd.Wait())
+
+	var buf bytes.Buffer
+	_, err = fe.NewCommand("true", execi.WithStdout(&buf)).Output()
+	testutil.AssertError(t, errors.New("exec: Stdout already set"), err)
+}
+
+// TestCmd_Panic tests that a panicking handler exits with status 2.
+func TestCmd_Panic(t *testing.T) {
+	fe := New()
+	fe.Register("crash", func(*Invocation) int { panic("oops") })
+
+	out, err := fe.NewCommand("crash").CombinedOutput()
+
+	var ee *exec.ExitError
+	testutil.AssertEqual(t, true, errors.As(err, &ee))
+	testutil.AssertEqual(t, 2, ee.ExitCode())
+	testutil.AssertEqual(t, "panic: oops\n", string(out))
+}
+
+// TestCmd_String tests the printable form of a command.
+func TestCmd_String(t *testing.T) {
+	fe := New()
+	fe.Register("ls", Respond("", "", 0))
+
+	cmd := fe.NewCommand("ls", execi.WithArgs("-l", "/tmp"))
+	testutil.AssertEqual(t, "/usr/bin/ls -l /tmp", cmd.String())
+}
+
+// TestCmd_Environ tests that Dir is reflected in PWD.
+func TestCmd_Environ(t *testing.T) {
+	fe := New()
+
+	cmd := fe.NewCommand("x", execi.WithDir("/work"), execi.WithEnv("PWD", "/old"), execi.WithEnv("A", "1"))
+	testutil.AssertEqual(t, "A=1,PWD=/work", strings.Join(cmd.Environ(), ","))
+}
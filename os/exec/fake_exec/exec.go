@@ -0,0 +1,291 @@
+// Package fake_exec provides a scriptable implementation of the
+// go-interfaces exec.Exec interface.
+//
+// Tests register a Handler for each program (and optionally for
+// particular argument patterns).  Commands created with NewCommand
+// then run the matching handler in a goroutine instead of starting a
+// real process, so Run, Output, CombinedOutput, Start/Wait and the
+// pipe methods all work without per-method expectations.  A non-zero
+// exit code is reported as a genuine *exec.ExitError.
+package fake_exec
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+
+	execi "github.com/pdutton/go-interfaces/os/exec"
+)
+
+// Handler simulates a program.  It reads the command's standard
+// input from inv.Stdin, writes to inv.Stdout and inv.Stderr, and
+// returns the process exit code.
+type Handler func(inv *Invocation) int
+
+// Invocation is what a Handler sees of the process it simulates.
+type Invocation struct {
+	Pid    int
+	Path   string
+	Args   []string
+	Env    []string
+	Dir    string
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Getenv returns the value of key in the process environment.
+func (inv *Invocation) Getenv(key string) string {
+	for i := len(inv.Env) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(inv.Env[i], "="); ok && k == key {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// Call records one command started through an Exec.
+type Call struct {
+	Path string
+	Args []string
+	Env  []string
+	Dir  string
+
+	// ExitCode is -1 until the handler has returned.
+	ExitCode int
+}
+
+// Respond returns a Handler that writes fixed output and exits
+// with code.
+func Respond(stdout, stderr string, code int) Handler {
+	return func(inv *Invocation) int {
+		io.WriteString(inv.Stdout, stdout)
+		io.WriteString(inv.Stderr, stderr)
+		return code
+	}
+}
+
+// Option configures an Exec created by New.
+type Option func(*Exec)
+
+// WithInheritedEnv sets the function that supplies the environment
+// of commands whose Env is not set explicitly, the role os.Environ
+// plays for real commands.  By default the environment is empty.
+func WithInheritedEnv(environ func() []string) Option {
+	return func(e *Exec) {
+		e.environ = environ
+	}
+}
+
+// WithBinDir sets the directory LookPath reports for registered
+// programs given without a directory.  The default is /usr/bin.
+func WithBinDir(dir string) Option {
+	return func(e *Exec) {
+		e.binDir = dir
+	}
+}
+
+type route struct {
+	name    string
+	args    []string
+	handler Handler
+}
+
+// Exec is a fake implementation of the go-interfaces exec.Exec
+// interface.  It is safe for concurrent use.
+type Exec struct {
+	mu      sync.Mutex
+	routes  []route
+	environ func() []string
+	binDir  string
+	nextPid int
+	calls   []*Call
+}
+
+var _ execi.Exec = (*Exec)(nil)
+
+// New returns an Exec with no registered programs.
+func New(options ...Option) *Exec {
+	var e = &Exec{
+		environ: func() []string { return nil },
+		binDir:  "/usr/bin",
+		nextPid: 1000,
+	}
+
+	for _, f := range options {
+		f(e)
+	}
+
+	return e
+}
+
+// Register installs h for the program name.  A name without a slash
+// matches any command with that base name; a name with a slash must
+// match the command path exactly.
+//
+// With no argPatterns the handler matches any arguments.  Otherwise
+// each pattern is matched against the corresponding argument with
+// path.Match, the number of arguments must be the same, and a final
+// pattern of "**" matches any remaining arguments.
+//
+// Later registrations take precedence over earlier ones, so a test
+// can override a handler installed by a shared helper.
+func (e *Exec) Register(name string, h Handler, argPatterns ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.routes = append(e.routes, route{name: name, args: argPatterns, handler: h})
+}
+
+// Calls returns a copy of every command started so far, in order.
+func (e *Exec) Calls() []Call {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var calls = make([]Call, 0, len(e.calls))
+	for _, c := range e.calls {
+		calls = append(calls, *c)
+	}
+
+	return calls
+}
+
+func (r route) matchesName(name string) bool {
+	if strings.Contains(r.name, "/") {
+		return r.name == name
+	}
+
+	return r.name == path.Base(name)
+}
+
+func (r route) matchesArgs(args []string) bool {
+	if r.args == nil {
+		return true
+	}
+
+	for i, p := range r.args {
+		if p == "**" && i == len(r.args)-1 {
+			return true
+		}
+		if i >= len(args) {
+			return false
+		}
+		if ok, _ := path.Match(p, args[i]); !ok {
+			return false
+		}
+	}
+
+	return len(args) == len(r.args)
+}
+
+// known reports whether any handler is registered for name.
+func (e *Exec) known(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, r := range e.routes {
+		if r.matchesName(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookup finds the handler for a command line.  argv[0] is ignored
+// in favour of name, as it is for real processes.
+func (e *Exec) lookup(name string, args []string) (Handler, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var found = false
+	for i := len(e.routes) - 1; i >= 0; i-- {
+		var r = e.routes[i]
+		if !r.matchesName(name) {
+			continue
+		}
+		found = true
+		if r.matchesArgs(args) {
+			return r.handler, nil
+		}
+	}
+
+	if !found {
+		return nil, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+
+	return nil, &exec.Error{Name: name, Err: fmt.Errorf("fake_exec: no handler matches arguments %q", args)}
+}
+
+// LookPath reports where a registered program would be found.
+func (e *Exec) LookPath(file string) (string, error) {
+	if !e.known(file) {
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+	if strings.Contains(file, "/") {
+		return file, nil
+	}
+
+	return path.Join(e.binDir, file), nil
+}
+
+// NewCommand returns a Cmd that will run the handler registered for
+// name.  The go-interfaces options are honoured except WithContext,
+// whose context is not visible outside that package, and
+// WithExtraFiles, WithSysProcAttr, WithCancel and WithWaitDelay,
+// which have no meaning for a simulated process.
+func (e *Exec) NewCommand(name string, options ...execi.CommandOption) execi.Cmd {
+	// Let the real facade decode the options; the command it builds
+	// is never started.
+	var decoded = execi.NewExec().NewCommand(name, options...)
+
+	var c = &Cmd{
+		exec:   e,
+		name:   name,
+		path:   name,
+		args:   decoded.Args(),
+		env:    decoded.Env(),
+		dir:    decoded.Dir(),
+		stdin:  decoded.Stdin(),
+		stdout: decoded.Stdout(),
+		stderr: decoded.Stderr(),
+	}
+
+	var p, err = e.LookPath(name)
+	if err == nil {
+		c.path = p
+	} else {
+		c.err = err
+	}
+
+	return c
+}
+
+func (e *Exec) start(c *Cmd) (*Call, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.nextPid++
+
+	var call = &Call{
+		Path:     c.path,
+		Args:     append([]string{}, c.args...),
+		Env:      c.Environ(),
+		Dir:      c.dir,
+		ExitCode: -1,
+	}
+	e.calls = append(e.calls, call)
+
+	return call, e.nextPid
+}
+
+func (e *Exec) finish(call *Call, code int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	call.ExitCode = code
+}
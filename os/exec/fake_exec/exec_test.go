@@ -0,0 +1,119 @@
+package fake_exec
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	execi "github.com/pdutton/go-interfaces/os/exec"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestExec_LookPath tests resolving registered programs.
+func TestExec_LookPath(t *testing.T) {
+	fe := New()
+	fe.Register("git", Respond("", "", 0))
+
+	p, err := fe.LookPath("git")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/usr/bin/git", p)
+
+	_, err = fe.LookPath("hg")
+	testutil.AssertEqual(t, true, errors.Is(err, exec.ErrNotFound))
+}
+
+// TestExec_NotFound tests running an unregistered program.
+func TestExec_NotFound(t *testing.T) {
+	fe := New()
+
+	err := fe.NewCommand("nope").Run()
+
+	var ee *exec.Error
+	if !errors.As(err, &ee) {
+		t.Fatalf("expected *exec.Error, got %T", err)
+	}
+	testutil.AssertEqual(t, "nope", ee.Name)
+	testutil.AssertEqual(t, true, errors.Is(err, exec.ErrNotFound))
+}
+
+// TestExec_ArgPatterns tests choosing a handler by arguments.
+func TestExec_ArgPatterns(t *testing.T) {
+	fe := New()
+	fe.Register("git", Respond("any\n", "", 0))
+	fe.Register("git", Respond("status\n", "", 0), "status")
+	fe.Register("git", Respond("commit\n", "", 0), "commit", "-m", "*")
+	fe.Register("git", Respond("log\n", "", 0), "log", "**")
+
+	cases := map[string][]string{
+		"status\n": {"status"},
+		"commit\n": {"commit", "-m", "fix bug"},
+		"log\n":    {"log", "--oneline", "-n", "3"},
+		"any\n":    {"commit", "--amend"},
+	}
+	for want, args := range cases {
+		out, err := fe.NewCommand("git", execi.WithArgs(args...)).Output()
+		testutil.AssertNil(t, err)
+		testutil.AssertEqual(t, want, string(out))
+	}
+}
+
+// TestExec_NoMatchingArgs tests a known program with unexpected arguments.
+func TestExec_NoMatchingArgs(t *testing.T) {
+	fe := New()
+	fe.Register("git", Respond("", "", 0), "status")
+
+	err := fe.NewCommand("git", execi.WithArgs("push")).Run()
+
+	var ee *exec.Error
+	testutil.AssertEqual(t, true, errors.As(err, &ee))
+	testutil.AssertEqual(t, false, errors.Is(err, exec.ErrNotFound))
+}
+
+// TestExec_FullPath tests registering a program by absolute path.
+func TestExec_FullPath(t *testing.T) {
+	fe := New()
+	fe.Register("/opt/tool/bin/run", Respond("ok", "", 0))
+
+	cmd := fe.NewCommand("/opt/tool/bin/run")
+	testutil.AssertEqual(t, "/opt/tool/bin/run", cmd.Path())
+	testutil.AssertNil(t, cmd.Run())
+
+	err := fe.NewCommand("run").Run()
+	testutil.AssertEqual(t, true, errors.Is(err, exec.ErrNotFound))
+}
+
+// TestExec_Calls tests that started commands are recorded.
+func TestExec_Calls(t *testing.T) {
+	fe := New()
+	fe.Register("make", Respond("", "", 2))
+
+	fe.NewCommand("make", execi.WithArgs("all"), execi.WithDir("/src")).Run()
+
+	calls := fe.Calls()
+	testutil.AssertEqual(t, 1, len(calls))
+	testutil.AssertEqual(t, "/usr/bin/make", calls[0].Path)
+	testutil.AssertEqual(t, "all", calls[0].Args[1])
+	testutil.AssertEqual(t, "/src", calls[0].Dir)
+	testutil.AssertEqual(t, 2, calls[0].ExitCode)
+}
+
+// TestExec_InheritedEnv tests the environment passed to handlers.
+func TestExec_InheritedEnv(t *testing.T) {
+	fe := New(WithInheritedEnv(func() []string {
+		return []string{"HOME=/home/user", "LANG=C"}
+	}))
+
+	var seen *Invocation
+	fe.Register("env", func(inv *Invocation) int {
+		seen = inv
+		return 0
+	})
+
+	testutil.AssertNil(t, fe.NewCommand("env").Run())
+	testutil.AssertEqual(t, "/home/user", seen.Getenv("HOME"))
+
+	testutil.AssertNil(t, fe.NewCommand("env", execi.WithEnv("LANG", "fr_FR")).Run())
+	testutil.AssertEqual(t, "", seen.Getenv("HOME"))
+	testutil.AssertEqual(t, "fr_FR", seen.Getenv("LANG"))
+}
@@ -0,0 +1,93 @@
+package fake_exec
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"sync"
+	"syscall"
+)
+
+// pipe connects a simulated process to its parent.  Unlike io.Pipe
+// it buffers without limit, so a handler that writes and exits does
+// not block on a parent that only reads after Wait, much as a small
+// write to a real pipe does not.
+type pipe struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     bytes.Buffer
+	rclosed bool
+	wclosed bool
+}
+
+func newPipe() *pipe {
+	var p = &pipe{}
+	p.cond = sync.NewCond(&p.mu)
+
+	return p
+}
+
+func (p *pipe) reader() pipeReader { return pipeReader{p} }
+func (p *pipe) writer() pipeWriter { return pipeWriter{p} }
+
+type pipeReader struct{ p *pipe }
+
+func (r pipeReader) Read(b []byte) (int, error) {
+	var p = r.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for p.buf.Len() == 0 && !p.wclosed && !p.rclosed {
+		p.cond.Wait()
+	}
+	if p.rclosed {
+		return 0, &fs.PathError{Op: "read", Path: "|0", Err: fs.ErrClosed}
+	}
+	if p.buf.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return p.buf.Read(b)
+}
+
+func (r pipeReader) Close() error {
+	var p = r.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rclosed = true
+	p.cond.Broadcast()
+
+	return nil
+}
+
+type pipeWriter struct{ p *pipe }
+
+func (w pipeWriter) Write(b []byte) (int, error) {
+	var p = w.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.wclosed {
+		return 0, &fs.PathError{Op: "write", Path: "|1", Err: fs.ErrClosed}
+	}
+	if p.rclosed {
+		return 0, &fs.PathError{Op: "write", Path: "|1", Err: syscall.EPIPE}
+	}
+
+	var n, err = p.buf.Write(b)
+	p.cond.Broadcast()
+
+	return n, err
+}
+
+func (w pipeWriter) Close() error {
+	var p = w.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.wclosed = true
+	p.cond.Broadcast()
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package fake_os
+
+import (
+	"io/fs"
+	"path"
+	"syscall"
+
+	osi "github.com/pdutton/go-interfaces/os"
+)
+
+// dirFS is the fs.FS returned by OS.DirFS and Root.FS.  Exactly one
+// of os and root is set.
+type dirFS struct {
+	os   *OS
+	dir  string
+	root *Root
+}
+
+var (
+	_ fs.StatFS     = dirFS{}
+	_ fs.ReadFileFS = dirFS{}
+	_ fs.ReadDirFS  = dirFS{}
+)
+
+func (d dirFS) open(op, name string) (*File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	var f osi.File
+	var err error
+	if d.root != nil {
+		f, err = d.root.Open(name)
+	} else {
+		f, err = d.os.Open(path.Join(d.dir, name))
+	}
+	if err != nil {
+		// Report the name relative to the FS, as os.DirFS does.
+		var pe, ok = err.(*fs.PathError)
+		if ok {
+			pe.Op = op
+			pe.Path = name
+		}
+		return nil, err
+	}
+
+	return f.(*File), nil
+}
+
+func (d dirFS) Open(name string) (fs.File, error) {
+	var f, err = d.open("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsFile{f}, nil
+}
+
+func (d dirFS) Stat(name string) (fs.FileInfo, error) {
+	var f, err = d.open("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return fsFile{f}.Stat()
+}
+
+func (d dirFS) ReadFile(name string) ([]byte, error) {
+	var f, err = d.open("readfile", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if f.node.isDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+
+	return append([]byte{}, f.node.data...), nil
+}
+
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var f, err = d.open("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return fsFile{f}.ReadDir(-1)
+}
+
+// fsFile adapts a *File to the io/fs interfaces, whose Stat and
+// ReadDir return the standard library types.
+type fsFile struct {
+	*File
+}
+
+var _ fs.ReadDirFile = fsFile{}
+
+func (f fsFile) Stat() (fs.FileInfo, error) {
+	var fi, err = f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return fi.Nub(), nil
+}
+
+func (f fsFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	var infos, err = f.File.readdir(n)
+
+	var entries = make([]fs.DirEntry, 0, len(infos))
+	for _, fi := range infos {
+		entries = append(entries, fs.FileInfoToDirEntry(fi))
+	}
+
+	return entries, err
+}
@@ -0,0 +1,182 @@
+package fake_os
+
+import (
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// TB is the part of testing.TB an Env reports through.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// EnvOption configures an Env created by NewEnv.
+type EnvOption func(*Env)
+
+// ReportUnset makes the Env report, as an error on t when the test
+// ends, every variable that was read but never set.
+func ReportUnset(t TB) EnvOption {
+	return func(e *Env) {
+		t.Cleanup(func() {
+			t.Helper()
+			for _, key := range e.Unset() {
+				t.Errorf("fake_os: environment variable %s was read but never set", key)
+			}
+		})
+	}
+}
+
+// Env is a process environment: the environment variable functions of
+// the go-interfaces os.OS interface over a map.  An OS keeps its
+// variables in one, and WithEnv shares one between an OS and, through
+// its Environ method, a fake_exec.Exec:
+//
+//	env := fake_os.NewEnv(map[string]string{"HOME": "/home/user"})
+//	fos := fake_os.New(fake_os.WithEnv(env))
+//	fex := fake_exec.New(fake_exec.WithInheritedEnv(env.Environ))
+//
+// Env also records the variables read with Getenv, LookupEnv and
+// ExpandEnv while they were not set, so a test can find the
+// configuration its code looked for and did not get.  The reads an OS
+// makes for UserHomeDir, UserCacheDir and UserConfigDir count too, but
+// not those for TempDir, which CreateTemp and MkdirTemp call on their
+// own.  It is safe for concurrent use.
+type Env struct {
+	mu     sync.Mutex
+	vars   map[string]string
+	wasSet map[string]bool
+	missed map[string]bool
+}
+
+// NewEnv returns an Env holding a copy of vars.
+func NewEnv(vars map[string]string, options ...EnvOption) *Env {
+	var e = &Env{
+		vars:   make(map[string]string, len(vars)),
+		wasSet: make(map[string]bool, len(vars)),
+		missed: make(map[string]bool),
+	}
+	for k, v := range vars {
+		e.vars[k] = v
+		e.wasSet[k] = true
+	}
+
+	for _, f := range options {
+		f(e)
+	}
+
+	return e
+}
+
+func (e *Env) Clearenv() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	clear(e.vars)
+}
+
+// Environ returns the variables as "key=value" strings, sorted by key.
+func (e *Env) Environ() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var env = make([]string, 0, len(e.vars))
+	for k, v := range e.vars {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+func (e *Env) Expand(s string, mapping func(string) string) string {
+	return os.Expand(s, mapping)
+}
+
+func (e *Env) ExpandEnv(s string) string {
+	return os.Expand(s, e.Getenv)
+}
+
+func (e *Env) Getenv(key string) string {
+	var v, _ = e.LookupEnv(key)
+	return v
+}
+
+// peek returns the variable without recording a read, for the
+// functions of an OS that fall back to a default when it is unset.
+func (e *Env) peek(key string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.vars[key]
+}
+
+func (e *Env) LookupEnv(key string) (string, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var v, ok = e.vars[key]
+	if !ok {
+		e.missed[key] = true
+	}
+
+	return v, ok
+}
+
+// Setenv sets the variable, rejecting the keys and values the real
+// function does.
+func (e *Env) Setenv(key, value string) error {
+	if key == "" || strings.ContainsAny(key, "=\x00") || strings.IndexByte(value, 0) >= 0 {
+		return os.NewSyscallError("setenv", syscall.EINVAL)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.vars[key] = value
+	e.wasSet[key] = true
+
+	return nil
+}
+
+// setDefault sets the variable unless it is already set.
+func (e *Env) setDefault(key, value string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.vars[key]; !ok {
+		e.vars[key] = value
+		e.wasSet[key] = true
+	}
+}
+
+func (e *Env) Unsetenv(key string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.vars, key)
+
+	return nil
+}
+
+// Unset returns, sorted, the variables that were read while unset and
+// have never been set, either by NewEnv or by Setenv.
+func (e *Env) Unset() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var keys []string
+	for k := range e.missed {
+		if !e.wasSet[k] {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
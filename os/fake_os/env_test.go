@@ -0,0 +1,131 @@
+package fake_os
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+	"github.com/pdutton/go-mocks/os/exec/fake_exec"
+)
+
+// recorder is a TB that records errors, running cleanups on demand.
+type recorder struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recorder) end() {
+	for _, f := range r.cleanups {
+		f()
+	}
+}
+
+// TestEnv tests mutating an environment that starts from a map.
+func TestEnv(t *testing.T) {
+	vars := map[string]string{"HOME": "/home/user", "A": "1"}
+	env := NewEnv(vars)
+
+	testutil.AssertNil(t, env.Setenv("B", "2"))
+	testutil.AssertNil(t, env.Unsetenv("A"))
+	testutil.AssertEqual(t, "B=2 HOME=/home/user", strings.Join(env.Environ(), " "))
+	testutil.AssertEqual(t, "1", vars["A"])
+
+	v, ok := env.LookupEnv("B")
+	testutil.AssertEqual(t, true, ok)
+	testutil.AssertEqual(t, "2", v)
+
+	testutil.AssertNil(t, env.Setenv("EMPTY", ""))
+	_, ok = env.LookupEnv("EMPTY")
+	testutil.AssertEqual(t, true, ok)
+
+	testutil.AssertError(t, syscall.EINVAL, env.Setenv("A=B", "x"))
+	testutil.AssertError(t, syscall.EINVAL, env.Setenv("", "x"))
+	testutil.AssertError(t, syscall.EINVAL, env.Setenv("A", "\x00"))
+
+	env.Clearenv()
+	testutil.AssertEqual(t, 0, len(env.Environ()))
+}
+
+// TestEnv_Expand tests the shell-like expansion of the real functions.
+func TestEnv_Expand(t *testing.T) {
+	env := NewEnv(map[string]string{"HOME": "/home/user", "N": "3"})
+
+	testutil.AssertEqual(t, "/home/user/x3 $ ", env.ExpandEnv("${HOME}/x$N $ ${"))
+	testutil.AssertEqual(t, "-/home/user-", env.ExpandEnv("-$HOME-"))
+	testutil.AssertEqual(t, "a.b", env.ExpandEnv("a.${MISSING}b"))
+	testutil.AssertEqual(t, "<1> <N>", env.Expand("$1 ${N}", func(s string) string {
+		return "<" + s + ">"
+	}))
+}
+
+// TestEnv_Unset tests finding the variables read but never set.
+func TestEnv_Unset(t *testing.T) {
+	r := &recorder{}
+	env := NewEnv(map[string]string{"GONE": "x"}, ReportUnset(r))
+
+	testutil.AssertNil(t, env.Unsetenv("GONE"))
+	env.Getenv("GONE")
+	env.Getenv("DEBUG")
+	env.LookupEnv("CONFIG")
+	env.ExpandEnv("$LATER")
+	testutil.AssertNil(t, env.Setenv("LATER", "1"))
+	env.Getenv("LATER")
+
+	testutil.AssertEqual(t, "CONFIG DEBUG", strings.Join(env.Unset(), " "))
+
+	r.end()
+	testutil.AssertEqual(t, 2, len(r.errors))
+	testutil.AssertEqual(t, "fake_os: environment variable CONFIG was read but never set", r.errors[0])
+}
+
+// TestEnv_Shared tests sharing one environment between an OS and the
+// commands of an Exec.
+func TestEnv_Shared(t *testing.T) {
+	env := NewEnv(map[string]string{"HOME": "/home/user"})
+	fos := New(WithEnv(env))
+	fex := fake_exec.New(fake_exec.WithInheritedEnv(env.Environ))
+	fex.Register("printenv", func(inv *fake_exec.Invocation) int {
+		fmt.Fprint(inv.Stdout, inv.Getenv("HOME")+" "+inv.Getenv("LANG"))
+		return 0
+	})
+
+	testutil.AssertNil(t, fos.Setenv("LANG", "C"))
+	testutil.AssertEqual(t, env, fos.Env())
+
+	out, err := fex.NewCommand("printenv").Output()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/home/user C", string(out))
+
+	// The OS's own fallbacks are not reads by the code under test.
+	testutil.AssertEqual(t, "/tmp", fos.TempDir())
+	_, err = fos.CreateTemp("", "x")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 0, len(env.Unset()))
+}
+
+// TestEnv_UnsetFallbacks tests that the variables an OS reads for its
+// directory functions are found when unset.
+func TestEnv_UnsetFallbacks(t *testing.T) {
+	env := NewEnv(nil)
+	fos := New(WithEnv(env))
+
+	_, err := fos.UserHomeDir()
+	testutil.AssertNotNil(t, err)
+	testutil.AssertEqual(t, "HOME", strings.Join(env.Unset(), " "))
+
+	_, err = fos.UserConfigDir()
+	testutil.AssertNotNil(t, err)
+	testutil.AssertEqual(t, "HOME XDG_CONFIG_HOME", strings.Join(env.Unset(), " "))
+}
@@ -0,0 +1,425 @@
+package fake_os
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"syscall"
+	"time"
+
+	fsi "github.com/pdutton/go-interfaces/io/fs"
+	osi "github.com/pdutton/go-interfaces/os"
+)
+
+var errWriteAtInAppendMode = errors.New("os: invalid use of WriteAt on file opened with O_APPEND")
+
+// File is an open handle on a node in the fake tree.  Like a real
+// file descriptor it keeps working after the node is unlinked.
+type File struct {
+	fsys *memFS
+	os   *OS
+	node *node
+	name string
+	path string
+	flag int
+	fd   uintptr
+
+	off    int64
+	closed bool
+
+	// Directory iteration state, captured on the first read.
+	dirNames []string
+	dirPos   int
+}
+
+var _ osi.File = (*File)(nil)
+
+func (f *File) readable() bool {
+	return f.flag&(osi.O_WRONLY|osi.O_RDWR) != osi.O_WRONLY
+}
+
+func (f *File) writable() bool {
+	return f.flag&(osi.O_WRONLY|osi.O_RDWR) != 0
+}
+
+// check validates the handle for op; it must be called with mu held.
+func (f *File) check(op string) error {
+	if f == nil {
+		return osi.ErrInvalid
+	}
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+
+	return nil
+}
+
+func (f *File) Chdir() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("chdir"); err != nil {
+		return err
+	}
+	if !f.node.isDir() {
+		return &fs.PathError{Op: "chdir", Path: f.name, Err: syscall.ENOTDIR}
+	}
+	if f.os != nil {
+		f.os.cwd = f.path
+	}
+
+	return nil
+}
+
+func (f *File) Chmod(mode osi.FSFileMode) error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("chmod"); err != nil {
+		return err
+	}
+	chmod(f.node, mode)
+
+	return nil
+}
+
+func (f *File) Chown(uid, gid int) error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("chown"); err != nil {
+		return err
+	}
+	chown(f.node, uid, gid)
+
+	return nil
+}
+
+func (f *File) Close() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("close"); err != nil {
+		return err
+	}
+	f.closed = true
+
+	return nil
+}
+
+func (f *File) Fd() uintptr {
+	if f == nil || f.closed {
+		return ^uintptr(0)
+	}
+
+	return f.fd
+}
+
+func (f *File) Name() string {
+	return f.name
+}
+
+func (f *File) Read(b []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	var n, err = f.readAt(b, f.off, "read")
+	f.off += int64(n)
+
+	return n, err
+}
+
+func (f *File) ReadAt(b []byte, off int64) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.name, Err: errors.New("negative offset")}
+	}
+
+	var n, err = f.readAt(b, off, "read")
+	if err == nil && n < len(b) {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+func (f *File) readAt(b []byte, off int64, op string) (int, error) {
+	if err := f.check(op); err != nil {
+		return 0, err
+	}
+	if !f.readable() {
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: syscall.EBADF}
+	}
+	if f.node.isDir() {
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: syscall.EISDIR}
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if f.node.dev == devNull || off >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	f.node.atime = f.fsys.now()
+
+	return copy(b, f.node.data[off:]), nil
+}
+
+func (f *File) Write(b []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("write"); err != nil {
+		return 0, err
+	}
+	if f.flag&osi.O_APPEND != 0 {
+		f.off = int64(len(f.node.data))
+	}
+
+	var n, err = f.writeAt(b, f.off, "write")
+	f.off += int64(n)
+
+	return n, err
+}
+
+func (f *File) WriteAt(b []byte, off int64) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("write"); err != nil {
+		return 0, err
+	}
+	if f.flag&osi.O_APPEND != 0 {
+		return 0, errWriteAtInAppendMode
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "writeat", Path: f.name, Err: errors.New("negative offset")}
+	}
+
+	return f.writeAt(b, off, "write")
+}
+
+func (f *File) writeAt(b []byte, off int64, op string) (int, error) {
+	if !f.writable() {
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: syscall.EBADF}
+	}
+	if f.node.dev == devNull {
+		return len(b), nil
+	}
+
+	var end = off + int64(len(b))
+	if end > int64(len(f.node.data)) {
+		var grown = make([]byte, end)
+		copy(grown, f.node.data)
+		f.node.data = grown
+	}
+	copy(f.node.data[off:], b)
+	f.node.mtime = f.fsys.now()
+
+	return len(b), nil
+}
+
+func (f *File) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{f})
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("seek"); err != nil {
+		return 0, err
+	}
+
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = f.off + offset
+	case io.SeekEnd:
+		pos = f.node.size() + offset
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+	if pos < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: syscall.EINVAL}
+	}
+
+	f.off = pos
+	if f.node.isDir() && pos == 0 {
+		f.dirNames = nil
+		f.dirPos = 0
+	}
+
+	return pos, nil
+}
+
+func (f *File) SetDeadline(time.Time) error {
+	return f.setDeadline("SetDeadline")
+}
+
+func (f *File) SetReadDeadline(time.Time) error {
+	return f.setDeadline("SetReadDeadline")
+}
+
+func (f *File) SetWriteDeadline(time.Time) error {
+	return f.setDeadline("SetWriteDeadline")
+}
+
+func (f *File) setDeadline(op string) error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check(op); err != nil {
+		return err
+	}
+
+	return &fs.PathError{Op: op, Path: f.name, Err: osi.ErrNoDeadline}
+}
+
+func (f *File) Stat() (osi.FileInfo, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("stat"); err != nil {
+		return nil, err
+	}
+
+	return wrapInfo(newFileInfo(path.Base(f.name), f.node)), nil
+}
+
+func (f *File) Sync() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	return f.check("sync")
+}
+
+func (f *File) SyscallConn() (syscall.RawConn, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("SyscallConn"); err != nil {
+		return nil, err
+	}
+
+	return nil, &fs.PathError{Op: "SyscallConn", Path: f.name, Err: errors.ErrUnsupported}
+}
+
+func (f *File) Truncate(size int64) error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("truncate"); err != nil {
+		return err
+	}
+	if size < 0 || !f.writable() || f.node.isDir() {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: syscall.EINVAL}
+	}
+	truncate(f.node, size, f.fsys.now())
+
+	return nil
+}
+
+func (f *File) ReadDir(n int) ([]fsi.DirEntry, error) {
+	var infos, err = f.readdir(n)
+
+	var entries = make([]fsi.DirEntry, 0, len(infos))
+	for _, fi := range infos {
+		entries = append(entries, wrapEntry(fi))
+	}
+
+	return entries, err
+}
+
+func (f *File) Readdir(n int) ([]osi.FileInfo, error) {
+	var infos, err = f.readdir(n)
+
+	var list = make([]osi.FileInfo, 0, len(infos))
+	for _, fi := range infos {
+		list = append(list, wrapInfo(fi))
+	}
+
+	return list, err
+}
+
+func (f *File) Readdirnames(n int) ([]string, error) {
+	var infos, err = f.readdir(n)
+
+	var names = make([]string, 0, len(infos))
+	for _, fi := range infos {
+		names = append(names, fi.name)
+	}
+
+	return names, err
+}
+
+// readdir implements the shared n <= 0 / n > 0 semantics of the
+// three directory reading methods.
+func (f *File) readdir(n int) ([]*fileInfo, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+
+	if err := f.check("readdirent"); err != nil {
+		return nil, err
+	}
+	if !f.node.isDir() {
+		return nil, &fs.PathError{Op: "readdirent", Path: f.name, Err: syscall.ENOTDIR}
+	}
+	if f.dirNames == nil {
+		f.dirNames = f.node.names()
+	}
+
+	var infos []*fileInfo
+	for f.dirPos < len(f.dirNames) && (n <= 0 || len(infos) < n) {
+		var name = f.dirNames[f.dirPos]
+		f.dirPos++
+
+		// Entries removed since the listing was captured are skipped.
+		if child := f.node.children[name]; child != nil {
+			infos = append(infos, newFileInfo(name, child))
+		}
+	}
+
+	if n > 0 && len(infos) == 0 {
+		return nil, io.EOF
+	}
+
+	return infos, nil
+}
+
+func chmod(n *node, mode fs.FileMode) {
+	const changeable = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+	n.mode = n.mode&^changeable | mode&changeable
+}
+
+func chown(n *node, uid, gid int) {
+	if uid != -1 {
+		n.uid = uid
+	}
+	if gid != -1 {
+		n.gid = gid
+	}
+}
+
+func truncate(n *node, size int64, now time.Time) {
+	if size <= int64(len(n.data)) {
+		n.data = n.data[:size:size]
+	} else {
+		var grown = make([]byte, size)
+		copy(grown, n.data)
+		n.data = grown
+	}
+	n.mtime = now
+}
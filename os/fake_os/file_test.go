@@ -0,0 +1,198 @@
+package fake_os
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestFile_ReadWriteSeek tests the basic file lifecycle.
+func TestFile_ReadWriteSeek(t *testing.T) {
+	fos := New()
+
+	f, err := fos.Create("/f")
+	testutil.AssertNil(t, err)
+
+	n, err := f.Write([]byte("hello world"))
+	testutil.AssertEqual(t, 11, n)
+	testutil.AssertNil(t, err)
+
+	pos, err := f.Seek(6, io.SeekStart)
+	testutil.AssertEqual(t, int64(6), pos)
+	testutil.AssertNil(t, err)
+
+	buf := make([]byte, 10)
+	n, err = f.Read(buf)
+	testutil.AssertEqual(t, "world", string(buf[:n]))
+	testutil.AssertNil(t, err)
+
+	n, err = f.Read(buf)
+	testutil.AssertEqual(t, 0, n)
+	testutil.AssertError(t, io.EOF, err)
+
+	testutil.AssertNil(t, f.Close())
+}
+
+// TestFile_ReadAtWriteAt tests positional I/O.
+func TestFile_ReadAtWriteAt(t *testing.T) {
+	fos := New()
+	f, _ := fos.Create("/f")
+
+	_, err := f.WriteAt([]byte("xyz"), 4)
+	testutil.AssertNil(t, err)
+
+	buf := make([]byte, 7)
+	n, err := f.ReadAt(buf, 0)
+	testutil.AssertEqual(t, 7, n)
+	testutil.AssertNil(t, err)
+	testutil.AssertBytes(t, []byte("\x00\x00\x00\x00xyz"), buf)
+
+	n, err = f.ReadAt(buf, 5)
+	testutil.AssertEqual(t, 2, n)
+	testutil.AssertError(t, io.EOF, err)
+}
+
+// TestFile_WriteAtAppend tests that WriteAt is refused in append mode.
+func TestFile_WriteAtAppend(t *testing.T) {
+	fos := New()
+	f, _ := fos.OpenFile("/f", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+
+	_, err := f.WriteAt([]byte("x"), 0)
+	testutil.AssertError(t, errWriteAtInAppendMode, err)
+}
+
+// TestFile_AccessMode tests reading a write-only file and vice versa.
+func TestFile_AccessMode(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/f", []byte("x"), 0o644))
+
+	w, _ := fos.OpenFile("/f", os.O_WRONLY, 0)
+	_, err := w.Read(make([]byte, 1))
+	assertPathError(t, err, "read", syscall.EBADF)
+
+	r, _ := fos.Open("/f")
+	_, err = r.Write([]byte("y"))
+	assertPathError(t, err, "write", syscall.EBADF)
+}
+
+// TestFile_Closed tests operations on a closed file.
+func TestFile_Closed(t *testing.T) {
+	fos := New()
+	f, _ := fos.Create("/f")
+	testutil.AssertNil(t, f.Close())
+
+	_, err := f.Write([]byte("x"))
+	assertPathError(t, err, "write", fs.ErrClosed)
+
+	err = f.Close()
+	assertPathError(t, err, "close", fs.ErrClosed)
+}
+
+// TestFile_Unlinked tests that an open handle survives removal.
+func TestFile_Unlinked(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/f", []byte("still here"), 0o644))
+
+	f, _ := fos.Open("/f")
+	testutil.AssertNil(t, fos.Remove("/f"))
+
+	data, err := io.ReadAll(f)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "still here", string(data))
+}
+
+// TestFile_Readdir tests incremental directory reads.
+func TestFile_Readdir(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.Mkdir("/d", 0o755))
+	for _, name := range []string{"a", "b", "c"} {
+		testutil.AssertNil(t, fos.WriteFile("/d/"+name, nil, 0o644))
+	}
+
+	f, _ := fos.Open("/d")
+	names, err := f.Readdirnames(2)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "a,b", strings.Join(names, ","))
+
+	infos, err := f.Readdir(2)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 1, len(infos))
+	testutil.AssertEqual(t, "c", infos[0].Name())
+
+	_, err = f.ReadDir(1)
+	testutil.AssertError(t, io.EOF, err)
+
+	_, err = f.Seek(0, io.SeekStart)
+	testutil.AssertNil(t, err)
+	entries, err := f.ReadDir(-1)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 3, len(entries))
+}
+
+// TestFile_ReaddirNotDir tests reading a regular file as a directory.
+func TestFile_ReaddirNotDir(t *testing.T) {
+	fos := New()
+	f, _ := fos.Create("/f")
+
+	_, err := f.ReadDir(-1)
+	assertPathError(t, err, "readdirent", syscall.ENOTDIR)
+}
+
+// TestFile_Truncate tests truncating through a handle.
+func TestFile_Truncate(t *testing.T) {
+	fos := New()
+	f, _ := fos.Create("/f")
+	f.WriteString("hello")
+
+	testutil.AssertNil(t, f.Truncate(10))
+	fi, _ := f.Stat()
+	testutil.AssertEqual(t, int64(10), fi.Size())
+
+	err := f.Truncate(-1)
+	assertPathError(t, err, "truncate", syscall.EINVAL)
+}
+
+// TestFile_Deadline tests that regular files do not support deadlines.
+func TestFile_Deadline(t *testing.T) {
+	fos := New()
+	f, _ := fos.Create("/f")
+
+	err := f.SetDeadline(time.Now())
+	testutil.AssertEqual(t, true, errors.Is(err, os.ErrNoDeadline))
+}
+
+// TestFile_ReadFromWriteTo tests the io.ReaderFrom and io.WriterTo methods.
+func TestFile_ReadFromWriteTo(t *testing.T) {
+	fos := New()
+	f, _ := fos.Create("/f")
+
+	n, err := f.ReadFrom(strings.NewReader("copied"))
+	testutil.AssertEqual(t, int64(6), n)
+	testutil.AssertNil(t, err)
+
+	f.Seek(0, io.SeekStart)
+	var sb strings.Builder
+	n, err = f.WriteTo(&sb)
+	testutil.AssertEqual(t, int64(6), n)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "copied", sb.String())
+}
+
+// TestFile_Chdir tests changing directory through a handle.
+func TestFile_Chdir(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.MkdirAll("/a/b", 0o755))
+
+	f, _ := fos.Open("/a/b")
+	testutil.AssertNil(t, f.Chdir())
+
+	wd, _ := fos.Getwd()
+	testutil.AssertEqual(t, "/a/b", wd)
+}
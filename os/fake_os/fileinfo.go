@@ -0,0 +1,63 @@
+package fake_os
+
+import (
+	"io/fs"
+	"time"
+
+	fsi "github.com/pdutton/go-interfaces/io/fs"
+)
+
+// Stat is the value returned by Sys() for files in the fake tree.
+// It plays the role that *syscall.Stat_t plays for real files.
+type Stat struct {
+	Ino   uint64
+	Nlink int
+	Uid   int
+	Gid   int
+	Atime time.Time
+}
+
+// fileInfo is a snapshot of a node taken at Stat time.
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	sys     *Stat
+}
+
+func newFileInfo(name string, n *node) *fileInfo {
+	return &fileInfo{
+		name:    name,
+		size:    n.size(),
+		mode:    n.mode,
+		modTime: n.mtime,
+		sys: &Stat{
+			Ino:   n.ino,
+			Nlink: n.nlink,
+			Uid:   n.uid,
+			Gid:   n.gid,
+			Atime: n.atime,
+		},
+	}
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() any           { return fi.sys }
+
+// wrapInfo converts a nil-able *fileInfo into the go-interfaces type.
+func wrapInfo(fi *fileInfo) fsi.FileInfo {
+	if fi == nil {
+		return nil
+	}
+
+	return fsi.NewFileInfo(fi)
+}
+
+func wrapEntry(fi *fileInfo) fsi.DirEntry {
+	return fsi.NewDirEntry(fs.FileInfoToDirEntry(fi))
+}
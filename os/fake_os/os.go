@@ -0,0 +1,1034 @@
+// Package fake_os provides an in-memory implementation of the
+// go-interfaces os.OS interface.
+//
+// Unlike mock_os.MockOS, which needs an expectation for every call,
+// the fake keeps a real directory tree so that files created with
+// Create or WriteFile can be read back with Open or ReadFile, renamed,
+// linked and removed.  Failures are reported with the same *PathError
+// and *LinkError values, wrapping the same syscall errors, that the
+// real os package returns on Linux.
+//
+// The identity of the process and host, such as its user and group
+// IDs, hostname and home directory, comes from a Profile.  Opening a
+// file checks its owner, group and mode against the profile's
+// effective user and groups, as the kernel would.
+//
+// Paths always use Unix semantics ('/' separators, a single root)
+// regardless of the host platform.
+package fake_os
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	fsi "github.com/pdutton/go-interfaces/io/fs"
+	osi "github.com/pdutton/go-interfaces/os"
+)
+
+var errPatternHasSeparator = errors.New("pattern contains path separator")
+
+// Option configures an OS created by New.
+type Option func(*OS)
+
+// WithArgs sets the value returned by Args.
+func WithArgs(args ...string) Option {
+	return func(o *OS) {
+		o.args = args
+	}
+}
+
+// WithWorkingDir sets the initial working directory, creating it
+// if it does not already exist.
+func WithWorkingDir(dir string) Option {
+	return func(o *OS) {
+		o.wd = dir
+	}
+}
+
+// WithEnv sets the environment, which may be shared with other fakes.
+// By default the environment is empty.
+func WithEnv(env *Env) Option {
+	return func(o *OS) {
+		o.env = env
+	}
+}
+
+// WithClock sets the function used to timestamp files.  By default
+// time.Now is used.
+func WithClock(now func() time.Time) Option {
+	return func(o *OS) {
+		o.fsys.now = now
+	}
+}
+
+// WithUmask sets the mask applied to the permissions of newly
+// created files and directories.  The default is 022.
+func WithUmask(mask osi.FSFileMode) Option {
+	return func(o *OS) {
+		o.fsys.umask = mask & fs.ModePerm
+	}
+}
+
+// OS is an in-memory implementation of the go-interfaces os.OS
+// interface.  It is safe for concurrent use.
+type OS struct {
+	fsys *memFS
+
+	args []string
+	wd   string
+	cwd  string
+	env  *Env
+
+	stdin  *File
+	stdout *File
+	stderr *File
+
+	tempSeq  int
+	exitCode *int
+	procs    map[int]*Process
+	profile  Profile
+}
+
+var _ osi.OS = (*OS)(nil)
+
+// New returns an OS with a minimal tree containing /tmp and /dev,
+// the working directory set to / and an empty environment.
+func New(options ...Option) *OS {
+	var o = &OS{
+		fsys: newMemFS(time.Now),
+		args: []string{"fake"},
+		wd:   "/",
+
+		profile: DefaultProfile(),
+	}
+
+	for _, f := range options {
+		f(o)
+	}
+	if o.env == nil {
+		o.env = NewEnv(nil)
+	}
+
+	o.applyProfile()
+	o.skeleton()
+	o.makeProfileDirs()
+
+	return o
+}
+
+// skeleton creates the directories and devices every process
+// expects to find.
+func (o *OS) skeleton() {
+	var m = o.fsys
+
+	var tmp = m.newNode(fs.ModeDir | fs.ModeSticky | 0o777)
+	m.link(m.root, "tmp", tmp)
+
+	var dev = m.newNode(fs.ModeDir | 0o755)
+	m.link(m.root, "dev", dev)
+
+	var null = m.newNode(fs.ModeDevice | fs.ModeCharDevice | 0o666)
+	null.dev = devNull
+	m.link(dev, "null", null)
+
+	var stdio = func(name string, flag int, fd uintptr) *File {
+		var n = m.newNode(fs.ModeDevice | fs.ModeCharDevice | 0o620)
+		m.link(dev, name, n)
+		return &File{fsys: m, os: o, node: n, name: "/dev/" + name, path: "/dev/" + name, flag: flag, fd: fd}
+	}
+	o.stdin = stdio("stdin", osi.O_RDONLY, 0)
+	o.stdout = stdio("stdout", osi.O_WRONLY|osi.O_APPEND, 1)
+	o.stderr = stdio("stderr", osi.O_WRONLY|osi.O_APPEND, 2)
+
+	o.cwd = "/"
+	if err := o.MkdirAll(o.wd, 0o755); err != nil {
+		panic("fake_os: cannot create working directory: " + err.Error())
+	}
+	if err := o.Chdir(o.wd); err != nil {
+		panic("fake_os: cannot change to working directory: " + err.Error())
+	}
+}
+
+// ExitCode returns the code passed to Exit, if it has been called.
+func (o *OS) ExitCode() (int, bool) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	if o.exitCode == nil {
+		return 0, false
+	}
+
+	return *o.exitCode, true
+}
+
+// ExitPanic is the value Exit panics with.  Exit cannot terminate
+// the test binary, so it unwinds the calling goroutine instead.
+type ExitPanic struct {
+	Code int
+}
+
+func (e ExitPanic) String() string {
+	return "fake_os: Exit(" + strconv.Itoa(e.Code) + ")"
+}
+
+// resolve looks up name relative to the working directory.  It must
+// be called with mu held.
+func (o *OS) resolve(name string, follow bool) (resolved, error) {
+	return o.fsys.resolve(o.cwd, name, follow, false)
+}
+
+// lookup resolves name and fails with ENOENT if it does not exist.
+func (o *OS) lookup(op, name string, follow bool) (resolved, error) {
+	var res, err = o.resolve(name, follow)
+	if err == nil && res.node == nil {
+		err = syscall.ENOENT
+	}
+	if err != nil {
+		return res, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	return res, nil
+}
+
+func (o *OS) Stdin() osi.File {
+	return o.stdin
+}
+
+func (o *OS) Stderr() osi.File {
+	return o.stderr
+}
+
+func (o *OS) Stdout() osi.File {
+	return o.stdout
+}
+
+func (o *OS) Args() []string {
+	return o.args
+}
+
+func (o *OS) Chdir(dir string) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("chdir", dir, true)
+	if err != nil {
+		return err
+	}
+	if !res.node.isDir() {
+		return &fs.PathError{Op: "chdir", Path: dir, Err: syscall.ENOTDIR}
+	}
+	o.cwd = res.path
+
+	return nil
+}
+
+func (o *OS) Chmod(name string, mode osi.FSFileMode) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("chmod", name, true)
+	if err != nil {
+		return err
+	}
+	chmod(res.node, mode)
+
+	return nil
+}
+
+func (o *OS) Chown(name string, uid, gid int) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("chown", name, true)
+	if err != nil {
+		return err
+	}
+	chown(res.node, uid, gid)
+
+	return nil
+}
+
+func (o *OS) Lchown(name string, uid, gid int) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("lchown", name, false)
+	if err != nil {
+		return err
+	}
+	chown(res.node, uid, gid)
+
+	return nil
+}
+
+// Chtimes changes the access and modification times.  As with the
+// real function, a zero time.Time leaves that time unchanged.
+func (o *OS) Chtimes(name string, atime, mtime time.Time) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("chtimes", name, true)
+	if err != nil {
+		return err
+	}
+	if !atime.IsZero() {
+		res.node.atime = atime
+	}
+	if !mtime.IsZero() {
+		res.node.mtime = mtime
+	}
+
+	return nil
+}
+
+func (o *OS) Clearenv() {
+	o.env.Clearenv()
+}
+
+// CopyFS copies fsys into the fake tree at dir, following the rules
+// of os.CopyFS: existing files are never overwritten and anything
+// other than regular files and directories is rejected.
+func (o *OS) CopyFS(dir string, fsys fsi.FS) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		var target = path.Join(dir, p)
+		if d.IsDir() {
+			return o.MkdirAll(target, 0o777)
+		}
+		if !d.Type().IsRegular() {
+			return &fs.PathError{Op: "CopyFS", Path: p, Err: fs.ErrInvalid}
+		}
+
+		var info, ierr = d.Info()
+		if ierr != nil {
+			return ierr
+		}
+		var data, rerr = fs.ReadFile(fsys, p)
+		if rerr != nil {
+			return rerr
+		}
+
+		var f, oerr = o.OpenFile(target, osi.O_CREATE|osi.O_EXCL|osi.O_WRONLY, 0o666|info.Mode()&0o777)
+		if oerr != nil {
+			return oerr
+		}
+		if _, werr := f.Write(data); werr != nil {
+			f.Close()
+			return &fs.PathError{Op: "Copy", Path: target, Err: werr}
+		}
+
+		return f.Close()
+	})
+}
+
+// DirFS returns a read-only fs.FS view of the tree rooted at dir.
+func (o *OS) DirFS(dir string) fsi.FS {
+	return dirFS{os: o, dir: dir}
+}
+
+// Env returns the environment of the OS.
+func (o *OS) Env() *Env {
+	return o.env
+}
+
+func (o *OS) Environ() []string {
+	return o.env.Environ()
+}
+
+func (o *OS) Executable() (string, error) {
+	if o.profile.Executable != "" {
+		return o.profile.Executable, nil
+	}
+	if len(o.args) == 0 || !path.IsAbs(o.args[0]) {
+		return "/usr/local/bin/fake", nil
+	}
+
+	return o.args[0], nil
+}
+
+// Exit records code and panics with an ExitPanic.
+func (o *OS) Exit(code int) {
+	o.fsys.mu.Lock()
+	o.exitCode = &code
+	o.fsys.mu.Unlock()
+
+	panic(ExitPanic{Code: code})
+}
+
+func (o *OS) Expand(s string, mapping func(string) string) string {
+	return os.Expand(s, mapping)
+}
+
+func (o *OS) ExpandEnv(s string) string {
+	return o.env.ExpandEnv(s)
+}
+
+func (o *OS) Getegid() int {
+	return o.profile.EGID
+}
+
+func (o *OS) Getenv(key string) string {
+	return o.env.Getenv(key)
+}
+
+func (o *OS) Geteuid() int {
+	return o.profile.EUID
+}
+
+func (o *OS) Getgid() int {
+	return o.profile.GID
+}
+
+func (o *OS) Getgroups() ([]int, error) {
+	return slices.Clone(o.profile.Groups), nil
+}
+
+func (o *OS) Getpagesize() int {
+	return 4096
+}
+
+func (o *OS) Getpid() int {
+	return o.profile.PID
+}
+
+func (o *OS) Getppid() int {
+	return o.profile.PPID
+}
+
+func (o *OS) Getuid() int {
+	return o.profile.UID
+}
+
+func (o *OS) Getwd() (string, error) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	return o.cwd, nil
+}
+
+func (o *OS) Hostname() (string, error) {
+	return o.profile.Hostname, nil
+}
+
+func (o *OS) IsExist(err error) bool {
+	return os.IsExist(err)
+}
+
+func (o *OS) IsNotExist(err error) bool {
+	return os.IsNotExist(err)
+}
+
+func (o *OS) IsPathSeparator(c uint8) bool {
+	return c == '/'
+}
+
+func (o *OS) IsPermission(err error) bool {
+	return os.IsPermission(err)
+}
+
+func (o *OS) IsTimeout(err error) bool {
+	return os.IsTimeout(err)
+}
+
+// Link creates newname as a hard link to oldname.  Symbolic links
+// are not followed, matching Linux.
+func (o *OS) Link(oldname, newname string) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var linkErr = func(err error) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err}
+	}
+
+	var src, err = o.resolve(oldname, false)
+	if err == nil && src.node == nil {
+		err = syscall.ENOENT
+	}
+	if err != nil {
+		return linkErr(err)
+	}
+	if src.node.isDir() {
+		return linkErr(syscall.EPERM)
+	}
+
+	var dst, derr = o.resolve(newname, false)
+	if derr != nil {
+		return linkErr(derr)
+	}
+	if dst.node != nil {
+		return linkErr(syscall.EEXIST)
+	}
+
+	src.node.nlink++
+	o.fsys.link(dst.parent, dst.base, src.node)
+
+	return nil
+}
+
+func (o *OS) LookupEnv(key string) (string, bool) {
+	return o.env.LookupEnv(key)
+}
+
+func (o *OS) Mkdir(name string, perm osi.FSFileMode) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.resolve(name, false)
+	if err != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	if res.node != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.EEXIST}
+	}
+
+	var mode = perm&(fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky) | perm&fs.ModePerm&^o.fsys.umask
+	o.fsys.link(res.parent, res.base, o.fsys.newNode(fs.ModeDir|mode))
+
+	return nil
+}
+
+// MkdirAll follows the algorithm of os.MkdirAll so that the errors
+// it returns for partially existing paths are the same.
+func (o *OS) MkdirAll(name string, perm osi.FSFileMode) error {
+	if fi, err := o.Stat(name); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	var i = len(name)
+	for i > 0 && name[i-1] == '/' {
+		i--
+	}
+	var j = i
+	for j > 0 && name[j-1] != '/' {
+		j--
+	}
+	if j > 1 {
+		if err := o.MkdirAll(name[:j-1], perm); err != nil {
+			return err
+		}
+	}
+
+	if err := o.Mkdir(name, perm); err != nil {
+		if fi, lerr := o.Lstat(name); lerr == nil && fi.IsDir() {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// MkdirTemp creates a directory whose name is pattern with the last
+// "*" (or the end) replaced by a sequence number.  Names are
+// predictable so that tests can assert on them.
+func (o *OS) MkdirTemp(dir, pattern string) (string, error) {
+	var prefix, suffix, err = o.tempPattern("mkdirtemp", dir, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		var name = prefix + o.nextTemp() + suffix
+		var merr = o.Mkdir(name, 0o700)
+		if merr == nil {
+			return name, nil
+		}
+		if !errors.Is(merr, fs.ErrExist) {
+			return "", merr
+		}
+	}
+}
+
+func (o *OS) CreateTemp(dir, pattern string) (osi.File, error) {
+	var prefix, suffix, err = o.tempPattern("createtemp", dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var name = prefix + o.nextTemp() + suffix
+		var f, oerr = o.OpenFile(name, osi.O_RDWR|osi.O_CREATE|osi.O_EXCL, 0o600)
+		if oerr == nil {
+			return f, nil
+		}
+		if !errors.Is(oerr, fs.ErrExist) {
+			return nil, oerr
+		}
+	}
+}
+
+func (o *OS) tempPattern(op, dir, pattern string) (string, string, error) {
+	if strings.ContainsRune(pattern, '/') {
+		return "", "", &fs.PathError{Op: op, Path: pattern, Err: errPatternHasSeparator}
+	}
+	if dir == "" {
+		dir = o.TempDir()
+	}
+
+	var prefix, suffix = pattern, ""
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+
+	if !strings.HasSuffix(dir, "/") {
+		dir += "/"
+	}
+
+	return dir + prefix, suffix, nil
+}
+
+func (o *OS) nextTemp() string {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	o.tempSeq++
+	return fmt.Sprintf("%09d", o.tempSeq)
+}
+
+func (o *OS) NewSyscallError(syscall string, err error) error {
+	return os.NewSyscallError(syscall, err)
+}
+
+// Pipe returns a connected pair of in-memory pipe ends.
+func (o *OS) Pipe() (osi.File, osi.File, error) {
+	var r, w = newPipe(o.fsys)
+	return r, w, nil
+}
+
+func (o *OS) ReadFile(name string) ([]byte, error) {
+	var f, err = o.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var file = f.(*File)
+	file.fsys.mu.Lock()
+	defer file.fsys.mu.Unlock()
+
+	if file.node.isDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: syscall.EISDIR}
+	}
+	if file.node.dev == devNull {
+		return []byte{}, nil
+	}
+	file.node.atime = o.fsys.now()
+
+	return append([]byte{}, file.node.data...), nil
+}
+
+func (o *OS) Readlink(name string) (string, error) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if !res.node.isSymlink() {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+
+	return res.node.target, nil
+}
+
+func (o *OS) Remove(name string) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("remove", name, false)
+	if err != nil {
+		return err
+	}
+
+	return o.remove(name, res)
+}
+
+func (o *OS) remove(name string, res resolved) error {
+	switch {
+	case res.dot:
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.EINVAL}
+	case res.parent == nil:
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.EBUSY}
+	case res.node.isDir() && len(res.node.children) > 0:
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	o.fsys.unlink(res.parent, res.base)
+
+	return nil
+}
+
+// RemoveAll removes name and any children it contains.  A missing
+// name is not an error.
+func (o *OS) RemoveAll(name string) error {
+	if name == "" {
+		return nil
+	}
+	if name == "." || name == ".." || strings.HasSuffix(name, "/.") || strings.HasSuffix(name, "/..") {
+		return &fs.PathError{Op: "RemoveAll", Path: name, Err: syscall.EINVAL}
+	}
+
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.resolve(name, false)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && res.node == nil {
+		return nil
+	}
+	if err != nil {
+		return &fs.PathError{Op: "unlinkat", Path: name, Err: err}
+	}
+	if res.parent == nil {
+		return &fs.PathError{Op: "unlinkat", Path: name, Err: syscall.EBUSY}
+	}
+	o.fsys.unlink(res.parent, res.base)
+
+	return nil
+}
+
+// Rename moves oldpath to newpath.  Like os.Rename on Unix it
+// refuses to replace an existing directory.
+func (o *OS) Rename(oldpath, newpath string) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var linkErr = func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: err}
+	}
+
+	var src, err = o.resolve(oldpath, false)
+	if err == nil && src.node == nil {
+		err = syscall.ENOENT
+	}
+	if err != nil {
+		return linkErr(err)
+	}
+	if src.dot || src.parent == nil {
+		return linkErr(syscall.EBUSY)
+	}
+
+	var dst, derr = o.resolve(newpath, false)
+	if derr != nil {
+		return linkErr(derr)
+	}
+	if dst.node == src.node {
+		return nil
+	}
+	if dst.node != nil {
+		if dst.node.isDir() {
+			return linkErr(syscall.EEXIST)
+		}
+		if src.node.isDir() {
+			return linkErr(syscall.ENOTDIR)
+		}
+	}
+	if src.node.isDir() && isWithin(dst.path, src.path) {
+		return linkErr(syscall.EINVAL)
+	}
+
+	if dst.node != nil {
+		o.fsys.unlink(dst.parent, dst.base)
+	}
+	delete(src.parent.children, src.base)
+	src.parent.mtime = o.fsys.now()
+	if src.node.isDir() {
+		src.parent.nlink--
+		dst.parent.nlink++
+	}
+	dst.parent.children[dst.base] = src.node
+	dst.parent.mtime = o.fsys.now()
+
+	return nil
+}
+
+// SameFile reports whether both infos describe the same node of
+// this fake.  Infos from other sources never match.
+func (o *OS) SameFile(fi1, fi2 osi.FileInfo) bool {
+	var s1, ok1 = fi1.Sys().(*Stat)
+	var s2, ok2 = fi2.Sys().(*Stat)
+
+	return ok1 && ok2 && s1.Ino == s2.Ino
+}
+
+func (o *OS) Setenv(key, value string) error {
+	return o.env.Setenv(key, value)
+}
+
+func (o *OS) Symlink(oldname, newname string) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.resolve(newname, false)
+	if err == nil && res.node != nil {
+		err = syscall.EEXIST
+	}
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: err}
+	}
+
+	var n = o.fsys.newNode(fs.ModeSymlink | 0o777)
+	n.target = oldname
+	o.fsys.link(res.parent, res.base, n)
+
+	return nil
+}
+
+// TempDir returns $TMPDIR, or the profile's TempDir if it is unset.
+func (o *OS) TempDir() string {
+	if dir := o.env.peek("TMPDIR"); dir != "" {
+		return dir
+	}
+	if o.profile.TempDir != "" {
+		return o.profile.TempDir
+	}
+
+	return "/tmp"
+}
+
+func (o *OS) Truncate(name string, size int64) error {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("truncate", name, true)
+	if err != nil {
+		return err
+	}
+	if res.node.isDir() {
+		return &fs.PathError{Op: "truncate", Path: name, Err: syscall.EISDIR}
+	}
+	if size < 0 {
+		return &fs.PathError{Op: "truncate", Path: name, Err: syscall.EINVAL}
+	}
+	truncate(res.node, size, o.fsys.now())
+
+	return nil
+}
+
+func (o *OS) Unsetenv(key string) error {
+	return o.env.Unsetenv(key)
+}
+
+// UserCacheDir follows the Linux rules: $XDG_CACHE_HOME, or
+// $HOME/.cache.
+func (o *OS) UserCacheDir() (string, error) {
+	return o.xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// UserConfigDir follows the Linux rules: $XDG_CONFIG_HOME, or
+// $HOME/.config.
+func (o *OS) UserConfigDir() (string, error) {
+	return o.xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+func (o *OS) xdgDir(env, fallback string) (string, error) {
+	if dir := o.env.Getenv(env); dir != "" {
+		if !path.IsAbs(dir) {
+			return "", errors.New("path in $" + env + " is relative")
+		}
+		return dir, nil
+	}
+
+	var home = o.env.Getenv("HOME")
+	if home == "" {
+		return "", errors.New("neither $" + env + " nor $HOME are defined")
+	}
+
+	return path.Join(home, fallback), nil
+}
+
+func (o *OS) UserHomeDir() (string, error) {
+	if home := o.env.Getenv("HOME"); home != "" {
+		return home, nil
+	}
+
+	return "", errors.New("$HOME is not defined")
+}
+
+func (o *OS) WriteFile(name string, data []byte, perm osi.FSFileMode) error {
+	var f, err = o.OpenFile(name, osi.O_WRONLY|osi.O_CREATE|osi.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// ReadDir returns the entries of the directory sorted by name.
+func (o *OS) ReadDir(name string) ([]osi.DirEntry, error) {
+	var f, err = o.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.ReadDir(-1)
+}
+
+func (o *OS) Create(name string) (osi.File, error) {
+	return o.OpenFile(name, osi.O_RDWR|osi.O_CREATE|osi.O_TRUNC, 0o666)
+}
+
+// NewFile returns the open file with descriptor fd, or nil if there
+// is none.  Only the standard streams and files opened through this
+// OS are known.
+func (o *OS) NewFile(fd uintptr, name string) osi.File {
+	switch fd {
+	case 0:
+		return o.stdin
+	case 1:
+		return o.stdout
+	case 2:
+		return o.stderr
+	}
+
+	return nil
+}
+
+func (o *OS) Open(name string) (osi.File, error) {
+	return o.OpenFile(name, osi.O_RDONLY, 0)
+}
+
+func (o *OS) OpenFile(name string, flag int, perm osi.FSFileMode) (osi.File, error) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var f, err = o.fsys.openFile(o.cwd, name, flag, perm, false)
+	if err != nil {
+		return nil, err
+	}
+	f.os = o
+
+	return f, nil
+}
+
+// openFile implements OpenFile relative to dir.  It must be called
+// with mu held.
+func (m *memFS) openFile(dir, name string, flag int, perm fs.FileMode, confined bool) (*File, error) {
+	var create = flag&osi.O_CREATE != 0
+	var excl = create && flag&osi.O_EXCL != 0
+
+	var res, err = m.resolve(dir, name, !excl, confined)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	var f = &File{fsys: m, name: name, path: res.path, flag: flag}
+	switch {
+	case res.node == nil && !create:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.ENOENT}
+	case res.node == nil && !m.access(res.parent, permWrite|permExec):
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+	case res.node == nil:
+		res.node = m.newNode(perm & fs.ModePerm &^ m.umask)
+		m.link(res.parent, res.base, res.node)
+	case excl:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EEXIST}
+	case res.node.isDir() && f.writable():
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+	case f.readable() && !m.access(res.node, permRead),
+		f.writable() && !m.access(res.node, permWrite):
+		return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EACCES}
+	case flag&osi.O_TRUNC != 0 && f.writable() && res.node.dev == devNone:
+		truncate(res.node, 0, m.now())
+	}
+
+	f.node = res.node
+	f.fd = m.nextFd
+	m.nextFd++
+
+	return f, nil
+}
+
+// OpenInRoot opens name within dir, refusing to follow any path
+// that leaves dir.
+func (o *OS) OpenInRoot(dir, name string) (osi.File, error) {
+	var r, err = o.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return r.Open(name)
+}
+
+func (o *OS) Lstat(name string) (osi.FileInfo, error) {
+	return o.stat("lstat", name, false)
+}
+
+func (o *OS) Stat(name string) (osi.FileInfo, error) {
+	return o.stat("stat", name, true)
+}
+
+func (o *OS) stat(op, name string, follow bool) (osi.FileInfo, error) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapInfo(newFileInfo(path.Base(name), res.node)), nil
+}
+
+// AddProcess makes p the process FindProcess returns for its PID.
+func (o *OS) AddProcess(p *Process) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	if o.procs == nil {
+		o.procs = make(map[int]*Process)
+	}
+	o.procs[p.PID()] = p
+}
+
+// FindProcess returns the process added for pid with AddProcess, and
+// otherwise reports that the process has finished.
+func (o *OS) FindProcess(pid int) (osi.Process, error) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	if p, ok := o.procs[pid]; ok {
+		return p, nil
+	}
+	return nil, osi.ErrProcessDone
+}
+
+// StartProcess is not supported.
+func (o *OS) StartProcess(name string, argv []string, attr *osi.ProcAttr) (osi.Process, error) {
+	return nil, &fs.PathError{Op: "fork/exec", Path: name, Err: errors.ErrUnsupported}
+}
+
+func (o *OS) OpenRoot(name string) (osi.Root, error) {
+	o.fsys.mu.Lock()
+	defer o.fsys.mu.Unlock()
+
+	var res, err = o.lookup("openat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !res.node.isDir() {
+		return nil, &fs.PathError{Op: "openat", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	return &Root{fsys: o.fsys, os: o, name: name, dir: res.path}, nil
+}
@@ -0,0 +1,455 @@
+package fake_os
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// assertPathError checks that err is a *PathError with the given op
+// and underlying error.
+func assertPathError(t *testing.T, err error, op string, target error) {
+	t.Helper()
+
+	var pe *fs.PathError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected *PathError, got %T (%v)", err, err)
+	}
+	testutil.AssertEqual(t, op, pe.Op)
+	if !errors.Is(pe.Err, target) {
+		t.Errorf("expected %v, got %v", target, pe.Err)
+	}
+}
+
+// TestOS_WriteReadFile tests that written data can be read back.
+func TestOS_WriteReadFile(t *testing.T) {
+	fos := New()
+
+	err := fos.WriteFile("/tmp/a.txt", []byte("hello"), 0o666)
+	testutil.AssertNil(t, err)
+
+	data, err := fos.ReadFile("/tmp/a.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertBytes(t, []byte("hello"), data)
+
+	fi, err := fos.Stat("/tmp/a.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "a.txt", fi.Name())
+	testutil.AssertEqual(t, int64(5), fi.Size())
+	testutil.AssertEqual(t, fs.FileMode(0o644), fi.Nub().Mode())
+}
+
+// TestOS_ReadFileNotExist tests the error for a missing file.
+func TestOS_ReadFileNotExist(t *testing.T) {
+	fos := New()
+
+	_, err := fos.ReadFile("/missing")
+	assertPathError(t, err, "open", syscall.ENOENT)
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrNotExist))
+	testutil.AssertEqual(t, true, fos.IsNotExist(err))
+}
+
+// TestOS_ReadFileDir tests reading a directory as a file.
+func TestOS_ReadFileDir(t *testing.T) {
+	fos := New()
+
+	_, err := fos.ReadFile("/tmp")
+	assertPathError(t, err, "read", syscall.EISDIR)
+}
+
+// TestOS_CreateInMissingDir tests creating a file below a missing directory.
+func TestOS_CreateInMissingDir(t *testing.T) {
+	fos := New()
+
+	_, err := fos.Create("/no/such/file")
+	assertPathError(t, err, "open", syscall.ENOENT)
+}
+
+// TestOS_CreateThroughFile tests a path whose parent is a file.
+func TestOS_CreateThroughFile(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/f", nil, 0o644))
+
+	_, err := fos.Create("/f/g")
+	assertPathError(t, err, "open", syscall.ENOTDIR)
+}
+
+// TestOS_OpenFileExcl tests O_EXCL on an existing file.
+func TestOS_OpenFileExcl(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/f", nil, 0o644))
+
+	_, err := fos.OpenFile("/f", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	assertPathError(t, err, "open", syscall.EEXIST)
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrExist))
+}
+
+// TestOS_OpenFileAppend tests appending writes.
+func TestOS_OpenFileAppend(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/log", []byte("one\n"), 0o644))
+
+	f, err := fos.OpenFile("/log", os.O_WRONLY|os.O_APPEND, 0)
+	testutil.AssertNil(t, err)
+	_, err = f.WriteString("two\n")
+	testutil.AssertNil(t, err)
+	testutil.AssertNil(t, f.Close())
+
+	data, _ := fos.ReadFile("/log")
+	testutil.AssertEqual(t, "one\ntwo\n", string(data))
+}
+
+// TestOS_MkdirAll tests creating nested directories.
+func TestOS_MkdirAll(t *testing.T) {
+	fos := New()
+
+	testutil.AssertNil(t, fos.MkdirAll("/a/b/c", 0o755))
+	testutil.AssertNil(t, fos.MkdirAll("/a/b/c", 0o755))
+
+	fi, err := fos.Stat("/a/b")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, fi.IsDir())
+
+	testutil.AssertNil(t, fos.WriteFile("/a/file", nil, 0o644))
+	err = fos.MkdirAll("/a/file/x", 0o755)
+	assertPathError(t, err, "mkdir", syscall.ENOTDIR)
+}
+
+// TestOS_MkdirExists tests Mkdir on an existing path.
+func TestOS_MkdirExists(t *testing.T) {
+	fos := New()
+
+	err := fos.Mkdir("/tmp", 0o755)
+	assertPathError(t, err, "mkdir", syscall.EEXIST)
+}
+
+// TestOS_ReadDir tests that directory entries are sorted.
+func TestOS_ReadDir(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.Mkdir("/d", 0o755))
+	testutil.AssertNil(t, fos.WriteFile("/d/b", nil, 0o644))
+	testutil.AssertNil(t, fos.WriteFile("/d/a", nil, 0o644))
+	testutil.AssertNil(t, fos.Mkdir("/d/c", 0o755))
+
+	entries, err := fos.ReadDir("/d")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 3, len(entries))
+	testutil.AssertEqual(t, "a", entries[0].Name())
+	testutil.AssertEqual(t, "b", entries[1].Name())
+	testutil.AssertEqual(t, "c", entries[2].Name())
+	testutil.AssertEqual(t, true, entries[2].IsDir())
+}
+
+// TestOS_Rename tests renaming files and directories.
+func TestOS_Rename(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/a", []byte("x"), 0o644))
+
+	testutil.AssertNil(t, fos.Rename("/a", "/b"))
+	_, err := fos.Stat("/a")
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrNotExist))
+	data, _ := fos.ReadFile("/b")
+	testutil.AssertEqual(t, "x", string(data))
+
+	testutil.AssertNil(t, fos.MkdirAll("/d/e", 0o755))
+	err = fos.Rename("/b", "/d")
+	var le *os.LinkError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LinkError, got %T", err)
+	}
+	testutil.AssertEqual(t, syscall.EEXIST, le.Err)
+
+	err = fos.Rename("/d", "/d/e/f")
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EINVAL))
+
+	err = fos.Rename("/missing", "/x")
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrNotExist))
+}
+
+// TestOS_Remove tests removing files and directories.
+func TestOS_Remove(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.MkdirAll("/d/e", 0o755))
+
+	err := fos.Remove("/d")
+	assertPathError(t, err, "remove", syscall.ENOTEMPTY)
+
+	testutil.AssertNil(t, fos.Remove("/d/e"))
+	testutil.AssertNil(t, fos.Remove("/d"))
+
+	err = fos.Remove("/d")
+	assertPathError(t, err, "remove", syscall.ENOENT)
+}
+
+// TestOS_RemoveAll tests recursive removal.
+func TestOS_RemoveAll(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.MkdirAll("/d/e/f", 0o755))
+	testutil.AssertNil(t, fos.WriteFile("/d/e/f/g", nil, 0o644))
+
+	testutil.AssertNil(t, fos.RemoveAll("/d"))
+	testutil.AssertNil(t, fos.RemoveAll("/d"))
+
+	_, err := fos.Stat("/d")
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrNotExist))
+
+	err = fos.RemoveAll("/tmp/.")
+	assertPathError(t, err, "RemoveAll", syscall.EINVAL)
+}
+
+// TestOS_Symlink tests creating and following symbolic links.
+func TestOS_Symlink(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/target", []byte("data"), 0o644))
+	testutil.AssertNil(t, fos.Symlink("target", "/link"))
+
+	dest, err := fos.Readlink("/link")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "target", dest)
+
+	data, err := fos.ReadFile("/link")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "data", string(data))
+
+	fi, err := fos.Lstat("/link")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, fi.Nub().Mode()&fs.ModeSymlink != 0)
+
+	_, err = fos.Readlink("/target")
+	assertPathError(t, err, "readlink", syscall.EINVAL)
+
+	err = fos.Symlink("x", "/link")
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrExist))
+}
+
+// TestOS_SymlinkLoop tests that a symlink loop is detected.
+func TestOS_SymlinkLoop(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.Symlink("/b", "/a"))
+	testutil.AssertNil(t, fos.Symlink("/a", "/b"))
+
+	_, err := fos.Stat("/a")
+	assertPathError(t, err, "stat", syscall.ELOOP)
+}
+
+// TestOS_DanglingSymlinkCreate tests creating a file through a dangling link.
+func TestOS_DanglingSymlinkCreate(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.Symlink("/real", "/link"))
+
+	testutil.AssertNil(t, fos.WriteFile("/link", []byte("x"), 0o644))
+	data, err := fos.ReadFile("/real")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "x", string(data))
+}
+
+// TestOS_Link tests hard links share content.
+func TestOS_Link(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/a", []byte("1"), 0o644))
+	testutil.AssertNil(t, fos.Link("/a", "/b"))
+	testutil.AssertNil(t, fos.WriteFile("/b", []byte("2"), 0o644))
+
+	data, _ := fos.ReadFile("/a")
+	testutil.AssertEqual(t, "2", string(data))
+
+	fa, _ := fos.Stat("/a")
+	fb, _ := fos.Stat("/b")
+	testutil.AssertEqual(t, true, fos.SameFile(fa, fb))
+	testutil.AssertEqual(t, 2, fa.Sys().(*Stat).Nlink)
+}
+
+// TestOS_Chmod tests changing permissions.
+func TestOS_Chmod(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/a", nil, 0o644))
+
+	testutil.AssertNil(t, fos.Chmod("/a", 0o600))
+	fi, _ := fos.Stat("/a")
+	testutil.AssertEqual(t, fs.FileMode(0o600), fi.Nub().Mode())
+
+	err := fos.Chmod("/missing", 0o600)
+	assertPathError(t, err, "chmod", syscall.ENOENT)
+}
+
+// TestOS_Chtimes tests changing modification times.
+func TestOS_Chtimes(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/a", nil, 0o644))
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	testutil.AssertNil(t, fos.Chtimes("/a", time.Time{}, mtime))
+
+	fi, _ := fos.Stat("/a")
+	testutil.AssertEqual(t, true, fi.ModTime().Equal(mtime))
+
+	err := fos.Chtimes("/missing", mtime, mtime)
+	assertPathError(t, err, "chtimes", syscall.ENOENT)
+}
+
+// TestOS_Chdir tests relative paths after changing directory.
+func TestOS_Chdir(t *testing.T) {
+	fos := New(WithWorkingDir("/home/user"))
+
+	wd, _ := fos.Getwd()
+	testutil.AssertEqual(t, "/home/user", wd)
+
+	testutil.AssertNil(t, fos.WriteFile("notes", []byte("n"), 0o644))
+	_, err := fos.Stat("/home/user/notes")
+	testutil.AssertNil(t, err)
+
+	testutil.AssertNil(t, fos.Chdir(".."))
+	wd, _ = fos.Getwd()
+	testutil.AssertEqual(t, "/home", wd)
+
+	err = fos.Chdir("user/notes")
+	assertPathError(t, err, "chdir", syscall.ENOTDIR)
+}
+
+// TestOS_Truncate tests truncating by name.
+func TestOS_Truncate(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.WriteFile("/a", []byte("hello"), 0o644))
+
+	testutil.AssertNil(t, fos.Truncate("/a", 2))
+	data, _ := fos.ReadFile("/a")
+	testutil.AssertEqual(t, "he", string(data))
+
+	err := fos.Truncate("/tmp", 0)
+	assertPathError(t, err, "truncate", syscall.EISDIR)
+}
+
+// TestOS_Temp tests temporary file and directory naming.
+func TestOS_Temp(t *testing.T) {
+	fos := New()
+
+	dir, err := fos.MkdirTemp("", "build-*")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/tmp/build-000000001", dir)
+
+	f, err := fos.CreateTemp(dir, "*.txt")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, dir+"/000000002.txt", f.Name())
+
+	_, err = fos.MkdirTemp("", "a/b")
+	testutil.AssertError(t, errPatternHasSeparator, errors.Unwrap(err))
+}
+
+// TestOS_Env tests the environment accessors.
+func TestOS_Env(t *testing.T) {
+	fos := New()
+
+	testutil.AssertNil(t, fos.Setenv("HOME", "/home/user"))
+	testutil.AssertNil(t, fos.Setenv("A", "1"))
+	testutil.AssertEqual(t, "/home/user", fos.Getenv("HOME"))
+	testutil.AssertEqual(t, "1/home/user", fos.ExpandEnv("${A}$HOME"))
+
+	dir, err := fos.UserConfigDir()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/home/user/.config", dir)
+
+	testutil.AssertNil(t, fos.Unsetenv("A"))
+	_, ok := fos.LookupEnv("A")
+	testutil.AssertEqual(t, false, ok)
+
+	fos.Clearenv()
+	testutil.AssertEqual(t, 0, len(fos.Environ()))
+	_, err = fos.UserHomeDir()
+	testutil.AssertNotNil(t, err)
+}
+
+// TestOS_Exit tests that Exit panics with the code.
+func TestOS_Exit(t *testing.T) {
+	fos := New()
+
+	defer func() {
+		r := recover()
+		testutil.AssertEqual(t, ExitPanic{Code: 3}, r)
+
+		code, ok := fos.ExitCode()
+		testutil.AssertEqual(t, true, ok)
+		testutil.AssertEqual(t, 3, code)
+	}()
+
+	fos.Exit(3)
+}
+
+// TestOS_Stdio tests that standard output is captured in /dev/stdout.
+func TestOS_Stdio(t *testing.T) {
+	fos := New()
+
+	_, err := fos.Stdout().WriteString("out")
+	testutil.AssertNil(t, err)
+
+	data, err := fos.ReadFile("/dev/stdout")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "out", string(data))
+
+	testutil.AssertNil(t, fos.WriteFile("/dev/null", []byte("gone"), 0))
+	data, _ = fos.ReadFile("/dev/null")
+	testutil.AssertEqual(t, 0, len(data))
+}
+
+// TestOS_Pipe tests the in-memory pipe.
+func TestOS_Pipe(t *testing.T) {
+	fos := New()
+
+	r, w, err := fos.Pipe()
+	testutil.AssertNil(t, err)
+
+	go func() {
+		w.Write([]byte("ping"))
+		w.Close()
+	}()
+
+	buf := make([]byte, 4)
+	n, err := r.Read(buf)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "ping", string(buf[:n]))
+
+	_, err = r.Seek(0, 0)
+	assertPathError(t, err, "seek", syscall.ESPIPE)
+}
+
+// TestOS_CopyFS tests copying an fs.FS into the tree.
+func TestOS_CopyFS(t *testing.T) {
+	fos := New()
+	src := fstest.MapFS{
+		"a.txt":   {Data: []byte("a")},
+		"sub/b.x": {Data: []byte("b"), Mode: 0o755},
+	}
+
+	testutil.AssertNil(t, fos.CopyFS("/dst", src))
+
+	data, _ := fos.ReadFile("/dst/sub/b.x")
+	testutil.AssertEqual(t, "b", string(data))
+	fi, _ := fos.Stat("/dst/sub/b.x")
+	testutil.AssertEqual(t, fs.FileMode(0o755), fi.Nub().Mode())
+
+	err := fos.CopyFS("/dst", src)
+	testutil.AssertEqual(t, true, errors.Is(err, fs.ErrExist))
+}
+
+// TestOS_DirFS runs the standard fs.FS conformance tests on DirFS.
+func TestOS_DirFS(t *testing.T) {
+	fos := New()
+	testutil.AssertNil(t, fos.MkdirAll("/srv/a/b", 0o755))
+	testutil.AssertNil(t, fos.WriteFile("/srv/a/b/c.txt", []byte("c"), 0o644))
+	testutil.AssertNil(t, fos.WriteFile("/srv/top.txt", []byte("top"), 0o644))
+
+	if err := fstest.TestFS(fos.DirFS("/srv"), "a/b/c.txt", "top.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := fs.Glob(fos.DirFS("/srv"), "*.txt")
+	testutil.AssertNil(t, err)
+	sort.Strings(names)
+	testutil.AssertEqual(t, 1, len(names))
+}
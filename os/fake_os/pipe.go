@@ -0,0 +1,142 @@
+package fake_os
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"syscall"
+	"time"
+
+	fsi "github.com/pdutton/go-interfaces/io/fs"
+	osi "github.com/pdutton/go-interfaces/os"
+)
+
+// pipeEnd is one side of the pair returned by OS.Pipe.  It is a
+// thin File wrapper around io.Pipe; operations that make no sense
+// on a pipe fail the way they do for a real pipe descriptor.
+type pipeEnd struct {
+	r    *io.PipeReader
+	w    *io.PipeWriter
+	name string
+	fd   uintptr
+	now  time.Time
+}
+
+var _ osi.File = (*pipeEnd)(nil)
+
+func newPipe(m *memFS) (*pipeEnd, *pipeEnd) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pr, pw = io.Pipe()
+	var now = m.now()
+	var r = &pipeEnd{r: pr, name: "|0", fd: m.nextFd, now: now}
+	var w = &pipeEnd{w: pw, name: "|1", fd: m.nextFd + 1, now: now}
+	m.nextFd += 2
+
+	return r, w
+}
+
+func (p *pipeEnd) err(op string, err error) error {
+	return &fs.PathError{Op: op, Path: p.name, Err: err}
+}
+
+func (p *pipeEnd) Chdir() error                { return p.err("chdir", syscall.ENOTDIR) }
+func (p *pipeEnd) Chmod(osi.FSFileMode) error  { return nil }
+func (p *pipeEnd) Chown(int, int) error        { return nil }
+func (p *pipeEnd) Fd() uintptr                 { return p.fd }
+func (p *pipeEnd) Name() string                { return p.name }
+func (p *pipeEnd) Sync() error                 { return p.err("sync", syscall.EINVAL) }
+func (p *pipeEnd) Truncate(int64) error        { return p.err("truncate", syscall.EINVAL) }
+func (p *pipeEnd) SetDeadline(time.Time) error { return p.err("SetDeadline", osi.ErrNoDeadline) }
+
+func (p *pipeEnd) SetReadDeadline(time.Time) error {
+	return p.err("SetReadDeadline", osi.ErrNoDeadline)
+}
+
+func (p *pipeEnd) SetWriteDeadline(time.Time) error {
+	return p.err("SetWriteDeadline", osi.ErrNoDeadline)
+}
+
+func (p *pipeEnd) Close() error {
+	if p.r != nil {
+		return p.r.Close()
+	}
+
+	return p.w.Close()
+}
+
+func (p *pipeEnd) Read(b []byte) (int, error) {
+	if p.r == nil {
+		return 0, p.err("read", syscall.EBADF)
+	}
+
+	var n, err = p.r.Read(b)
+	if errors.Is(err, io.ErrClosedPipe) {
+		err = p.err("read", fs.ErrClosed)
+	}
+
+	return n, err
+}
+
+func (p *pipeEnd) Write(b []byte) (int, error) {
+	if p.w == nil {
+		return 0, p.err("write", syscall.EBADF)
+	}
+
+	var n, err = p.w.Write(b)
+	if errors.Is(err, io.ErrClosedPipe) {
+		err = p.err("write", syscall.EPIPE)
+	}
+
+	return n, err
+}
+
+func (p *pipeEnd) WriteString(s string) (int, error) {
+	return p.Write([]byte(s))
+}
+
+func (p *pipeEnd) ReadAt([]byte, int64) (int, error) {
+	return 0, p.err("read", syscall.ESPIPE)
+}
+
+func (p *pipeEnd) WriteAt([]byte, int64) (int, error) {
+	return 0, p.err("write", syscall.ESPIPE)
+}
+
+func (p *pipeEnd) Seek(int64, int) (int64, error) {
+	return 0, p.err("seek", syscall.ESPIPE)
+}
+
+func (p *pipeEnd) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{p}, r)
+}
+
+func (p *pipeEnd) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{p})
+}
+
+func (p *pipeEnd) ReadDir(int) ([]fsi.DirEntry, error) {
+	return nil, p.err("readdirent", syscall.ENOTDIR)
+}
+
+func (p *pipeEnd) Readdir(int) ([]osi.FileInfo, error) {
+	return nil, p.err("readdirent", syscall.ENOTDIR)
+}
+
+func (p *pipeEnd) Readdirnames(int) ([]string, error) {
+	return nil, p.err("readdirent", syscall.ENOTDIR)
+}
+
+func (p *pipeEnd) Stat() (osi.FileInfo, error) {
+	return wrapInfo(&fileInfo{
+		name:    p.name,
+		mode:    fs.ModeNamedPipe | 0o600,
+		modTime: p.now,
+		sys:     &Stat{Nlink: 1},
+	}), nil
+}
+
+func (p *pipeEnd) SyscallConn() (syscall.RawConn, error) {
+	return nil, p.err("SyscallConn", errors.ErrUnsupported)
+}
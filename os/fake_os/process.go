@@ -0,0 +1,225 @@
+package fake_os
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+
+	osi "github.com/pdutton/go-interfaces/os"
+
+	"github.com/pdutton/go-mocks/internal/procstate"
+)
+
+var errProcessReleased = errors.New("os: process already released")
+
+// ProcessOption configures a Process created by NewProcess.
+type ProcessOption func(*Process)
+
+// OnSignal sets what the process does when it receives sig: fn is
+// called with the process and the signal, and may call Exit or
+// Terminate to end it.  A nil fn ignores the signal.  The handler for
+// os.Kill is never called, as SIGKILL cannot be caught.
+func OnSignal(sig os.Signal, fn func(p *Process, sig os.Signal)) ProcessOption {
+	return func(p *Process) {
+		p.handlers[sig] = fn
+	}
+}
+
+// ExitOnSignal makes the process exit with code when it receives sig,
+// as a program that shuts down cleanly on SIGTERM would.
+func ExitOnSignal(sig os.Signal, code int) ProcessOption {
+	return OnSignal(sig, func(p *Process, _ os.Signal) {
+		p.Exit(code)
+	})
+}
+
+// Process is a simulated process implementing the go-interfaces
+// os.Process interface.  It runs until the test calls Exit or
+// Terminate, or a signal ends it.  It is safe for concurrent use.
+//
+// A signal the process has no handler for terminates it, which is the
+// default action for most signals, unless its default action is to be
+// ignored, as for SIGCHLD, SIGURG and SIGWINCH.
+type Process struct {
+	pid int
+
+	mu       sync.Mutex
+	handlers map[os.Signal]func(*Process, os.Signal)
+	signals  []os.Signal
+	released bool
+	waited   bool
+	exited   bool
+	code     int
+	signal   os.Signal
+	done     chan struct{}
+}
+
+var _ osi.Process = (*Process)(nil)
+
+// NewProcess returns a running process with the given PID.
+func NewProcess(pid int, options ...ProcessOption) *Process {
+	var p = &Process{
+		pid:      pid,
+		handlers: make(map[os.Signal]func(*Process, os.Signal)),
+		done:     make(chan struct{}),
+	}
+
+	for _, f := range options {
+		f(p)
+	}
+
+	return p
+}
+
+func (p *Process) PID() int {
+	return p.pid
+}
+
+// Nub always returns nil; there is no operating system process behind
+// a fake one.
+func (p *Process) Nub() *os.Process {
+	return nil
+}
+
+func (p *Process) Kill() error {
+	return p.Signal(os.Kill)
+}
+
+// Release marks the process as released.  Later calls to Signal and
+// Wait fail, as they do for a real process.
+func (p *Process) Release() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.released = true
+
+	return nil
+}
+
+// Signal delivers sig to the process, running its handler.  It fails
+// with os.ErrProcessDone once the process has exited.  Signal 0 is not
+// delivered; it only checks that the process is still running.
+func (p *Process) Signal(sig osi.Signal) error {
+	p.mu.Lock()
+	if p.released {
+		p.mu.Unlock()
+		return errProcessReleased
+	}
+	if p.exited {
+		p.mu.Unlock()
+		return os.ErrProcessDone
+	}
+	if sig == syscall.Signal(0) {
+		p.mu.Unlock()
+		return nil
+	}
+	p.signals = append(p.signals, sig)
+	var fn, handled = p.handlers[sig]
+	p.mu.Unlock()
+
+	switch {
+	case sig == os.Kill, !handled && !slices.Contains(ignoredSignals, sig):
+		p.Terminate(sig)
+	case fn != nil:
+		fn(p, sig)
+	}
+
+	return nil
+}
+
+// Wait waits for the process to end and returns its state.  On
+// platforms where os.ProcessState cannot be built the state is nil;
+// ExitCode and ExitSignal report the same information portably.
+func (p *Process) Wait() (*os.ProcessState, error) {
+	p.mu.Lock()
+	if p.released {
+		p.mu.Unlock()
+		return nil, syscall.EINVAL
+	}
+	if p.waited {
+		p.mu.Unlock()
+		return nil, os.NewSyscallError("wait", syscall.ECHILD)
+	}
+	p.waited = true
+	p.mu.Unlock()
+
+	<-p.done
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.signal != nil {
+		return procstate.Signaled(p.pid, p.signal), nil
+	}
+	return procstate.Exited(p.pid, p.code), nil
+}
+
+// Exit ends the process with code, unless it has already ended.
+func (p *Process) Exit(code int) {
+	p.end(code, nil)
+}
+
+// Terminate ends the process as if killed by sig, unless it has
+// already ended.
+func (p *Process) Terminate(sig os.Signal) {
+	p.end(-1, sig)
+}
+
+func (p *Process) end(code int, sig os.Signal) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.exited {
+		return
+	}
+
+	p.exited = true
+	p.code = code
+	p.signal = sig
+	close(p.done)
+}
+
+// Done returns a channel that is closed when the process ends.
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// Exited reports whether the process has ended.
+func (p *Process) Exited() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.exited
+}
+
+// ExitCode returns the code the process exited with, or -1 if it is
+// still running or was terminated by a signal, like
+// os.ProcessState.ExitCode.
+func (p *Process) ExitCode() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.exited {
+		return -1
+	}
+	return p.code
+}
+
+// ExitSignal returns the signal that terminated the process, or nil if
+// it is still running or exited normally.
+func (p *Process) ExitSignal() os.Signal {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.signal
+}
+
+// Signals returns the signals delivered to the process, in order.
+func (p *Process) Signals() []os.Signal {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.signals)
+}
@@ -0,0 +1,125 @@
+package fake_os
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestProcess_Exit tests waiting for a process that exits with a code.
+func TestProcess_Exit(t *testing.T) {
+	p := NewProcess(42)
+	testutil.AssertEqual(t, 42, p.PID())
+	testutil.AssertEqual(t, false, p.Exited())
+	testutil.AssertEqual(t, -1, p.ExitCode())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.Exit(3)
+	}()
+
+	ps, err := p.Wait()
+	testutil.AssertNil(t, err)
+	if ps != nil {
+		testutil.AssertEqual(t, 42, ps.Pid())
+		testutil.AssertEqual(t, 3, ps.ExitCode())
+	}
+	testutil.AssertEqual(t, 3, p.ExitCode())
+	testutil.AssertNil(t, p.ExitSignal())
+
+	_, err = p.Wait()
+	testutil.AssertError(t, syscall.ECHILD, err)
+	testutil.AssertEqual(t, os.ErrProcessDone, p.Signal(os.Interrupt))
+
+	// Only the first ending counts.
+	p.Terminate(os.Kill)
+	testutil.AssertEqual(t, 3, p.ExitCode())
+}
+
+// TestProcess_Signal tests signals with and without handlers.
+func TestProcess_Signal(t *testing.T) {
+	var got []os.Signal
+	p := NewProcess(7,
+		OnSignal(syscall.SIGHUP, func(p *Process, sig os.Signal) {
+			got = append(got, sig)
+		}),
+		OnSignal(syscall.SIGPIPE, nil),
+		ExitOnSignal(syscall.SIGTERM, 143),
+	)
+
+	testutil.AssertNil(t, p.Signal(syscall.SIGHUP))
+	testutil.AssertNil(t, p.Signal(syscall.SIGPIPE))
+	testutil.AssertEqual(t, false, p.Exited())
+	testutil.AssertEqual(t, 1, len(got))
+
+	testutil.AssertNil(t, p.Signal(syscall.SIGTERM))
+	<-p.Done()
+	testutil.AssertEqual(t, 143, p.ExitCode())
+	testutil.AssertEqual(t, 3, len(p.Signals()))
+}
+
+// TestProcess_Kill tests that Kill and unhandled signals terminate the
+// process, even with a handler for SIGKILL.
+func TestProcess_Kill(t *testing.T) {
+	p := NewProcess(1, OnSignal(os.Kill, nil))
+	testutil.AssertNil(t, p.Kill())
+
+	ps, err := p.Wait()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, os.Kill, p.ExitSignal())
+	testutil.AssertEqual(t, -1, p.ExitCode())
+	if ps != nil {
+		testutil.AssertEqual(t, false, ps.Success())
+	}
+
+	p = NewProcess(2)
+	testutil.AssertNil(t, p.Signal(os.Interrupt))
+	testutil.AssertEqual(t, os.Interrupt, p.ExitSignal())
+}
+
+// TestProcess_SignalZero tests that signal 0 checks the process is
+// running without ending it.
+func TestProcess_SignalZero(t *testing.T) {
+	p := NewProcess(1)
+	testutil.AssertNil(t, p.Signal(syscall.Signal(0)))
+	testutil.AssertEqual(t, false, p.Exited())
+	testutil.AssertEqual(t, 0, len(p.Signals()))
+
+	p.Exit(0)
+	testutil.AssertEqual(t, os.ErrProcessDone, p.Signal(syscall.Signal(0)))
+}
+
+// TestProcess_Release tests that a released process can no longer be
+// signalled or waited for.
+func TestProcess_Release(t *testing.T) {
+	p := NewProcess(1)
+	testutil.AssertNil(t, p.Release())
+
+	testutil.AssertEqual(t, "os: process already released", p.Signal(os.Interrupt).Error())
+	_, err := p.Wait()
+	testutil.AssertEqual(t, true, errors.Is(err, syscall.EINVAL))
+	testutil.AssertEqual(t, false, p.Exited())
+}
+
+// TestOS_FindProcess tests finding an added process.
+func TestOS_FindProcess(t *testing.T) {
+	o := New()
+	o.AddProcess(NewProcess(99, ExitOnSignal(syscall.SIGTERM, 0)))
+
+	p, err := o.FindProcess(99)
+	testutil.AssertNil(t, err)
+	testutil.AssertNil(t, p.Signal(syscall.SIGTERM))
+
+	ps, err := p.Wait()
+	testutil.AssertNil(t, err)
+	if ps != nil {
+		testutil.AssertEqual(t, true, ps.Success())
+	}
+
+	_, err = o.FindProcess(100)
+	testutil.AssertError(t, os.ErrProcessDone, err)
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package fake_os
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestProcess_IgnoredSignals tests that signals ignored by default do
+// not end a process without a handler for them.
+func TestProcess_IgnoredSignals(t *testing.T) {
+	var got []os.Signal
+	p := NewProcess(1, OnSignal(syscall.SIGWINCH, func(p *Process, sig os.Signal) {
+		got = append(got, sig)
+	}))
+
+	for _, sig := range []os.Signal{syscall.SIGCHLD, syscall.SIGURG, syscall.SIGWINCH} {
+		testutil.AssertNil(t, p.Signal(sig))
+	}
+	testutil.AssertEqual(t, false, p.Exited())
+	testutil.AssertEqual(t, 3, len(p.Signals()))
+	testutil.AssertEqual(t, 1, len(got))
+}
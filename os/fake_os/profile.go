@@ -0,0 +1,130 @@
+package fake_os
+
+import (
+	"io/fs"
+	"slices"
+)
+
+// Profile is the identity of the simulated process and the host it
+// runs on.  Start from DefaultProfile and change what matters to the
+// test:
+//
+//	p := fake_os.DefaultProfile()
+//	p.UID, p.EUID, p.GID, p.EGID, p.Groups = 0, 0, 0, 0, []int{0}
+//	p.Hostname = "build-01"
+//	fos := fake_os.New(fake_os.WithProfile(p))
+type Profile struct {
+	// UID and GID are the real user and group IDs, and EUID and EGID
+	// the effective ones, which own new files and are checked against
+	// the permissions of existing ones.  Groups are the supplementary
+	// groups, also checked against file permissions.
+	UID, EUID int
+	GID, EGID int
+	Groups    []int
+
+	Hostname string
+	PID      int
+	PPID     int
+
+	// Executable is the path Executable returns.  If it is empty, the
+	// first argument is used when it is absolute, and otherwise
+	// /usr/local/bin/fake.
+	Executable string
+
+	// Home, if set, is created and becomes $HOME unless the
+	// environment already sets it.
+	Home string
+
+	// TempDir is created, and returned by TempDir when $TMPDIR is
+	// unset.
+	TempDir string
+}
+
+// DefaultProfile returns the profile an OS has unless WithProfile is
+// given: an unprivileged user with uid and gid 1000 on host
+// "localhost", running as pid 4242.
+func DefaultProfile() Profile {
+	return Profile{
+		UID:      1000,
+		EUID:     1000,
+		GID:      1000,
+		EGID:     1000,
+		Groups:   []int{1000},
+		Hostname: "localhost",
+		PID:      4242,
+		PPID:     1,
+		TempDir:  "/tmp",
+	}
+}
+
+// WithProfile sets the identity of the process and host.
+func WithProfile(p Profile) Option {
+	return func(o *OS) {
+		o.profile = p
+		o.profile.Groups = slices.Clone(p.Groups)
+	}
+}
+
+// Profile returns the identity of the process and host.
+func (o *OS) Profile() Profile {
+	var p = o.profile
+	p.Groups = slices.Clone(p.Groups)
+
+	return p
+}
+
+// applyProfile makes the tree's owner the effective user, and creates
+// the home and temporary directories.  It runs before the skeleton is
+// built.
+func (o *OS) applyProfile() {
+	var m = o.fsys
+	m.uid, m.gid = o.profile.EUID, o.profile.EGID
+	m.groups = o.profile.Groups
+	m.root.uid, m.root.gid = m.uid, m.gid
+
+	if o.profile.Home != "" {
+		o.env.setDefault("HOME", o.profile.Home)
+	}
+}
+
+// makeProfileDirs creates the directories the profile names, once the
+// skeleton exists.
+func (o *OS) makeProfileDirs() {
+	for _, dir := range []string{o.profile.Home, o.profile.TempDir} {
+		if dir == "" {
+			continue
+		}
+		if err := o.MkdirAll(dir, 0o755); err != nil {
+			panic("fake_os: cannot create " + dir + ": " + err.Error())
+		}
+	}
+}
+
+// Permission bits, shifted to the "other" position.
+const (
+	permRead  fs.FileMode = 4
+	permWrite fs.FileMode = 2
+	permExec  fs.FileMode = 1
+)
+
+// access reports whether the effective user may use n as want, a
+// combination of permRead, permWrite and permExec.  It follows the
+// Unix rules: root may do anything except execute a file no one can,
+// the owner is checked against the owner bits, members of the file's
+// group against the group bits, and everyone else against the other
+// bits.  It must be called with mu held.
+func (m *memFS) access(n *node, want fs.FileMode) bool {
+	var perm = n.mode.Perm()
+	if m.uid == 0 {
+		return want&permExec == 0 || n.isDir() || perm&0o111 != 0
+	}
+
+	switch {
+	case n.uid == m.uid:
+		perm >>= 6
+	case n.gid == m.gid || slices.Contains(m.groups, n.gid):
+		perm >>= 3
+	}
+
+	return perm&want == want
+}
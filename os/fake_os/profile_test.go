@@ -0,0 +1,129 @@
+package fake_os
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestOS_DefaultProfile tests the identity of an OS with no profile.
+func TestOS_DefaultProfile(t *testing.T) {
+	fos := New(WithArgs("/opt/app/bin/app", "-v"))
+
+	testutil.AssertEqual(t, 1000, fos.Getuid())
+	testutil.AssertEqual(t, 1000, fos.Getegid())
+	testutil.AssertEqual(t, 4242, fos.Getpid())
+	testutil.AssertEqual(t, 1, fos.Getppid())
+	testutil.AssertEqual(t, "/tmp", fos.TempDir())
+
+	host, err := fos.Hostname()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "localhost", host)
+
+	exe, err := fos.Executable()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/opt/app/bin/app", exe)
+
+	_, err = fos.UserHomeDir()
+	testutil.AssertNotNil(t, err)
+}
+
+// TestOS_RootProfile tests root on a build host, who may open any file.
+func TestOS_RootProfile(t *testing.T) {
+	p := DefaultProfile()
+	p.UID, p.EUID, p.GID, p.EGID, p.Groups = 0, 0, 0, 0, []int{0}
+	p.Hostname = "build-01"
+	p.Executable = "/usr/bin/builder"
+	fos := New(WithProfile(p))
+
+	testutil.AssertEqual(t, 0, fos.Geteuid())
+	host, _ := fos.Hostname()
+	testutil.AssertEqual(t, "build-01", host)
+	exe, _ := fos.Executable()
+	testutil.AssertEqual(t, "/usr/bin/builder", exe)
+
+	testutil.AssertNil(t, fos.WriteFile("/secret", []byte("x"), 0o644))
+	testutil.AssertNil(t, fos.Chown("/secret", 1000, 1000))
+	testutil.AssertNil(t, fos.Chmod("/secret", 0))
+	data, err := fos.ReadFile("/secret")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "x", string(data))
+
+	info, err := fos.Stat("/")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 0, info.Sys().(*Stat).Uid)
+}
+
+// TestOS_UserProfile tests an unprivileged user's home and temporary
+// directories.
+func TestOS_UserProfile(t *testing.T) {
+	p := DefaultProfile()
+	p.UID, p.EUID = 1001, 1001
+	p.Home = "/home/alice"
+	p.TempDir = "/var/tmp/alice"
+	p.PID, p.PPID = 300, 299
+	fos := New(WithProfile(p))
+
+	home, err := fos.UserHomeDir()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/home/alice", home)
+	testutil.AssertEqual(t, "/home/alice", fos.Getenv("HOME"))
+
+	dir, err := fos.UserCacheDir()
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/home/alice/.cache", dir)
+
+	testutil.AssertEqual(t, "/var/tmp/alice", fos.TempDir())
+	f, err := fos.CreateTemp("", "x")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, strings.HasPrefix(f.Name(), "/var/tmp/alice/x"))
+
+	testutil.AssertEqual(t, 300, fos.Getpid())
+	testutil.AssertEqual(t, 299, fos.Profile().PPID)
+
+	// An explicit $HOME wins.
+	fos = New(WithProfile(p), WithEnv(NewEnv(map[string]string{"HOME": "/srv"})))
+	home, _ = fos.UserHomeDir()
+	testutil.AssertEqual(t, "/srv", home)
+}
+
+// TestOS_Permissions tests that opening files honours the owner, group
+// and mode.
+func TestOS_Permissions(t *testing.T) {
+	p := DefaultProfile()
+	p.Groups = []int{1000, 50}
+	fos := New(WithProfile(p))
+
+	testutil.AssertNil(t, fos.WriteFile("/root-only", nil, 0o600))
+	testutil.AssertNil(t, fos.Chown("/root-only", 0, 0))
+	_, err := fos.Open("/root-only")
+	testutil.AssertError(t, syscall.EACCES, err)
+	testutil.AssertEqual(t, "open /root-only: permission denied", err.Error())
+
+	// Readable through a supplementary group, but not writable.
+	testutil.AssertNil(t, fos.WriteFile("/shared", []byte("s"), 0o640))
+	testutil.AssertNil(t, fos.Chown("/shared", 0, 50))
+	data, err := fos.ReadFile("/shared")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "s", string(data))
+	err = fos.WriteFile("/shared", nil, 0o644)
+	testutil.AssertError(t, syscall.EACCES, err)
+
+	// The owner bits apply to the owner, even when the others allow
+	// more.
+	testutil.AssertNil(t, fos.WriteFile("/mine", nil, 0o066))
+	_, err = fos.Open("/mine")
+	testutil.AssertError(t, syscall.EACCES, err)
+
+	// Creating needs write and search permission on the directory,
+	// and reaching anything in it search permission.
+	testutil.AssertNil(t, fos.MkdirAll("/locked/sub", 0o755))
+	testutil.AssertNil(t, fos.Chmod("/locked", 0o500))
+	_, err = fos.Create("/locked/new")
+	testutil.AssertError(t, syscall.EACCES, err)
+	testutil.AssertNil(t, fos.Chmod("/locked", 0o600))
+	_, err = fos.Stat("/locked/sub")
+	testutil.AssertError(t, syscall.EACCES, err)
+}
@@ -0,0 +1,173 @@
+package fake_os
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+
+	fsi "github.com/pdutton/go-interfaces/io/fs"
+	osi "github.com/pdutton/go-interfaces/os"
+)
+
+// Root is the fake equivalent of *os.Root.  Every name is resolved
+// inside the root directory, and any path or symbolic link that
+// would leave it fails with "path escapes from parent".
+type Root struct {
+	fsys   *memFS
+	os     *OS
+	name   string
+	dir    string
+	closed bool
+}
+
+var _ osi.Root = (*Root)(nil)
+
+// Nub always returns nil because there is no underlying *os.Root.
+func (r *Root) Nub() *os.Root {
+	return nil
+}
+
+// check must be called with mu held.
+func (r *Root) check(op, name string) error {
+	if r.closed {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrClosed}
+	}
+
+	return nil
+}
+
+func (r *Root) lookup(op, name string, follow bool) (resolved, error) {
+	if err := r.check(op, name); err != nil {
+		return resolved{}, err
+	}
+
+	var res, err = r.fsys.resolve(r.dir, name, follow, true)
+	if err == nil && res.node == nil {
+		err = syscall.ENOENT
+	}
+	if err != nil {
+		return res, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	return res, nil
+}
+
+func (r *Root) Close() error {
+	r.fsys.mu.Lock()
+	defer r.fsys.mu.Unlock()
+
+	r.closed = true
+
+	return nil
+}
+
+func (r *Root) Create(name string) (osi.File, error) {
+	return r.OpenFile(name, osi.O_RDWR|osi.O_CREATE|osi.O_TRUNC, 0o666)
+}
+
+// FS returns a read-only fs.FS view of the root.
+func (r *Root) FS() fsi.FS {
+	return dirFS{root: r}
+}
+
+func (r *Root) Lstat(name string) (osi.FileInfo, error) {
+	return r.stat("statat", name, false)
+}
+
+func (r *Root) Stat(name string) (osi.FileInfo, error) {
+	return r.stat("statat", name, true)
+}
+
+func (r *Root) stat(op, name string, follow bool) (osi.FileInfo, error) {
+	r.fsys.mu.Lock()
+	defer r.fsys.mu.Unlock()
+
+	var res, err = r.lookup(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+
+	return wrapInfo(newFileInfo(path.Base(name), res.node)), nil
+}
+
+func (r *Root) Mkdir(name string, perm osi.FSFileMode) error {
+	r.fsys.mu.Lock()
+	defer r.fsys.mu.Unlock()
+
+	if err := r.check("mkdirat", name); err != nil {
+		return err
+	}
+
+	var res, err = r.fsys.resolve(r.dir, name, false, true)
+	if err != nil {
+		return &fs.PathError{Op: "mkdirat", Path: name, Err: err}
+	}
+	if res.node != nil {
+		return &fs.PathError{Op: "mkdirat", Path: name, Err: syscall.EEXIST}
+	}
+
+	r.fsys.link(res.parent, res.base, r.fsys.newNode(fs.ModeDir|perm&fs.ModePerm&^r.fsys.umask))
+
+	return nil
+}
+
+func (r *Root) Name() string {
+	return r.name
+}
+
+func (r *Root) Open(name string) (osi.File, error) {
+	return r.OpenFile(name, osi.O_RDONLY, 0)
+}
+
+func (r *Root) OpenFile(name string, flag int, perm osi.FSFileMode) (osi.File, error) {
+	r.fsys.mu.Lock()
+	defer r.fsys.mu.Unlock()
+
+	if err := r.check("openat", name); err != nil {
+		return nil, err
+	}
+
+	var f, err = r.fsys.openFile(r.dir, name, flag, perm, true)
+	if err != nil {
+		err.(*fs.PathError).Op = "openat"
+		return nil, err
+	}
+	f.os = r.os
+
+	return f, nil
+}
+
+func (r *Root) OpenRoot(name string) (osi.Root, error) {
+	r.fsys.mu.Lock()
+	defer r.fsys.mu.Unlock()
+
+	var res, err = r.lookup("openat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !res.node.isDir() {
+		return nil, &fs.PathError{Op: "openat", Path: name, Err: syscall.ENOTDIR}
+	}
+
+	return &Root{fsys: r.fsys, os: r.os, name: path.Join(r.name, name), dir: res.path}, nil
+}
+
+func (r *Root) Remove(name string) error {
+	r.fsys.mu.Lock()
+	defer r.fsys.mu.Unlock()
+
+	var res, err = r.lookup("removeat", name, false)
+	if err != nil {
+		return err
+	}
+	if res.path == r.dir {
+		return &fs.PathError{Op: "removeat", Path: name, Err: syscall.EINVAL}
+	}
+	if res.node.isDir() && len(res.node.children) > 0 {
+		return &fs.PathError{Op: "removeat", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	r.fsys.unlink(res.parent, res.base)
+
+	return nil
+}
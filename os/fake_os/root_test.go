@@ -0,0 +1,132 @@
+package fake_os
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+func newRootFixture(t *testing.T) *OS {
+	t.Helper()
+
+	fos := New()
+	testutil.AssertNil(t, fos.MkdirAll("/jail/sub", 0o755))
+	testutil.AssertNil(t, fos.WriteFile("/jail/sub/file", []byte("inside"), 0o644))
+	testutil.AssertNil(t, fos.WriteFile("/secret", []byte("outside"), 0o600))
+	testutil.AssertNil(t, fos.Symlink("../secret", "/jail/escape"))
+	testutil.AssertNil(t, fos.Symlink("/secret", "/jail/absolute"))
+	testutil.AssertNil(t, fos.Symlink("sub/file", "/jail/ok"))
+
+	return fos
+}
+
+// TestRoot_Open tests opening files inside the root.
+func TestRoot_Open(t *testing.T) {
+	fos := newRootFixture(t)
+
+	r, err := fos.OpenRoot("/jail")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/jail", r.Name())
+
+	f, err := r.Open("ok")
+	testutil.AssertNil(t, err)
+	buf := make([]byte, 6)
+	n, _ := f.Read(buf)
+	testutil.AssertEqual(t, "inside", string(buf[:n]))
+
+	_, err = r.Open("sub/../sub/file")
+	testutil.AssertNil(t, err)
+}
+
+// TestRoot_Escape tests that paths leaving the root are refused.
+func TestRoot_Escape(t *testing.T) {
+	fos := newRootFixture(t)
+	r, _ := fos.OpenRoot("/jail")
+
+	for _, name := range []string{"../secret", "escape", "absolute", "/secret"} {
+		_, err := r.Open(name)
+		if !errors.Is(err, errPathEscapes) {
+			t.Errorf("Open(%q): expected path escape error, got %v", name, err)
+		}
+	}
+
+	_, err := fos.OpenInRoot("/jail", "escape")
+	testutil.AssertEqual(t, true, errors.Is(err, errPathEscapes))
+}
+
+// TestRoot_CreateMkdirRemove tests modifying the tree through a root.
+func TestRoot_CreateMkdirRemove(t *testing.T) {
+	fos := newRootFixture(t)
+	r, _ := fos.OpenRoot("/jail")
+
+	testutil.AssertNil(t, r.Mkdir("new", 0o755))
+	f, err := r.Create("new/x")
+	testutil.AssertNil(t, err)
+	f.WriteString("x")
+	f.Close()
+
+	data, _ := fos.ReadFile("/jail/new/x")
+	testutil.AssertEqual(t, "x", string(data))
+
+	err = r.Remove("new")
+	assertPathError(t, err, "removeat", syscall.ENOTEMPTY)
+	testutil.AssertNil(t, r.Remove("new/x"))
+	testutil.AssertNil(t, r.Remove("new"))
+
+	err = r.Mkdir("sub", 0o755)
+	assertPathError(t, err, "mkdirat", syscall.EEXIST)
+}
+
+// TestRoot_Stat tests Stat and Lstat within a root.
+func TestRoot_Stat(t *testing.T) {
+	fos := newRootFixture(t)
+	r, _ := fos.OpenRoot("/jail")
+
+	fi, err := r.Stat("ok")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, int64(6), fi.Size())
+
+	fi, err = r.Lstat("escape")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, fi.Nub().Mode()&fs.ModeSymlink != 0)
+}
+
+// TestRoot_OpenRoot tests nested roots.
+func TestRoot_OpenRoot(t *testing.T) {
+	fos := newRootFixture(t)
+	r, _ := fos.OpenRoot("/jail")
+
+	sub, err := r.OpenRoot("sub")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/jail/sub", sub.Name())
+
+	_, err = sub.Open("../ok")
+	testutil.AssertEqual(t, true, errors.Is(err, errPathEscapes))
+	testutil.AssertEqual(t, true, sub.Nub() == nil)
+}
+
+// TestRoot_Close tests that a closed root refuses operations.
+func TestRoot_Close(t *testing.T) {
+	fos := newRootFixture(t)
+	r, _ := fos.OpenRoot("/jail")
+	testutil.AssertNil(t, r.Close())
+
+	_, err := r.Open("ok")
+	assertPathError(t, err, "openat", fs.ErrClosed)
+}
+
+// TestRoot_FS runs the standard fs.FS conformance tests on a root.
+func TestRoot_FS(t *testing.T) {
+	fos := newRootFixture(t)
+	testutil.AssertNil(t, fos.Remove("/jail/escape"))
+	testutil.AssertNil(t, fos.Remove("/jail/absolute"))
+	r, _ := fos.OpenRoot("/jail")
+
+	if err := fstest.TestFS(r.FS(), "sub/file", "ok"); err != nil {
+		t.Fatal(err)
+	}
+}
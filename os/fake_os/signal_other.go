@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package fake_os
+
+import "os"
+
+// ignoredSignals are the signals whose default action is to do
+// nothing.  There are none outside Unix.
+var ignoredSignals []os.Signal
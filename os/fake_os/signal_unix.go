@@ -0,0 +1,12 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package fake_os
+
+import (
+	"os"
+	"syscall"
+)
+
+// ignoredSignals are the signals whose default action is to do
+// nothing.
+var ignoredSignals = []os.Signal{syscall.SIGCHLD, syscall.SIGURG, syscall.SIGWINCH}
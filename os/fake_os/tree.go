@@ -0,0 +1,320 @@
+package fake_os
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxSymlinks mirrors the Linux limit on the number of symbolic
+// links followed while resolving a single path.
+const maxSymlinks = 40
+
+// errPathEscapes matches the error returned by os.Root when a
+// path would leave the root directory.
+var errPathEscapes = errors.New("path escapes from parent")
+
+type device int
+
+const (
+	devNone device = iota
+	devNull
+)
+
+// node is a single inode in the in-memory tree.  Several directory
+// entries may refer to the same node when hard links are used.
+type node struct {
+	mode     fs.FileMode
+	data     []byte
+	target   string
+	children map[string]*node
+	dev      device
+
+	atime time.Time
+	mtime time.Time
+
+	ino   uint64
+	nlink int
+	uid   int
+	gid   int
+}
+
+func (n *node) isDir() bool {
+	return n.mode.IsDir()
+}
+
+func (n *node) isSymlink() bool {
+	return n.mode&fs.ModeSymlink != 0
+}
+
+func (n *node) size() int64 {
+	switch {
+	case n.isDir():
+		return 4096
+	case n.isSymlink():
+		return int64(len(n.target))
+	}
+	return int64(len(n.data))
+}
+
+// names returns the sorted names of the children of a directory.
+func (n *node) names() []string {
+	var names = make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// memFS is the shared state behind an OS and every File and Root
+// opened from it.  All access is serialised by mu.
+type memFS struct {
+	mu sync.Mutex
+
+	root    *node
+	nextIno uint64
+	nextFd  uintptr
+	now     func() time.Time
+	umask   fs.FileMode
+	uid     int
+	gid     int
+	groups  []int
+}
+
+func newMemFS(now func() time.Time) *memFS {
+	var m = &memFS{
+		nextFd: 3,
+		now:    now,
+		umask:  0o022,
+		uid:    1000,
+		gid:    1000,
+	}
+
+	m.root = m.newNode(fs.ModeDir | 0o755)
+	m.root.nlink = 2
+
+	return m
+}
+
+// newNode allocates an inode owned by the default user.  It must
+// be called with mu held (or before the memFS is shared).
+func (m *memFS) newNode(mode fs.FileMode) *node {
+	m.nextIno++
+
+	var t = m.now()
+	var n = &node{
+		mode:  mode,
+		atime: t,
+		mtime: t,
+		ino:   m.nextIno,
+		nlink: 1,
+		uid:   m.uid,
+		gid:   m.gid,
+	}
+	if mode.IsDir() {
+		n.children = map[string]*node{}
+	}
+
+	return n
+}
+
+// link adds n to dir under name and updates the bookkeeping the
+// same way a real filesystem would.
+func (m *memFS) link(dir *node, name string, n *node) {
+	dir.children[name] = n
+	dir.mtime = m.now()
+	if n.isDir() {
+		dir.nlink++
+	}
+}
+
+func (m *memFS) unlink(dir *node, name string) {
+	var n = dir.children[name]
+	delete(dir.children, name)
+	dir.mtime = m.now()
+	if n.isDir() {
+		dir.nlink--
+	} else {
+		n.nlink--
+	}
+}
+
+// resolved describes the result of walking a path through the tree.
+// The final element may not exist, in which case node is nil and
+// parent/base say where it would be created.
+type resolved struct {
+	parent *node
+	base   string
+	node   *node
+	path   string
+
+	// dot is set when the final element was "." or "..".
+	dot bool
+}
+
+type frame struct {
+	name string
+	node *node
+}
+
+// resolve walks name starting at the directory start (an absolute,
+// symlink-free path).  A trailing symlink is only followed when
+// follow is set.  When confined is set the walk may not leave start,
+// which gives the semantics of os.Root.  Errors are bare syscall
+// errors; callers wrap them in a *PathError with their own op.
+func (m *memFS) resolve(start, name string, follow, confined bool) (resolved, error) {
+	if name == "" {
+		return resolved{}, syscall.ENOENT
+	}
+
+	var stack = []frame{{"/", m.root}}
+	if !path.IsAbs(name) || confined {
+		for _, c := range split(start) {
+			var child = stack[len(stack)-1].node.children[c]
+			if child == nil || !child.isDir() {
+				return resolved{}, syscall.ENOENT
+			}
+			stack = append(stack, frame{c, child})
+		}
+	}
+	if path.IsAbs(name) && confined {
+		return resolved{}, errPathEscapes
+	}
+
+	var floor = len(stack)
+	if !confined {
+		floor = 1
+	}
+
+	var trailingSlash = strings.HasSuffix(name, "/")
+	var comps = split(name)
+	var links = 0
+	var dot = false
+
+	for len(comps) > 0 {
+		var c = comps[0]
+		comps = comps[1:]
+		var cur = stack[len(stack)-1].node
+
+		if c == "." || c == ".." {
+			if !cur.isDir() {
+				return resolved{}, syscall.ENOTDIR
+			}
+			if c == ".." {
+				if len(stack) > floor {
+					stack = stack[:len(stack)-1]
+				} else if confined {
+					return resolved{}, errPathEscapes
+				}
+			}
+			dot = len(comps) == 0
+			continue
+		}
+		dot = false
+
+		if !cur.isDir() {
+			return resolved{}, syscall.ENOTDIR
+		}
+		if !m.access(cur, permExec) {
+			return resolved{}, syscall.EACCES
+		}
+
+		var last = len(comps) == 0
+		var child = cur.children[c]
+		if child == nil {
+			if !last {
+				return resolved{}, syscall.ENOENT
+			}
+			return resolved{
+				parent: cur,
+				base:   c,
+				path:   path.Join(framePath(stack), c),
+			}, nil
+		}
+
+		if child.isSymlink() && (!last || follow || trailingSlash) {
+			links++
+			if links > maxSymlinks {
+				return resolved{}, syscall.ELOOP
+			}
+			if path.IsAbs(child.target) {
+				if confined {
+					return resolved{}, errPathEscapes
+				}
+				stack = stack[:1]
+			}
+			comps = append(split(child.target), comps...)
+			continue
+		}
+
+		stack = append(stack, frame{c, child})
+	}
+
+	var top = stack[len(stack)-1]
+	if trailingSlash && !top.node.isDir() {
+		return resolved{}, syscall.ENOTDIR
+	}
+
+	var res = resolved{
+		base: top.name,
+		node: top.node,
+		path: framePath(stack),
+		dot:  dot,
+	}
+	if len(stack) > 1 {
+		res.parent = stack[len(stack)-2].node
+	}
+
+	return res, nil
+}
+
+// walkDir returns the node for an absolute, symlink-free directory
+// path, or nil.
+func (m *memFS) walkDir(dir string) *node {
+	var n = m.root
+	for _, c := range split(dir) {
+		n = n.children[c]
+		if n == nil || !n.isDir() {
+			return nil
+		}
+	}
+
+	return n
+}
+
+// split breaks a slash separated path into its non-empty elements.
+func split(name string) []string {
+	var comps []string
+	for _, c := range strings.Split(name, "/") {
+		if c != "" {
+			comps = append(comps, c)
+		}
+	}
+
+	return comps
+}
+
+func framePath(stack []frame) string {
+	var b strings.Builder
+	for _, f := range stack[1:] {
+		b.WriteString("/")
+		b.WriteString(f.name)
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+
+	return b.String()
+}
+
+// isWithin reports whether path p is dir or lies below it.
+func isWithin(p, dir string) bool {
+	return p == dir || dir == "/" || strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,323 @@
+// Package fake_signal provides an implementation of the go-interfaces
+// os/signal Signal interface that delivers synthetic signals.
+//
+// Nothing is registered with the operating system.  Instead a test
+// calls Raise to deliver a signal to the channels and contexts the
+// code under test subscribed with Notify and NotifyContext, just as
+// the real package would:
+//
+//	sig := fake_signal.New()
+//	go server.Run(sig) // calls sig.NotifyContext(ctx, os.Interrupt)
+//
+//	sig.Raise(os.Interrupt)
+package fake_signal
+
+import (
+	"context"
+	"os"
+	"slices"
+	"sync"
+
+	signali "github.com/pdutton/go-interfaces/os/signal"
+)
+
+// Signal is a fake implementation of the go-interfaces Signal
+// interface.
+type Signal struct {
+	mu        sync.Mutex
+	handlers  map[chan<- os.Signal]*handler
+	ignored   map[os.Signal]bool
+	ignoreAll bool
+	unhandled []os.Signal
+}
+
+var _ signali.Signal = (*Signal)(nil)
+
+// handler is the set of signals relayed to a channel: every signal but
+// those in except if all is set, and otherwise those in sigs.  A
+// handler for a NotifyContext context also has a notify function.
+type handler struct {
+	all    bool
+	sigs   map[os.Signal]bool
+	except map[os.Signal]bool
+	notify func(os.Signal)
+}
+
+func (h *handler) want(sig os.Signal) bool {
+	if h.all {
+		return !h.except[sig]
+	}
+	return h.sigs[sig]
+}
+
+// New returns a Signal with no subscriptions and no ignored signals.
+func New() *Signal {
+	return &Signal{
+		handlers: make(map[chan<- os.Signal]*handler),
+		ignored:  make(map[os.Signal]bool),
+	}
+}
+
+// Notify relays the signals to c, or every signal if none are given.
+// Delivery does not block: the signal is dropped if c is not ready to
+// receive it.  Notify stops the signals being ignored.  It panics if c
+// is nil.
+func (s *Signal) Notify(c chan<- os.Signal, sig ...os.Signal) {
+	if c == nil {
+		panic("os/signal: Notify using nil channel")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.notify(c, nil, sig)
+}
+
+func (s *Signal) notify(c chan<- os.Signal, notify func(os.Signal), sigs []os.Signal) {
+	var h = s.handlers[c]
+	if h == nil {
+		h = &handler{
+			sigs:   make(map[os.Signal]bool),
+			except: make(map[os.Signal]bool),
+			notify: notify,
+		}
+		s.handlers[c] = h
+	}
+
+	if len(sigs) == 0 {
+		h.all = true
+		clear(h.except)
+		s.ignoreAll = false
+		clear(s.ignored)
+		return
+	}
+
+	for _, sig := range sigs {
+		h.sigs[sig] = true
+		delete(h.except, sig)
+		s.ignored[sig] = false
+	}
+}
+
+// Stop stops relaying signals to c.  When Stop returns, c receives no
+// more signals.
+func (s *Signal) Stop(c chan<- os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.handlers, c)
+}
+
+// Ignore causes the signals, or every signal if none are given, to be
+// ignored, undoing any prior calls to Notify for them.
+func (s *Signal) Ignore(sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancel(sig)
+	if len(sig) == 0 {
+		s.ignoreAll = true
+		clear(s.ignored)
+		return
+	}
+
+	for _, sig := range sig {
+		s.ignored[sig] = true
+	}
+}
+
+// Ignored reports whether sig is being ignored.
+func (s *Signal) Ignored(sig os.Signal) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ignoredLocked(sig)
+}
+
+func (s *Signal) ignoredLocked(sig os.Signal) bool {
+	if ignored, ok := s.ignored[sig]; ok {
+		return ignored
+	}
+	return s.ignoreAll
+}
+
+// Reset undoes any prior calls to Notify and Ignore for the signals,
+// or for every signal if none are given, restoring their default
+// behaviour.
+func (s *Signal) Reset(sig ...os.Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancel(sig)
+	if len(sig) == 0 {
+		s.ignoreAll = false
+		clear(s.ignored)
+		return
+	}
+
+	for _, sig := range sig {
+		s.ignored[sig] = false
+	}
+}
+
+// cancel stops relaying the signals, or every signal if none are
+// given, to any channel.
+func (s *Signal) cancel(sigs []os.Signal) {
+	if len(sigs) == 0 {
+		clear(s.handlers)
+		return
+	}
+
+	for c, h := range s.handlers {
+		for _, sig := range sigs {
+			if h.all {
+				h.except[sig] = true
+			}
+			delete(h.sigs, sig)
+		}
+		if !h.all && len(h.sigs) == 0 {
+			delete(s.handlers, c)
+		}
+	}
+}
+
+// NotifyContext returns a copy of parent that is cancelled when one of
+// the signals, or any signal if none are given, is raised, when stop
+// is called, or when parent is done.  The context's cause names the
+// signal received, as with the real package.
+func (s *Signal) NotifyContext(parent context.Context, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	var ctx, cancel = context.WithCancelCause(parent)
+	var c = &signalCtx{
+		Context: ctx,
+		signals: signals,
+	}
+
+	var ch = make(chan os.Signal, 1)
+	c.stop = func() {
+		cancel(nil)
+		s.Stop(ch)
+	}
+
+	s.mu.Lock()
+	s.notify(ch, func(sig os.Signal) {
+		cancel(signalError(sig.String() + " signal received"))
+	}, signals)
+	s.mu.Unlock()
+
+	return c, c.stop
+}
+
+// Raise delivers sig to every channel and context subscribed to it.
+// Each channel receives it at most once, and only if it is ready to,
+// so a full channel misses the signal as it would a real one.  When
+// Raise returns, contexts from NotifyContext for sig are done.
+//
+// A signal that is neither ignored nor relayed anywhere is recorded
+// as unhandled: a real process would have taken the default action,
+// typically exiting.  os.Kill cannot be caught, so it is always
+// unhandled.
+func (s *Signal) Raise(sig os.Signal) {
+	s.mu.Lock()
+
+	var notify []func(os.Signal)
+	var handled = s.ignoredLocked(sig)
+	if sig == os.Kill {
+		handled = false
+	} else {
+		for c, h := range s.handlers {
+			if !h.want(sig) {
+				continue
+			}
+			handled = true
+
+			if h.notify != nil {
+				notify = append(notify, h.notify)
+				continue
+			}
+			select {
+			case c <- sig:
+			default:
+			}
+		}
+	}
+
+	if !handled {
+		s.unhandled = append(s.unhandled, sig)
+	}
+
+	s.mu.Unlock()
+
+	for _, f := range notify {
+		f(sig)
+	}
+}
+
+// Notified returns the number of channels and contexts subscribed to
+// sig.
+func (s *Signal) Notified(sig os.Signal) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int
+	for _, h := range s.handlers {
+		if h.want(sig) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Unhandled returns the signals raised while nothing was relaying or
+// ignoring them, in the order they were raised.
+func (s *Signal) Unhandled() []os.Signal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.unhandled)
+}
+
+// signalCtx is a context returned by NotifyContext.
+type signalCtx struct {
+	context.Context
+
+	signals []os.Signal
+	stop    context.CancelFunc
+}
+
+type stringer interface {
+	String() string
+}
+
+// String describes the context as the real package does.
+func (c *signalCtx) String() string {
+	var name = c.Context.(stringer).String()
+	name = name[:len(name)-len(".WithCancel")]
+
+	var buf = []byte("signal.NotifyContext(" + name)
+	if len(c.signals) != 0 {
+		buf = append(buf, ", ["...)
+		for i, s := range c.signals {
+			buf = append(buf, s.String()...)
+			if i != len(c.signals)-1 {
+				buf = append(buf, ' ')
+			}
+		}
+		buf = append(buf, ']')
+	}
+	buf = append(buf, ')')
+
+	return string(buf)
+}
+
+// signalError is the cause of a NotifyContext context cancelled by a
+// signal.  It is also context.Canceled.
+type signalError string
+
+func (e signalError) Error() string {
+	return string(e)
+}
+
+func (e signalError) Is(target error) bool {
+	return target == context.Canceled
+}
@@ -0,0 +1,148 @@
+package fake_signal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// received returns the signal waiting in c, or nil.
+func received(c chan os.Signal) os.Signal {
+	select {
+	case sig := <-c:
+		return sig
+	default:
+		return nil
+	}
+}
+
+// TestSignal_Notify tests relaying raised signals to channels.
+func TestSignal_Notify(t *testing.T) {
+	s := New()
+	a := make(chan os.Signal, 1)
+	b := make(chan os.Signal, 1)
+	s.Notify(a, os.Interrupt)
+	s.Notify(a, os.Interrupt, syscall.SIGTERM)
+	s.Notify(b, syscall.SIGTERM)
+	testutil.AssertEqual(t, 1, s.Notified(os.Interrupt))
+	testutil.AssertEqual(t, 2, s.Notified(syscall.SIGTERM))
+
+	s.Raise(os.Interrupt)
+	testutil.AssertEqual(t, os.Interrupt, received(a))
+	testutil.AssertEqual(t, nil, received(a))
+	testutil.AssertEqual(t, nil, received(b))
+
+	s.Raise(syscall.SIGTERM)
+	testutil.AssertEqual(t, syscall.SIGTERM, received(a))
+	testutil.AssertEqual(t, syscall.SIGTERM, received(b))
+
+	s.Stop(a)
+	s.Raise(syscall.SIGTERM)
+	testutil.AssertEqual(t, nil, received(a))
+	testutil.AssertEqual(t, syscall.SIGTERM, received(b))
+
+	testutil.AssertPanic(t, func() { s.Notify(nil, os.Interrupt) }, "nil channel")
+}
+
+// TestSignal_NonBlocking tests that a full channel misses signals.
+func TestSignal_NonBlocking(t *testing.T) {
+	s := New()
+	c := make(chan os.Signal, 1)
+	unbuffered := make(chan os.Signal)
+	s.Notify(c)
+	s.Notify(unbuffered)
+
+	s.Raise(syscall.SIGHUP)
+	s.Raise(syscall.SIGUSR1)
+	testutil.AssertEqual(t, syscall.SIGHUP, received(c))
+	testutil.AssertEqual(t, nil, received(c))
+	testutil.AssertEqual(t, 0, len(s.Unhandled()))
+}
+
+// TestSignal_Ignore tests ignoring and resetting signals.
+func TestSignal_Ignore(t *testing.T) {
+	s := New()
+	c := make(chan os.Signal, 1)
+	s.Notify(c, syscall.SIGHUP, syscall.SIGTERM)
+
+	s.Ignore(syscall.SIGHUP)
+	testutil.AssertEqual(t, true, s.Ignored(syscall.SIGHUP))
+	testutil.AssertEqual(t, false, s.Ignored(syscall.SIGTERM))
+
+	s.Raise(syscall.SIGHUP)
+	testutil.AssertEqual(t, nil, received(c))
+	testutil.AssertEqual(t, 0, len(s.Unhandled()))
+
+	s.Notify(c, syscall.SIGHUP)
+	testutil.AssertEqual(t, false, s.Ignored(syscall.SIGHUP))
+
+	s.Ignore()
+	testutil.AssertEqual(t, true, s.Ignored(syscall.SIGUSR2))
+	testutil.AssertEqual(t, 0, s.Notified(syscall.SIGTERM))
+
+	s.Reset(syscall.SIGUSR2)
+	testutil.AssertEqual(t, false, s.Ignored(syscall.SIGUSR2))
+	testutil.AssertEqual(t, true, s.Ignored(syscall.SIGHUP))
+
+	s.Reset()
+	testutil.AssertEqual(t, false, s.Ignored(syscall.SIGHUP))
+}
+
+// TestSignal_Reset tests undoing Notify for some signals.
+func TestSignal_Reset(t *testing.T) {
+	s := New()
+	all := make(chan os.Signal, 1)
+	one := make(chan os.Signal, 1)
+	s.Notify(all)
+	s.Notify(one, syscall.SIGINT)
+
+	s.Reset(syscall.SIGINT)
+	testutil.AssertEqual(t, 0, s.Notified(syscall.SIGINT))
+	testutil.AssertEqual(t, 1, s.Notified(syscall.SIGTERM))
+
+	s.Raise(syscall.SIGINT)
+	testutil.AssertEqual(t, nil, received(all))
+	testutil.AssertEqual(t, nil, received(one))
+	testutil.AssertEqual(t, "[interrupt]", fmt.Sprint(s.Unhandled()))
+
+	s.Raise(os.Kill)
+	testutil.AssertEqual(t, nil, received(all))
+	testutil.AssertEqual(t, "[interrupt killed]", fmt.Sprint(s.Unhandled()))
+}
+
+// TestSignal_NotifyContext tests cancelling contexts on a signal.
+func TestSignal_NotifyContext(t *testing.T) {
+	s := New()
+	ctx, stop := s.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	testutil.AssertEqual(t, "signal.NotifyContext(context.Background, [interrupt terminated])", fmt.Sprint(ctx))
+
+	s.Raise(syscall.SIGHUP)
+	testutil.AssertNil(t, ctx.Err())
+
+	s.Raise(syscall.SIGTERM)
+	testutil.AssertEqual(t, context.Canceled, ctx.Err())
+	cause := context.Cause(ctx)
+	testutil.AssertEqual(t, "terminated signal received", cause.Error())
+	testutil.AssertEqual(t, true, errors.Is(cause, context.Canceled))
+}
+
+// TestSignal_NotifyContextStop tests stopping a NotifyContext context.
+func TestSignal_NotifyContextStop(t *testing.T) {
+	s := New()
+	ctx, stop := s.NotifyContext(context.Background())
+	testutil.AssertEqual(t, 1, s.Notified(syscall.SIGUSR1))
+
+	stop()
+	testutil.AssertEqual(t, context.Canceled, ctx.Err())
+	testutil.AssertEqual(t, context.Canceled, context.Cause(ctx))
+	testutil.AssertEqual(t, 0, s.Notified(syscall.SIGUSR1))
+
+	s.Raise(syscall.SIGUSR1)
+	testutil.AssertEqual(t, "[user defined signal 1]", fmt.Sprint(s.Unhandled()))
+}
@@ -0,0 +1,545 @@
+// Package fake_filepath provides an implementation of the go-interfaces
+// path/filepath.FilePath interface over a virtual filesystem.
+//
+// Unlike mock_filepath.MockFilePath, which needs an expectation for
+// every call, a FilePath answers the lexical functions, such as Join
+// and Clean, itself, and runs Walk, WalkDir, Glob, Abs and
+// EvalSymlinks against an FS, usually a fake_os.OS:
+//
+//	fos := fake_os.New(fake_os.WithWorkingDir("/src"))
+//	fp := fake_filepath.New(fos)
+//
+//	fos.WriteFile("/src/main.go", nil, 0o644)
+//	matches, _ := fp.Glob("*.go") // [main.go]
+//
+// FromFS adapts any fs.FS, such as a fake_fs.FileSystem, for trees
+// that are only read.  WithStyle chooses Unix or Windows path syntax
+// whatever the host, so code handling Windows paths can be tested
+// anywhere:
+//
+//	fp := fake_filepath.New(fos, fake_filepath.WithStyle(fake_filepath.Windows))
+//	matches, _ := fp.Glob(`C:\src\*.go`) // [C:\src\main.go]
+package fake_filepath
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	osi "github.com/pdutton/go-interfaces/os"
+	filepathi "github.com/pdutton/go-interfaces/path/filepath"
+)
+
+// FS is the part of the go-interfaces os.OS that a FilePath reads the
+// filesystem through.  A fake_os.OS implements it.
+type FS interface {
+	Getwd() (string, error)
+	Lstat(name string) (osi.FileInfo, error)
+	Stat(name string) (osi.FileInfo, error)
+	ReadDir(name string) ([]osi.DirEntry, error)
+	Readlink(name string) (string, error)
+}
+
+// defaultVolume is the volume given to the working directory in the
+// Windows style, when the FS reports one without a volume.
+const defaultVolume = "C:"
+
+// Option configures a FilePath created by New.
+type Option func(*FilePath)
+
+// WithStyle sets the path syntax.  The default is Native.
+func WithStyle(s Style) Option {
+	return func(fp *FilePath) {
+		fp.style = s
+	}
+}
+
+// WithWorkingDir sets the directory relative paths are resolved
+// against, instead of the working directory of the FS.  It is written
+// in the FilePath's style.
+func WithWorkingDir(dir string) Option {
+	return func(fp *FilePath) {
+		fp.wd = dir
+	}
+}
+
+// FilePath is a go-interfaces filepath.FilePath whose stateful
+// functions operate on an FS.
+//
+// The FS is always given Unix-style paths.  In the Windows style, paths
+// are converted by dropping their volume name and turning backslashes
+// into slashes, so every drive shares the FS's single tree.
+type FilePath struct {
+	fsys  FS
+	style Style
+	lex   lexer
+	syn   *syntax
+	wd    string
+}
+
+var _ filepathi.FilePath = (*FilePath)(nil)
+
+// New returns a FilePath over fsys.
+func New(fsys FS, options ...Option) *FilePath {
+	var fp = &FilePath{fsys: fsys}
+	for _, opt := range options {
+		opt(fp)
+	}
+
+	fp.lex, fp.syn = lexerFor(fp.style)
+	if fp.wd != "" {
+		fp.wd = fp.lex.Clean(fp.wd)
+	}
+
+	return fp
+}
+
+// Style returns the path syntax of the FilePath.
+func (fp *FilePath) Style() Style {
+	return fp.style
+}
+
+// sys returns the path in the FS for p.
+func (fp *FilePath) sys(p string) string {
+	if p == "" {
+		return p
+	}
+
+	var rest = p[fp.syn.volumeNameLen(p):]
+	if fp.wd != "" && (rest == "" || !fp.syn.isSep(rest[0])) {
+		rest = fp.wd[fp.syn.volumeNameLen(fp.wd):] + string(fp.syn.sep) + rest
+	}
+
+	return fp.syn.ToSlash(rest)
+}
+
+// getwd returns the working directory in the FilePath's style.
+func (fp *FilePath) getwd() (string, error) {
+	if fp.wd != "" {
+		return fp.wd, nil
+	}
+
+	var wd, err = fp.fsys.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	if fp.syn.windows && fp.syn.volumeNameLen(wd) == 0 {
+		wd = defaultVolume + fp.syn.FromSlash(wd)
+	}
+
+	return wd, nil
+}
+
+func (fp *FilePath) Base(p string) string {
+	return fp.lex.Base(p)
+}
+
+func (fp *FilePath) Clean(p string) string {
+	return fp.lex.Clean(p)
+}
+
+func (fp *FilePath) Dir(p string) string {
+	return fp.lex.Dir(p)
+}
+
+func (fp *FilePath) Ext(p string) string {
+	return fp.lex.Ext(p)
+}
+
+func (fp *FilePath) FromSlash(p string) string {
+	return fp.lex.FromSlash(p)
+}
+
+func (fp *FilePath) IsAbs(p string) bool {
+	return fp.lex.IsAbs(p)
+}
+
+func (fp *FilePath) IsLocal(p string) bool {
+	return fp.lex.IsLocal(p)
+}
+
+func (fp *FilePath) Join(elem ...string) string {
+	return fp.lex.Join(elem...)
+}
+
+func (fp *FilePath) Localize(p string) (string, error) {
+	return fp.lex.Localize(p)
+}
+
+func (fp *FilePath) Match(pattern, name string) (bool, error) {
+	return fp.lex.Match(pattern, name)
+}
+
+func (fp *FilePath) Rel(basepath, targpath string) (string, error) {
+	return fp.lex.Rel(basepath, targpath)
+}
+
+func (fp *FilePath) Split(p string) (string, string) {
+	return fp.lex.Split(p)
+}
+
+func (fp *FilePath) SplitList(p string) []string {
+	return fp.lex.SplitList(p)
+}
+
+func (fp *FilePath) ToSlash(p string) string {
+	return fp.lex.ToSlash(p)
+}
+
+func (fp *FilePath) VolumeName(p string) string {
+	return fp.lex.VolumeName(p)
+}
+
+// Abs returns an absolute path for p, joining it to the working
+// directory if it is relative.  In the Windows style, a path rooted
+// without a volume name takes the working directory's, and a path on
+// another drive is taken relative to that drive's root.
+func (fp *FilePath) Abs(p string) (string, error) {
+	if fp.lex.IsAbs(p) {
+		return fp.lex.Clean(p), nil
+	}
+
+	var wd, err = fp.getwd()
+	if err != nil {
+		return "", err
+	}
+
+	if fp.syn.windows {
+		var vol = p[:fp.syn.volumeNameLen(p)]
+		var rest = p[len(vol):]
+		switch {
+		case rest != "" && fp.syn.isSep(rest[0]):
+			if vol == "" {
+				vol = fp.lex.VolumeName(wd)
+			}
+			return fp.lex.Clean(vol + rest), nil
+		case vol != "" && !strings.EqualFold(vol, fp.lex.VolumeName(wd)):
+			return fp.lex.Join(vol+string(fp.syn.sep), rest), nil
+		}
+		p = rest
+	}
+
+	return fp.lex.Join(wd, p), nil
+}
+
+// EvalSymlinks returns p after resolving every symbolic link in it, in
+// the same way as the real function: relative paths stay relative, and
+// more than 255 links is an error.
+func (fp *FilePath) EvalSymlinks(p string) (string, error) {
+	var syn = fp.syn
+	var sep = string(syn.sep)
+
+	var volLen = syn.volumeNameLen(p)
+	if volLen < len(p) && syn.isSep(p[volLen]) {
+		volLen++
+	}
+
+	var vol = p[:volLen]
+	var dest = vol
+	var linksWalked int
+	for start, end := volLen, volLen; start < len(p); start = end {
+		for start < len(p) && syn.isSep(p[start]) {
+			start++
+		}
+		end = start
+		for end < len(p) && !syn.isSep(p[end]) {
+			end++
+		}
+
+		switch {
+		case end == start:
+			// No more path components.
+			return fp.lex.Clean(dest), nil
+
+		case p[start:end] == ".":
+			continue
+
+		case p[start:end] == "..":
+			// Back up a component, unless there is none or it is
+			// itself a ".." that had to be kept.
+			var r = fp.lastSep(dest, volLen)
+			if r < volLen || dest[r+1:] == ".." {
+				if len(dest) > volLen {
+					dest += sep
+				}
+				dest += ".."
+			} else {
+				dest = dest[:r]
+			}
+			continue
+		}
+
+		if len(dest) > syn.volumeNameLen(dest) && !syn.isSep(dest[len(dest)-1]) {
+			dest += sep
+		}
+		dest += p[start:end]
+
+		var fi, err = fp.fsys.Lstat(fp.sys(dest))
+		if err != nil {
+			return "", err
+		}
+
+		if fi.Mode().Nub()&fs.ModeSymlink == 0 {
+			if !fi.IsDir() && end < len(p) {
+				return "", syscall.ENOTDIR
+			}
+			continue
+		}
+
+		linksWalked++
+		if linksWalked > 255 {
+			return "", errors.New("EvalSymlinks: too many links")
+		}
+
+		link, err := fp.fsys.Readlink(fp.sys(dest))
+		if err != nil {
+			return "", err
+		}
+		link = syn.FromSlash(link)
+
+		p = link + p[end:]
+		if v := syn.volumeNameLen(link); v > 0 {
+			// Start again from the link's volume.
+			if v < len(link) && syn.isSep(link[v]) {
+				v++
+			}
+			vol, dest, end = link[:v], link[:v], v
+		} else if len(link) > 0 && syn.isSep(link[0]) {
+			// Start again from the root.
+			dest, vol, volLen, end = link[:1], link[:1], 1, 1
+		} else {
+			// Replace the link's own component in dest.
+			var r = fp.lastSep(dest, volLen)
+			if r < volLen {
+				dest = vol
+			} else {
+				dest = dest[:r]
+			}
+			end = 0
+		}
+	}
+
+	return fp.lex.Clean(dest), nil
+}
+
+// lastSep returns the index of the last separator in p after its first
+// volLen bytes, or volLen-1 if there is none.
+func (fp *FilePath) lastSep(p string, volLen int) int {
+	var r = len(p) - 1
+	for r >= volLen && !fp.syn.isSep(p[r]) {
+		r--
+	}
+
+	return r
+}
+
+// Glob returns the names of the files in the FS matching pattern, in
+// the same way as the real function.  It ignores errors reading
+// directories, and only fails for a malformed pattern.
+func (fp *FilePath) Glob(pattern string) ([]string, error) {
+	return fp.glob(pattern, 0)
+}
+
+func (fp *FilePath) glob(pattern string, depth int) ([]string, error) {
+	// Limit the recursion, as the real function does.
+	const pathSeparatorsLimit = 10000
+	if depth == pathSeparatorsLimit {
+		return nil, filepath.ErrBadPattern
+	}
+
+	if _, err := fp.lex.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if !fp.syn.hasMeta(pattern) {
+		if _, err := fp.fsys.Lstat(fp.sys(pattern)); err != nil {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	var dir, file = fp.lex.Split(pattern)
+	dir = fp.cleanGlobPath(dir)
+
+	if !fp.syn.hasMeta(dir[fp.syn.volumeNameLen(dir):]) {
+		return fp.globDir(dir, file, nil)
+	}
+	if dir == pattern {
+		return nil, filepath.ErrBadPattern
+	}
+
+	var dirs, err = fp.glob(dir, depth+1)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, d := range dirs {
+		matches, err = fp.globDir(d, file, matches)
+		if err != nil {
+			return matches, err
+		}
+	}
+
+	return matches, nil
+}
+
+// cleanGlobPath prepares the directory part of a pattern for globDir,
+// dropping its trailing separator.
+func (fp *FilePath) cleanGlobPath(p string) string {
+	var vol = fp.syn.volumeNameLen(p)
+	switch {
+	case p == "":
+		return "."
+	case len(p) == vol+1 && fp.syn.isSep(p[vol]):
+		// The root of a volume, or just the root.
+		return p
+	case vol == len(p):
+		// A volume with no path, such as "C:", stands for its
+		// current directory.
+		return p + "."
+	}
+
+	return p[:len(p)-1]
+}
+
+// globDir appends the names in dir matching pattern to matches, in
+// lexical order.
+func (fp *FilePath) globDir(dir, pattern string, matches []string) ([]string, error) {
+	var fi, err = fp.fsys.Stat(fp.sys(dir))
+	if err != nil || !fi.IsDir() {
+		return matches, nil
+	}
+
+	names, err := fp.readDirNames(dir)
+	if err != nil {
+		return matches, nil
+	}
+
+	for _, n := range names {
+		var matched, err = fp.lex.Match(pattern, n)
+		if err != nil {
+			return matches, err
+		}
+		if matched {
+			matches = append(matches, fp.lex.Join(dir, n))
+		}
+	}
+
+	return matches, nil
+}
+
+// Walk walks the tree rooted at root in lexical order, calling fn for
+// each file or directory, in the same way as the real function.  It
+// does not follow symbolic links.
+func (fp *FilePath) Walk(root string, fn filepathi.WalkFunc) error {
+	var info, err = fp.fsys.Lstat(fp.sys(root))
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = fp.walk(root, info.Nub(), fn)
+	}
+
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (fp *FilePath) walk(p string, info fs.FileInfo, fn filepathi.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(p, info, nil)
+	}
+
+	var names, err = fp.readDirNames(p)
+	var err1 = fn(p, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, name := range names {
+		var filename = fp.lex.Join(p, name)
+
+		var info, err = fp.fsys.Lstat(fp.sys(filename))
+		if err != nil {
+			if err := fn(filename, nil, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+
+		err = fp.walk(filename, info.Nub(), fn)
+		if err != nil && (!info.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fp *FilePath) readDirNames(dir string) ([]string, error) {
+	var entries, err = fp.fsys.ReadDir(fp.sys(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var names = make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// WalkDir walks the tree rooted at root in lexical order, calling fn
+// for each file or directory, in the same way as the real function.
+// It does not follow symbolic links.
+func (fp *FilePath) WalkDir(root string, fn filepathi.WalkDirFunc) error {
+	var info, err = fp.fsys.Lstat(fp.sys(root))
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = fp.walkDir(root, fs.FileInfoToDirEntry(info.Nub()), fn)
+	}
+
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func (fp *FilePath) walkDir(p string, d fs.DirEntry, fn filepathi.WalkDirFunc) error {
+	if err := fn(p, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+
+	var entries, err = fp.fsys.ReadDir(fp.sys(p))
+	if err != nil {
+		// Call fn again to report the error.
+		err = fn(p, d, err)
+		if err != nil {
+			if err == filepath.SkipDir && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		if err := fp.walkDir(fp.lex.Join(p, e.Name()), e.Nub(), fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,329 @@
+package fake_filepath
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+	"github.com/pdutton/go-mocks/os/fake_os"
+)
+
+// newTree returns a FilePath over an OS holding a small tree in /src,
+// its working directory.
+func newTree(t *testing.T) (*FilePath, *fake_os.OS) {
+	t.Helper()
+
+	fos := fake_os.New(fake_os.WithWorkingDir("/src"))
+	for _, dir := range []string{"/src/a/b", "/src/c"} {
+		testutil.AssertNil(t, fos.MkdirAll(dir, 0o755))
+	}
+	for _, name := range []string{"/src/a/x.go", "/src/a/b/y.go", "/src/c/z.txt", "/src/main.go"} {
+		testutil.AssertNil(t, fos.WriteFile(name, []byte(name), 0o644))
+	}
+	testutil.AssertNil(t, fos.Symlink("a/b", "/src/link"))
+
+	return New(fos), fos
+}
+
+// TestFilePath_Lexical tests that the pure functions match the real
+// package.
+func TestFilePath_Lexical(t *testing.T) {
+	fp, _ := newTree(t)
+
+	testutil.AssertEqual(t, filepath.Join("a", "../b", "c"), fp.Join("a", "../b", "c"))
+	testutil.AssertEqual(t, "/a/b", fp.Clean("/a//./b/"))
+	testutil.AssertEqual(t, ".go", fp.Ext("x.go"))
+	dir, file := fp.Split("/a/b.go")
+	testutil.AssertEqual(t, "/a/ b.go", dir+" "+file)
+
+	rel, err := fp.Rel("/a/b", "/a/c/d")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "../c/d", rel)
+
+	testutil.AssertEqual(t, false, fp.IsLocal("../x"))
+	testutil.AssertEqual(t, "a b", strings.Join(fp.SplitList("a:b"), " "))
+}
+
+// TestFilePath_Abs tests resolving relative paths against the working
+// directory.
+func TestFilePath_Abs(t *testing.T) {
+	fp, fos := newTree(t)
+
+	abs, err := fp.Abs("a/../c")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src/c", abs)
+
+	testutil.AssertNil(t, fos.Chdir("/src/a"))
+	abs, err = fp.Abs(".")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src/a", abs)
+
+	abs, err = fp.Abs("/x/../y")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/y", abs)
+}
+
+// TestFilePath_EvalSymlinks tests resolving links in relative and
+// absolute paths.
+func TestFilePath_EvalSymlinks(t *testing.T) {
+	fp, fos := newTree(t)
+
+	p, err := fp.EvalSymlinks("link/y.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "a/b/y.go", p)
+
+	testutil.AssertNil(t, fos.Symlink("/src/c", "/src/a/b/abs"))
+	p, err = fp.EvalSymlinks("/src/link/abs/../main.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src/main.go", p)
+
+	p, err = fp.EvalSymlinks("./a/..")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ".", p)
+
+	_, err = fp.EvalSymlinks("main.go/x")
+	testutil.AssertError(t, syscall.ENOTDIR, err)
+
+	_, err = fp.EvalSymlinks("link/missing")
+	testutil.AssertError(t, fs.ErrNotExist, err)
+	testutil.AssertEqual(t, "lstat a/b/missing: no such file or directory", err.Error())
+}
+
+// TestFilePath_Glob tests matching patterns against the tree.
+func TestFilePath_Glob(t *testing.T) {
+	fp, _ := newTree(t)
+
+	matches, err := fp.Glob("*/*.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "a/x.go link/y.go", strings.Join(matches, " "))
+
+	matches, err = fp.Glob("/src/*/*")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src/a/b /src/a/x.go /src/c/z.txt /src/link/y.go", strings.Join(matches, " "))
+
+	matches, err = fp.Glob("main.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "main.go", strings.Join(matches, " "))
+
+	matches, err = fp.Glob("missing")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, 0, len(matches))
+
+	_, err = fp.Glob("a/[")
+	testutil.AssertError(t, filepath.ErrBadPattern, err)
+}
+
+// TestFilePath_Walk tests visiting the tree, without following links.
+func TestFilePath_Walk(t *testing.T) {
+	fp, _ := newTree(t)
+
+	var visited []string
+	err := fp.Walk(".", func(p string, info fs.FileInfo, err error) error {
+		testutil.AssertNil(t, err)
+		if info.Mode()&fs.ModeSymlink != 0 {
+			p += "@"
+		}
+		visited = append(visited, p)
+		if p == "a/b" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ". a a/b a/x.go c c/z.txt link@ main.go", strings.Join(visited, " "))
+
+	err = fp.Walk("missing", func(p string, info fs.FileInfo, err error) error {
+		testutil.AssertNil(t, info)
+		return err
+	})
+	testutil.AssertError(t, fs.ErrNotExist, err)
+}
+
+// TestFilePath_WalkDir tests visiting the tree, stopping part way.
+func TestFilePath_WalkDir(t *testing.T) {
+	fp, _ := newTree(t)
+
+	var visited []string
+	err := fp.WalkDir("/src", func(p string, d fs.DirEntry, err error) error {
+		testutil.AssertNil(t, err)
+		visited = append(visited, p)
+		if p == "/src/c/z.txt" {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src /src/a /src/a/b /src/a/b/y.go /src/a/x.go /src/c /src/c/z.txt", strings.Join(visited, " "))
+
+	// A file's SkipDir skips the rest of its directory.
+	visited = nil
+	err = fp.WalkDir("a", func(p string, d fs.DirEntry, err error) error {
+		visited = append(visited, p)
+		if p == "a/b/y.go" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "a a/b a/b/y.go a/x.go", strings.Join(visited, " "))
+}
+
+// TestFilePath_EvalSymlinks_Loop tests that a cycle of links is an
+// error rather than a hang.
+func TestFilePath_EvalSymlinks_Loop(t *testing.T) {
+	fp, fos := newTree(t)
+
+	testutil.AssertNil(t, fos.Symlink("b/loop", "/src/a/loop"))
+	testutil.AssertNil(t, fos.Symlink("/src/a/loop", "/src/a/b/loop"))
+
+	_, err := fp.EvalSymlinks("a/loop/x")
+	testutil.AssertEqual(t, "EvalSymlinks: too many links", err.Error())
+
+	testutil.AssertNil(t, fos.Symlink("self", "/src/self"))
+	_, err = fp.EvalSymlinks("/src/self")
+	testutil.AssertEqual(t, "EvalSymlinks: too many links", err.Error())
+}
+
+// TestFilePath_WithWorkingDir tests resolving relative paths against a
+// directory other than the FS's.
+func TestFilePath_WithWorkingDir(t *testing.T) {
+	_, fos := newTree(t)
+	fp := New(fos, WithWorkingDir("/src/a/"))
+
+	abs, err := fp.Abs("b/y.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src/a/b/y.go", abs)
+
+	matches, err := fp.Glob("*/*.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "b/y.go", strings.Join(matches, " "))
+
+	p, err := fp.EvalSymlinks("../link/y.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "../a/b/y.go", p)
+
+	var visited []string
+	err = fp.WalkDir("b", func(p string, d fs.DirEntry, err error) error {
+		visited = append(visited, p)
+		return err
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "b b/y.go", strings.Join(visited, " "))
+}
+
+// TestFilePath_Unix tests the Unix style, where a backslash is an
+// ordinary character or an escape.
+func TestFilePath_Unix(t *testing.T) {
+	_, fos := newTree(t)
+	fp := New(fos, WithStyle(Unix))
+	testutil.AssertEqual(t, Unix, fp.Style())
+
+	testutil.AssertEqual(t, `a\b/c`, fp.Join(`a\b`, "c"))
+	testutil.AssertEqual(t, "", fp.VolumeName("C:/x"))
+	testutil.AssertEqual(t, false, fp.IsAbs(`C:\x`))
+	testutil.AssertEqual(t, "a b", strings.Join(fp.SplitList("a:b"), " "))
+
+	ok, err := fp.Match(`\*`, "*")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, ok)
+
+	matches, err := fp.Glob("/src/a/*.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src/a/x.go", strings.Join(matches, " "))
+}
+
+// TestFilePath_Windows tests the Windows style over a Unix tree.
+func TestFilePath_Windows(t *testing.T) {
+	_, fos := newTree(t)
+	fp := New(fos, WithStyle(Windows))
+	testutil.AssertEqual(t, "Windows", fp.Style().String())
+
+	testutil.AssertEqual(t, `C:\a\c`, fp.Join(`C:/a\b`, "..", "c"))
+	testutil.AssertEqual(t, `C:\`, fp.Clean(`C:/`))
+	testutil.AssertEqual(t, "C:", fp.VolumeName(`C:\x`))
+	testutil.AssertEqual(t, true, fp.IsAbs(`C:\x`))
+	testutil.AssertEqual(t, false, fp.IsAbs(`\x`))
+	testutil.AssertEqual(t, `C:\a`, fp.Dir(`C:\a\b`))
+	testutil.AssertEqual(t, "b", fp.Base(`C:\a\b\`))
+	testutil.AssertEqual(t, "a b", strings.Join(fp.SplitList("a;b"), " "))
+	testutil.AssertEqual(t, "a/b", fp.ToSlash(`a\b`))
+	testutil.AssertEqual(t, false, fp.IsLocal("c:x"))
+
+	dir, file := fp.Split(`C:\a\b.go`)
+	testutil.AssertEqual(t, `C:\a\ b.go`, dir+" "+file)
+
+	rel, err := fp.Rel(`C:\a\b`, `C:\a\c\d`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `..\c\d`, rel)
+
+	ok, err := fp.Match(`a\*.go`, `a\x.go`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, true, ok)
+
+	abs, err := fp.Abs(`a\..\c`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `C:\src\c`, abs)
+	abs, err = fp.Abs(`\x`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `C:\x`, abs)
+	abs, err = fp.Abs(`D:x`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `D:\x`, abs)
+
+	matches, err := fp.Glob(`C:\src\*\*.go`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `C:\src\a\x.go C:\src\link\y.go`, strings.Join(matches, " "))
+
+	matches, err = fp.Glob(`c/*`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `c\z.txt`, strings.Join(matches, " "))
+
+	p, err := fp.EvalSymlinks(`C:\src\link\y.go`)
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `C:\src\a\b\y.go`, p)
+
+	var visited []string
+	err = fp.Walk(`a`, func(p string, info fs.FileInfo, err error) error {
+		visited = append(visited, p)
+		return err
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, `a a\b a\b\y.go a\x.go`, strings.Join(visited, " "))
+}
+
+// TestFromFS tests a FilePath over an fs.FS.
+func TestFromFS(t *testing.T) {
+	fp := New(FromFS(fstest.MapFS{
+		"go.mod":      {Data: []byte("module example\n")},
+		"src/main.go": {Data: []byte("package main\n")},
+		"src/util.go": {Data: []byte("package main\n")},
+	}))
+
+	abs, err := fp.Abs("src")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src", abs)
+
+	matches, err := fp.Glob("/src/*.go")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "/src/main.go /src/util.go", strings.Join(matches, " "))
+
+	p, err := fp.EvalSymlinks("src/../go.mod")
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, "go.mod", p)
+
+	var visited []string
+	err = fp.WalkDir(".", func(p string, d fs.DirEntry, err error) error {
+		visited = append(visited, p)
+		return err
+	})
+	testutil.AssertNil(t, err)
+	testutil.AssertEqual(t, ". go.mod src src/main.go src/util.go", strings.Join(visited, " "))
+
+	_, err = fp.EvalSymlinks("missing")
+	testutil.AssertError(t, fs.ErrNotExist, err)
+	testutil.AssertEqual(t, "lstat missing: file does not exist", err.Error())
+}
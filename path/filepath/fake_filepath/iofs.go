@@ -0,0 +1,127 @@
+package fake_filepath
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+	"syscall"
+
+	fsi "github.com/pdutton/go-interfaces/io/fs"
+	osi "github.com/pdutton/go-interfaces/os"
+)
+
+// FromFS returns an FS reading fsys, which appears rooted at "/" and
+// is also the working directory.  Symbolic links are only seen if fsys
+// has Lstat and ReadLink methods, as an fs.ReadLinkFS does; otherwise
+// every file is reported as fs.Stat sees it.
+func FromFS(fsys fs.FS) FS {
+	return ioFS{fsys: fsys}
+}
+
+type ioFS struct {
+	fsys fs.FS
+}
+
+var _ FS = ioFS{}
+
+// name returns the name in the fs.FS for the path p.
+func (f ioFS) name(op, p string) (string, error) {
+	if p == "" {
+		return "", &fs.PathError{Op: op, Path: p, Err: fs.ErrNotExist}
+	}
+
+	var name = strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		name = "."
+	}
+
+	return name, nil
+}
+
+// pathError reports err against the path the caller gave, rather than
+// the name in the fs.FS.
+func pathError(err error, op, p string) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return &fs.PathError{Op: op, Path: p, Err: pe.Err}
+	}
+
+	return err
+}
+
+func (f ioFS) Getwd() (string, error) {
+	return "/", nil
+}
+
+func (f ioFS) Lstat(p string) (osi.FileInfo, error) {
+	var name, err = f.name("lstat", p)
+	if err != nil {
+		return nil, err
+	}
+
+	var fi fs.FileInfo
+	if lfs, ok := f.fsys.(interface {
+		Lstat(string) (fs.FileInfo, error)
+	}); ok {
+		fi, err = lfs.Lstat(name)
+	} else {
+		fi, err = fs.Stat(f.fsys, name)
+	}
+	if err != nil {
+		return nil, pathError(err, "lstat", p)
+	}
+
+	return fsi.NewFileInfo(fi), nil
+}
+
+func (f ioFS) Stat(p string) (osi.FileInfo, error) {
+	var name, err = f.name("stat", p)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := fs.Stat(f.fsys, name)
+	if err != nil {
+		return nil, pathError(err, "stat", p)
+	}
+
+	return fsi.NewFileInfo(fi), nil
+}
+
+func (f ioFS) ReadDir(p string) ([]osi.DirEntry, error) {
+	var name, err = f.name("open", p)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(f.fsys, name)
+	if err != nil {
+		return nil, pathError(err, "open", p)
+	}
+
+	return fsi.NewDirEntryList(entries), nil
+}
+
+func (f ioFS) Readlink(p string) (string, error) {
+	var name, err = f.name("readlink", p)
+	if err != nil {
+		return "", err
+	}
+
+	if rfs, ok := f.fsys.(interface {
+		ReadLink(string) (string, error)
+	}); ok {
+		link, err := rfs.ReadLink(name)
+		if err != nil {
+			return "", pathError(err, "readlink", p)
+		}
+		return link, nil
+	}
+
+	// Without links, every file is not one.
+	if _, err := fs.Stat(f.fsys, name); err != nil {
+		return "", pathError(err, "readlink", p)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: p, Err: syscall.EINVAL}
+}
@@ -0,0 +1,883 @@
+package fake_filepath
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	filepathi "github.com/pdutton/go-interfaces/path/filepath"
+)
+
+// Style selects the path syntax a FilePath uses.
+type Style int
+
+const (
+	// Native is the syntax of the host, with the lexical functions
+	// answered by the real path/filepath package.
+	Native Style = iota
+
+	// Unix has '/' as its only separator, no volume names, and ':'
+	// between the elements of a list.
+	Unix
+
+	// Windows accepts both '\\' and '/' as separators, producing '\\'.
+	// Paths may start with a volume name: a drive letter, such as
+	// "C:", a UNC share, such as `\\host\share`, or a device path,
+	// such as `\\.\C:` or `\\?\UNC\host\share`.  Lists are separated
+	// by ';' and may quote elements, reserved device names such as NUL
+	// are not local, and Rel ignores case.
+	Windows
+)
+
+func (s Style) String() string {
+	switch s {
+	case Native:
+		return "Native"
+	case Unix:
+		return "Unix"
+	case Windows:
+		return "Windows"
+	}
+
+	return "Style(" + strconv.Itoa(int(s)) + ")"
+}
+
+// lexer is the part of the FilePath interface that needs no
+// filesystem.
+type lexer interface {
+	Base(string) string
+	Clean(string) string
+	Dir(string) string
+	Ext(string) string
+	FromSlash(string) string
+	IsAbs(string) bool
+	IsLocal(string) bool
+	Join(...string) string
+	Localize(string) (string, error)
+	Match(string, string) (bool, error)
+	Rel(string, string) (string, error)
+	Split(string) (string, string)
+	SplitList(string) []string
+	ToSlash(string) string
+	VolumeName(string) string
+}
+
+// syntax implements the lexical functions for one style of path.  The
+// functions are adapted from the standard library's path/filepath, with
+// the separator and volume rules chosen at run time rather than by the
+// build.
+type syntax struct {
+	windows bool
+	sep     byte
+	listSep byte
+}
+
+var (
+	unixSyntax    = &syntax{sep: '/', listSep: ':'}
+	windowsSyntax = &syntax{windows: true, sep: '\\', listSep: ';'}
+
+	_ lexer = (*syntax)(nil)
+	_ lexer = filepathi.NewFilePath()
+)
+
+// lexerFor returns the lexical functions of style s, and the syntax
+// the stateful functions use to take paths apart.
+func lexerFor(s Style) (lexer, *syntax) {
+	switch s {
+	case Unix:
+		return unixSyntax, unixSyntax
+	case Windows:
+		return windowsSyntax, windowsSyntax
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepathi.NewFilePath(), windowsSyntax
+	}
+	return filepathi.NewFilePath(), unixSyntax
+}
+
+var errInvalidPath = errors.New("invalid path")
+
+func (s *syntax) isSep(c byte) bool {
+	return c == s.sep || s.windows && c == '/'
+}
+
+// volumeNameLen returns the length of the volume name at the start of
+// p.
+func (s *syntax) volumeNameLen(p string) int {
+	if !s.windows {
+		return 0
+	}
+
+	switch {
+	case len(p) >= 2 && p[1] == ':':
+		// A drive letter, which is not checked to be one.
+		return 2
+
+	case len(p) == 0 || !s.isSep(p[0]):
+		return 0
+
+	case s.hasPrefixFold(p, `\\.`) || s.hasPrefixFold(p, `\\?`) || s.hasPrefixFold(p, `\??`):
+		// A device path.  The next element is part of the volume,
+		// as are the host and share of a UNC device path.
+		switch {
+		case len(p) == 3:
+			return 3
+		case s.hasPrefixFold(p[4:], "UNC"):
+			return s.validVolumeNameLen(p, s.uncLen(p, len(`\\.\UNC\`)))
+		}
+
+		var _, rest, ok = s.cutPath(p[4:])
+		if !ok {
+			return s.validVolumeNameLen(p, len(p))
+		}
+		return s.validVolumeNameLen(p, len(p)-len(rest)-1)
+
+	case len(p) >= 2 && s.isSep(p[1]):
+		// A UNC path.
+		return s.validVolumeNameLen(p, s.uncLen(p, 2))
+	}
+
+	return 0
+}
+
+// validVolumeNameLen returns n, unless p[:n] has a ".." element.
+func (s *syntax) validVolumeNameLen(p string, n int) int {
+	for v := p[:n]; v != ""; {
+		var elem string
+		elem, v, _ = s.cutPath(v)
+		if elem == ".." {
+			return 0
+		}
+	}
+
+	return n
+}
+
+// hasPrefixFold reports whether p starts with the element prefix,
+// ignoring case and treating all separators alike.
+func (s *syntax) hasPrefixFold(p, prefix string) bool {
+	if len(p) < len(prefix) {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if s.isSep(prefix[i]) {
+			if !s.isSep(p[i]) {
+				return false
+			}
+		} else if toUpper(prefix[i]) != toUpper(p[i]) {
+			return false
+		}
+	}
+
+	return len(p) == len(prefix) || s.isSep(p[len(prefix)])
+}
+
+// uncLen returns the length of the host and share of a UNC path, whose
+// host starts at prefixLen.
+func (s *syntax) uncLen(p string, prefixLen int) int {
+	var count int
+	for i := prefixLen; i < len(p); i++ {
+		if s.isSep(p[i]) {
+			count++
+			if count == 2 {
+				return i
+			}
+		}
+	}
+
+	return len(p)
+}
+
+// cutPath slices p around its first separator.
+func (s *syntax) cutPath(p string) (string, string, bool) {
+	for i := 0; i < len(p); i++ {
+		if s.isSep(p[i]) {
+			return p[:i], p[i+1:], true
+		}
+	}
+
+	return p, "", false
+}
+
+// isUNC reports whether the volume name of p is longer than a drive
+// letter.
+func (s *syntax) isUNC(p string) bool {
+	return s.volumeNameLen(p) > 2
+}
+
+func toUpper(c byte) byte {
+	if 'a' <= c && c <= 'z' {
+		return c - ('a' - 'A')
+	}
+
+	return c
+}
+
+// asciiUpper returns s with only its ASCII letters in upper case.
+func asciiUpper(s string) string {
+	var b = []byte(s)
+	for i, c := range b {
+		b[i] = toUpper(c)
+	}
+
+	return string(b)
+}
+
+// isReservedName reports whether name is a Windows device name, such as
+// NUL or COM1.  Unlike Windows 11, which allows "CON.txt", a device
+// name followed by an extension is reserved, as it is on earlier
+// versions.
+func isReservedName(name string) bool {
+	// Device names may be followed by anything after a dot or colon,
+	// and by trailing spaces.
+	var base = name
+	if i := strings.IndexAny(base, ".:"); i >= 0 {
+		base = base[:i]
+	}
+	base = strings.TrimRight(base, " ")
+
+	switch asciiUpper(base) {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+
+	if len(base) >= 4 {
+		switch asciiUpper(base[:3]) {
+		case "COM", "LPT":
+			// Superscript digits count as digits.
+			switch base[3:] {
+			case "1", "2", "3", "4", "5", "6", "7", "8", "9", "\u00b2", "\u00b3", "\u00b9":
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s *syntax) replace(p string, old, new byte) string {
+	if old == new || strings.IndexByte(p, old) < 0 {
+		return p
+	}
+
+	return strings.ReplaceAll(p, string(old), string(new))
+}
+
+func (s *syntax) FromSlash(p string) string {
+	return s.replace(p, '/', s.sep)
+}
+
+func (s *syntax) ToSlash(p string) string {
+	return s.replace(p, s.sep, '/')
+}
+
+func (s *syntax) VolumeName(p string) string {
+	return s.FromSlash(p[:s.volumeNameLen(p)])
+}
+
+func (s *syntax) IsAbs(p string) bool {
+	if !s.windows {
+		return strings.HasPrefix(p, "/")
+	}
+
+	var l = s.volumeNameLen(p)
+	if l == 0 {
+		return false
+	}
+	if s.isSep(p[0]) && s.isSep(p[1]) {
+		// UNC and device paths are always absolute.
+		return true
+	}
+
+	p = p[l:]
+	return p != "" && s.isSep(p[0])
+}
+
+// A lazybuf is a lazily constructed path buffer, which only allocates
+// once the output differs from the input.
+type lazybuf struct {
+	path       string
+	buf        []byte
+	w          int
+	volAndPath string
+	volLen     int
+}
+
+func (b *lazybuf) index(i int) byte {
+	if b.buf != nil {
+		return b.buf[i]
+	}
+	return b.path[i]
+}
+
+func (b *lazybuf) append(c byte) {
+	if b.buf == nil {
+		if b.w < len(b.path) && b.path[b.w] == c {
+			b.w++
+			return
+		}
+		b.buf = make([]byte, len(b.path))
+		copy(b.buf, b.path[:b.w])
+	}
+	b.buf[b.w] = c
+	b.w++
+}
+
+func (b *lazybuf) prepend(prefix ...byte) {
+	b.buf = slices.Insert(b.buf, 0, prefix...)
+	b.w += len(prefix)
+}
+
+func (b *lazybuf) string() string {
+	if b.buf == nil {
+		return b.volAndPath[:b.volLen+b.w]
+	}
+	return b.volAndPath[:b.volLen] + string(b.buf[:b.w])
+}
+
+func (s *syntax) Clean(p string) string {
+	var originalPath = p
+	var volLen = s.volumeNameLen(p)
+	p = p[volLen:]
+	if p == "" {
+		if volLen > 1 && s.isSep(originalPath[0]) && s.isSep(originalPath[1]) {
+			// A UNC volume on its own.
+			return s.FromSlash(originalPath)
+		}
+		return originalPath + "."
+	}
+	var rooted = s.isSep(p[0])
+
+	// Reading from p, r is the index of the next byte to process.
+	// Writing to out, dotdot is the index where ".." must stop,
+	// either because it is the leading slash or it is a leading
+	// "../../.." prefix.
+	var n = len(p)
+	var out = lazybuf{path: p, volAndPath: originalPath, volLen: volLen}
+	var r, dotdot int
+	if rooted {
+		out.append(s.sep)
+		r, dotdot = 1, 1
+	}
+
+	for r < n {
+		switch {
+		case s.isSep(p[r]):
+			r++
+		case p[r] == '.' && (r+1 == n || s.isSep(p[r+1])):
+			r++
+		case p[r] == '.' && p[r+1] == '.' && (r+2 == n || s.isSep(p[r+2])):
+			r += 2
+			switch {
+			case out.w > dotdot:
+				out.w--
+				for out.w > dotdot && !s.isSep(out.index(out.w)) {
+					out.w--
+				}
+			case !rooted:
+				if out.w > 0 {
+					out.append(s.sep)
+				}
+				out.append('.')
+				out.append('.')
+				dotdot = out.w
+			}
+		default:
+			if rooted && out.w != 1 || !rooted && out.w != 0 {
+				out.append(s.sep)
+			}
+			for ; r < n && !s.isSep(p[r]); r++ {
+				out.append(p[r])
+			}
+		}
+	}
+
+	if out.w == 0 {
+		out.append('.')
+	}
+
+	if s.windows {
+		s.postClean(&out)
+	}
+
+	return s.FromSlash(out.string())
+}
+
+// postClean stops Clean turning a relative Windows path into an
+// absolute or rooted one.
+func (s *syntax) postClean(out *lazybuf) {
+	if out.volLen != 0 || out.buf == nil {
+		return
+	}
+
+	// Keep a colon in the first element from making it a volume
+	// name, turning a/../c: into .\c: rather than c:.
+	for _, c := range out.buf {
+		if s.isSep(c) {
+			break
+		}
+		if c == ':' {
+			out.prepend('.', s.sep)
+			return
+		}
+	}
+
+	// Keep \a\..\??\c:\x from becoming the device path \??\c:\x.
+	if len(out.buf) >= 3 && s.isSep(out.buf[0]) && out.buf[1] == '?' && out.buf[2] == '?' {
+		out.prepend(s.sep, '.')
+	}
+}
+
+func (s *syntax) Join(elem ...string) string {
+	if s.windows {
+		return s.joinWindows(elem)
+	}
+
+	for i, e := range elem {
+		if e != "" {
+			return s.Clean(strings.Join(elem[i:], string(s.sep)))
+		}
+	}
+
+	return ""
+}
+
+func (s *syntax) joinWindows(elem []string) string {
+	var b strings.Builder
+	var lastChar byte
+	for _, e := range elem {
+		switch {
+		case b.Len() == 0:
+			// The first non-empty element is added unchanged.
+		case s.isSep(lastChar):
+			// Strip leading separators, so that joining elements
+			// never makes a UNC path, and keep \ followed by ?? from
+			// making a device path.
+			for len(e) > 0 && s.isSep(e[0]) {
+				e = e[1:]
+			}
+			if b.Len() == 1 && strings.HasPrefix(e, "??") && (len(e) == len("??") || s.isSep(e[2])) {
+				b.WriteString(`.\`)
+			}
+		case lastChar == ':':
+			// Keep C: followed by f relative to the drive's current
+			// directory, as C:f.
+		default:
+			b.WriteByte(s.sep)
+			lastChar = s.sep
+		}
+
+		if len(e) > 0 {
+			b.WriteString(e)
+			lastChar = e[len(e)-1]
+		}
+	}
+
+	if b.Len() == 0 {
+		return ""
+	}
+	return s.Clean(b.String())
+}
+
+func (s *syntax) Split(p string) (string, string) {
+	var vol = s.VolumeName(p)
+	var i = len(p) - 1
+	for i >= len(vol) && !s.isSep(p[i]) {
+		i--
+	}
+
+	return p[:i+1], p[i+1:]
+}
+
+func (s *syntax) Ext(p string) string {
+	for i := len(p) - 1; i >= 0 && !s.isSep(p[i]); i-- {
+		if p[i] == '.' {
+			return p[i:]
+		}
+	}
+
+	return ""
+}
+
+func (s *syntax) Base(p string) string {
+	if p == "" {
+		return "."
+	}
+	for len(p) > 0 && s.isSep(p[len(p)-1]) {
+		p = p[:len(p)-1]
+	}
+	p = p[len(s.VolumeName(p)):]
+
+	var i = len(p) - 1
+	for i >= 0 && !s.isSep(p[i]) {
+		i--
+	}
+	if i >= 0 {
+		p = p[i+1:]
+	}
+	if p == "" {
+		return string(s.sep)
+	}
+
+	return p
+}
+
+func (s *syntax) Dir(p string) string {
+	var vol = s.VolumeName(p)
+	var i = len(p) - 1
+	for i >= len(vol) && !s.isSep(p[i]) {
+		i--
+	}
+
+	var dir = s.Clean(p[len(vol) : i+1])
+	if dir == "." && len(vol) > 2 {
+		// A UNC volume on its own.
+		return vol
+	}
+
+	return vol + dir
+}
+
+func (s *syntax) IsLocal(p string) bool {
+	if p == "" || s.isSep(p[0]) || s.IsAbs(p) {
+		return false
+	}
+	if s.windows {
+		if strings.IndexByte(p, ':') >= 0 {
+			// Colons are only valid when marking a drive letter.
+			return false
+		}
+		for rest := p; rest != ""; {
+			var elem string
+			elem, rest, _ = s.cutPath(rest)
+			if isReservedName(elem) {
+				return false
+			}
+		}
+	}
+
+	p = s.Clean(p)
+	return p != ".." && !strings.HasPrefix(p, ".."+string(s.sep))
+}
+
+func (s *syntax) Localize(p string) (string, error) {
+	if !fs.ValidPath(p) {
+		return "", errInvalidPath
+	}
+
+	var invalid = "\x00"
+	if s.windows {
+		invalid = "\x00:\\"
+	}
+	if strings.ContainsAny(p, invalid) {
+		return "", errInvalidPath
+	}
+	if s.windows {
+		for _, elem := range strings.Split(p, "/") {
+			if isReservedName(elem) {
+				return "", errInvalidPath
+			}
+		}
+	}
+
+	return s.FromSlash(p), nil
+}
+
+func (s *syntax) SplitList(p string) []string {
+	if p == "" {
+		return []string{}
+	}
+
+	if !s.windows {
+		return strings.Split(p, string(s.listSep))
+	}
+
+	// Split at separators outside quotes, then drop the quotes.
+	var list []string
+	var start int
+	var quoted bool
+	for i := 0; i < len(p); i++ {
+		switch p[i] {
+		case '"':
+			quoted = !quoted
+		case s.listSep:
+			if !quoted {
+				list = append(list, p[start:i])
+				start = i + 1
+			}
+		}
+	}
+	list = append(list, p[start:])
+
+	for i, elem := range list {
+		list[i] = strings.ReplaceAll(elem, `"`, ``)
+	}
+
+	return list
+}
+
+// sameWord reports whether two path elements are the same, ignoring
+// case on Windows.
+func (s *syntax) sameWord(a, b string) bool {
+	if s.windows {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
+func (s *syntax) Rel(basepath, targpath string) (string, error) {
+	var baseVol = s.VolumeName(basepath)
+	var targVol = s.VolumeName(targpath)
+	var base = s.Clean(basepath)
+	var targ = s.Clean(targpath)
+	if s.sameWord(targ, base) {
+		return ".", nil
+	}
+
+	base = base[len(baseVol):]
+	targ = targ[len(targVol):]
+	if base == "." {
+		base = ""
+	} else if base == "" && s.isUNC(baseVol) {
+		// A share on its own is the root of the share.
+		base = string(s.sep)
+	}
+
+	var baseSlashed = len(base) > 0 && base[0] == s.sep
+	var targSlashed = len(targ) > 0 && targ[0] == s.sep
+	if baseSlashed != targSlashed || !s.sameWord(baseVol, targVol) {
+		return "", errors.New("Rel: can't make " + targpath + " relative to " + basepath)
+	}
+
+	// Position base[b0:bi] and targ[t0:ti] at the first differing
+	// elements.
+	var bl, tl = len(base), len(targ)
+	var b0, bi, t0, ti int
+	for {
+		for bi < bl && base[bi] != s.sep {
+			bi++
+		}
+		for ti < tl && targ[ti] != s.sep {
+			ti++
+		}
+		if !s.sameWord(targ[t0:ti], base[b0:bi]) {
+			break
+		}
+		if bi < bl {
+			bi++
+		}
+		if ti < tl {
+			ti++
+		}
+		b0, t0 = bi, ti
+	}
+
+	if base[b0:bi] == ".." {
+		return "", errors.New("Rel: can't make " + targpath + " relative to " + basepath)
+	}
+	if b0 == bl {
+		return targ[t0:], nil
+	}
+
+	// Go up out of the remaining base elements, then down into targ.
+	var up = ".." + strings.Repeat(string(s.sep)+"..", strings.Count(base[b0:bl], string(s.sep)))
+	if t0 != tl {
+		up += string(s.sep) + targ[t0:]
+	}
+
+	return s.Clean(up), nil
+}
+
+// hasMeta reports whether p contains any of the characters special to
+// Match.
+func (s *syntax) hasMeta(p string) bool {
+	if s.windows {
+		return strings.ContainsAny(p, `*?[`)
+	}
+
+	return strings.ContainsAny(p, `*?[\`)
+}
+
+func (s *syntax) Match(pattern, name string) (bool, error) {
+Pattern:
+	for len(pattern) > 0 {
+		var star bool
+		var chunk string
+		star, chunk, pattern = s.scanChunk(pattern)
+		if star && chunk == "" {
+			// A trailing * matches the rest of the name, unless it
+			// has a separator.
+			return !strings.Contains(name, string(s.sep)), nil
+		}
+
+		var t, ok, err = s.matchChunk(chunk, name)
+		if ok && (len(t) == 0 || len(pattern) > 0) {
+			name = t
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if star {
+			// Look for a match skipping i+1 bytes, but not a
+			// separator.
+			for i := 0; i < len(name) && name[i] != s.sep; i++ {
+				var t, ok, err = s.matchChunk(chunk, name[i+1:])
+				if ok {
+					if len(pattern) == 0 && len(t) > 0 {
+						continue
+					}
+					name = t
+					continue Pattern
+				}
+				if err != nil {
+					return false, err
+				}
+			}
+		}
+
+		return false, nil
+	}
+
+	return len(name) == 0, nil
+}
+
+// scanChunk returns the next segment of pattern, which is a string
+// without stars possibly preceded by a star.
+func (s *syntax) scanChunk(pattern string) (star bool, chunk, rest string) {
+	for len(pattern) > 0 && pattern[0] == '*' {
+		pattern = pattern[1:]
+		star = true
+	}
+
+	var inrange bool
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if !s.windows && i+1 < len(pattern) {
+				i++
+			}
+		case '[':
+			inrange = true
+		case ']':
+			inrange = false
+		case '*':
+			if !inrange {
+				return star, pattern[:i], pattern[i:]
+			}
+		}
+	}
+
+	return star, pattern, ""
+}
+
+// matchChunk checks whether chunk matches the start of str, and if so
+// returns the rest of str.
+func (s *syntax) matchChunk(chunk, str string) (string, bool, error) {
+	// After the match fails, the rest of chunk is still checked to
+	// report a malformed pattern.
+	var failed bool
+	for len(chunk) > 0 {
+		failed = failed || len(str) == 0
+		switch chunk[0] {
+		case '[':
+			var r rune
+			if !failed {
+				var n int
+				r, n = utf8.DecodeRuneInString(str)
+				str = str[n:]
+			}
+			chunk = chunk[1:]
+
+			var negated bool
+			if len(chunk) > 0 && chunk[0] == '^' {
+				negated = true
+				chunk = chunk[1:]
+			}
+
+			var match bool
+			var nrange int
+			for {
+				if len(chunk) > 0 && chunk[0] == ']' && nrange > 0 {
+					chunk = chunk[1:]
+					break
+				}
+
+				var lo, hi rune
+				var err error
+				if lo, chunk, err = s.getEsc(chunk); err != nil {
+					return "", false, err
+				}
+				hi = lo
+				if chunk[0] == '-' {
+					if hi, chunk, err = s.getEsc(chunk[1:]); err != nil {
+						return "", false, err
+					}
+				}
+				match = match || lo <= r && r <= hi
+				nrange++
+			}
+			failed = failed || match == negated
+
+		case '?':
+			if !failed {
+				failed = str[0] == s.sep
+				var _, n = utf8.DecodeRuneInString(str)
+				str = str[n:]
+			}
+			chunk = chunk[1:]
+
+		case '\\':
+			if !s.windows {
+				chunk = chunk[1:]
+				if len(chunk) == 0 {
+					return "", false, filepath.ErrBadPattern
+				}
+			}
+			fallthrough
+
+		default:
+			if !failed {
+				failed = chunk[0] != str[0]
+				str = str[1:]
+			}
+			chunk = chunk[1:]
+		}
+	}
+
+	if failed {
+		return "", false, nil
+	}
+	return str, true, nil
+}
+
+// getEsc returns a possibly escaped character from a character class.
+func (s *syntax) getEsc(chunk string) (rune, string, error) {
+	if len(chunk) == 0 || chunk[0] == '-' || chunk[0] == ']' {
+		return 0, "", filepath.ErrBadPattern
+	}
+	if chunk[0] == '\\' && !s.windows {
+		chunk = chunk[1:]
+		if len(chunk) == 0 {
+			return 0, "", filepath.ErrBadPattern
+		}
+	}
+
+	var r, n = utf8.DecodeRuneInString(chunk)
+	if r == utf8.RuneError && n == 1 {
+		return 0, "", filepath.ErrBadPattern
+	}
+	if len(chunk[n:]) == 0 {
+		return 0, "", filepath.ErrBadPattern
+	}
+
+	return r, chunk[n:], nil
+}
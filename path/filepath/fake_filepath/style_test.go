@@ -0,0 +1,736 @@
+package fake_filepath
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// The cases below are taken from the standard library's path/filepath
+// tests.  Results common to every platform are written with slashes,
+// and converted for the Windows style.
+
+type pathTest struct {
+	path, result string
+}
+
+type isLocalTest struct {
+	path    string
+	isLocal bool
+}
+
+type localizeTest struct {
+	path string
+	want string
+}
+
+type splitTest struct {
+	path, dir, file string
+}
+
+type joinTest struct {
+	elem []string
+	path string
+}
+
+type isAbsTest struct {
+	path  string
+	isAbs bool
+}
+
+type relTest struct {
+	root, path, want string
+}
+
+type volumeNameTest struct {
+	path string
+	vol  string
+}
+
+var cleantests = []pathTest{
+	// Already clean
+	{"abc", "abc"},
+	{"abc/def", "abc/def"},
+	{"a/b/c", "a/b/c"},
+	{".", "."},
+	{"..", ".."},
+	{"../..", "../.."},
+	{"../../abc", "../../abc"},
+	{"/abc", "/abc"},
+	{"/", "/"},
+
+	// Empty is current dir
+	{"", "."},
+
+	// Remove trailing slash
+	{"abc/", "abc"},
+	{"abc/def/", "abc/def"},
+	{"a/b/c/", "a/b/c"},
+	{"./", "."},
+	{"../", ".."},
+	{"../../", "../.."},
+	{"/abc/", "/abc"},
+
+	// Remove doubled slash
+	{"abc//def//ghi", "abc/def/ghi"},
+	{"abc//", "abc"},
+
+	// Remove . elements
+	{"abc/./def", "abc/def"},
+	{"/./abc/def", "/abc/def"},
+	{"abc/.", "abc"},
+
+	// Remove .. elements
+	{"abc/def/ghi/../jkl", "abc/def/jkl"},
+	{"abc/def/../ghi/../jkl", "abc/jkl"},
+	{"abc/def/..", "abc"},
+	{"abc/def/../..", "."},
+	{"/abc/def/../..", "/"},
+	{"abc/def/../../..", ".."},
+	{"/abc/def/../../..", "/"},
+	{"abc/def/../../../ghi/jkl/../../../mno", "../../mno"},
+	{"/../abc", "/abc"},
+	{"a/../b:/../../c", `../c`},
+
+	// Combinations
+	{"abc/./../def", "def"},
+	{"abc//./../def", "def"},
+	{"abc/../../././../def", "../../def"},
+}
+
+var nonwincleantests = []pathTest{
+	// Remove leading doubled slash
+	{"//abc", "/abc"},
+	{"///abc", "/abc"},
+	{"//abc//", "/abc"},
+}
+
+var wincleantests = []pathTest{
+	{`c:`, `c:.`},
+	{`c:\`, `c:\`},
+	{`c:\abc`, `c:\abc`},
+	{`c:abc\..\..\.\.\..\def`, `c:..\..\def`},
+	{`c:\abc\def\..\..`, `c:\`},
+	{`c:\..\abc`, `c:\abc`},
+	{`c:..\abc`, `c:..\abc`},
+	{`c:\b:\..\..\..\d`, `c:\d`},
+	{`\`, `\`},
+	{`/`, `\`},
+	{`\\i\..\c$`, `\c$`},
+	{`\\i\..\i\c$`, `\i\c$`},
+	{`\\i\..\I\c$`, `\I\c$`},
+	{`\\..\..\a`, `\a`},
+	{`//../../a`, `\a`},
+	{`\\host\share\foo\..\bar`, `\\host\share\bar`},
+	{`//host/share/foo/../baz`, `\\host\share\baz`},
+	{`\\host\share\foo\..\..\..\..\bar`, `\\host\share\bar`},
+	{`\\?\UNC\host\share\foo\..\..\..\..\bar`, `\\?\UNC\host\share\bar`},
+	{`\??\UNC\host\share\foo\..\..\..\..\bar`, `\??\UNC\host\share\bar`},
+	{`\\.\C:\a\..\..\..\..\bar`, `\\.\C:\bar`},
+	{`\\.\C:\\\\a`, `\\.\C:\a`},
+	{`\\a\b\..\c`, `\\a\b\c`},
+	{`\\a\b`, `\\a\b`},
+	{`.\c:`, `.\c:`},
+	{`.\c:\foo`, `.\c:\foo`},
+	{`.\c:foo`, `.\c:foo`},
+	{`//abc`, `\\abc`},
+	{`///abc`, `\\\abc`},
+	{`//abc//`, `\\abc\\`},
+	{`\\?\C:\`, `\\?\C:\`},
+	{`\\?\C:\a`, `\\?\C:\a`},
+
+	// Don't allow cleaning to move an element with a colon to the start of the path.
+	{`a/../c:`, `.\c:`},
+	{`a\..\c:`, `.\c:`},
+	{`a/../c:/a`, `.\c:\a`},
+	{`a/../../c:`, `..\c:`},
+	{`foo:bar`, `foo:bar`},
+
+	// Don't allow cleaning to create a Root Local Device path like \??\a.
+	{`/a/../??/a`, `\.\??\a`},
+}
+
+var islocaltests = []isLocalTest{
+	{"", false},
+	{".", true},
+	{"..", false},
+	{"../a", false},
+	{"/", false},
+	{"/a", false},
+	{"/a/../..", false},
+	{"a", true},
+	{"a/../a", true},
+	{"a/", true},
+	{"a/.", true},
+	{"a/./b/./c", true},
+	{`a/../b:/../../c`, false},
+}
+
+var winislocaltests = []isLocalTest{
+	{"NUL", false},
+	{"nul", false},
+	{"nul ", false},
+	{"nul.", false},
+	{"a/nul:", false},
+	{"a/nul : a", false},
+	{"com0", true},
+	{"com1", false},
+	{"com2", false},
+	{"com3", false},
+	{"com4", false},
+	{"com5", false},
+	{"com6", false},
+	{"com7", false},
+	{"com8", false},
+	{"com9", false},
+	{"com¹", false},
+	{"com²", false},
+	{"com³", false},
+	{"com¹ : a", false},
+	{"cOm1", false},
+	{"lpt1", false},
+	{"LPT1", false},
+	{"lpt³", false},
+	{"./nul", false},
+	{`\`, false},
+	{`\a`, false},
+	{`C:`, false},
+	{`C:\a`, false},
+	{`..\a`, false},
+	{`a/../c:`, false},
+	{`CONIN$`, false},
+	{`conin$`, false},
+	{`CONOUT$`, false},
+	{`conout$`, false},
+	{`dollar$`, true}, // not a special file name
+}
+
+var localizetests = []localizeTest{
+	{"", ""},
+	{".", "."},
+	{"..", ""},
+	{"a/..", ""},
+	{"/", ""},
+	{"/a", ""},
+	{"a\xffb", ""},
+	{"a/", ""},
+	{"a/./b", ""},
+	{"\x00", ""},
+	{"a", "a"},
+	{"a/b/c", "a/b/c"},
+}
+
+var unixlocalizetests = []localizeTest{
+	{"#a", "#a"},
+	{`a\b:c`, `a\b:c`},
+}
+
+var winlocalizetests = []localizeTest{
+	{"#a", "#a"},
+	{"c:", ""},
+	{`a\b`, ""},
+	{`a:b`, ""},
+	{`a/b:c`, ""},
+	{`NUL`, ""},
+	{`a/NUL`, ""},
+	{`./com1`, ""},
+	{`a/nul/b`, ""},
+}
+
+var unixsplittests = []splitTest{
+	{"a/b", "a/", "b"},
+	{"a/b/", "a/b/", ""},
+	{"a/", "a/", ""},
+	{"a", "", "a"},
+	{"/", "/", ""},
+}
+
+var winsplittests = []splitTest{
+	{`c:`, `c:`, ``},
+	{`c:/`, `c:/`, ``},
+	{`c:/foo`, `c:/`, `foo`},
+	{`c:/foo/bar`, `c:/foo/`, `bar`},
+	{`//host/share`, `//host/share`, ``},
+	{`//host/share/`, `//host/share/`, ``},
+	{`//host/share/foo`, `//host/share/`, `foo`},
+	{`\\host\share`, `\\host\share`, ``},
+	{`\\host\share\`, `\\host\share\`, ``},
+	{`\\host\share\foo`, `\\host\share\`, `foo`},
+}
+
+var jointests = []joinTest{
+	// zero parameters
+	{[]string{}, ""},
+
+	// one parameter
+	{[]string{""}, ""},
+	{[]string{"/"}, "/"},
+	{[]string{"a"}, "a"},
+
+	// two parameters
+	{[]string{"a", "b"}, "a/b"},
+	{[]string{"a", ""}, "a"},
+	{[]string{"", "b"}, "b"},
+	{[]string{"/", "a"}, "/a"},
+	{[]string{"/", "a/b"}, "/a/b"},
+	{[]string{"/", ""}, "/"},
+	{[]string{"/a", "b"}, "/a/b"},
+	{[]string{"a", "/b"}, "a/b"},
+	{[]string{"/a", "/b"}, "/a/b"},
+	{[]string{"a/", "b"}, "a/b"},
+	{[]string{"a/", ""}, "a"},
+	{[]string{"", ""}, ""},
+
+	// three parameters
+	{[]string{"/", "a", "b"}, "/a/b"},
+}
+
+var nonwinjointests = []joinTest{
+	{[]string{"//", "a"}, "/a"},
+}
+
+var winjointests = []joinTest{
+	{[]string{`directory`, `file`}, `directory\file`},
+	{[]string{`C:\Windows\`, `System32`}, `C:\Windows\System32`},
+	{[]string{`C:\Windows\`, ``}, `C:\Windows`},
+	{[]string{`C:\`, `Windows`}, `C:\Windows`},
+	{[]string{`C:`, `a`}, `C:a`},
+	{[]string{`C:`, `a\b`}, `C:a\b`},
+	{[]string{`C:`, `a`, `b`}, `C:a\b`},
+	{[]string{`C:`, ``, `b`}, `C:b`},
+	{[]string{`C:`, ``, ``, `b`}, `C:b`},
+	{[]string{`C:`, ``}, `C:.`},
+	{[]string{`C:`, ``, ``}, `C:.`},
+	{[]string{`C:`, `\a`}, `C:\a`},
+	{[]string{`C:`, ``, `\a`}, `C:\a`},
+	{[]string{`C:.`, `a`}, `C:a`},
+	{[]string{`C:a`, `b`}, `C:a\b`},
+	{[]string{`C:a`, `b`, `d`}, `C:a\b\d`},
+	{[]string{`\\host\share`, `foo`}, `\\host\share\foo`},
+	{[]string{`\\host\share\foo`}, `\\host\share\foo`},
+	{[]string{`//host/share`, `foo/bar`}, `\\host\share\foo\bar`},
+	{[]string{`\`}, `\`},
+	{[]string{`\`, ``}, `\`},
+	{[]string{`\`, `a`}, `\a`},
+	{[]string{`\\`, `a`}, `\\a`},
+	{[]string{`\`, `a`, `b`}, `\a\b`},
+	{[]string{`\\`, `a`, `b`}, `\\a\b`},
+	{[]string{`\`, `\\a\b`, `c`}, `\a\b\c`},
+	{[]string{`\\a`, `b`, `c`}, `\\a\b\c`},
+	{[]string{`\\a\`, `b`, `c`}, `\\a\b\c`},
+	{[]string{`//`, `a`}, `\\a`},
+	{[]string{`a:\b\c`, `x\..\y:\..\..\z`}, `a:\b\z`},
+	{[]string{`\`, `??\a`}, `\.\??\a`},
+}
+
+var basetests = []pathTest{
+	{"", "."},
+	{".", "."},
+	{"/.", "."},
+	{"/", "/"},
+	{"////", "/"},
+	{"x/", "x"},
+	{"abc", "abc"},
+	{"abc/def", "def"},
+	{"a/b/.x", ".x"},
+	{"a/b/c.", "c."},
+	{"a/b/c.x", "c.x"},
+}
+
+var winbasetests = []pathTest{
+	{`c:\`, `\`},
+	{`c:.`, `.`},
+	{`c:\a\b`, `b`},
+	{`c:a\b`, `b`},
+	{`c:a\b\c`, `c`},
+	{`\\host\share\`, `\`},
+	{`\\host\share\a`, `a`},
+	{`\\host\share\a\b`, `b`},
+}
+
+var dirtests = []pathTest{
+	{"", "."},
+	{".", "."},
+	{"/.", "/"},
+	{"/", "/"},
+	{"/foo", "/"},
+	{"x/", "x"},
+	{"abc", "."},
+	{"abc/def", "abc"},
+	{"a/b/.x", "a/b"},
+	{"a/b/c.", "a/b"},
+	{"a/b/c.x", "a/b"},
+}
+
+var nonwindirtests = []pathTest{
+	{"////", "/"},
+}
+
+var windirtests = []pathTest{
+	{`c:\`, `c:\`},
+	{`c:.`, `c:.`},
+	{`c:\a\b`, `c:\a`},
+	{`c:a\b`, `c:a`},
+	{`c:a\b\c`, `c:a\b`},
+	{`\\host\share`, `\\host\share`},
+	{`\\host\share\`, `\\host\share\`},
+	{`\\host\share\a`, `\\host\share\`},
+	{`\\host\share\a\b`, `\\host\share\a`},
+	{`\\\\`, `\\\\`},
+}
+
+var isabstests = []isAbsTest{
+	{"", false},
+	{"/", true},
+	{"/usr/bin/gcc", true},
+	{"..", false},
+	{"/a/../bb", true},
+	{".", false},
+	{"./", false},
+	{"lala", false},
+}
+
+var winisabstests = []isAbsTest{
+	{`C:\`, true},
+	{`c\`, false},
+	{`c::`, false},
+	{`c:`, false},
+	{`/`, false},
+	{`\`, false},
+	{`\Windows`, false},
+	{`c:a\b`, false},
+	{`c:\a\b`, true},
+	{`c:/a/b`, true},
+	{`\\host\share`, true},
+	{`\\host\share\`, true},
+	{`\\host\share\foo`, true},
+	{`//host/share/foo/bar`, true},
+	{`\\..\..\a`, false},
+	{`//../../a`, false},
+	{`\\i\..\c$`, false},
+	{`//?/../x`, false},
+	{`//./../x`, false},
+	{`\\?\a\b\c`, true},
+	{`\??\a\b\c`, true},
+}
+
+var reltests = []relTest{
+	{"a/b", "a/b", "."},
+	{"a/b/.", "a/b", "."},
+	{"a/b", "a/b/.", "."},
+	{"./a/b", "a/b", "."},
+	{"a/b", "./a/b", "."},
+	{"ab/cd", "ab/cde", "../cde"},
+	{"ab/cd", "ab/c", "../c"},
+	{"a/b", "a/b/c/d", "c/d"},
+	{"a/b", "a/b/../c", "../c"},
+	{"a/b/../c", "a/b", "../b"},
+	{"a/b/c", "a/c/d", "../../c/d"},
+	{"a/b", "c/d", "../../c/d"},
+	{"a/b/c/d", "a/b", "../.."},
+	{"a/b/c/d", "a/b/", "../.."},
+	{"a/b/c/d/", "a/b", "../.."},
+	{"a/b/c/d/", "a/b/", "../.."},
+	{"../../a/b", "../../a/b/c/d", "c/d"},
+	{"/a/b", "/a/b", "."},
+	{"/a/b/.", "/a/b", "."},
+	{"/a/b", "/a/b/.", "."},
+	{"/ab/cd", "/ab/cde", "../cde"},
+	{"/ab/cd", "/ab/c", "../c"},
+	{"/a/b", "/a/b/c/d", "c/d"},
+	{"/a/b", "/a/b/../c", "../c"},
+	{"/a/b/../c", "/a/b", "../b"},
+	{"/a/b/c", "/a/c/d", "../../c/d"},
+	{"/a/b", "/c/d", "../../c/d"},
+	{"/a/b/c/d", "/a/b", "../.."},
+	{"/a/b/c/d", "/a/b/", "../.."},
+	{"/a/b/c/d/", "/a/b", "../.."},
+	{"/a/b/c/d/", "/a/b/", "../.."},
+	{"/../../a/b", "/../../a/b/c/d", "c/d"},
+	{".", "a/b", "a/b"},
+	{".", "..", ".."},
+	{"", "../../.", "../.."},
+
+	// can't do purely lexically
+	{"..", ".", "err"},
+	{"..", "a", "err"},
+	{"../..", "..", "err"},
+	{"a", "/a", "err"},
+	{"/a", "a", "err"},
+}
+
+var winreltests = []relTest{
+	{`C:a\b\c`, `C:a/b/d`, `..\d`},
+	{`C:\`, `D:\`, `err`},
+	{`C:`, `D:`, `err`},
+	{`C:\Projects`, `c:\projects\src`, `src`},
+	{`C:\Projects`, `c:\projects`, `.`},
+	{`C:\Projects\a\..`, `c:\projects`, `.`},
+	{`\\host\share`, `\\host\share\file.txt`, `file.txt`},
+}
+
+var volumenametests = []volumeNameTest{
+	{`c:/foo/bar`, `c:`},
+	{`c:`, `c:`},
+	{`c:\`, `c:`},
+	{`2:`, `2:`},
+	{``, ``},
+	{`\\\host`, `\\\host`},
+	{`\\\host\`, `\\\host`},
+	{`\\\host\share`, `\\\host`},
+	{`\\\host\\share`, `\\\host`},
+	{`\\host`, `\\host`},
+	{`//host`, `\\host`},
+	{`\\host\`, `\\host\`},
+	{`//host/`, `\\host\`},
+	{`\\host\share`, `\\host\share`},
+	{`//host/share`, `\\host\share`},
+	{`\\host\share\`, `\\host\share`},
+	{`//host/share/`, `\\host\share`},
+	{`\\host\share\foo`, `\\host\share`},
+	{`//host/share/foo`, `\\host\share`},
+	{`\\host\share\\foo\\\bar\\\\baz`, `\\host\share`},
+	{`//host/share//foo///bar////baz`, `\\host\share`},
+	{`\\host\share\foo\..\bar`, `\\host\share`},
+	{`//host/share/foo/../bar`, `\\host\share`},
+	{`\\..\..\a`, ``},
+	{`//../../a`, ``},
+	{`\\i\..\c$`, ``},
+	{`//./UNC/../share`, ``},
+	{`//?/../x`, ``},
+	{`//./../x`, ``},
+	{`//.../share`, `\\...\share`},
+	{`//host/...`, `\\host\...`},
+	{`//?/..x`, `\\?\..x`},
+	{`//.`, `\\.`},
+	{`//./`, `\\.\`},
+	{`//./NUL`, `\\.\NUL`},
+	{`//?`, `\\?`},
+	{`//?/`, `\\?\`},
+	{`//?/NUL`, `\\?\NUL`},
+	{`/??`, `\??`},
+	{`/??/`, `\??\`},
+	{`/??/NUL`, `\??\NUL`},
+	{`//./a/b`, `\\.\a`},
+	{`//./C:`, `\\.\C:`},
+	{`//./C:/`, `\\.\C:`},
+	{`//./C:/a/b/c`, `\\.\C:`},
+	{`//./UNC/host/share/a/b/c`, `\\.\UNC\host\share`},
+	{`//?/UNC/host/share/a/b/c`, `\\?\UNC\host\share`},
+	{`/??/UNC/host/share/a/b/c`, `\??\UNC\host\share`},
+	{`//./UNC/host`, `\\.\UNC\host`},
+	{`//./UNC/host\`, `\\.\UNC\host\`},
+	{`//./UNC`, `\\.\UNC`},
+	{`//./UNC/`, `\\.\UNC\`},
+	{`\\?\x`, `\\?\x`},
+	{`\??\x`, `\??\x`},
+}
+
+var winsplitlisttests = []struct {
+	list   string
+	result []string
+}{
+	// quoted
+	{`"a"`, []string{`a`}},
+
+	// semicolon
+	{`";"`, []string{`;`}},
+	{`"a;b"`, []string{`a;b`}},
+	{`";";`, []string{`;`, ``}},
+	{`;";"`, []string{``, `;`}},
+
+	// partially quoted
+	{`a";"b`, []string{`a;b`}},
+	{`a; ""b`, []string{`a`, ` b`}},
+	{`"a;b`, []string{`a;b`}},
+	{`""a;b`, []string{`a`, `b`}},
+	{`"""a;b`, []string{`a;b`}},
+	{`""""a;b`, []string{`a`, `b`}},
+	{`a";b`, []string{`a;b`}},
+	{`a;b";c`, []string{`a`, `b;c`}},
+	{`"a";b";c`, []string{`a`, `b;c`}},
+}
+
+// fromSlash returns tests with their results written with backslashes.
+func fromSlash(tests []pathTest) []pathTest {
+	var out = slices.Clone(tests)
+	for i := range out {
+		out[i].result = windowsSyntax.FromSlash(out[i].result)
+	}
+
+	return out
+}
+
+// TestStyle_Clean tests Clean in the Unix and Windows styles.
+func TestStyle_Clean(t *testing.T) {
+	for style, tests := range map[Style][]pathTest{
+		Unix:    append(slices.Clone(cleantests), nonwincleantests...),
+		Windows: append(fromSlash(cleantests), wincleantests...),
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests {
+			testutil.AssertEqual(t, test.result, fp.Clean(test.path))
+			testutil.AssertEqual(t, test.result, fp.Clean(test.result))
+		}
+	}
+}
+
+// TestStyle_Join tests Join in the Unix and Windows styles.
+func TestStyle_Join(t *testing.T) {
+	for style, tests := range map[Style][]joinTest{
+		Unix:    append(slices.Clone(jointests), nonwinjointests...),
+		Windows: append(slices.Clone(jointests), winjointests...),
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests {
+			want := test.path
+			if style == Windows {
+				want = windowsSyntax.FromSlash(want)
+			}
+			testutil.AssertEqual(t, want, fp.Join(test.elem...))
+		}
+	}
+}
+
+// TestStyle_Split tests Split in the Unix and Windows styles.
+func TestStyle_Split(t *testing.T) {
+	for style, tests := range map[Style][]splitTest{
+		Unix:    unixsplittests,
+		Windows: append(slices.Clone(unixsplittests), winsplittests...),
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests {
+			dir, file := fp.Split(test.path)
+			testutil.AssertEqual(t, test.dir+" "+test.file, dir+" "+file)
+		}
+	}
+}
+
+// TestStyle_BaseDir tests Base and Dir in the Unix and Windows styles.
+func TestStyle_BaseDir(t *testing.T) {
+	for style, tests := range map[Style][2][]pathTest{
+		Unix:    {basetests, append(slices.Clone(dirtests), nonwindirtests...)},
+		Windows: {append(fromSlash(basetests), winbasetests...), append(fromSlash(dirtests), windirtests...)},
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests[0] {
+			testutil.AssertEqual(t, test.result, fp.Base(test.path))
+		}
+		for _, test := range tests[1] {
+			testutil.AssertEqual(t, test.result, fp.Dir(test.path))
+		}
+	}
+}
+
+// TestStyle_IsAbs tests IsAbs in the Unix and Windows styles.
+func TestStyle_IsAbs(t *testing.T) {
+	// Without a volume name, no path is absolute on Windows.
+	var tests = slices.Clone(winisabstests)
+	for _, test := range isabstests {
+		tests = append(tests, isAbsTest{test.path, false}, isAbsTest{"c:" + test.path, test.isAbs})
+	}
+
+	for style, tests := range map[Style][]isAbsTest{
+		Unix:    isabstests,
+		Windows: tests,
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests {
+			testutil.AssertEqual(t, test.isAbs, fp.IsAbs(test.path))
+		}
+	}
+}
+
+// TestStyle_IsLocal tests IsLocal in the Unix and Windows styles.
+func TestStyle_IsLocal(t *testing.T) {
+	for style, tests := range map[Style][]isLocalTest{
+		Unix:    islocaltests,
+		Windows: append(slices.Clone(islocaltests), winislocaltests...),
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests {
+			testutil.AssertEqual(t, test.isLocal, fp.IsLocal(test.path))
+		}
+	}
+}
+
+// TestStyle_Localize tests Localize in the Unix and Windows styles,
+// where a want of "" is an error.
+func TestStyle_Localize(t *testing.T) {
+	for style, tests := range map[Style][]localizeTest{
+		Unix:    append(slices.Clone(localizetests), unixlocalizetests...),
+		Windows: append(slices.Clone(localizetests), winlocalizetests...),
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests {
+			got, err := fp.Localize(test.path)
+			if test.want == "" {
+				testutil.AssertNotNil(t, err)
+				continue
+			}
+			testutil.AssertNil(t, err)
+			testutil.AssertEqual(t, fp.FromSlash(test.want), got)
+		}
+	}
+}
+
+// TestStyle_Rel tests Rel in the Unix and Windows styles, where a want
+// of "err" is an error.
+func TestStyle_Rel(t *testing.T) {
+	for style, tests := range map[Style][]relTest{
+		Unix:    reltests,
+		Windows: append(slices.Clone(reltests), winreltests...),
+	} {
+		fp := New(nil, WithStyle(style))
+		for _, test := range tests {
+			got, err := fp.Rel(test.root, test.path)
+			if test.want == "err" {
+				testutil.AssertNotNil(t, err)
+				continue
+			}
+			testutil.AssertNil(t, err)
+			testutil.AssertEqual(t, fp.FromSlash(test.want), got)
+		}
+	}
+}
+
+// TestStyle_VolumeName tests VolumeName in the Windows style, and that
+// there are no volumes in the Unix style.
+func TestStyle_VolumeName(t *testing.T) {
+	win := New(nil, WithStyle(Windows))
+	unix := New(nil, WithStyle(Unix))
+	for _, test := range volumenametests {
+		testutil.AssertEqual(t, test.vol, win.VolumeName(test.path))
+		testutil.AssertEqual(t, "", unix.VolumeName(test.path))
+	}
+}
+
+// TestStyle_SplitList tests SplitList in the Unix and Windows styles.
+func TestStyle_SplitList(t *testing.T) {
+	for style, listSep := range map[Style]string{Unix: ":", Windows: ";"} {
+		fp := New(nil, WithStyle(style))
+		testutil.AssertEqual(t, 0, len(fp.SplitList("")))
+		testutil.AssertEqual(t, "a b", strings.Join(fp.SplitList("a"+listSep+"b"), " "))
+		testutil.AssertEqual(t, " a b", strings.Join(fp.SplitList(listSep+"a"+listSep+"b"), " "))
+	}
+
+	fp := New(nil, WithStyle(Windows))
+	for _, test := range winsplitlisttests {
+		testutil.AssertEqual(t, strings.Join(test.result, "|"), strings.Join(fp.SplitList(test.list), "|"))
+	}
+}
+
+// TestStyle_Slash tests FromSlash and ToSlash in the Unix and Windows
+// styles.
+func TestStyle_Slash(t *testing.T) {
+	for style, sep := range map[Style]string{Unix: "/", Windows: `\`} {
+		fp := New(nil, WithStyle(style))
+		for _, p := range []string{"", "/", "/a/b", "a//b"} {
+			want := strings.ReplaceAll(p, "/", sep)
+			testutil.AssertEqual(t, want, fp.FromSlash(p))
+			testutil.AssertEqual(t, p, fp.ToSlash(want))
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package fake_sync
+
+import (
+	"sync"
+
+	synci "github.com/pdutton/go-interfaces/sync"
+)
+
+// Mutex is a mutual exclusion lock whose use is recorded by its Sync.
+type Mutex struct {
+	sync *Sync
+	lock *lockInfo
+	mu   sync.Mutex
+}
+
+var _ synci.Mutex = (*Mutex)(nil)
+
+// NewMutex returns an unlocked Mutex, or a locked one with the
+// WithLocked option.
+func (s *Sync) NewMutex(options ...synci.MutexOption) synci.Mutex {
+	return s.NewNamedMutex("", options...)
+}
+
+// NewNamedMutex returns a Mutex with a name for reports, instead of
+// one numbered in creation order.
+func (s *Sync) NewNamedMutex(name string, options ...synci.MutexOption) *Mutex {
+	var m = &Mutex{sync: s, lock: s.newLock("mutex", name)}
+	for _, opt := range options {
+		opt(&m.mu)
+	}
+
+	if m.mu.TryLock() {
+		m.mu.Unlock()
+	} else {
+		s.initiallyHeld(m.lock, false)
+	}
+
+	return m
+}
+
+// Name returns the name used for the mutex in reports.
+func (m *Mutex) Name() string {
+	return m.lock.name
+}
+
+// Stats returns the mutex's statistics so far.
+func (m *Mutex) Stats() LockStats {
+	return m.sync.lockStats(m.lock)
+}
+
+// Lock locks m, blocking until it is available.
+func (m *Mutex) Lock() {
+	var g = goid()
+	m.sync.request(m.lock, g)
+
+	var contended = !m.mu.TryLock()
+	if contended {
+		var unblock = m.sync.block(g, "Lock of "+m.lock.name)
+		m.mu.Lock()
+		unblock()
+	}
+
+	m.sync.acquired(m.lock, g, false, contended)
+}
+
+// TryLock locks m if it is available, and reports whether it did.
+func (m *Mutex) TryLock() bool {
+	if !m.mu.TryLock() {
+		m.sync.tryFailed(m.lock, false)
+		return false
+	}
+
+	m.sync.acquired(m.lock, goid(), false, false)
+	return true
+}
+
+// Unlock unlocks m.  Unlike a real mutex, unlocking an unlocked one
+// panics rather than ending the program.
+func (m *Mutex) Unlock() {
+	m.sync.release(m.lock, goid(), false, "sync: unlock of unlocked mutex")
+	m.mu.Unlock()
+}
+
+// RWMutex is a reader/writer mutual exclusion lock whose use is
+// recorded by its Sync.
+type RWMutex struct {
+	sync *Sync
+	lock *lockInfo
+	mu   sync.RWMutex
+}
+
+var _ synci.RWMutex = (*RWMutex)(nil)
+
+// NewRWMutex returns an unlocked RWMutex, or a locked one with the
+// WithRLocked or WithWLocked option.
+func (s *Sync) NewRWMutex(options ...synci.RWMutexOption) synci.RWMutex {
+	return s.NewNamedRWMutex("", options...)
+}
+
+// NewNamedRWMutex returns an RWMutex with a name for reports, instead
+// of one numbered in creation order.
+func (s *Sync) NewNamedRWMutex(name string, options ...synci.RWMutexOption) *RWMutex {
+	var m = &RWMutex{sync: s, lock: s.newLock("rwmutex", name)}
+	for _, opt := range options {
+		opt(&m.mu)
+	}
+
+	switch {
+	case m.mu.TryLock():
+		m.mu.Unlock()
+	case m.mu.TryRLock():
+		m.mu.RUnlock()
+		s.initiallyHeld(m.lock, true)
+	default:
+		s.initiallyHeld(m.lock, false)
+	}
+
+	return m
+}
+
+// Name returns the name used for the mutex in reports.
+func (m *RWMutex) Name() string {
+	return m.lock.name
+}
+
+// Stats returns the mutex's statistics so far.
+func (m *RWMutex) Stats() LockStats {
+	return m.sync.lockStats(m.lock)
+}
+
+// Lock locks m for writing, blocking until it is available.
+func (m *RWMutex) Lock() {
+	var g = goid()
+	m.sync.request(m.lock, g)
+
+	var contended = !m.mu.TryLock()
+	if contended {
+		var unblock = m.sync.block(g, "Lock of "+m.lock.name)
+		m.mu.Lock()
+		unblock()
+	}
+
+	m.sync.acquired(m.lock, g, false, contended)
+}
+
+// RLock locks m for reading, blocking while a writer holds or is
+// waiting for it.
+func (m *RWMutex) RLock() {
+	var g = goid()
+	m.sync.request(m.lock, g)
+
+	var contended = !m.mu.TryRLock()
+	if contended {
+		var unblock = m.sync.block(g, "RLock of "+m.lock.name)
+		m.mu.RLock()
+		unblock()
+	}
+
+	m.sync.acquired(m.lock, g, true, contended)
+}
+
+// TryLock locks m for writing if it is available, and reports whether
+// it did.
+func (m *RWMutex) TryLock() bool {
+	if !m.mu.TryLock() {
+		m.sync.tryFailed(m.lock, false)
+		return false
+	}
+
+	m.sync.acquired(m.lock, goid(), false, false)
+	return true
+}
+
+// TryRLock locks m for reading if it is available, and reports
+// whether it did.
+func (m *RWMutex) TryRLock() bool {
+	if !m.mu.TryRLock() {
+		m.sync.tryFailed(m.lock, true)
+		return false
+	}
+
+	m.sync.acquired(m.lock, goid(), true, false)
+	return true
+}
+
+// Unlock unlocks m for writing.
+func (m *RWMutex) Unlock() {
+	m.sync.release(m.lock, goid(), false, "sync: Unlock of unlocked RWMutex")
+	m.mu.Unlock()
+}
+
+// RUnlock undoes a single RLock.
+func (m *RWMutex) RUnlock() {
+	m.sync.release(m.lock, goid(), true, "sync: RUnlock of unlocked RWMutex")
+	m.mu.RUnlock()
+}
+
+// RLocker returns a Locker whose Lock and Unlock call RLock and
+// RUnlock.
+func (m *RWMutex) RLocker() synci.Locker {
+	return rlocker{m}
+}
+
+type rlocker struct {
+	m *RWMutex
+}
+
+func (r rlocker) Lock()   { r.m.RLock() }
+func (r rlocker) Unlock() { r.m.RUnlock() }
@@ -0,0 +1,293 @@
+package fake_sync
+
+import (
+	"sync"
+	"time"
+
+	synci "github.com/pdutton/go-interfaces/sync"
+)
+
+// LockStats counts how a Mutex or RWMutex has been used.  The read
+// fields are only used by an RWMutex.
+type LockStats struct {
+	Name string
+
+	// Locks and RLocks count acquisitions for writing and reading,
+	// including by TryLock and TryRLock.
+	Locks, RLocks int
+
+	// Contended and RContended count the acquisitions that had to
+	// wait for the lock.
+	Contended, RContended int
+
+	// TryLockFailures and TryRLockFailures count the TryLock and
+	// TryRLock calls that failed.
+	TryLockFailures, TryRLockFailures int
+
+	// MaxHold and MaxRHold are the longest the lock was held for
+	// writing and by a reader.
+	MaxHold, MaxRHold time.Duration
+}
+
+// PoolStats counts how a Pool has been used.
+type PoolStats struct {
+	Name string
+
+	// Hits counts the Gets that returned a pooled value, and Misses
+	// the Gets that called New, or returned nil without it.
+	Gets, Hits, Misses int
+
+	// Puts counts the values put in the pool.
+	Puts int
+}
+
+// HitRatio returns the fraction of Gets that returned a pooled value,
+// or zero if there have been none.
+func (s PoolStats) HitRatio() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+// MapStats counts the calls to each method of a Map.
+type MapStats struct {
+	Name string
+
+	Clear            int
+	CompareAndDelete int
+	CompareAndSwap   int
+	Delete           int
+	Load             int
+	LoadAndDelete    int
+	LoadOrStore      int
+	Range            int
+	Store            int
+	Swap             int
+}
+
+// Locks returns the statistics for every Mutex and RWMutex made, in
+// the order they were made.
+func (s *Sync) Locks() []LockStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats = make([]LockStats, len(s.locks))
+	for i, l := range s.locks {
+		stats[i] = l.stats
+	}
+
+	return stats
+}
+
+func (s *Sync) lockStats(l *lockInfo) LockStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return l.stats
+}
+
+// Pools returns the statistics for every Pool made, in the order they
+// were made.
+func (s *Sync) Pools() []PoolStats {
+	s.mu.Lock()
+	var pools = s.pools
+	s.mu.Unlock()
+
+	var stats = make([]PoolStats, len(pools))
+	for i, p := range pools {
+		stats[i] = p.Stats()
+	}
+
+	return stats
+}
+
+// Maps returns the statistics for every Map made, in the order they
+// were made.
+func (s *Sync) Maps() []MapStats {
+	s.mu.Lock()
+	var maps = s.maps
+	s.mu.Unlock()
+
+	var stats = make([]MapStats, len(maps))
+	for i, m := range maps {
+		stats[i] = m.Stats()
+	}
+
+	return stats
+}
+
+// Pool is a sync.Pool that counts hits and misses.
+type Pool struct {
+	pool sync.Pool
+	new  func() any
+
+	mu    sync.Mutex
+	stats PoolStats
+}
+
+var _ synci.Pool = (*Pool)(nil)
+
+// NewPool returns a Pool configured by the options.
+func (s *Sync) NewPool(options ...synci.PoolOption) synci.Pool {
+	return s.NewNamedPool("", options...)
+}
+
+// NewNamedPool returns a Pool with a name for its statistics, instead
+// of one numbered in creation order.
+func (s *Sync) NewNamedPool(name string, options ...synci.PoolOption) *Pool {
+	if name == "" {
+		name = s.newName("pool")
+	}
+
+	var p = &Pool{stats: PoolStats{Name: name}}
+	for _, opt := range options {
+		opt(&p.pool)
+	}
+	p.new, p.pool.New = p.pool.New, nil
+
+	s.mu.Lock()
+	s.pools = append(s.pools, p)
+	s.mu.Unlock()
+
+	return p
+}
+
+// Get returns a pooled value if there is one, and otherwise the result
+// of New, or nil without it.
+func (p *Pool) Get() any {
+	var v = p.pool.Get()
+
+	p.mu.Lock()
+	p.stats.Gets++
+	if v != nil {
+		p.stats.Hits++
+	} else {
+		p.stats.Misses++
+	}
+	p.mu.Unlock()
+
+	if v == nil && p.new != nil {
+		v = p.new()
+	}
+
+	return v
+}
+
+// Put adds v to the pool.  Like a real pool, it ignores nil.
+func (p *Pool) Put(v any) {
+	if v == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.stats.Puts++
+	p.mu.Unlock()
+
+	p.pool.Put(v)
+}
+
+// Stats returns the pool's statistics so far.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stats
+}
+
+// Map is a sync.Map that counts calls to each method.
+type Map struct {
+	m sync.Map
+
+	mu    sync.Mutex
+	stats MapStats
+}
+
+var _ synci.Map = (*Map)(nil)
+
+// NewMap returns an empty Map.
+func (s *Sync) NewMap() synci.Map {
+	return s.NewNamedMap("")
+}
+
+// NewNamedMap returns a Map with a name for its statistics, instead of
+// one numbered in creation order.
+func (s *Sync) NewNamedMap(name string) *Map {
+	if name == "" {
+		name = s.newName("map")
+	}
+
+	var m = &Map{stats: MapStats{Name: name}}
+
+	s.mu.Lock()
+	s.maps = append(s.maps, m)
+	s.mu.Unlock()
+
+	return m
+}
+
+// count increments the counter chosen by f.
+func (m *Map) count(f func(*MapStats) *int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	*f(&m.stats)++
+}
+
+// Stats returns the map's statistics so far.
+func (m *Map) Stats() MapStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stats
+}
+
+func (m *Map) Clear() {
+	m.count(func(s *MapStats) *int { return &s.Clear })
+	m.m.Clear()
+}
+
+func (m *Map) CompareAndDelete(key, old any) bool {
+	m.count(func(s *MapStats) *int { return &s.CompareAndDelete })
+	return m.m.CompareAndDelete(key, old)
+}
+
+func (m *Map) CompareAndSwap(key, old, new any) bool {
+	m.count(func(s *MapStats) *int { return &s.CompareAndSwap })
+	return m.m.CompareAndSwap(key, old, new)
+}
+
+func (m *Map) Delete(key any) {
+	m.count(func(s *MapStats) *int { return &s.Delete })
+	m.m.Delete(key)
+}
+
+func (m *Map) Load(key any) (any, bool) {
+	m.count(func(s *MapStats) *int { return &s.Load })
+	return m.m.Load(key)
+}
+
+func (m *Map) LoadAndDelete(key any) (any, bool) {
+	m.count(func(s *MapStats) *int { return &s.LoadAndDelete })
+	return m.m.LoadAndDelete(key)
+}
+
+func (m *Map) LoadOrStore(key, value any) (any, bool) {
+	m.count(func(s *MapStats) *int { return &s.LoadOrStore })
+	return m.m.LoadOrStore(key, value)
+}
+
+func (m *Map) Range(f func(key, value any) bool) {
+	m.count(func(s *MapStats) *int { return &s.Range })
+	m.m.Range(f)
+}
+
+func (m *Map) Store(key, value any) {
+	m.count(func(s *MapStats) *int { return &s.Store })
+	m.m.Store(key, value)
+}
+
+func (m *Map) Swap(key, value any) (any, bool) {
+	m.count(func(s *MapStats) *int { return &s.Swap })
+	return m.m.Swap(key, value)
+}
@@ -0,0 +1,138 @@
+package fake_sync
+
+import (
+	"testing"
+	"time"
+
+	synci "github.com/pdutton/go-interfaces/sync"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// TestStats_Mutex tests counting lock acquisitions, contention and
+// hold times.
+func TestStats_Mutex(t *testing.T) {
+	s := New(t)
+	mu := s.NewNamedMutex("mu")
+
+	mu.Lock()
+	testutil.AssertEqual(t, false, mu.TryLock())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mu.Lock()
+		mu.Unlock()
+	}()
+	waitBlocked(t, s, 1)
+	time.Sleep(10 * time.Millisecond)
+	mu.Unlock()
+	<-done
+
+	st := mu.Stats()
+	testutil.AssertEqual(t, "mu", st.Name)
+	testutil.AssertEqual(t, 2, st.Locks)
+	testutil.AssertEqual(t, 1, st.Contended)
+	testutil.AssertEqual(t, 1, st.TryLockFailures)
+	testutil.AssertEqual(t, true, st.MaxHold >= 10*time.Millisecond)
+	testutil.AssertEqual(t, 0, st.RLocks)
+}
+
+// TestStats_RWMutex tests asserting that a path only reads.
+func TestStats_RWMutex(t *testing.T) {
+	s := New(t)
+	rw := s.NewRWMutex()
+	s.NewMutex()
+
+	for range 3 {
+		rw.RLock()
+		rw.RUnlock()
+	}
+	testutil.AssertEqual(t, true, rw.TryRLock())
+	testutil.AssertEqual(t, false, rw.TryLock())
+	rw.RUnlock()
+
+	locks := s.Locks()
+	testutil.AssertEqual(t, 2, len(locks))
+	testutil.AssertEqual(t, "rwmutex 1", locks[0].Name)
+	testutil.AssertEqual(t, 4, locks[0].RLocks)
+	testutil.AssertEqual(t, 0, locks[0].Locks)
+	testutil.AssertEqual(t, 1, locks[0].TryLockFailures)
+	testutil.AssertEqual(t, 0, locks[0].TryRLockFailures)
+	testutil.AssertEqual(t, "mutex 2", locks[1].Name)
+}
+
+// TestStats_Initial tests that a lock made locked is not counted as
+// acquired.
+func TestStats_Initial(t *testing.T) {
+	s := New(t)
+	rw := s.NewNamedRWMutex("rw", synci.WithWLocked())
+	rw.Unlock()
+
+	testutil.AssertEqual(t, 0, rw.Stats().Locks)
+	testutil.AssertEqual(t, 0, len(s.Acquisitions()))
+}
+
+// TestStats_Pool tests counting pool hits and misses.
+func TestStats_Pool(t *testing.T) {
+	s := New(t)
+	p := s.NewNamedPool("buffers", synci.WithNew(func() any { return "new" }))
+
+	testutil.AssertEqual(t, "new", p.Get())
+	p.Put("pooled")
+	p.Put(nil)
+
+	// A real pool may drop values, so the second Get may miss too.
+	hit := p.Get() == "pooled"
+
+	st := p.Stats()
+	testutil.AssertEqual(t, "buffers", st.Name)
+	testutil.AssertEqual(t, 2, st.Gets)
+	testutil.AssertEqual(t, 1, st.Puts)
+	if hit {
+		testutil.AssertEqual(t, 1, st.Hits)
+		testutil.AssertEqual(t, 0.5, st.HitRatio())
+	} else {
+		testutil.AssertEqual(t, 2, st.Misses)
+		testutil.AssertEqual(t, 0.0, st.HitRatio())
+	}
+
+	empty := s.NewPool()
+	testutil.AssertNil(t, empty.Get())
+	testutil.AssertEqual(t, "pool 1", s.Pools()[1].Name)
+	testutil.AssertEqual(t, 1, s.Pools()[1].Misses)
+	testutil.AssertEqual(t, 0.0, PoolStats{}.HitRatio())
+}
+
+// TestStats_Map tests counting map operations.
+func TestStats_Map(t *testing.T) {
+	s := New(t)
+	m := s.NewMap()
+
+	m.Store("a", 1)
+	m.Store("b", 2)
+	m.Load("a")
+	m.LoadOrStore("c", 3)
+	m.Swap("a", 4)
+	m.CompareAndSwap("a", 4, 5)
+	m.CompareAndDelete("a", 5)
+	m.LoadAndDelete("b")
+	m.Delete("c")
+	m.Range(func(k, v any) bool { return true })
+	m.Clear()
+
+	st := s.Maps()[0]
+	testutil.AssertEqual(t, MapStats{
+		Name:             "map 1",
+		Clear:            1,
+		CompareAndDelete: 1,
+		CompareAndSwap:   1,
+		Delete:           1,
+		Load:             1,
+		LoadAndDelete:    1,
+		LoadOrStore:      1,
+		Range:            1,
+		Store:            2,
+		Swap:             1,
+	}, st)
+}
@@ -0,0 +1,436 @@
+// Package fake_sync provides implementations of the go-interfaces sync
+// interfaces that really synchronize, and watch how they are used.
+//
+// Every Mutex and RWMutex made by a Sync shares one record of which
+// goroutine acquired which lock while holding which others.  From it
+// the Sync reports lock-order inversions, where two locks are taken in
+// opposite orders and so can deadlock, even if the test never hits the
+// deadlock.  When the test ends, it also reports every goroutine still
+// blocked in Lock, RLock, or a WaitGroup or Cond Wait, with the stack
+// it blocked at:
+//
+//	func TestCache(t *testing.T) {
+//		s := fake_sync.New(t)
+//		c := cache.New(s) // calls s.NewMutex() and s.NewRWMutex()
+//		// ...
+//	}
+//
+// Each primitive also keeps statistics: how often a lock was taken,
+// contended and failed with TryLock and the longest it was held, the
+// hits and misses of a Pool, and the calls to each method of a Map.
+// A test can assert, say, that a hot path never takes a write lock:
+//
+//	c.Get("key")
+//	if n := s.Locks()[0].Locks; n != 0 {
+//		t.Errorf("Get took the write lock %d times", n)
+//	}
+package fake_sync
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	synci "github.com/pdutton/go-interfaces/sync"
+)
+
+// TB is the part of testing.TB a Sync reports through.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// Sync is a fake implementation of the go-interfaces Sync interface.
+type Sync struct {
+	mu      sync.Mutex
+	objects int
+
+	// held is the locks each goroutine holds, in acquisition order.
+	held map[int64][]holding
+
+	// edges records, for each pair of locks, where the second was
+	// first requested while holding the first.
+	edges map[[2]*lockInfo]string
+
+	acquisitions []Acquisition
+	inversions   []Inversion
+	blocked      map[*Blocked]struct{}
+
+	// The primitives made, for their statistics.
+	locks []*lockInfo
+	pools []*Pool
+	maps  []*Map
+}
+
+var _ synci.Sync = (*Sync)(nil)
+
+// lockInfo identifies a Mutex or RWMutex.
+type lockInfo struct {
+	name    string
+	writer  bool
+	readers int
+	stats   LockStats
+}
+
+type holding struct {
+	lock *lockInfo
+	read bool
+	at   time.Time
+}
+
+// is reports whether h is a holding of l in the given mode.
+func (h holding) is(l *lockInfo, read bool) bool {
+	return h.lock == l && h.read == read
+}
+
+// Acquisition is a lock acquired by a goroutine.
+type Acquisition struct {
+	Lock      string
+	Goroutine int64
+	Read      bool
+}
+
+func (a Acquisition) String() string {
+	var op = "Lock"
+	if a.Read {
+		op = "RLock"
+	}
+	return "goroutine " + strconv.FormatInt(a.Goroutine, 10) + ": " + op + " " + a.Lock
+}
+
+// Inversion is a pair of locks acquired in both orders.  Once one
+// goroutine holds First and waits for Second while another holds
+// Second and waits for First, neither can continue.
+type Inversion struct {
+	First, Second string
+
+	// Stack is where Second was requested while holding First, and
+	// ReverseStack where First was requested while holding Second.
+	Stack, ReverseStack string
+}
+
+func (inv Inversion) String() string {
+	return fmt.Sprintf("lock order inversion: %s acquired while holding %s at\n%s\nbut %s acquired while holding %s at\n%s",
+		inv.Second, inv.First, inv.Stack, inv.First, inv.Second, inv.ReverseStack)
+}
+
+// Blocked is a goroutine blocked in a call.
+type Blocked struct {
+	Goroutine int64
+	Op        string
+	Stack     string
+}
+
+func (b Blocked) String() string {
+	return fmt.Sprintf("goroutine %d blocked in %s at\n%s", b.Goroutine, b.Op, b.Stack)
+}
+
+// New returns a Sync that, when the test ends, reports lock-order
+// inversions and goroutines left blocked as errors on t.  With a nil t
+// nothing is reported, and a test inspects Inversions and Blocked
+// itself.
+func New(t TB) *Sync {
+	var s = &Sync{
+		held:    make(map[int64][]holding),
+		edges:   make(map[[2]*lockInfo]string),
+		blocked: make(map[*Blocked]struct{}),
+	}
+
+	if t != nil {
+		t.Cleanup(func() {
+			t.Helper()
+			for _, inv := range s.Inversions() {
+				t.Errorf("fake_sync: %v", inv)
+			}
+			for _, b := range s.Blocked() {
+				t.Errorf("fake_sync: %v", b)
+			}
+		})
+	}
+
+	return s
+}
+
+// Acquisitions returns every lock acquisition, in order.
+func (s *Sync) Acquisitions() []Acquisition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.acquisitions)
+}
+
+// Inversions returns the lock-order inversions seen, each pair of
+// locks once.
+func (s *Sync) Inversions() []Inversion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.inversions)
+}
+
+// Blocked returns the goroutines blocked in a Lock, RLock or Wait,
+// ordered by goroutine.
+func (s *Sync) Blocked() []Blocked {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var blocked []Blocked
+	for b := range s.blocked {
+		blocked = append(blocked, *b)
+	}
+	slices.SortFunc(blocked, func(a, b Blocked) int {
+		return int(a.Goroutine - b.Goroutine)
+	})
+
+	return blocked
+}
+
+// newName returns a name for reports made from kind and a number in
+// creation order.
+func (s *Sync) newName(kind string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects++
+	return kind + " " + strconv.Itoa(s.objects)
+}
+
+// newLock returns a lock named name, or a name made from kind.
+func (s *Sync) newLock(kind, name string) *lockInfo {
+	if name == "" {
+		name = s.newName(kind)
+	}
+
+	var l = &lockInfo{name: name, stats: LockStats{Name: name}}
+
+	s.mu.Lock()
+	s.locks = append(s.locks, l)
+	s.mu.Unlock()
+
+	return l
+}
+
+// request records goroutine g asking for l while holding its other
+// locks, and any inversion that creates.
+func (s *Sync) request(l *lockInfo, g int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stack string
+	for _, h := range s.held[g] {
+		if h.lock == l {
+			continue
+		}
+
+		var edge = [2]*lockInfo{h.lock, l}
+		if _, ok := s.edges[edge]; ok {
+			continue
+		}
+		if stack == "" {
+			stack = callers()
+		}
+		s.edges[edge] = stack
+
+		if reverse, ok := s.path(l, h.lock); ok {
+			s.inversions = append(s.inversions, Inversion{
+				First:        h.lock.name,
+				Second:       l.name,
+				Stack:        stack,
+				ReverseStack: reverse,
+			})
+		}
+	}
+}
+
+// path reports whether some goroutine has requested to after from,
+// directly or through other locks, and where the first step was
+// taken.
+func (s *Sync) path(from, to *lockInfo) (string, bool) {
+	var seen = map[*lockInfo]bool{from: true}
+	var queue = []*lockInfo{from}
+	var first = map[*lockInfo]string{}
+
+	for len(queue) > 0 {
+		var l = queue[0]
+		queue = queue[1:]
+
+		for edge, stack := range s.edges {
+			if edge[0] != l || seen[edge[1]] {
+				continue
+			}
+			seen[edge[1]] = true
+			if l == from {
+				first[edge[1]] = stack
+			} else {
+				first[edge[1]] = first[l]
+			}
+			if edge[1] == to {
+				return first[to], true
+			}
+			queue = append(queue, edge[1])
+		}
+	}
+
+	return "", false
+}
+
+// acquired records g acquiring l, after waiting for it if contended.
+func (s *Sync) acquired(l *lockInfo, g int64, read, contended bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if read {
+		l.readers++
+		l.stats.RLocks++
+		if contended {
+			l.stats.RContended++
+		}
+	} else {
+		l.writer = true
+		l.stats.Locks++
+		if contended {
+			l.stats.Contended++
+		}
+	}
+	s.held[g] = append(s.held[g], holding{lock: l, read: read, at: time.Now()})
+	s.acquisitions = append(s.acquisitions, Acquisition{Lock: l.name, Goroutine: g, Read: read})
+}
+
+// initiallyHeld records l being created locked by an option, which
+// is not counted as an acquisition.
+func (s *Sync) initiallyHeld(l *lockInfo, read bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if read {
+		l.readers++
+	} else {
+		l.writer = true
+	}
+	s.held[0] = append(s.held[0], holding{lock: l, read: read, at: time.Now()})
+}
+
+// tryFailed records a TryLock or TryRLock of l failing.
+func (s *Sync) tryFailed(l *lockInfo, read bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if read {
+		l.stats.TryRLockFailures++
+	} else {
+		l.stats.TryLockFailures++
+	}
+}
+
+// release records l being released by g, or by another goroutine if
+// g does not hold it.  It panics with msg if l is not held.
+func (s *Sync) release(l *lockInfo, g int64, read bool, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if read && l.readers == 0 || !read && !l.writer {
+		panic(msg)
+	}
+	if read {
+		l.readers--
+	} else {
+		l.writer = false
+	}
+
+	var match = func(h holding) bool { return h.is(l, read) }
+	if !slices.ContainsFunc(s.held[g], match) {
+		for other, held := range s.held {
+			if slices.ContainsFunc(held, match) {
+				g = other
+				break
+			}
+		}
+	}
+
+	var held = s.held[g]
+	if i := slices.IndexFunc(held, match); i >= 0 {
+		var hold = time.Since(held[i].at)
+		if read {
+			l.stats.MaxRHold = max(l.stats.MaxRHold, hold)
+		} else {
+			l.stats.MaxHold = max(l.stats.MaxHold, hold)
+		}
+		held = slices.Delete(held, i, i+1)
+	}
+	if len(held) == 0 {
+		delete(s.held, g)
+	} else {
+		s.held[g] = held
+	}
+}
+
+// block records g blocking in op, until the returned function is
+// called.
+func (s *Sync) block(g int64, op string) func() {
+	var b = &Blocked{Goroutine: g, Op: op, Stack: callers()}
+
+	s.mu.Lock()
+	s.blocked[b] = struct{}{}
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		delete(s.blocked, b)
+		s.mu.Unlock()
+	}
+}
+
+// OnceFunc returns a function that calls f only once, like
+// sync.OnceFunc.
+func (s *Sync) OnceFunc(f func()) func() {
+	return sync.OnceFunc(f)
+}
+
+// NewOnce returns a Once.
+func (s *Sync) NewOnce() synci.Once {
+	return &sync.Once{}
+}
+
+// goid returns the current goroutine's id.
+func goid() int64 {
+	var buf [64]byte
+	var b = buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	b = b[:bytes.IndexByte(b, ' ')]
+
+	var id, _ = strconv.ParseInt(string(b), 10, 64)
+	return id
+}
+
+// callers returns the current goroutine's stack, without its header
+// or the frames inside this package.
+func callers() string {
+	var buf = make([]byte, 16<<10)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	var lines = strings.Split(strings.TrimSpace(string(buf)), "\n")
+	var out []string
+	for i := 1; i+1 < len(lines); i += 2 {
+		var fn, file = lines[i], lines[i+1]
+		if strings.HasPrefix(fn, pkgPrefix) && !strings.Contains(file, "_test.go:") {
+			continue
+		}
+		out = append(out, fn, file)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// pkgPrefix starts the names of this package's functions in stacks.
+var pkgPrefix = func() string {
+	var pc, _, _, _ = runtime.Caller(0)
+	var name = runtime.FuncForPC(pc).Name()
+	var slash = strings.LastIndex(name, "/")
+	return name[:slash+strings.Index(name[slash:], ".")+1]
+}()
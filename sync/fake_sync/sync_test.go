@@ -0,0 +1,270 @@
+package fake_sync
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	synci "github.com/pdutton/go-interfaces/sync"
+
+	"github.com/pdutton/go-mocks/internal/testutil"
+)
+
+// reporter is a TB that records errors and cleanups instead of acting
+// on them.
+type reporter struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (r *reporter) Helper() {}
+func (r *reporter) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+func (r *reporter) Cleanup(f func()) { r.cleanups = append(r.cleanups, f) }
+
+func (r *reporter) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+// waitBlocked waits until n goroutines are blocked.
+func waitBlocked(t *testing.T, s *Sync, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); len(s.Blocked()) != n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines blocked, want %d", len(s.Blocked()), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestMutex_Exclusion tests that a Mutex really excludes.
+func TestMutex_Exclusion(t *testing.T) {
+	s := New(t)
+	mu := s.NewMutex()
+	wg := s.NewWaitGroup()
+
+	var n int
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				mu.Lock()
+				n++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	testutil.AssertEqual(t, 5000, n)
+	testutil.AssertEqual(t, 5000, len(s.Acquisitions()))
+}
+
+// TestMutex_Acquisitions tests recording the order locks are taken.
+func TestMutex_Acquisitions(t *testing.T) {
+	s := New(t)
+	a := s.NewNamedMutex("a")
+	b := s.NewNamedRWMutex("b")
+	c := s.NewMutex()
+	testutil.AssertEqual(t, "mutex 1", c.(*Mutex).Name())
+
+	a.Lock()
+	b.RLock()
+	testutil.AssertEqual(t, false, b.TryLock())
+	b.RUnlock()
+	a.Unlock()
+	testutil.AssertEqual(t, true, c.TryLock())
+	c.Unlock()
+
+	var got []string
+	for _, acq := range s.Acquisitions() {
+		got = append(got, strings.SplitN(acq.String(), ": ", 2)[1])
+	}
+	testutil.AssertEqual(t, "Lock a, RLock b, Lock mutex 1", strings.Join(got, ", "))
+}
+
+// TestMutex_Inversion tests detecting two locks taken in both orders,
+// without deadlocking.
+func TestMutex_Inversion(t *testing.T) {
+	s := New(nil)
+	a := s.NewNamedMutex("a")
+	b := s.NewNamedRWMutex("b")
+
+	a.Lock()
+	b.Lock()
+	b.Unlock()
+	a.Unlock()
+	testutil.AssertEqual(t, 0, len(s.Inversions()))
+
+	b.RLock()
+	a.Lock()
+	a.Unlock()
+	b.RUnlock()
+
+	inv := s.Inversions()
+	testutil.AssertEqual(t, 1, len(inv))
+	testutil.AssertEqual(t, "b", inv[0].First)
+	testutil.AssertEqual(t, "a", inv[0].Second)
+	testutil.AssertEqual(t, true, strings.Contains(inv[0].Stack, "fake_sync.TestMutex_Inversion"))
+	testutil.AssertEqual(t, false, strings.Contains(inv[0].Stack, "fake_sync.(*Mutex).Lock"))
+	testutil.AssertEqual(t, true, strings.HasPrefix(inv[0].String(), "lock order inversion: a acquired while holding b at\n"))
+
+	// Repeating the inversion does not report it again.
+	b.Lock()
+	a.Lock()
+	a.Unlock()
+	b.Unlock()
+	testutil.AssertEqual(t, 1, len(s.Inversions()))
+}
+
+// TestMutex_InversionCycle tests detecting an inversion through a
+// third lock, taken on different goroutines.
+func TestMutex_InversionCycle(t *testing.T) {
+	s := New(nil)
+	a, b, c := s.NewNamedMutex("a"), s.NewNamedMutex("b"), s.NewNamedMutex("c")
+
+	lockBoth := func(first, second synci.Mutex) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			first.Lock()
+			second.Lock()
+			second.Unlock()
+			first.Unlock()
+		}()
+		<-done
+	}
+
+	lockBoth(a, b)
+	lockBoth(b, c)
+	testutil.AssertEqual(t, 0, len(s.Inversions()))
+
+	lockBoth(c, a)
+	inv := s.Inversions()
+	testutil.AssertEqual(t, 1, len(inv))
+	testutil.AssertEqual(t, "c", inv[0].First)
+	testutil.AssertEqual(t, "a", inv[0].Second)
+}
+
+// TestSync_Blocked tests reporting goroutines left blocked when the
+// test ends.
+func TestSync_Blocked(t *testing.T) {
+	r := &reporter{}
+	s := New(r)
+	mu := s.NewNamedMutex("db", synci.WithLocked())
+	rw := s.NewNamedRWMutex("cache", synci.WithWLocked())
+	wg := s.NewWaitGroup(synci.WithCount(1))
+
+	go mu.Lock()
+	go rw.RLock()
+	go wg.Wait()
+	waitBlocked(t, s, 3)
+
+	r.finish()
+	testutil.AssertEqual(t, 3, len(r.errors))
+
+	msgs := strings.Join(r.errors, "\n")
+	testutil.AssertEqual(t, true, strings.Contains(msgs, "blocked in Lock of db at\n"))
+	testutil.AssertEqual(t, true, strings.Contains(msgs, "blocked in RLock of cache at\n"))
+	testutil.AssertEqual(t, true, strings.Contains(msgs, "blocked in Wait of waitgroup 1 at\n"))
+	testutil.AssertEqual(t, true, strings.Contains(msgs, "sync_test.go:"))
+
+	mu.Unlock()
+	rw.Unlock()
+	wg.Done()
+	waitBlocked(t, s, 0)
+}
+
+// TestCond_Wait tests waiting on a Cond over a Mutex.
+func TestCond_Wait(t *testing.T) {
+	s := New(t)
+	mu := s.NewNamedMutex("mu")
+	cond := s.NewCond(mu)
+
+	ready := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mu.Lock()
+		for !ready {
+			cond.Wait()
+		}
+		mu.Unlock()
+	}()
+	waitBlocked(t, s, 1)
+	testutil.AssertEqual(t, "Wait of cond 1", s.Blocked()[0].Op)
+
+	mu.Lock()
+	ready = true
+	cond.Broadcast()
+	mu.Unlock()
+	<-done
+}
+
+// TestMutex_Unlocked tests unlocking locks that are not locked.
+func TestMutex_Unlocked(t *testing.T) {
+	s := New(t)
+	mu := s.NewMutex()
+	rw := s.NewRWMutex(synci.WithRLocked())
+
+	testutil.AssertPanic(t, mu.Unlock, "unlock of unlocked mutex")
+	testutil.AssertPanic(t, rw.Unlock, "Unlock of unlocked RWMutex")
+
+	rw.RUnlock()
+	testutil.AssertPanic(t, rw.RUnlock, "RUnlock of unlocked RWMutex")
+
+	// A lock can be released by another goroutine.
+	mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		mu.Unlock()
+		close(done)
+	}()
+	<-done
+	testutil.AssertEqual(t, true, mu.TryLock())
+	mu.Unlock()
+}
+
+// TestRWMutex_RLocker tests the read Locker of an RWMutex.
+func TestRWMutex_RLocker(t *testing.T) {
+	s := New(t)
+	rw := s.NewRWMutex()
+	l := rw.RLocker()
+
+	l.Lock()
+	testutil.AssertEqual(t, true, rw.TryRLock())
+	testutil.AssertEqual(t, false, rw.TryLock())
+	rw.RUnlock()
+	l.Unlock()
+	testutil.AssertEqual(t, true, rw.TryLock())
+	rw.Unlock()
+}
+
+// TestSync_Others tests the primitives that are not tracked.
+func TestSync_Others(t *testing.T) {
+	s := New(t)
+
+	var n int
+	once := s.NewOnce()
+	once.Do(func() { n++ })
+	once.Do(func() { n++ })
+	f := s.OnceFunc(func() { n++ })
+	f()
+	f()
+	testutil.AssertEqual(t, 2, n)
+
+	m := s.NewMap()
+	m.Store("k", 1)
+	v, ok := m.Load("k")
+	testutil.AssertEqual(t, true, ok)
+	testutil.AssertEqual(t, 1, v)
+
+	p := s.NewPool(synci.WithNew(func() any { return "new" }))
+	testutil.AssertEqual(t, "new", p.Get())
+}
@@ -0,0 +1,80 @@
+package fake_sync
+
+import (
+	"sync"
+
+	synci "github.com/pdutton/go-interfaces/sync"
+)
+
+// WaitGroup is a wait group whose waiters are recorded by its Sync.
+type WaitGroup struct {
+	sync *Sync
+	name string
+	wg   sync.WaitGroup
+}
+
+var _ synci.WaitGroup = (*WaitGroup)(nil)
+
+// NewWaitGroup returns a WaitGroup with a count of zero, or the count
+// given by the WithCount option.
+func (s *Sync) NewWaitGroup(options ...synci.WaitGroupOption) synci.WaitGroup {
+	var wg = &WaitGroup{sync: s, name: s.newName("waitgroup")}
+	for _, opt := range options {
+		opt(&wg.wg)
+	}
+
+	return wg
+}
+
+// Add adds delta to the count.  It panics if the count goes negative.
+func (wg *WaitGroup) Add(delta int) {
+	wg.wg.Add(delta)
+}
+
+// Done decrements the count.
+func (wg *WaitGroup) Done() {
+	wg.wg.Done()
+}
+
+// Wait blocks until the count is zero.
+func (wg *WaitGroup) Wait() {
+	var unblock = wg.sync.block(goid(), "Wait of "+wg.name)
+	defer unblock()
+
+	wg.wg.Wait()
+}
+
+// Cond is a condition variable whose waiters are recorded by its
+// Sync.  Waiting unlocks and relocks its Locker, so a Mutex or RWMutex
+// from the same Sync sees the waiter release and reacquire it.
+type Cond struct {
+	sync *Sync
+	name string
+	cond *sync.Cond
+}
+
+var _ synci.Cond = (*Cond)(nil)
+
+// NewCond returns a Cond on l.
+func (s *Sync) NewCond(l synci.Locker) synci.Cond {
+	return &Cond{sync: s, name: s.newName("cond"), cond: sync.NewCond(l)}
+}
+
+// Wait unlocks the Locker, waits for Signal or Broadcast, and locks it
+// again.
+func (c *Cond) Wait() {
+	var unblock = c.sync.block(goid(), "Wait of "+c.name)
+	defer unblock()
+
+	c.cond.Wait()
+}
+
+// Signal wakes one waiting goroutine, if there is one.
+func (c *Cond) Signal() {
+	c.cond.Signal()
+}
+
+// Broadcast wakes all waiting goroutines.
+func (c *Cond) Broadcast() {
+	c.cond.Broadcast()
+}